@@ -17,7 +17,6 @@
 package caldav
 
 import (
-	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -31,6 +30,9 @@ import (
 // DateFormat is the caldav date format
 const DateFormat = `20060102T150405`
 
+// DateOnlyFormat is the caldav format for dates without a time, used for all-day events
+const DateOnlyFormat = `20060102`
+
 // Event holds a single caldav event
 type Event struct {
 	Summary     string
@@ -42,6 +44,8 @@ type Event struct {
 	Timestamp time.Time
 	Start     time.Time
 	End       time.Time
+	// If true, only the dates of start and end are used. The end date is exclusive.
+	AllDay bool
 }
 
 // Todo holds a single VTODO
@@ -57,7 +61,14 @@ type Todo struct {
 	Organizer    *user.User
 	Priority     int64 // 0-9, 1 is highest
 	RelatedToUID string
+	Relations    []Relation
 	Color        string
+	Categories   []string
+	Alarms       []Alarm
+	// 0-100
+	PercentComplete int64
+	// Properties holds raw ical lines which are added to the todo as they are
+	Properties string
 
 	Start    time.Time
 	End      time.Time
@@ -68,6 +79,20 @@ type Todo struct {
 	Updated time.Time // last-mod
 }
 
+// Relation holds a single RELATED-TO property of a todo
+type Relation struct {
+	// One of the RelationType* constants
+	Type string
+	UID  string
+}
+
+// All relation types a todo can have
+const (
+	RelationTypeParent  = `PARENT`
+	RelationTypeChild   = `CHILD`
+	RelationTypeSibling = `SIBLING`
+)
+
 // Alarm holds infos about an alarm from a caldav event
 type Alarm struct {
 	Time        time.Time
@@ -124,9 +149,17 @@ BEGIN:VEVENT
 UID:` + e.UID + `
 SUMMARY:` + e.Summary + getCaldavColor(e.Color) + `
 DESCRIPTION:` + formattedDescription + `
-DTSTAMP:` + makeCalDavTimeFromTimeStamp(e.Timestamp) + `
+DTSTAMP:` + makeCalDavTimeFromTimeStamp(e.Timestamp)
+
+		if e.AllDay {
+			caldavevents += `
+DTSTART;VALUE=DATE:` + makeCalDavDateFromTimeStamp(e.Start) + `
+DTEND;VALUE=DATE:` + makeCalDavDateFromTimeStamp(e.End)
+		} else {
+			caldavevents += `
 DTSTART:` + makeCalDavTimeFromTimeStamp(e.Start) + `
 DTEND:` + makeCalDavTimeFromTimeStamp(e.End)
+		}
 
 		for _, a := range e.Alarms {
 			if a.Description == "" {
@@ -172,11 +205,11 @@ SUMMARY:` + t.Summary + getCaldavColor(t.Color)
 
 		if t.Start.Unix() > 0 {
 			caldavtodos += `
-DTSTART: ` + makeCalDavTimeFromTimeStamp(t.Start)
+DTSTART:` + makeCalDavTimeFromTimeStamp(t.Start)
 		}
 		if t.End.Unix() > 0 {
 			caldavtodos += `
-DTEND: ` + makeCalDavTimeFromTimeStamp(t.End)
+DTEND:` + makeCalDavTimeFromTimeStamp(t.End)
 		}
 		if t.Description != "" {
 			re := regexp.MustCompile(`\r?\n`)
@@ -209,9 +242,9 @@ DUE:` + makeCalDavTimeFromTimeStamp(t.DueDate)
 CREATED:` + makeCalDavTimeFromTimeStamp(t.Created)
 		}
 
-		if t.Duration != 0 {
+		if t.Duration > 0 {
 			caldavtodos += `
-DURATION:PT` + fmt.Sprintf("%.6f", t.Duration.Hours()) + `H` + fmt.Sprintf("%.6f", t.Duration.Minutes()) + `M` + fmt.Sprintf("%.6f", t.Duration.Seconds()) + `S`
+DURATION:` + makeCalDavDuration(t.Duration)
 		}
 
 		if t.Priority != 0 {
@@ -219,9 +252,45 @@ DURATION:PT` + fmt.Sprintf("%.6f", t.Duration.Hours()) + `H` + fmt.Sprintf("%.6f
 PRIORITY:` + strconv.Itoa(int(t.Priority))
 		}
 
+		if t.PercentComplete > 0 {
+			caldavtodos += `
+PERCENT-COMPLETE:` + strconv.FormatInt(t.PercentComplete, 10)
+		}
+
+		if len(t.Categories) > 0 {
+			categories := make([]string, 0, len(t.Categories))
+			for _, c := range t.Categories {
+				categories = append(categories, strings.ReplaceAll(c, ",", `\,`))
+			}
+			caldavtodos += `
+CATEGORIES:` + strings.Join(categories, ",")
+		}
+
+		for _, r := range t.Relations {
+			caldavtodos += `
+RELATED-TO;RELTYPE=` + r.Type + `:` + r.UID
+		}
+
+		if t.Properties != "" {
+			caldavtodos += "\n" + strings.TrimSpace(t.Properties)
+		}
+
 		caldavtodos += `
 LAST-MODIFIED:` + makeCalDavTimeFromTimeStamp(t.Updated)
 
+		for _, a := range t.Alarms {
+			if a.Description == "" {
+				a.Description = t.Summary
+			}
+
+			caldavtodos += `
+BEGIN:VALARM
+TRIGGER;VALUE=DATE-TIME:` + makeCalDavUTCTimeFromTimeStamp(a.Time) + `
+ACTION:DISPLAY
+DESCRIPTION:` + a.Description + `
+END:VALARM`
+		}
+
 		caldavtodos += `
 END:VTODO`
 	}
@@ -236,6 +305,22 @@ func makeCalDavTimeFromTimeStamp(ts time.Time) (caldavtime string) {
 	return ts.In(config.GetTimeZone()).Format(DateFormat)
 }
 
+func makeCalDavDateFromTimeStamp(ts time.Time) (caldavdate string) {
+	return ts.In(config.GetTimeZone()).Format(DateOnlyFormat)
+}
+
+func makeCalDavUTCTimeFromTimeStamp(ts time.Time) (caldavtime string) {
+	return ts.UTC().Format(DateFormat) + `Z`
+}
+
+// https://tools.ietf.org/html/rfc5545#section-3.3.6
+func makeCalDavDuration(d time.Duration) (duration string) {
+	seconds := int64(d.Seconds())
+	return `PT` + strconv.FormatInt(seconds/3600, 10) + `H` +
+		strconv.FormatInt(seconds%3600/60, 10) + `M` +
+		strconv.FormatInt(seconds%60, 10) + `S`
+}
+
 func calcAlarmDateFromReminder(eventStart, reminder time.Time) (alarmTime string) {
 	diff := reminder.Sub(eventStart)
 	diffStr := strings.ToUpper(diff.String())
@@ -293,6 +293,35 @@ END:VCALENDAR`,
 	}
 }
 
+func TestParseEventsAllDay(t *testing.T) {
+	events := []*Event{
+		{
+			Summary:   "All day event",
+			UID:       "randommduid",
+			Timestamp: time.Unix(1543626724, 0).In(config.GetTimeZone()),
+			Start:     time.Unix(1543626724, 0).In(config.GetTimeZone()),
+			End:       time.Unix(1543626724, 0).In(config.GetTimeZone()).AddDate(0, 0, 1),
+			AllDay:    true,
+		},
+	}
+
+	assert.Equal(t, `BEGIN:VCALENDAR
+VERSION:2.0
+METHOD:PUBLISH
+X-PUBLISHED-TTL:PT4H
+X-WR-CALNAME:test
+PRODID:-//RandomProdID which is not random//EN
+BEGIN:VEVENT
+UID:randommduid
+SUMMARY:All day event
+DESCRIPTION:
+DTSTAMP:20181201T011204
+DTSTART;VALUE=DATE:20181201
+DTEND;VALUE=DATE:20181202
+END:VEVENT
+END:VCALENDAR`, ParseEvents(&Config{Name: "test", ProdID: "RandomProdID which is not random"}, events))
+}
+
 func TestParseTodos(t *testing.T) {
 	type args struct {
 		config *Config
@@ -375,6 +404,60 @@ COMPLETED:20181201T013024
 STATUS:COMPLETED
 LAST-MODIFIED:00010101T000000
 END:VTODO
+END:VCALENDAR`,
+		},
+		{
+			name: "Test caldavparsing with categories, alarms, relations and unknown properties",
+			args: args{
+				config: &Config{
+					Name:   "test",
+					ProdID: "RandomProdID which is not random",
+				},
+				todos: []*Todo{
+					{
+						Summary:         "Todo #1",
+						UID:             "randommduid",
+						Timestamp:       time.Unix(1543626724, 0).In(config.GetTimeZone()),
+						Start:           time.Unix(1543626724, 0).In(config.GetTimeZone()),
+						Duration:        time.Hour + 30*time.Minute,
+						PercentComplete: 50,
+						Categories:      []string{"Label #1", "Label, with comma"},
+						Relations: []Relation{
+							{Type: RelationTypeParent, UID: "parentuid"},
+							{Type: RelationTypeChild, UID: "childuid"},
+						},
+						Alarms: []Alarm{
+							{Time: time.Unix(1543626724, 0).In(config.GetTimeZone())},
+						},
+						Properties: "X-CUSTOM-PROPERTY:Some value\nCLASS:PRIVATE",
+					},
+				},
+			},
+			wantCaldavtasks: `BEGIN:VCALENDAR
+VERSION:2.0
+METHOD:PUBLISH
+X-PUBLISHED-TTL:PT4H
+X-WR-CALNAME:test
+PRODID:-//RandomProdID which is not random//EN
+BEGIN:VTODO
+UID:randommduid
+DTSTAMP:20181201T011204
+SUMMARY:Todo #1
+DTSTART:20181201T011204
+DURATION:PT1H30M0S
+PERCENT-COMPLETE:50
+CATEGORIES:Label #1,Label\, with comma
+RELATED-TO;RELTYPE=PARENT:parentuid
+RELATED-TO;RELTYPE=CHILD:childuid
+X-CUSTOM-PROPERTY:Some value
+CLASS:PRIVATE
+LAST-MODIFIED:00010101T000000
+BEGIN:VALARM
+TRIGGER;VALUE=DATE-TIME:20181201T011204Z
+ACTION:DISPLAY
+DESCRIPTION:Todo #1
+END:VALARM
+END:VTODO
 END:VCALENDAR`,
 		},
 	}
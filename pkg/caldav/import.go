@@ -0,0 +1,242 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/utils"
+	"github.com/laurent22/ical-go"
+)
+
+// SkippedComponent is a component of an ical file which was not imported
+type SkippedComponent struct {
+	// The type of the component, for example `VEVENT` or `VJOURNAL`.
+	Component string `json:"component"`
+	// The uid of the component, if it has one.
+	UID string `json:"uid"`
+	// The summary of the component, if it has one.
+	Summary string `json:"summary"`
+	// Why the component was not imported.
+	Reason string `json:"reason"`
+}
+
+func (sc *SkippedComponent) String() string {
+	name := sc.Component
+	if sc.Summary != "" {
+		name += ` "` + sc.Summary + `"`
+	}
+	if sc.UID != "" {
+		name += ` (` + sc.UID + `)`
+	}
+	return name + ` was not imported: ` + sc.Reason
+}
+
+// ICalImport holds all tasks of an ical file
+type ICalImport struct {
+	// The name of the calendar, if the file contains one
+	Title string
+	// All tasks of the file. Relations between tasks of the file are set as related tasks, labels only have a title.
+	// Relations to tasks which are not part of the file are dropped.
+	Tasks []*models.Task
+	// All components of the file which can't be imported
+	Skipped []*SkippedComponent
+}
+
+var inverseImportRelationKinds = map[models.RelationKind]models.RelationKind{
+	models.RelationKindParenttask: models.RelationKindSubtask,
+	models.RelationKindSubtask:    models.RelationKindParenttask,
+	models.RelationKindRelated:    models.RelationKindRelated,
+}
+
+func childValue(node *ical.Node, name string) string {
+	if c := node.ChildByName(name); c != nil {
+		return c.Value
+	}
+	return ""
+}
+
+// ParseICalImport converts all VTODOs and, if withEvents is true, all VEVENTs of an ical file into tasks.
+// Components which can't be converted don't stop the import, they are returned as skipped instead.
+func ParseICalImport(r io.Reader, withEvents bool) (imp *ICalImport, err error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := ical.ParseCalendar(string(content))
+	if err != nil {
+		return nil, models.ErrInvalidMigrationFile{Reason: err.Error()}
+	}
+	if parsed.Name != "VCALENDAR" {
+		return nil, models.ErrInvalidMigrationFile{Reason: "the file is not an ical file"}
+	}
+
+	imp = &ICalImport{}
+
+	skip := func(c *ical.Node, reason string) {
+		imp.Skipped = append(imp.Skipped, &SkippedComponent{
+			Component: c.Name,
+			UID:       childValue(c, "UID"),
+			Summary:   childValue(c, "SUMMARY"),
+			Reason:    reason,
+		})
+	}
+
+	var parsedTasks []*ParsedTask
+	uids := make(map[string]bool)
+	for _, c := range parsed.Children {
+		var ct *ParsedTask
+		switch {
+		case c.Name == "X-WR-CALNAME":
+			imp.Title = c.Value
+			continue
+		case c.Name == "VTODO":
+			ct, err = parseVTODO(c)
+		case c.Name == "VEVENT" && withEvents:
+			ct, err = parseVEVENT(c)
+		case c.Name == "VEVENT":
+			skip(c, "importing events was not enabled")
+			continue
+		case c.Type == 1 && c.Name != "VTIMEZONE":
+			skip(c, "only tasks and events can be imported")
+			continue
+		default:
+			// Properties of the calendar itself and time zones
+			continue
+		}
+		if err != nil {
+			skip(c, err.Error())
+			continue
+		}
+		if ct.Task.Title == "" {
+			skip(c, "it does not have a summary")
+			continue
+		}
+
+		// Uids are kept so caldav clients which synced the same tasks before match them with the imported ones
+		if ct.Task.UID == "" {
+			ct.Task.UID = utils.MakeRandomString(40)
+		}
+		if uids[ct.Task.UID] {
+			skip(c, "another component of the file has the same uid")
+			continue
+		}
+		uids[ct.Task.UID] = true
+
+		for _, title := range ct.Labels {
+			ct.Task.Labels = append(ct.Task.Labels, &models.Label{Title: title})
+		}
+
+		parsedTasks = append(parsedTasks, ct)
+		imp.Tasks = append(imp.Tasks, ct.Task)
+	}
+
+	imp.resolveRelations(parsedTasks)
+
+	return imp, nil
+}
+
+// resolveRelations sets the relations between tasks of the file as related tasks.
+func (imp *ICalImport) resolveRelations(parsedTasks []*ParsedTask) {
+	tasksByUID := make(map[string]*models.Task, len(parsedTasks))
+	for _, ct := range parsedTasks {
+		tasksByUID[ct.Task.UID] = ct.Task
+	}
+
+	// Relations always exist in both directions, often both tasks of a relation reference each other
+	relations := make(map[string]bool)
+	for _, ct := range parsedTasks {
+		for _, kind := range []models.RelationKind{models.RelationKindParenttask, models.RelationKindSubtask, models.RelationKindRelated} {
+			for _, uid := range ct.Related[kind] {
+				other, exists := tasksByUID[uid]
+				if !exists || other == ct.Task {
+					continue
+				}
+
+				key := ct.Task.UID + "|" + other.UID + "|" + string(kind)
+				if other.UID < ct.Task.UID {
+					key = other.UID + "|" + ct.Task.UID + "|" + string(inverseImportRelationKinds[kind])
+				}
+				if relations[key] {
+					continue
+				}
+				relations[key] = true
+
+				if ct.Task.RelatedTasks == nil {
+					ct.Task.RelatedTasks = make(models.RelatedTaskMap)
+				}
+				ct.Task.RelatedTasks[kind] = append(ct.Task.RelatedTasks[kind], other)
+			}
+		}
+	}
+}
+
+// parseVEVENT converts a single parsed VEVENT component into a task, the opposite of getCaldavEventForTask
+func parseVEVENT(event *ical.Node) (ct *ParsedTask, err error) {
+	if event.ChildByName("RRULE") != nil || event.ChildByName("RECURRENCE-ID") != nil {
+		return nil, errors.New("recurring events can't be imported")
+	}
+
+	start, end, allDay, err := parseEventDates(event)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := make(map[string]*ical.Node)
+	for _, entry := range event.Children {
+		properties[entry.Name] = entry
+	}
+
+	task := &models.Task{
+		UID:         childValue(event, "UID"),
+		Title:       childValue(event, "SUMMARY"),
+		Description: childValue(event, "DESCRIPTION"),
+		HexColor:    getHexColorFromCaldav(properties),
+	}
+
+	// All-day events on a single day are shown for tasks with only one date
+	if allDay && !end.After(start.AddDate(0, 0, 1)) {
+		task.DueDate = start
+	} else {
+		task.StartDate = start
+		task.EndDate = end
+	}
+
+	ct = &ParsedTask{
+		Task:    task,
+		Related: make(map[models.RelationKind][]string),
+	}
+	for _, entry := range event.Children {
+		switch entry.Name {
+		case "CATEGORIES":
+			ct.Labels = append(ct.Labels, splitCaldavList(entry.Value)...)
+		case "RELATED-TO":
+			kind := getRelationKindFromCaldav(entry)
+			ct.Related[kind] = append(ct.Related[kind], entry.Value)
+		case "VALARM":
+			if reminder := getReminderFromVALARM(entry, task); !reminder.IsZero() {
+				task.Reminders = append(task.Reminders, reminder)
+			}
+		}
+	}
+
+	return ct, nil
+}
@@ -0,0 +1,124 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testICalImport = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Some client//EN
+X-WR-CALNAME:Work
+BEGIN:VTODO
+UID:parent
+SUMMARY:Parent
+CATEGORIES:Label #1
+LOCATION:Office
+END:VTODO
+BEGIN:VTODO
+UID:child
+SUMMARY:Child
+RELATED-TO:parent
+RELATED-TO:unknown
+END:VTODO
+BEGIN:VTODO
+UID:parent
+SUMMARY:Duplicate
+END:VTODO
+BEGIN:VTODO
+UID:notitle
+END:VTODO
+BEGIN:VEVENT
+UID:event
+SUMMARY:Meeting
+DTSTART:20210301T100000Z
+DTEND:20210301T110000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:allday
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20210302
+DTEND;VALUE=DATE:20210303
+END:VEVENT
+BEGIN:VEVENT
+UID:recurring
+SUMMARY:Weekly
+DTSTART:20210301T100000Z
+RRULE:FREQ=WEEKLY
+END:VEVENT
+BEGIN:VJOURNAL
+UID:journal
+SUMMARY:Notes
+END:VJOURNAL
+END:VCALENDAR`
+
+func TestParseICalImport(t *testing.T) {
+	t.Run("tasks only", func(t *testing.T) {
+		imp, err := ParseICalImport(strings.NewReader(testICalImport), false)
+		assert.NoError(t, err)
+		assert.Equal(t, "Work", imp.Title)
+		assert.Len(t, imp.Tasks, 2)
+
+		parent := imp.Tasks[0]
+		child := imp.Tasks[1]
+		assert.Equal(t, "parent", parent.UID)
+		assert.Equal(t, "Label #1", parent.Labels[0].Title)
+		assert.Equal(t, "child", child.UID)
+		assert.Equal(t, []*models.Task{parent}, child.RelatedTasks[models.RelationKindParenttask])
+		assert.Len(t, child.RelatedTasks, 1)
+
+		reasons := make(map[string]string)
+		for _, s := range imp.Skipped {
+			reasons[s.Component+"/"+s.UID] = s.Reason
+		}
+		assert.Len(t, imp.Skipped, 6)
+		assert.Equal(t, "another component of the file has the same uid", reasons["VTODO/parent"])
+		assert.Equal(t, "it does not have a summary", reasons["VTODO/notitle"])
+		assert.Equal(t, "importing events was not enabled", reasons["VEVENT/event"])
+		assert.Equal(t, "only tasks and events can be imported", reasons["VJOURNAL/journal"])
+	})
+	t.Run("with events", func(t *testing.T) {
+		imp, err := ParseICalImport(strings.NewReader(testICalImport), true)
+		assert.NoError(t, err)
+		assert.Len(t, imp.Tasks, 4)
+
+		event := imp.Tasks[2]
+		assert.Equal(t, "event", event.UID)
+		assert.Equal(t, time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC), event.StartDate.UTC())
+		assert.Equal(t, time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC), event.EndDate.UTC())
+
+		allDay := imp.Tasks[3]
+		assert.Equal(t, "allday", allDay.UID)
+		assert.True(t, allDay.StartDate.IsZero())
+		assert.Equal(t, 2, allDay.DueDate.Day())
+
+		assert.Len(t, imp.Skipped, 4)
+		assert.Equal(t, "recurring", imp.Skipped[2].UID)
+		assert.Equal(t, "recurring events can't be imported", imp.Skipped[2].Reason)
+	})
+	t.Run("invalid file", func(t *testing.T) {
+		_, err := ParseICalImport(strings.NewReader("not a calendar"), false)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+}
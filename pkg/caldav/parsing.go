@@ -0,0 +1,437 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/laurent22/ical-go"
+)
+
+// All properties of a VTODO we map to task fields. Everything else is kept as it is.
+var knownVTODOProperties = map[string]bool{
+	"UID":                    true,
+	"DTSTAMP":                true,
+	"SUMMARY":                true,
+	"DESCRIPTION":            true,
+	"PRIORITY":               true,
+	"DUE":                    true,
+	"DTSTART":                true,
+	"DTEND":                  true,
+	"DURATION":               true,
+	"COMPLETED":              true,
+	"STATUS":                 true,
+	"CREATED":                true,
+	"LAST-MODIFIED":          true,
+	"CATEGORIES":             true,
+	"RELATED-TO":             true,
+	"PERCENT-COMPLETE":       true,
+	"X-APPLE-CALENDAR-COLOR": true,
+	"X-OUTLOOK-COLOR":        true,
+	"X-FUNAMBOL-COLOR":       true,
+	"VALARM":                 true,
+}
+
+// RelationTypes maps the relation kinds of Vikunja to the RELTYPE of caldav relations
+var RelationTypes = map[models.RelationKind]string{
+	models.RelationKindParenttask: RelationTypeParent,
+	models.RelationKindSubtask:    RelationTypeChild,
+	models.RelationKindRelated:    RelationTypeSibling,
+}
+
+// ParsedTask holds everything we could get out of a VTODO or VEVENT, including the things which don't map
+// directly to fields of a task.
+type ParsedTask struct {
+	Task *models.Task
+	// Titles of all categories of the task
+	Labels []string
+	// All related tasks, referenced by their uid
+	Related map[models.RelationKind][]string
+	// All properties Vikunja does not know about as raw ical lines
+	Properties []string
+}
+
+// ParseTaskFromVTODO converts the first VTODO of a calendar into a task
+func ParseTaskFromVTODO(content string) (ct *ParsedTask, err error) {
+	parsed, err := ical.ParseCalendar(content)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the first task is processed
+	vtodo := &ical.Node{Name: "VTODO"}
+	for _, c := range parsed.Children {
+		if c.Name == "VTODO" {
+			vtodo = c
+			break
+		}
+	}
+
+	return parseVTODO(vtodo)
+}
+
+// parseVTODO converts a single parsed VTODO component into a task
+func parseVTODO(vtodo *ical.Node) (ct *ParsedTask, err error) {
+	// We put the task details in a map to be able to handle them more easily
+	task := make(map[string]*ical.Node)
+	ct = &ParsedTask{
+		Related: make(map[models.RelationKind][]string),
+	}
+	var alarms []*ical.Node
+	for _, entry := range vtodo.Children {
+		switch entry.Name {
+		case "CATEGORIES":
+			ct.Labels = append(ct.Labels, splitCaldavList(entry.Value)...)
+		case "RELATED-TO":
+			kind := getRelationKindFromCaldav(entry)
+			ct.Related[kind] = append(ct.Related[kind], entry.Value)
+		case "VALARM":
+			alarms = append(alarms, entry)
+		}
+
+		if !knownVTODOProperties[entry.Name] {
+			ct.Properties = append(ct.Properties, serializeCaldavNode(entry)...)
+			continue
+		}
+		task[entry.Name] = entry
+	}
+
+	value := func(name string) string {
+		if n, has := task[name]; has {
+			return n.Value
+		}
+		return ""
+	}
+
+	// Parse the UID
+	var priority int64
+	if _, ok := task["PRIORITY"]; ok {
+		priority, err = strconv.ParseInt(value("PRIORITY"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var percentDone float64
+	if _, ok := task["PERCENT-COMPLETE"]; ok {
+		percent, err := strconv.ParseInt(value("PERCENT-COMPLETE"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		percentDone = float64(percent) / 100
+	}
+
+	vTask := &models.Task{
+		UID:         value("UID"),
+		Title:       value("SUMMARY"),
+		Description: value("DESCRIPTION"),
+		Priority:    priority,
+		DueDate:     caldavTimeToTimestamp(value("DUE")),
+		Updated:     caldavTimeToTimestamp(value("DTSTAMP")),
+		StartDate:   caldavTimeToTimestamp(value("DTSTART")),
+		EndDate:     caldavTimeToTimestamp(value("DTEND")),
+		DoneAt:      caldavTimeToTimestamp(value("COMPLETED")),
+		PercentDone: percentDone,
+		HexColor:    getHexColorFromCaldav(task),
+	}
+	ct.Task = vTask
+
+	if value("STATUS") == "COMPLETED" {
+		vTask.Done = true
+	}
+
+	// Parse the enddate
+	duration := parseCaldavDuration(value("DURATION"))
+	if duration > 0 && !vTask.StartDate.IsZero() && vTask.EndDate.IsZero() {
+		vTask.EndDate = vTask.StartDate.Add(duration)
+	}
+
+	for _, alarm := range alarms {
+		reminder := getReminderFromVALARM(alarm, vTask)
+		if reminder.IsZero() {
+			// Alarms we can't convert to a reminder are kept as they are
+			ct.Properties = append(ct.Properties, serializeCaldavNode(alarm)...)
+			continue
+		}
+		vTask.Reminders = append(vTask.Reminders, reminder)
+	}
+
+	return
+}
+
+// UnresolvedRelationProperties returns the raw RELATED-TO properties for all relations which could not be resolved
+func UnresolvedRelationProperties(unresolved map[models.RelationKind][]string) (properties []string) {
+	for _, kind := range []models.RelationKind{models.RelationKindParenttask, models.RelationKindSubtask, models.RelationKindRelated} {
+		for _, uid := range unresolved[kind] {
+			properties = append(properties, `RELATED-TO;RELTYPE=`+RelationTypes[kind]+`:`+uid)
+		}
+	}
+	return
+}
+
+// https://tools.ietf.org/html/rfc5545#section-3.8.4.5
+func getRelationKindFromCaldav(node *ical.Node) models.RelationKind {
+	switch strings.ToUpper(node.Parameters["RELTYPE"]) {
+	case RelationTypeChild:
+		return models.RelationKindSubtask
+	case RelationTypeSibling:
+		return models.RelationKindRelated
+	default:
+		// PARENT is the default if no type is given
+		return models.RelationKindParenttask
+	}
+}
+
+func getHexColorFromCaldav(task map[string]*ical.Node) string {
+	for _, name := range []string{"X-APPLE-CALENDAR-COLOR", "X-OUTLOOK-COLOR", "X-FUNAMBOL-COLOR"} {
+		n, has := task[name]
+		if !has {
+			continue
+		}
+
+		color := strings.TrimPrefix(n.Value, "#")
+		// Caldav colors may contain an alpha channel which we don't support
+		if len(color) == 8 {
+			color = color[:6]
+		}
+		if len(color) == 6 {
+			return color
+		}
+	}
+
+	return ""
+}
+
+// https://tools.ietf.org/html/rfc5545#section-3.8.6.3
+func getReminderFromVALARM(alarm *ical.Node, task *models.Task) time.Time {
+	trigger := alarm.ChildByName("TRIGGER")
+	if trigger == nil {
+		return time.Time{}
+	}
+
+	if trigger.Parameters["VALUE"] == "DATE-TIME" {
+		return caldavTimeToTimestamp(trigger.Value)
+	}
+
+	// Relative triggers are relative to the start of a todo by default
+	relativeTo := task.StartDate
+	if trigger.Parameters["RELATED"] == "END" || relativeTo.IsZero() {
+		relativeTo = task.DueDate
+	}
+	if relativeTo.IsZero() {
+		return time.Time{}
+	}
+
+	if !strings.Contains(trigger.Value, "P") {
+		return time.Time{}
+	}
+
+	return relativeTo.Add(parseCaldavDuration(trigger.Value))
+}
+
+// https://tools.ietf.org/html/rfc5545#section-3.3.6
+var caldavDurationRegex = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func parseCaldavDuration(value string) (duration time.Duration) {
+	parts := caldavDurationRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if parts == nil {
+		return 0
+	}
+
+	units := []time.Duration{0, 0, 7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i := 2; i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		amount, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return 0
+		}
+		duration += time.Duration(amount) * units[i]
+	}
+
+	if parts[1] == "-" {
+		duration *= -1
+	}
+
+	return
+}
+
+// Splits a comma separated ical value while respecting escaped commas
+func splitCaldavList(value string) (values []string) {
+	var current strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			values = append(values, strings.TrimSpace(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	values = append(values, strings.TrimSpace(current.String()))
+
+	// Remove empty values
+	result := values[:0]
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// serializeCaldavNode turns a parsed ical node back into its raw lines
+func serializeCaldavNode(node *ical.Node) (lines []string) {
+	if node.Type == 1 {
+		lines = append(lines, "BEGIN:"+node.Name)
+		for _, c := range node.Children {
+			lines = append(lines, serializeCaldavNode(c)...)
+		}
+		return append(lines, "END:"+node.Name)
+	}
+
+	params := make([]string, 0, len(node.Parameters))
+	for k := range node.Parameters {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+
+	line := node.Name
+	for _, k := range params {
+		line += ";" + k + "=" + node.Parameters[k]
+	}
+	return append(lines, line+":"+node.Value)
+}
+
+// https://tools.ietf.org/html/rfc5545#section-3.3.5
+func caldavTimeToTimestamp(tstring string) time.Time {
+	if tstring == "" {
+		return time.Time{}
+	}
+
+	format := DateFormat
+
+	if strings.HasSuffix(tstring, "Z") {
+		format = `20060102T150405Z`
+	}
+
+	t, err := time.Parse(format, tstring)
+	if err != nil {
+		log.Warningf("Error while parsing caldav time %s to TimeStamp: %s", tstring, err)
+		return time.Time{}
+	}
+	return t
+}
+
+// ParseEventDatesFromVEVENT returns the start and end time of the first event in a calendar.
+// The end time of all-day events is exclusive, as in caldav.
+func ParseEventDatesFromVEVENT(content string) (start, end time.Time, allDay bool, err error) {
+	parsed, err := ical.ParseCalendar(content)
+	if err != nil {
+		return
+	}
+
+	var event *ical.Node
+	for _, c := range parsed.Children {
+		if c.Name == "VEVENT" {
+			event = c
+			break
+		}
+	}
+	if event == nil {
+		return start, end, false, errors.New("calendar does not contain an event")
+	}
+
+	return parseEventDates(event)
+}
+
+// parseEventDates returns the start and end time of a single parsed VEVENT component
+func parseEventDates(event *ical.Node) (start, end time.Time, allDay bool, err error) {
+	dtstart := event.ChildByName("DTSTART")
+	if dtstart == nil {
+		return start, end, false, errors.New("event has no start date")
+	}
+	start, allDay = caldavEventTimeToTimestamp(dtstart)
+	if start.IsZero() {
+		return start, end, false, errors.New("event has an invalid start date")
+	}
+
+	if dtend := event.ChildByName("DTEND"); dtend != nil {
+		end, _ = caldavEventTimeToTimestamp(dtend)
+	}
+	if end.IsZero() {
+		if duration := event.ChildByName("DURATION"); duration != nil {
+			end = start.Add(parseCaldavDuration(duration.Value))
+		}
+	}
+	if end.IsZero() {
+		// https://tools.ietf.org/html/rfc5545#section-3.6.1
+		end = start
+		if allDay {
+			end = start.AddDate(0, 0, 1)
+		}
+	}
+
+	return
+}
+
+// caldavEventTimeToTimestamp parses a date or date-time property, respecting its time zone.
+// Times without a time zone are interpreted in the configured time zone of Vikunja.
+func caldavEventTimeToTimestamp(node *ical.Node) (t time.Time, isDate bool) {
+	loc := config.GetTimeZone()
+	if tzid, has := node.Parameters["TZID"]; has {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			log.Warningf("Unknown time zone %s in caldav event, using %s instead", tzid, loc)
+		} else {
+			loc = l
+		}
+	}
+
+	value := node.Value
+	format := DateFormat
+	switch {
+	case node.Parameters["VALUE"] == "DATE" || len(value) == len(DateOnlyFormat):
+		format = DateOnlyFormat
+		isDate = true
+	case strings.HasSuffix(value, "Z"):
+		format = `20060102T150405Z`
+		loc = time.UTC
+	}
+
+	t, err := time.ParseInLocation(format, value, loc)
+	if err != nil {
+		log.Warningf("Error while parsing caldav time %s to TimeStamp: %s", value, err)
+		return time.Time{}, false
+	}
+	return t, isDate
+}
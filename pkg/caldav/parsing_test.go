@@ -0,0 +1,133 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTaskFromVTODO(t *testing.T) {
+	t.Run("full task", func(t *testing.T) {
+		ct, err := ParseTaskFromVTODO(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Some client//EN
+BEGIN:VTODO
+UID:randomuid
+DTSTAMP:20181201T011204Z
+SUMMARY:Todo #1
+DESCRIPTION:Lorem Ipsum
+DTSTART:20181201T011204Z
+DUE:20181202T011204Z
+PRIORITY:3
+PERCENT-COMPLETE:40
+CATEGORIES:Label #1,Label\, with comma
+CATEGORIES:Label #2
+RELATED-TO:parentuid
+RELATED-TO;RELTYPE=CHILD:childuid
+X-APPLE-CALENDAR-COLOR:#affffeFF
+CLASS:PRIVATE
+X-CUSTOM;PARAM=value:Some value
+BEGIN:VALARM
+TRIGGER;VALUE=DATE-TIME:20181201T120000Z
+ACTION:DISPLAY
+END:VALARM
+BEGIN:VALARM
+TRIGGER;RELATED=END:-PT1H30M
+ACTION:DISPLAY
+END:VALARM
+END:VTODO
+END:VCALENDAR`)
+		assert.NoError(t, err)
+
+		assert.Equal(t, "randomuid", ct.Task.UID)
+		assert.Equal(t, "Todo #1", ct.Task.Title)
+		assert.Equal(t, int64(3), ct.Task.Priority)
+		assert.Equal(t, 0.4, ct.Task.PercentDone)
+		assert.Equal(t, "affffe", ct.Task.HexColor)
+		assert.Equal(t, []string{"Label #1", "Label, with comma", "Label #2"}, ct.Labels)
+		assert.Equal(t, map[models.RelationKind][]string{
+			models.RelationKindParenttask: {"parentuid"},
+			models.RelationKindSubtask:    {"childuid"},
+		}, ct.Related)
+		assert.Equal(t, []string{"CLASS:PRIVATE", "X-CUSTOM;PARAM=value:Some value"}, ct.Properties)
+		assert.Equal(t, []time.Time{
+			time.Date(2018, 12, 1, 12, 0, 0, 0, time.UTC),
+			time.Date(2018, 12, 1, 23, 42, 4, 0, time.UTC),
+		}, ct.Task.Reminders)
+	})
+	t.Run("duration", func(t *testing.T) {
+		ct, err := ParseTaskFromVTODO(`BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VTODO
+UID:randomuid
+SUMMARY:Todo #1
+DTSTART:20181201T011204Z
+DURATION:P1DT2H
+END:VTODO
+END:VCALENDAR`)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2018, 12, 2, 3, 12, 4, 0, time.UTC), ct.Task.EndDate)
+	})
+}
+
+func TestParseCaldavDuration(t *testing.T) {
+	assert.Equal(t, 15*time.Minute, parseCaldavDuration("PT15M"))
+	assert.Equal(t, -15*time.Minute, parseCaldavDuration("-PT15M"))
+	assert.Equal(t, 7*24*time.Hour+time.Second, parseCaldavDuration("P1WT1S"))
+	assert.Equal(t, time.Duration(0), parseCaldavDuration("invalid"))
+}
+
+func TestParseEventDatesFromVEVENT(t *testing.T) {
+	t.Run("timed event with time zone", func(t *testing.T) {
+		start, end, allDay, err := ParseEventDatesFromVEVENT(`BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:randomuid
+DTSTART;TZID=Europe/Berlin:20181201T100000
+DTEND:20181201T110000Z
+END:VEVENT
+END:VCALENDAR`)
+		assert.NoError(t, err)
+		assert.False(t, allDay)
+		assert.True(t, time.Date(2018, 12, 1, 9, 0, 0, 0, time.UTC).Equal(start))
+		assert.True(t, time.Date(2018, 12, 1, 11, 0, 0, 0, time.UTC).Equal(end))
+	})
+	t.Run("all-day event without end", func(t *testing.T) {
+		start, end, allDay, err := ParseEventDatesFromVEVENT(`BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:randomuid
+DTSTART;VALUE=DATE:20181203
+END:VEVENT
+END:VCALENDAR`)
+		assert.NoError(t, err)
+		assert.True(t, allDay)
+		assert.Equal(t, time.Date(2018, 12, 3, 0, 0, 0, 0, config.GetTimeZone()), start)
+		assert.Equal(t, time.Date(2018, 12, 4, 0, 0, 0, 0, config.GetTimeZone()), end)
+	})
+	t.Run("no event", func(t *testing.T) {
+		_, _, _, err := ParseEventDatesFromVEVENT(`BEGIN:VCALENDAR
+VERSION:2.0
+END:VCALENDAR`)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,88 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Contact holds a single vcard of an address book
+type Contact struct {
+	// Required
+	UID string
+	// The formatted name of the contact
+	Name string
+
+	// Optional
+	Nickname string
+	Email    string
+	Updated  time.Time
+
+	// If true, the contact is a group which consists of all contacts with the uids in Members
+	IsGroup bool
+	Members []string
+}
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `;`, `\;`)
+
+func escapeVCardValue(value string) string {
+	re := regexp.MustCompile(`\r?\n`)
+	return re.ReplaceAllString(vcardEscaper.Replace(value), `\n`)
+}
+
+// ParseContact returns a vcard string for a contact
+func ParseContact(prodID string, c *Contact) (vcard string) {
+	name := escapeVCardValue(c.Name)
+
+	vcard = `BEGIN:VCARD
+VERSION:3.0
+PRODID:-//` + prodID + `//EN
+UID:` + c.UID + `
+FN:` + name + `
+N:` + name + `;;;;`
+
+	if c.Nickname != "" {
+		vcard += `
+NICKNAME:` + escapeVCardValue(c.Nickname)
+	}
+
+	if c.Email != "" {
+		vcard += `
+EMAIL;TYPE=INTERNET:` + c.Email
+	}
+
+	if c.IsGroup {
+		vcard += `
+X-ADDRESSBOOKSERVER-KIND:group`
+		for _, m := range c.Members {
+			vcard += `
+X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:` + m
+		}
+	}
+
+	if c.Updated.Unix() > 0 {
+		vcard += `
+REV:` + makeCalDavUTCTimeFromTimeStamp(c.Updated)
+	}
+
+	vcard += `
+END:VCARD`
+
+	return
+}
@@ -0,0 +1,64 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContact(t *testing.T) {
+	t.Run("user", func(t *testing.T) {
+		vcard := ParseContact("Vikunja", &Contact{
+			UID:      "vikunja-user-1",
+			Name:     "Doe, John",
+			Nickname: "john",
+			Email:    "john@example.com",
+			Updated:  time.Unix(1543626724, 0),
+		})
+		assert.Equal(t, `BEGIN:VCARD
+VERSION:3.0
+PRODID:-//Vikunja//EN
+UID:vikunja-user-1
+FN:Doe\, John
+N:Doe\, John;;;;
+NICKNAME:john
+EMAIL;TYPE=INTERNET:john@example.com
+REV:20181201T011204Z
+END:VCARD`, vcard)
+	})
+	t.Run("group", func(t *testing.T) {
+		vcard := ParseContact("Vikunja", &Contact{
+			UID:     "vikunja-team-1",
+			Name:    "Team; One",
+			IsGroup: true,
+			Members: []string{"vikunja-user-1", "vikunja-user-2"},
+		})
+		assert.Equal(t, `BEGIN:VCARD
+VERSION:3.0
+PRODID:-//Vikunja//EN
+UID:vikunja-team-1
+FN:Team\; One
+N:Team\; One;;;;
+X-ADDRESSBOOKSERVER-KIND:group
+X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:vikunja-user-1
+X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:vikunja-user-2
+END:VCARD`, vcard)
+	})
+}
@@ -0,0 +1,80 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/initialize"
+	"code.vikunja.io/api/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	filesFlagFrom string
+	filesFlagTo   string
+)
+
+func init() {
+	filesMigrateCmd.Flags().StringVar(&filesFlagFrom, "from", "", "The storage type to copy all files from, for example local or s3.")
+	_ = filesMigrateCmd.MarkFlagRequired("from")
+	filesMigrateCmd.Flags().StringVar(&filesFlagTo, "to", "", "The storage type to copy all files to. Defaults to the configured files.type.")
+
+	filesCmd.AddCommand(filesMigrateCmd)
+	rootCmd.AddCommand(filesCmd)
+}
+
+var filesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "Manage the files Vikunja stores, like attachments, avatars or list backgrounds.",
+}
+
+var filesMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy all files from one storage backend to another.",
+	Long: `Copy all files from one storage backend to another, for example from a local directory to an s3 bucket.
+Both storage backends are configured through the files settings of the config. The files are not removed from the old storage.
+Files which already exist in the new storage are skipped, run the command again to continue an interrupted migration or
+to retry files which could not be copied.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initialize.FullInit()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		to := filesFlagTo
+		if to == "" {
+			to = config.FilesType.GetString()
+		}
+		if filesFlagFrom == to {
+			log.Fatalf("Can't migrate files from %s to the same storage type.", to)
+		}
+
+		fromStorage, err := files.NewStorage(filesFlagFrom)
+		if err != nil {
+			log.Fatalf("Could not initialize the storage to migrate from: %s", err)
+		}
+		toStorage, err := files.NewStorage(to)
+		if err != nil {
+			log.Fatalf("Could not initialize the storage to migrate to: %s", err)
+		}
+
+		log.Infof("Copying all files from %s to %s...", filesFlagFrom, to)
+		if err := files.MigrateStorage(fromStorage, toStorage); err != nil {
+			log.Fatalf("Could not migrate files: %s", err)
+		}
+		log.Infof("Done. Set files.type to %s if you haven't already.", to)
+	},
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"time"
 
+	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/db"
 	"code.vikunja.io/api/pkg/initialize"
 	"code.vikunja.io/api/pkg/log"
@@ -42,6 +43,10 @@ var (
 	userFlagResetPasswordDirectly bool
 	userFlagEnableUser            bool
 	userFlagDisableUser           bool
+	userFlagAdmin                 bool
+	userFlagGrantAdmin            bool
+	userFlagRevokeAdmin           bool
+	userFlagDeleteConfirm         bool
 )
 
 func init() {
@@ -52,6 +57,7 @@ func init() {
 	_ = userCreateCmd.MarkFlagRequired("email")
 	userCreateCmd.Flags().StringVarP(&userFlagPassword, "password", "p", "", "The password of the new user. You will be asked to enter it if not provided through the flag.")
 	userCreateCmd.Flags().StringVarP(&userFlagAvatar, "avatar-provider", "a", "", "The avatar provider of the new user. Optional.")
+	userCreateCmd.Flags().BoolVar(&userFlagAdmin, "admin", false, "Make the new user an administrator of this instance.")
 
 	// User update flags
 	userUpdateCmd.Flags().StringVarP(&userFlagUsername, "username", "u", "", "The new username of the user.")
@@ -66,7 +72,14 @@ func init() {
 	userChangeEnabledCmd.Flags().BoolVarP(&userFlagDisableUser, "disable", "d", false, "Disable the user.")
 	userChangeEnabledCmd.Flags().BoolVarP(&userFlagEnableUser, "enable", "e", false, "Enable the user.")
 
-	userCmd.AddCommand(userListCmd, userCreateCmd, userUpdateCmd, userResetPasswordCmd, userChangeEnabledCmd)
+	// Change admin flags
+	userChangeAdminCmd.Flags().BoolVarP(&userFlagGrantAdmin, "grant", "g", false, "Make the user an administrator.")
+	userChangeAdminCmd.Flags().BoolVarP(&userFlagRevokeAdmin, "revoke", "r", false, "Revoke the administrator role of the user.")
+
+	// Delete flags
+	userDeleteCmd.Flags().BoolVarP(&userFlagDeleteConfirm, "confirm", "c", false, "Delete the user without asking for confirmation.")
+
+	userCmd.AddCommand(userListCmd, userCreateCmd, userUpdateCmd, userResetPasswordCmd, userChangeEnabledCmd, userChangeAdminCmd, userResetTOTPCmd, userUnlockCmd, userDeleteCmd)
 	rootCmd.AddCommand(userCmd)
 }
 
@@ -136,6 +149,7 @@ var userListCmd = &cobra.Command{
 			"Username",
 			"Email",
 			"Active",
+			"Admin",
 			"Created",
 			"Updated",
 		})
@@ -146,6 +160,7 @@ var userListCmd = &cobra.Command{
 				u.Username,
 				u.Email,
 				strconv.FormatBool(u.IsActive),
+				strconv.FormatBool(u.IsAdmin),
 				u.Created.Format(time.RFC3339),
 				u.Updated.Format(time.RFC3339),
 			})
@@ -182,6 +197,14 @@ var userCreateCmd = &cobra.Command{
 			log.Fatalf("Error creating new namespace for user: %s", err)
 		}
 
+		if userFlagAdmin {
+			err = user.SetUserAdmin(s, newUser, true)
+			if err != nil {
+				_ = s.Rollback()
+				log.Fatalf("Error making the new user an administrator: %s", err)
+			}
+		}
+
 		if err := s.Commit(); err != nil {
 			log.Fatalf("Error saving everything: %s", err)
 		}
@@ -276,14 +299,13 @@ var userChangeEnabledCmd = &cobra.Command{
 
 		u := getUserFromArg(s, args[0])
 
+		active := !u.IsActive
 		if userFlagEnableUser {
-			u.IsActive = true
+			active = true
 		} else if userFlagDisableUser {
-			u.IsActive = false
-		} else {
-			u.IsActive = !u.IsActive
+			active = false
 		}
-		_, err := user.UpdateUser(s, u)
+		err := user.SetUserStatus(s, u, active)
 		if err != nil {
 			_ = s.Rollback()
 			log.Fatalf("Could not enable the user")
@@ -296,3 +318,140 @@ var userChangeEnabledCmd = &cobra.Command{
 		fmt.Printf("User status successfully changed, user is now active: %t.\n", u.IsActive)
 	},
 }
+
+var userChangeAdminCmd = &cobra.Command{
+	Use:   "change-admin [user id]",
+	Short: "Grant or revoke the instance administrator role of a user. Will toggle the current role if no flag (--grant or --revoke) is provided.",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initialize.FullInit()
+	},
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s := db.NewSession()
+		defer s.Close()
+
+		u := getUserFromArg(s, args[0])
+
+		admin := !u.IsAdmin
+		if userFlagGrantAdmin {
+			admin = true
+		} else if userFlagRevokeAdmin {
+			admin = false
+		}
+		err := user.SetUserAdmin(s, u, admin)
+		if err != nil {
+			_ = s.Rollback()
+			log.Fatalf("Could not change the administrator role: %s", err)
+		}
+
+		if err := s.Commit(); err != nil {
+			log.Fatalf("Error saving everything: %s", err)
+		}
+
+		fmt.Printf("Administrator role successfully changed, user is now an administrator: %t.\n", u.IsAdmin)
+	},
+}
+
+var userResetTOTPCmd = &cobra.Command{
+	Use:   "reset-totp [user id]",
+	Short: "Disable totp for a user and remove all of their recovery codes. Use this if a user lost access to their totp device.",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initialize.FullInit()
+	},
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s := db.NewSession()
+		defer s.Close()
+
+		u := getUserFromArg(s, args[0])
+
+		err := user.DisableTOTP(s, u)
+		if err != nil {
+			_ = s.Rollback()
+			log.Fatalf("Could not reset totp: %s", err)
+		}
+
+		if err := s.Commit(); err != nil {
+			log.Fatalf("Error saving everything: %s", err)
+		}
+
+		fmt.Println("TOTP was reset successfully. The user can now log in with only their password.")
+	},
+}
+
+var userUnlockCmd = &cobra.Command{
+	Use:   "unlock [user id]",
+	Short: "Unlock a user who was locked temporarily after too many failed login attempts.",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initialize.FullInit()
+	},
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s := db.NewSession()
+		defer s.Close()
+
+		if config.KeyvalueType.GetString() != "redis" {
+			log.Warning("Failed login attempts are only shared with a running Vikunja instance if redis is used as keyvalue store.")
+		}
+
+		// Failed attempts are counted by the username and email address used to log in
+		u, err := user.GetUserWithEmail(s, &user.User{ID: getUserFromArg(s, args[0]).ID})
+		if err != nil {
+			log.Fatalf("Could not get user: %s", err)
+		}
+
+		if err := s.Commit(); err != nil {
+			log.Fatalf("Error getting the user: %s", err)
+		}
+
+		err = user.UnlockUser(u)
+		if err != nil {
+			log.Fatalf("Could not unlock the user: %s", err)
+		}
+
+		fmt.Println("User unlocked successfully.")
+	},
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete [user id]",
+	Short: "Delete a user right away, together with all namespaces and lists they own. Namespaces and lists the user chose to transfer to other users are transferred.",
+	PreRun: func(cmd *cobra.Command, args []string) {
+		initialize.FullInit()
+	},
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s := db.NewSession()
+		defer s.Close()
+
+		u := getUserFromArg(s, args[0])
+		u, err := user.GetUserWithEmail(s, u)
+		if err != nil {
+			log.Fatalf("Could not get user: %s", err)
+		}
+
+		if !userFlagDeleteConfirm {
+			fmt.Printf("Do you really want to delete the user %s (%d) and everything they own? This cannot be undone. [y/N]: ", u.Username, u.ID)
+			var answer string
+			_, _ = fmt.Scanln(&answer)
+			if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+				fmt.Println("Aborted.")
+				return
+			}
+		}
+
+		err = models.DeleteUser(s, u)
+		if err != nil {
+			_ = s.Rollback()
+			log.Fatalf("Could not delete the user: %s", err)
+		}
+
+		if err := s.Commit(); err != nil {
+			log.Fatalf("Error saving everything: %s", err)
+		}
+
+		models.NotifyUserDeleted(u)
+
+		fmt.Println("User deleted successfully.")
+	},
+}
@@ -27,6 +27,7 @@ import (
 	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/initialize"
 	"code.vikunja.io/api/pkg/log"
+	migrator "code.vikunja.io/api/pkg/modules/migration"
 	"code.vikunja.io/api/pkg/routes"
 	"code.vikunja.io/api/pkg/swagger"
 	"code.vikunja.io/api/pkg/version"
@@ -54,6 +55,10 @@ var webCmd = &cobra.Command{
 		// Start the webserver
 		e := routes.NewEcho()
 		routes.RegisterRoutes(e)
+
+		// Migrators are only known once their routes are registered
+		migrator.ResumeMigrationJobs()
+
 		// Start server
 		go func() {
 			if err := e.Start(config.ServiceInterface.GetString()); err != nil {
@@ -43,22 +43,36 @@ const (
 	ServiceRootpath        Key = `service.rootpath`
 	ServiceMaxItemsPerPage Key = `service.maxitemsperpage`
 	// Deprecated. Use metrics.enabled
-	ServiceEnableMetrics         Key = `service.enablemetrics`
-	ServiceMotd                  Key = `service.motd`
-	ServiceEnableLinkSharing     Key = `service.enablelinksharing`
-	ServiceEnableRegistration    Key = `service.enableregistration`
-	ServiceEnableTaskAttachments Key = `service.enabletaskattachments`
-	ServiceTimeZone              Key = `service.timezone`
-	ServiceEnableTaskComments    Key = `service.enabletaskcomments`
-	ServiceEnableTotp            Key = `service.enabletotp`
-	ServiceSentryDsn             Key = `service.sentrydsn`
-	ServiceTestingtoken          Key = `service.testingtoken`
-	ServiceEnableEmailReminders  Key = `service.enableemailreminders`
-
-	AuthLocalEnabled      Key = `auth.local.enabled`
-	AuthOpenIDEnabled     Key = `auth.openid.enabled`
-	AuthOpenIDRedirectURL Key = `auth.openid.redirecturl`
-	AuthOpenIDProviders   Key = `auth.openid.providers`
+	ServiceEnableMetrics              Key = `service.enablemetrics`
+	ServiceMotd                       Key = `service.motd`
+	ServiceEnableLinkSharing          Key = `service.enablelinksharing`
+	ServiceEnableRegistration         Key = `service.enableregistration`
+	ServiceEnableTaskAttachments      Key = `service.enabletaskattachments`
+	ServiceTimeZone                   Key = `service.timezone`
+	ServiceEnableTaskComments         Key = `service.enabletaskcomments`
+	ServiceEnableTotp                 Key = `service.enabletotp`
+	ServiceEnableWebAuthn             Key = `service.enablewebauthn`
+	ServiceEnableWebAuthnPasswordless Key = `service.enablewebauthnpasswordless`
+	ServiceMaxFailedLoginAttempts     Key = `service.maxfailedloginattempts`
+	ServiceEnableOAuth2Server         Key = `service.enableoauth2server`
+	ServiceLoginLockoutDuration       Key = `service.loginlockoutduration`
+	ServiceEnableUserDeletion         Key = `service.enableuserdeletion`
+	ServiceUserDeletionGracePeriod    Key = `service.userdeletiongraceperiod`
+	ServiceSentryDsn                  Key = `service.sentrydsn`
+	ServiceTestingtoken               Key = `service.testingtoken`
+	ServiceEnableEmailReminders       Key = `service.enableemailreminders`
+
+	AuthLocalEnabled                             Key = `auth.local.enabled`
+	AuthLocalPasswordPolicyMinLength             Key = `auth.local.passwordpolicy.minlength`
+	AuthLocalPasswordPolicyRequireUppercase      Key = `auth.local.passwordpolicy.requireuppercase`
+	AuthLocalPasswordPolicyRequireLowercase      Key = `auth.local.passwordpolicy.requirelowercase`
+	AuthLocalPasswordPolicyRequireDigit          Key = `auth.local.passwordpolicy.requiredigit`
+	AuthLocalPasswordPolicyRequireSpecial        Key = `auth.local.passwordpolicy.requirespecial`
+	AuthLocalPasswordPolicyDisallowUserInfo      Key = `auth.local.passwordpolicy.disallowuserinfo`
+	AuthLocalPasswordPolicyBreachedPasswordsFile Key = `auth.local.passwordpolicy.breachedpasswordsfile`
+	AuthOpenIDEnabled                            Key = `auth.openid.enabled`
+	AuthOpenIDRedirectURL                        Key = `auth.openid.redirecturl`
+	AuthOpenIDProviders                          Key = `auth.openid.providers`
 
 	LegalImprintURL Key = `legal.imprinturl`
 	LegalPrivacyURL Key = `legal.privacyurl`
@@ -111,8 +125,18 @@ const (
 	RateLimitLimit   Key = `ratelimit.limit`
 	RateLimitStore   Key = `ratelimit.store`
 
-	FilesBasePath Key = `files.basepath`
-	FilesMaxSize  Key = `files.maxsize`
+	FilesType              Key = `files.type`
+	FilesBasePath          Key = `files.basepath`
+	FilesMaxSize           Key = `files.maxsize`
+	FilesS3Endpoint        Key = `files.s3.endpoint`
+	FilesS3Region          Key = `files.s3.region`
+	FilesS3Bucket          Key = `files.s3.bucket`
+	FilesS3AccessKey       Key = `files.s3.accesskey`
+	FilesS3SecretKey       Key = `files.s3.secretkey`
+	FilesS3UsePathStyle    Key = `files.s3.usepathstyle`
+	FilesS3SignedURLs      Key = `files.s3.signedurls`
+	FilesS3SignedURLExpiry Key = `files.s3.signedurlexpiry`
+	FilesS3Timeout         Key = `files.s3.timeout`
 
 	MigrationWunderlistEnable          Key = `migration.wunderlist.enable`
 	MigrationWunderlistClientID        Key = `migration.wunderlist.clientid`
@@ -129,6 +153,14 @@ const (
 	MigrationMicrosoftTodoClientID     Key = `migration.microsofttodo.clientid`
 	MigrationMicrosoftTodoClientSecret Key = `migration.microsofttodo.clientsecret`
 	MigrationMicrosoftTodoRedirectURL  Key = `migration.microsofttodo.redirecturl`
+	MigrationTrelloFileEnable          Key = `migration.trellofile.enable`
+	MigrationTodoistFileEnable         Key = `migration.todoistfile.enable`
+	MigrationVikunjaFileEnable         Key = `migration.vikunjafile.enable`
+	MigrationTodoTxtEnable             Key = `migration.todotxt.enable`
+	MigrationTaskwarriorEnable         Key = `migration.taskwarrior.enable`
+	MigrationAsanaEnable               Key = `migration.asana.enable`
+	MigrationJiraEnable                Key = `migration.jira.enable`
+	MigrationICalEnable                Key = `migration.ical.enable`
 
 	CorsEnable  Key = `cors.enable`
 	CorsOrigins Key = `cors.origins`
@@ -147,6 +179,10 @@ const (
 	MetricsEnabled  Key = `metrics.enabled`
 	MetricsUsername Key = `metrics.username`
 	MetricsPassword Key = `metrics.password`
+
+	SCIMEnabled Key = `scim.enabled`
+	SCIMToken   Key = `scim.token`
+	SCIMIssuer  Key = `scim.issuer`
 )
 
 // GetString returns a string config value
@@ -241,10 +277,24 @@ func InitDefaultConfig() {
 	ServiceTimeZone.setDefault("GMT")
 	ServiceEnableTaskComments.setDefault(true)
 	ServiceEnableTotp.setDefault(true)
+	ServiceEnableWebAuthn.setDefault(false)
+	ServiceEnableWebAuthnPasswordless.setDefault(false)
+	ServiceMaxFailedLoginAttempts.setDefault(10)
+	ServiceLoginLockoutDuration.setDefault(900)
+	ServiceEnableOAuth2Server.setDefault(false)
+	ServiceEnableUserDeletion.setDefault(true)
+	ServiceUserDeletionGracePeriod.setDefault(3)
 	ServiceEnableEmailReminders.setDefault(true)
 
 	// Auth
 	AuthLocalEnabled.setDefault(true)
+	AuthLocalPasswordPolicyMinLength.setDefault(0)
+	AuthLocalPasswordPolicyRequireUppercase.setDefault(false)
+	AuthLocalPasswordPolicyRequireLowercase.setDefault(false)
+	AuthLocalPasswordPolicyRequireDigit.setDefault(false)
+	AuthLocalPasswordPolicyRequireSpecial.setDefault(false)
+	AuthLocalPasswordPolicyDisallowUserInfo.setDefault(true)
+	AuthLocalPasswordPolicyBreachedPasswordsFile.setDefault("")
 	AuthOpenIDEnabled.setDefault(false)
 
 	// Database
@@ -297,8 +347,14 @@ func InitDefaultConfig() {
 	RateLimitPeriod.setDefault(60)
 	RateLimitStore.setDefault("memory")
 	// Files
+	FilesType.setDefault("local")
 	FilesBasePath.setDefault("files")
 	FilesMaxSize.setDefault("20MB")
+	FilesS3Region.setDefault("us-east-1")
+	FilesS3UsePathStyle.setDefault(false)
+	FilesS3SignedURLs.setDefault(false)
+	FilesS3SignedURLExpiry.setDefault(900)
+	FilesS3Timeout.setDefault(60)
 	// Cors
 	CorsEnable.setDefault(true)
 	CorsOrigins.setDefault([]string{"*"})
@@ -308,6 +364,14 @@ func InitDefaultConfig() {
 	MigrationTodoistEnable.setDefault(false)
 	MigrationTrelloEnable.setDefault(false)
 	MigrationMicrosoftTodoEnable.setDefault(false)
+	MigrationTrelloFileEnable.setDefault(true)
+	MigrationTodoistFileEnable.setDefault(true)
+	MigrationVikunjaFileEnable.setDefault(true)
+	MigrationTodoTxtEnable.setDefault(true)
+	MigrationTaskwarriorEnable.setDefault(true)
+	MigrationAsanaEnable.setDefault(true)
+	MigrationJiraEnable.setDefault(true)
+	MigrationICalEnable.setDefault(true)
 	// Avatar
 	AvatarGravaterExpiration.setDefault(3600)
 	// List Backgrounds
@@ -318,6 +382,9 @@ func InitDefaultConfig() {
 	KeyvalueType.setDefault("memory")
 	// Metrics
 	MetricsEnabled.setDefault(false)
+	// SCIM
+	SCIMEnabled.setDefault(false)
+	SCIMIssuer.setDefault("scim")
 }
 
 // InitConfig initializes the config, sets defaults etc.
@@ -17,8 +17,10 @@
 package files
 
 import (
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/db"
@@ -28,19 +30,49 @@ import (
 )
 
 // This file handles storing and retrieving a file for different backends
-var fs afero.Fs
+var store Storage
+
+// The file system of the test storage, used to access files directly in tests
 var afs *afero.Afero
 
 // InitFileHandler creates a new file handler for the file backend we want to use
 func InitFileHandler() {
-	fs = afero.NewOsFs()
-	afs = &afero.Afero{Fs: fs}
+	var err error
+	store, err = NewStorage(config.FilesType.GetString())
+	if err != nil {
+		log.Fatalf("Could not initialize the file storage: %s", err)
+	}
+}
+
+// NewStorage creates a storage backend of a type, using the settings from the config
+func NewStorage(storageType string) (Storage, error) {
+	switch storageType {
+	case StorageTypeLocal:
+		return newLocalStorage(afero.NewOsFs()), nil
+	case StorageTypeS3:
+		s, err := newS3Storage(
+			config.FilesS3Endpoint.GetString(),
+			config.FilesS3Region.GetString(),
+			config.FilesS3Bucket.GetString(),
+			config.FilesS3AccessKey.GetString(),
+			config.FilesS3SecretKey.GetString(),
+			config.FilesS3UsePathStyle.GetBool(),
+			time.Duration(config.FilesS3Timeout.GetInt64())*time.Second,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown file storage type '%s'", storageType)
+	}
 }
 
 // InitTestFileHandler initializes a new memory file system for testing
 func InitTestFileHandler() {
-	fs = afero.NewMemMapFs()
-	afs = &afero.Afero{Fs: fs}
+	local := newLocalStorage(afero.NewMemMapFs())
+	store = local
+	afs = local.afs
 }
 
 func initFixtures(t *testing.T) {
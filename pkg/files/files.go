@@ -23,9 +23,9 @@ import (
 
 	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/web"
 	"github.com/c2h5oh/datasize"
-	"github.com/spf13/afero"
 )
 
 // File holds all information about a file
@@ -38,7 +38,13 @@ type File struct {
 	Created     time.Time `xorm:"created" json:"created"`
 	CreatedByID int64     `xorm:"bigint not null" json:"-"`
 
-	File afero.File `xorm:"-" json:"-"`
+	// The id of the file this file was generated from, for example if it is the preview of an image
+	DerivedFromID int64 `xorm:"bigint null unique(derived)" json:"-"`
+	// What kind of file was generated from the original, for example "preview_md". There is only one derived file
+	// of each kind per original.
+	DerivedKind string `xorm:"varchar(50) null unique(derived)" json:"-"`
+
+	File Content `xorm:"-" json:"-"`
 	// This ReadCloser is only used for migration purposes. Use with care!
 	// There is currentlc no better way of doing this.
 	FileContent []byte `xorm:"-" json:"-"`
@@ -49,16 +55,32 @@ func (File) TableName() string {
 	return "files"
 }
 
+// getFileName returns the name of the file in the storage backend
 func (f *File) getFileName() string {
-	return config.FilesBasePath.GetString() + "/" + strconv.FormatInt(f.ID, 10)
+	return strconv.FormatInt(f.ID, 10)
 }
 
 // LoadFileByID returns a file by its ID
 func (f *File) LoadFileByID() (err error) {
-	f.File, err = afs.Open(f.getFileName())
+	f.File, err = store.Open(f.getFileName())
 	return
 }
 
+// SignedURL returns a url the file can be downloaded from directly for a limited time.
+// The url is empty if the storage backend does not support this or it is disabled in the config.
+func (f *File) SignedURL() (string, error) {
+	if !config.FilesS3SignedURLs.GetBool() {
+		return "", nil
+	}
+
+	signer, is := store.(SignedURLStorage)
+	if !is {
+		return "", nil
+	}
+
+	return signer.SignedURL(f.getFileName(), f.Name, f.Mime, time.Duration(config.FilesS3SignedURLExpiry.GetInt64())*time.Second)
+}
+
 // LoadFileMetaByID loads everything about a file without loading the actual file
 func (f *File) LoadFileMetaByID() (err error) {
 	exists, err := x.Where("id = ?", f.ID).Get(f)
@@ -68,6 +90,13 @@ func (f *File) LoadFileMetaByID() (err error) {
 	return
 }
 
+// MaxSize returns the configured maximum size of files in bytes
+func MaxSize() (uint64, error) {
+	var maxSize datasize.ByteSize
+	err := maxSize.UnmarshalText([]byte(config.FilesMaxSize.GetString()))
+	return maxSize.Bytes(), err
+}
+
 // Create creates a new file from an FileHeader
 func Create(f io.Reader, realname string, realsize uint64, a web.Auth) (file *File, err error) {
 	return CreateWithMime(f, realname, realsize, a, "")
@@ -76,16 +105,20 @@ func Create(f io.Reader, realname string, realsize uint64, a web.Auth) (file *Fi
 // CreateWithMime creates a new file from an FileHeader and sets its mime type
 func CreateWithMime(f io.Reader, realname string, realsize uint64, a web.Auth, mime string) (file *File, err error) {
 
-	// Get and parse the configured file size
-	var maxSize datasize.ByteSize
-	err = maxSize.UnmarshalText([]byte(config.FilesMaxSize.GetString()))
+	maxSize, err := MaxSize()
 	if err != nil {
 		return nil, err
 	}
-	if realsize > maxSize.Bytes() {
+	if realsize > maxSize {
 		return nil, ErrFileIsTooLarge{Size: realsize}
 	}
 
+	return CreateWithoutSizeLimit(f, realname, realsize, a, mime)
+}
+
+// CreateWithoutSizeLimit creates a new file regardless of the configured maximum file size.
+// Only use this for files Vikunja creates itself, like data exports.
+func CreateWithoutSizeLimit(f io.Reader, realname string, realsize uint64, a web.Auth, mime string) (file *File, err error) {
 	// We first insert the file into the db to get it's ID
 	file = &File{
 		Name:        realname,
@@ -112,12 +145,69 @@ func CreateWithMime(f io.Reader, realname string, realsize uint64, a web.Auth, m
 	return
 }
 
+// CreateDerived stores a file which was generated from another file, like the preview of an image.
+// Derived files are deleted together with the file they were generated from.
+// If a file of the same kind was already generated from the original, that file is returned instead.
+func CreateDerived(original *File, kind string, f io.Reader, realsize uint64, mime string) (file *File, err error) {
+	file, err = GetDerivedFile(original.ID, kind)
+	if err == nil || !IsErrFileDoesNotExist(err) {
+		return file, err
+	}
+
+	file = &File{
+		Name:          original.Name,
+		Size:          realsize,
+		CreatedByID:   original.CreatedByID,
+		Mime:          mime,
+		DerivedFromID: original.ID,
+		DerivedKind:   kind,
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	_, err = s.Insert(file)
+	if err != nil {
+		// Another process could have generated the same file in the meantime
+		existing, err2 := GetDerivedFile(original.ID, kind)
+		if err2 == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	err = file.Save(f)
+	if err != nil {
+		// The row would otherwise prevent generating the file again
+		if _, err2 := s.Where("id = ?", file.ID).Delete(&File{}); err2 != nil {
+			log.Errorf("Could not delete file %d after it could not be saved: %s", file.ID, err2)
+		}
+		return nil, err
+	}
+	return
+}
+
+// GetDerivedFile returns the file of a kind which was generated from another file
+func GetDerivedFile(originalID int64, kind string) (file *File, err error) {
+	file = &File{}
+	exists, err := x.
+		Where("derived_from_id = ? AND derived_kind = ?", originalID, kind).
+		Get(file)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrFileDoesNotExist{}
+	}
+	return file, nil
+}
+
 // Delete removes a file from the DB and the file system
 func (f *File) Delete() (err error) {
 	s := db.NewSession()
 	defer s.Close()
 
-	deleted, err := s.Where("id = ?", f.ID).Delete(f)
+	deleted, err := s.Where("id = ?", f.ID).Delete(&File{})
 	if err != nil {
 		_ = s.Rollback()
 		return err
@@ -127,15 +217,28 @@ func (f *File) Delete() (err error) {
 		return ErrFileDoesNotExist{FileID: f.ID}
 	}
 
-	err = afs.Remove(f.getFileName())
+	err = store.Remove(f.getFileName())
 	if err != nil {
 		_ = s.Rollback()
 		return err
 	}
+
+	// Derived files like previews are useless without the original
+	derived := []*File{}
+	err = s.Where("derived_from_id = ?", f.ID).Find(&derived)
+	if err != nil {
+		_ = s.Rollback()
+		return err
+	}
+	for _, d := range derived {
+		if err := d.Delete(); err != nil {
+			log.Errorf("Could not delete file %d derived from file %d: %s", d.ID, f.ID, err)
+		}
+	}
 	return
 }
 
 // Save saves a file to storage
 func (f *File) Save(fcontent io.Reader) error {
-	return afs.WriteReader(f.getFileName(), fcontent)
+	return store.Save(f.getFileName(), fcontent, f.Size)
 }
@@ -17,10 +17,14 @@
 package files
 
 import (
+	"errors"
 	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -97,6 +101,52 @@ func TestFile_Delete(t *testing.T) {
 	})
 }
 
+func TestCreateDerived(t *testing.T) {
+	initFixtures(t)
+	original := &File{ID: 1}
+	err := original.LoadFileMetaByID()
+	assert.NoError(t, err)
+
+	derived, err := CreateDerived(original, "preview_sm", &testfile{content: []byte("preview")}, 7, "image/png")
+	assert.NoError(t, err)
+	assert.Equal(t, "test", derived.Name)
+	assert.Equal(t, int64(1), derived.CreatedByID)
+
+	loaded, err := GetDerivedFile(1, "preview_sm")
+	assert.NoError(t, err)
+	assert.Equal(t, derived.ID, loaded.ID)
+	assert.Equal(t, "image/png", loaded.Mime)
+
+	_, err = GetDerivedFile(1, "preview_xl")
+	assert.Error(t, err)
+	assert.True(t, IsErrFileDoesNotExist(err))
+
+	// A file of the same kind is only created once
+	again, err := CreateDerived(original, "preview_sm", strings.NewReader("other"), 5, "image/png")
+	assert.NoError(t, err)
+	assert.Equal(t, derived.ID, again.ID)
+
+	// Deleting the original deletes all derived files
+	err = original.Delete()
+	assert.NoError(t, err)
+	_, err = GetDerivedFile(1, "preview_sm")
+	assert.True(t, IsErrFileDoesNotExist(err))
+}
+
+func TestCreateDerived_SaveFails(t *testing.T) {
+	initFixtures(t)
+	defer func(s Storage) {
+		store = s
+	}(store)
+	store = &failingStorage{Storage: store, all: true}
+
+	_, err := CreateDerived(&File{ID: 1, Name: "test"}, "preview_sm", strings.NewReader("preview"), 7, "image/png")
+	assert.Error(t, err)
+
+	_, err = GetDerivedFile(1, "preview_sm")
+	assert.True(t, IsErrFileDoesNotExist(err))
+}
+
 func TestFile_LoadFileByID(t *testing.T) {
 	t.Run("Normal", func(t *testing.T) {
 		initFixtures(t)
@@ -129,3 +179,78 @@ func TestFile_LoadFileMetaByID(t *testing.T) {
 		assert.True(t, IsErrFileDoesNotExist(err))
 	})
 }
+
+// failingStorage is a storage which can't save some or all files
+type failingStorage struct {
+	Storage
+	failing map[string]bool
+	all     bool
+}
+
+func (f *failingStorage) Save(name string, content io.Reader, size uint64) error {
+	if f.all || f.failing[name] {
+		return errors.New("storage failure")
+	}
+	return f.Storage.Save(name, content, size)
+}
+
+func TestMigrateStorage(t *testing.T) {
+	readFile := func(t *testing.T, s Storage, name string) string {
+		content, err := s.Open(name)
+		assert.NoError(t, err)
+		defer content.Close()
+		read, err := ioutil.ReadAll(content)
+		assert.NoError(t, err)
+		return string(read)
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		initFixtures(t)
+		to := newLocalStorage(afero.NewMemMapFs())
+
+		err := MigrateStorage(store, to)
+		assert.NoError(t, err)
+		assert.Equal(t, "testfile1", readFile(t, to, "1"))
+	})
+	t.Run("Skips existing files", func(t *testing.T) {
+		initFixtures(t)
+		to := newLocalStorage(afero.NewMemMapFs())
+		err := to.Save("1", strings.NewReader("otherfile"), 9)
+		assert.NoError(t, err)
+
+		err = MigrateStorage(store, to)
+		assert.NoError(t, err)
+		assert.Equal(t, "otherfile", readFile(t, to, "1"))
+	})
+	t.Run("Copies incomplete files again", func(t *testing.T) {
+		initFixtures(t)
+		to := newLocalStorage(afero.NewMemMapFs())
+		err := to.Save("1", strings.NewReader("test"), 4)
+		assert.NoError(t, err)
+
+		err = MigrateStorage(store, to)
+		assert.NoError(t, err)
+		assert.Equal(t, "testfile1", readFile(t, to, "1"))
+	})
+	t.Run("Continues after errors", func(t *testing.T) {
+		initFixtures(t)
+		defer func(size int) {
+			migrateStorageBatchSize = size
+		}(migrateStorageBatchSize)
+		migrateStorageBatchSize = 1
+
+		second, err := Create(strings.NewReader("testfile2"), "testfile2", 9, &testauth{id: 1})
+		assert.NoError(t, err)
+		to := &failingStorage{
+			Storage: newLocalStorage(afero.NewMemMapFs()),
+			failing: map[string]bool{"1": true},
+		}
+
+		err = MigrateStorage(store, to)
+		assert.Error(t, err)
+		assert.Equal(t, "testfile2", readFile(t, to, second.getFileName()))
+
+		_, err = to.Open("1")
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,116 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package files
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"code.vikunja.io/api/pkg/log"
+)
+
+// The number of files loaded from the database at once while migrating them
+var migrateStorageBatchSize = 100
+
+// MigrateStorage copies all files from one storage backend to another.
+// The files are not removed from the old storage. Files which don't exist in the old storage are skipped, as well as
+// files which already exist with the same size in the new storage. That way, an interrupted migration continues
+// where it stopped when it is started again.
+// Files which can't be copied don't stop the migration, an error is returned after all other files were copied.
+func MigrateStorage(from Storage, to Storage) (err error) {
+	var lastID int64
+	var copied, existing, missing, failed int
+	for {
+		batch := []*File{}
+		err = x.
+			Where("id > ?", lastID).
+			OrderBy("id asc").
+			Limit(migrateStorageBatchSize).
+			Find(&batch)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+
+		for _, f := range batch {
+			wasCopied, err := migrateFile(from, to, f.getFileName())
+			switch {
+			case os.IsNotExist(err):
+				log.Warningf("File %d does not exist in the old storage, skipping it.", f.ID)
+				missing++
+			case err != nil:
+				log.Errorf("Could not copy file %d: %s", f.ID, err)
+				failed++
+			case !wasCopied:
+				log.Debugf("File %d already exists in the new storage, skipping it.", f.ID)
+				existing++
+			default:
+				log.Debugf("Copied file %d", f.ID)
+				copied++
+			}
+		}
+	}
+
+	log.Infof("Copied %d files, %d files already existed in the new storage, %d files did not exist in the old storage.", copied, existing, missing)
+	if failed > 0 {
+		return fmt.Errorf("%d files could not be copied, run the migration again to retry them", failed)
+	}
+	return nil
+}
+
+// migrateFile copies a single file to another storage if it does not already exist there with the same size.
+// If the file does not exist in the old storage, the error satisfies os.IsNotExist.
+func migrateFile(from Storage, to Storage, name string) (copied bool, err error) {
+	content, err := from.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer content.Close()
+
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, fmt.Errorf("could not get the size: %s", err)
+	}
+	if existsWithSize(to, name, size) {
+		return false, nil
+	}
+
+	_, err = content.Seek(0, io.SeekStart)
+	if err != nil {
+		return false, fmt.Errorf("could not read the file: %s", err)
+	}
+	err = to.Save(name, content, uint64(size))
+	if err != nil {
+		return false, fmt.Errorf("could not save the file: %s", err)
+	}
+	return true, nil
+}
+
+func existsWithSize(s Storage, name string, size int64) bool {
+	content, err := s.Open(name)
+	if err != nil {
+		return false
+	}
+	defer content.Close()
+
+	existingSize, err := content.Seek(0, io.SeekEnd)
+	return err == nil && existingSize == size
+}
@@ -0,0 +1,538 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package files
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The minimum size of all parts of a multipart upload except the last one
+const s3MinPartSize = 5 * 1024 * 1024
+
+const (
+	s3Algorithm       = `AWS4-HMAC-SHA256`
+	s3DateFormat      = `20060102T150405Z`
+	s3ShortDateFormat = `20060102`
+	s3UnsignedPayload = `UNSIGNED-PAYLOAD`
+)
+
+// s3Storage stores files as objects in a bucket of an S3-compatible object storage.
+// All requests are signed with AWS signature version 4, see https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+type s3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	// If true, the bucket is part of the path instead of the host name. Most self-hosted services need this.
+	pathStyle bool
+
+	client *http.Client
+	// Files larger than this are uploaded in multiple parts of this size, which means at most one part of a
+	// file is held in memory at any time. Downloads are split into requests of the same size, that way every
+	// request finishes within the timeout of the client, regardless of the size of the file.
+	partSize int
+	now      func() time.Time
+}
+
+// s3Error is an error response of the object storage
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (err *s3Error) Error() string {
+	return fmt.Sprintf("s3 request failed [Status: %d, Code: %s, Message: %s]", err.StatusCode, err.Code, err.Message)
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name           `xml:"CompleteMultipartUpload"`
+	Parts   []*s3CompletedPart `xml:"Part"`
+}
+
+func newS3Storage(endpoint, region, bucket, accessKey, secretKey string, pathStyle bool, timeout time.Duration) (*s3Storage, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid s3 endpoint: the endpoint needs to start with http:// or https://")
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("no s3 bucket provided")
+	}
+
+	return &s3Storage{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		client:    &http.Client{Timeout: timeout},
+		partSize:  s3MinPartSize,
+		now:       time.Now,
+	}, nil
+}
+
+// s3URIEncode encodes a string as required for signing, every byte except the unreserved characters is encoded
+func s3URIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(query))
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			params = append(params, s3URIEncode(k, true)+"="+s3URIEncode(v, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+func s3Sha256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func s3HMAC(key []byte, content string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(content))
+	return h.Sum(nil)
+}
+
+func (s *s3Storage) getScope(t time.Time) string {
+	return t.Format(s3ShortDateFormat) + "/" + s.region + "/s3/aws4_request"
+}
+
+func (s *s3Storage) getSignature(t time.Time, canonicalRequest string) string {
+	stringToSign := s3Algorithm + "\n" +
+		t.Format(s3DateFormat) + "\n" +
+		s.getScope(t) + "\n" +
+		s3Sha256([]byte(canonicalRequest))
+
+	key := s3HMAC([]byte("AWS4"+s.secretKey), t.Format(s3ShortDateFormat))
+	key = s3HMAC(key, s.region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign))
+}
+
+func (s *s3Storage) getObjectURL(name string) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		u.Path = basePath + "/" + s.bucket + "/" + name
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = basePath + "/" + name
+	}
+	u.RawPath = s3URIEncode(u.Path, false)
+	u.RawQuery = ""
+	return &u
+}
+
+// signRequest adds the authorization header to a request. The host, range and all x-amz-* headers are signed.
+func (s *s3Storage) signRequest(req *http.Request, payloadHash string) {
+	t := s.now().UTC()
+	req.Header.Set("x-amz-date", t.Format(s3DateFormat))
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-amz-") || name == "range" {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := req.Method + "\n" +
+		s3URIEncode(req.URL.Path, false) + "\n" +
+		s3CanonicalQuery(req.URL.Query()) + "\n" +
+		canonicalHeaders.String() + "\n" +
+		signedHeaders + "\n" +
+		payloadHash
+
+	req.Header.Set("Authorization", s3Algorithm+" Credential="+s.accessKey+"/"+s.getScope(t)+
+		",SignedHeaders="+signedHeaders+
+		",Signature="+s.getSignature(t, canonicalRequest))
+}
+
+func (s *s3Storage) newRequest(method, name string, query url.Values, body []byte, header http.Header) (*http.Request, error) {
+	u := s.getObjectURL(name)
+	u.RawQuery = s3CanonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	s.signRequest(req, s3Sha256(body))
+	return req, nil
+}
+
+func (s *s3Storage) do(method, name string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	req, err := s.newRequest(method, name, query, body, header)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	content, _ := ioutil.ReadAll(resp.Body)
+	s3err := &s3Error{}
+	_ = xml.Unmarshal(content, s3err)
+	s3err.StatusCode = resp.StatusCode
+
+	if resp.StatusCode == http.StatusNotFound && (s3err.Code == "" || s3err.Code == "NoSuchKey") {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return nil, s3err
+}
+
+// Open returns the content of an object. Nothing is downloaded until the content is read.
+func (s *s3Storage) Open(name string) (Content, error) {
+	resp, err := s.do(http.MethodHead, name, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+
+	size, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size of s3 object %s: %s", name, err)
+	}
+
+	return &s3Object{
+		storage: s,
+		name:    name,
+		size:    size,
+	}, nil
+}
+
+// Save uploads an object. Small files are uploaded with a single request, all others as a multipart upload.
+func (s *s3Storage) Save(name string, content io.Reader, size uint64) error {
+	buf := make([]byte, s.partSize)
+	n, err := io.ReadFull(content, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		resp, err := s.do(http.MethodPut, name, nil, buf[:n], nil)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.multipartUpload(name, buf, content)
+}
+
+func (s *s3Storage) multipartUpload(name string, buf []byte, content io.Reader) (err error) {
+	resp, err := s.do(http.MethodPost, name, url.Values{"uploads": {""}}, nil, nil)
+	if err != nil {
+		return err
+	}
+	initiated := &s3InitiateMultipartUploadResult{}
+	err = xml.NewDecoder(resp.Body).Decode(initiated)
+	_ = resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	uploadQuery := func() url.Values {
+		return url.Values{"uploadId": {initiated.UploadID}}
+	}
+
+	// Uploaded parts take up space until the upload is either completed or aborted
+	defer func() {
+		if err == nil {
+			return
+		}
+		resp, abortErr := s.do(http.MethodDelete, name, uploadQuery(), nil, nil)
+		if abortErr == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	complete := &s3CompleteMultipartUpload{}
+	part := buf
+	for number := 1; len(part) > 0; number++ {
+		query := uploadQuery()
+		query.Set("partNumber", strconv.Itoa(number))
+		resp, err := s.do(http.MethodPut, name, query, part, nil)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		complete.Parts = append(complete.Parts, &s3CompletedPart{
+			PartNumber: number,
+			ETag:       resp.Header.Get("ETag"),
+		})
+
+		n, err := io.ReadFull(content, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		part = buf[:n]
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	resp, err = s.do(http.MethodPost, name, uploadQuery(), body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Completing an upload can fail even if the response has a 200 status
+	result, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(result, []byte("<Error>")) {
+		s3err := &s3Error{StatusCode: resp.StatusCode}
+		_ = xml.Unmarshal(result, s3err)
+		return s3err
+	}
+	return nil
+}
+
+// Remove deletes an object
+func (s *s3Storage) Remove(name string) error {
+	resp, err := s.do(http.MethodDelete, name, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// SignedURL returns a presigned url of an object, see https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func (s *s3Storage) SignedURL(name string, downloadName string, contentType string, expires time.Duration) (string, error) {
+	query := url.Values{}
+	if downloadName != "" {
+		query.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName}))
+	}
+	if contentType != "" {
+		query.Set("response-content-type", contentType)
+	}
+
+	t := s.now().UTC()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.getScope(t))
+	query.Set("X-Amz-Date", t.Format(s3DateFormat))
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expires.Seconds()), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	u := s.getObjectURL(name)
+	canonicalRequest := http.MethodGet + "\n" +
+		s3URIEncode(u.Path, false) + "\n" +
+		s3CanonicalQuery(query) + "\n" +
+		"host:" + u.Host + "\n\n" +
+		"host\n" +
+		s3UnsignedPayload
+
+	query.Set("X-Amz-Signature", s.getSignature(t, canonicalRequest))
+	u.RawQuery = s3CanonicalQuery(query)
+	return u.String(), nil
+}
+
+// s3Object is the content of an object which is downloaded with ranged requests when it is read
+type s3Object struct {
+	storage *s3Storage
+	name    string
+	size    int64
+	offset  int64
+	// The body of the request which is currently read from, starting at offset
+	body io.ReadCloser
+}
+
+func (s *s3Storage) getRange(name string, start, end int64) (io.ReadCloser, error) {
+	byteRange := "bytes=" + strconv.FormatInt(start, 10) + "-" + strconv.FormatInt(end, 10)
+
+	resp, err := s.do(http.MethodGet, name, nil, nil, http.Header{"Range": {byteRange}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Servers which don't support ranges send the whole object
+	if resp.StatusCode != http.StatusPartialContent && start > 0 {
+		if _, err := io.CopyN(ioutil.Discard, resp.Body, start); err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+	}
+
+	return resp.Body, nil
+}
+
+// Name returns the name of the object
+func (o *s3Object) Name() string {
+	return o.name
+}
+
+// Read reads the object from the current offset
+func (o *s3Object) Read(p []byte) (n int, err error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+
+	if o.body == nil {
+		end := o.offset + int64(o.storage.partSize) - 1
+		if end >= o.size {
+			end = o.size - 1
+		}
+		o.body, err = o.storage.getRange(o.name, o.offset, end)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = o.body.Read(p)
+	o.offset += int64(n)
+	if err == io.EOF && o.offset < o.size {
+		// Only the current part was read completely, the next read requests the next one
+		err = o.body.Close()
+		o.body = nil
+	}
+	return n, err
+}
+
+// ReadAt reads len(p) bytes of the object starting at off, regardless of the current offset
+func (o *s3Object) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= o.size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	body, err := o.storage.getRange(o.name, off, off+int64(len(p))-1)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err = io.ReadFull(body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek sets the offset of the next read. The object is only downloaded again if the offset changes.
+func (o *s3Object) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = o.offset + offset
+	case io.SeekEnd:
+		abs = o.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	if abs != o.offset && o.body != nil {
+		_ = o.body.Close()
+		o.body = nil
+	}
+	o.offset = abs
+	return abs, nil
+}
+
+// Close closes the request which is currently read from
+func (o *s3Object) Close() error {
+	if o.body == nil {
+		return nil
+	}
+	err := o.body.Close()
+	o.body = nil
+	return err
+}
@@ -0,0 +1,87 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package files
+
+import (
+	"io"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"github.com/spf13/afero"
+)
+
+// All storage backends which can be configured as files.type
+const (
+	StorageTypeLocal = `local`
+	StorageTypeS3    = `s3`
+)
+
+// Storage defines an interface for a backend which stores the content of files
+type Storage interface {
+	// Open returns the content of a stored file. If the file does not exist, the error satisfies os.IsNotExist.
+	Open(name string) (Content, error)
+	// Save stores the content of a file. The size is only a hint, backends must not rely on it being correct.
+	Save(name string, content io.Reader, size uint64) error
+	// Remove deletes a stored file
+	Remove(name string) error
+}
+
+// SignedURLStorage is a storage backend which can give clients direct access to files
+type SignedURLStorage interface {
+	Storage
+	// SignedURL returns a url which can be used to download a file without further authentication until it expires.
+	// The downloadName and mime are sent to the client when downloading the file.
+	SignedURL(name string, downloadName string, mime string, expires time.Duration) (string, error)
+}
+
+// Content is the content of a stored file. It can be read starting at any offset, which makes it possible to
+// answer ranged requests without reading the whole file.
+type Content interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	Name() string
+}
+
+// localStorage stores files in a directory of a file system
+type localStorage struct {
+	afs *afero.Afero
+}
+
+func newLocalStorage(fs afero.Fs) *localStorage {
+	return &localStorage{afs: &afero.Afero{Fs: fs}}
+}
+
+func (l *localStorage) getPath(name string) string {
+	return config.FilesBasePath.GetString() + "/" + name
+}
+
+// Open returns the content of a file in the directory
+func (l *localStorage) Open(name string) (Content, error) {
+	return l.afs.Open(l.getPath(name))
+}
+
+// Save writes a file to the directory
+func (l *localStorage) Save(name string, content io.Reader, size uint64) error {
+	return l.afs.WriteReader(l.getPath(name), content)
+}
+
+// Remove deletes a file from the directory
+func (l *localStorage) Remove(name string) error {
+	return l.afs.Remove(l.getPath(name))
+}
@@ -84,6 +84,12 @@ func FullInit() {
 	// Set Engine
 	InitEngines()
 
+	// A breached passwords file which can't be read should be noticed when starting, not when a user sets a password
+	err := user.InitBreachedPasswords()
+	if err != nil {
+		log.Fatalf("Could not load the breached passwords file: %s", err)
+	}
+
 	// Initialize the files handler
 	files.InitFileHandler()
 
@@ -93,11 +99,14 @@ func FullInit() {
 	// Start the cron
 	cron.Init()
 	models.RegisterReminderCron()
+	models.RegisterUserDeletionCron()
+	models.RegisterOldExportCleanupCron()
 
 	// Start processing events
 	go func() {
 		models.RegisterListeners()
 		user.RegisterListeners()
+		migrator.RegisterListeners()
 		err := events.InitEvents()
 		if err != nil {
 			log.Fatal(err.Error())
@@ -0,0 +1,76 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package integrations
+
+import (
+	"net/http"
+	"testing"
+
+	"code.vikunja.io/api/pkg/models"
+	apiv1 "code.vikunja.io/api/pkg/routes/api/v1"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmin(t *testing.T) {
+	t.Run("Stats", func(t *testing.T) {
+		t.Run("Admin", func(t *testing.T) {
+			rec, err := newTestRequestWithUser(t, http.MethodGet, apiv1.CheckAdmin(apiv1.AdminGetStats), &testuser15, "", nil, nil)
+			assert.NoError(t, err)
+			assert.Contains(t, rec.Body.String(), `"teams":14`)
+		})
+		t.Run("Not an admin", func(t *testing.T) {
+			_, err := newTestRequestWithUser(t, http.MethodGet, apiv1.CheckAdmin(apiv1.AdminGetStats), &testuser1, "", nil, nil)
+			assert.Error(t, err)
+			assertHandlerErrorCode(t, err, models.ErrorCodeGenericForbidden)
+		})
+	})
+	t.Run("Users", func(t *testing.T) {
+		testHandler := webHandlerTest{
+			user: &testuser15,
+			strFunc: func() handler.CObject {
+				return &models.AdminUser{}
+			},
+			t: t,
+		}
+		t.Run("ReadAll", func(t *testing.T) {
+			rec, err := testHandler.testReadAllWithUser(nil, nil)
+			assert.NoError(t, err)
+			assert.Contains(t, rec.Body.String(), `"username":"user1","name":"","email":"user1@example.com","is_active":true,"is_admin":false`)
+			assert.Contains(t, rec.Body.String(), `"username":"admin","name":"","email":"admin@example.com","is_active":true,"is_admin":true`)
+		})
+		t.Run("ReadAll as non-admin", func(t *testing.T) {
+			testHandler.user = &testuser1
+			defer func() {
+				testHandler.user = &testuser15
+			}()
+			_, err := testHandler.testReadAllWithUser(nil, nil)
+			assert.Error(t, err)
+			assertHandlerErrorCode(t, err, models.ErrorCodeGenericForbidden)
+		})
+		t.Run("Create as non-admin", func(t *testing.T) {
+			testHandler.user = &testuser1
+			defer func() {
+				testHandler.user = &testuser15
+			}()
+			_, err := testHandler.testCreateWithUser(nil, nil, `{"username":"newuser","email":"newuser@example.com","password":"12345678"}`)
+			assert.Error(t, err)
+			assert.Contains(t, err.(*echo.HTTPError).Message, `Forbidden`)
+		})
+	})
+}
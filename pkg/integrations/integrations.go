@@ -77,6 +77,15 @@ var (
 		EmailConfirmToken: "tiepiQueed8ahc7zeeFe1eveiy4Ein8osooxegiephauph2Ael",
 		IsActive:          false,
 	}
+	// The instance administrator
+	testuser15 = user.User{
+		ID:       15,
+		Username: "admin",
+		Password: "$2a$14$dcadBoMBL9jQoOcZK8Fju.cy0Ptx2oZECkKLnaa8ekRoTFe1w7To.",
+		Email:    "admin@example.com",
+		IsActive: true,
+		IsAdmin:  true,
+	}
 )
 
 func setupTestEnv() (e *echo.Echo, err error) {
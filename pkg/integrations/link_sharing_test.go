@@ -17,10 +17,13 @@
 package integrations
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	"code.vikunja.io/api/pkg/models"
+	apiv1 "code.vikunja.io/api/pkg/routes/api/v1"
 	"code.vikunja.io/web/handler"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -1087,3 +1090,54 @@ func TestLinkSharing(t *testing.T) {
 		})
 	})
 }
+
+func TestLinkSharingAuth(t *testing.T) {
+	authenticate := func(t *testing.T, hash string, payload string) (rec *httptest.ResponseRecorder, err error) {
+		rec, c := testRequestSetup(t, http.MethodPost, payload, nil, map[string]string{"share": hash})
+		err = apiv1.AuthenticateLinkShare(c)
+		return
+	}
+
+	t.Run("Without password", func(t *testing.T) {
+		rec, err := authenticate(t, "test", "")
+		assert.NoError(t, err)
+		assert.Contains(t, rec.Body.String(), `"token":"`)
+		assert.Contains(t, rec.Body.String(), `"list_id":1`)
+	})
+	t.Run("Password protected", func(t *testing.T) {
+		rec, err := authenticate(t, "testWithPassword", `{"password":"1234"}`)
+		assert.NoError(t, err)
+		assert.Contains(t, rec.Body.String(), `"token":"`)
+		assert.Contains(t, rec.Body.String(), `"hash":"testWithPassword"`)
+		assert.NotContains(t, rec.Body.String(), `$2a$14$`)
+	})
+	t.Run("Password protected without password", func(t *testing.T) {
+		_, err := authenticate(t, "testWithPassword", "")
+		assert.Error(t, err)
+		assertHandlerErrorCode(t, err, models.ErrCodeLinkSharePasswordRequired)
+	})
+	t.Run("Password protected with wrong password", func(t *testing.T) {
+		_, err := authenticate(t, "testWithPassword", `{"password":"wrong"}`)
+		assert.Error(t, err)
+		assertHandlerErrorCode(t, err, models.ErrCodeLinkSharePasswordInvalid)
+	})
+	t.Run("Nonexisting", func(t *testing.T) {
+		_, err := authenticate(t, "doesnotexist", "")
+		assert.Error(t, err)
+		assertHandlerErrorCode(t, err, models.ErrCodeListShareDoesNotExist)
+	})
+	t.Run("Shares of the list", func(t *testing.T) {
+		testHandler := webHandlerTest{
+			user: &testuser1,
+			strFunc: func() handler.CObject {
+				return &models.LinkSharing{}
+			},
+			t: t,
+		}
+		rec, err := testHandler.testReadAllWithUser(nil, map[string]string{"list": "1"})
+		assert.NoError(t, err)
+		assert.Contains(t, rec.Body.String(), `"hash":"test"`)
+		assert.Contains(t, rec.Body.String(), `"hash":"testWithPassword"`)
+		assert.NotContains(t, rec.Body.String(), `$2a$14$`)
+	})
+}
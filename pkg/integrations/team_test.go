@@ -0,0 +1,88 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package integrations
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeam(t *testing.T) {
+	testuser14 := &user.User{
+		ID:       14,
+		Username: "user14",
+		Email:    "user15@some.service.com",
+		IsActive: true,
+	}
+
+	t.Run("Externally managed", func(t *testing.T) {
+		testHandler := webHandlerTest{
+			user: testuser14,
+			strFunc: func() handler.CObject {
+				return &models.Team{}
+			},
+			t: t,
+		}
+		testHandlerMember := webHandlerTest{
+			user: testuser14,
+			strFunc: func() handler.CObject {
+				return &models.TeamMember{}
+			},
+			t: t,
+		}
+
+		t.Run("ReadOne", func(t *testing.T) {
+			rec, err := testHandler.testReadOneWithUser(nil, map[string]string{"team": "14"})
+			assert.NoError(t, err)
+			assert.Contains(t, rec.Body.String(), `"name":"testteam14_external"`)
+			assert.Contains(t, rec.Body.String(), `"external_id":"vikunja-admins"`)
+		})
+		t.Run("Update keeps the external id", func(t *testing.T) {
+			rec, err := testHandler.testUpdateWithUser(nil, map[string]string{"team": "14"}, `{"name":"Lorem","external_id":"other"}`)
+			assert.NoError(t, err)
+			assert.Contains(t, rec.Body.String(), `"name":"Lorem"`)
+			assert.Contains(t, rec.Body.String(), `"external_id":"vikunja-admins"`)
+		})
+		t.Run("Add member", func(t *testing.T) {
+			_, err := testHandlerMember.testCreateWithUser(nil, map[string]string{"team": "14"}, `{"username":"user1"}`)
+			assert.Error(t, err)
+			assertHandlerErrorCode(t, err, models.ErrCodeCannotModifyExternalTeamMembers)
+		})
+		t.Run("Remove member", func(t *testing.T) {
+			_, err := testHandlerMember.testDeleteWithUser(nil, map[string]string{"team": "14", "user": "user14"})
+			assert.Error(t, err)
+			assertHandlerErrorCode(t, err, models.ErrCodeCannotModifyExternalTeamMembers)
+		})
+	})
+	t.Run("Create ignores the external id", func(t *testing.T) {
+		testHandler := webHandlerTest{
+			user: &testuser1,
+			strFunc: func() handler.CObject {
+				return &models.Team{}
+			},
+			t: t,
+		}
+		rec, err := testHandler.testCreateWithUser(nil, nil, `{"name":"Lorem","external_id":"vikunja-admins"}`)
+		assert.NoError(t, err)
+		assert.Contains(t, rec.Body.String(), `"name":"Lorem"`)
+		assert.Contains(t, rec.Body.String(), `"external_id":""`)
+	})
+}
@@ -0,0 +1,68 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"src.techknowlogick.com/xormigrate"
+	"xorm.io/xorm"
+)
+
+type oauth2Clients20210406103220 struct {
+	ID           int64     `xorm:"bigint autoincr not null unique pk"`
+	ClientID     string    `xorm:"varchar(40) not null unique"`
+	Name         string    `xorm:"varchar(250) not null"`
+	RedirectURIs []string  `xorm:"'redirect_uris' JSON not null"`
+	Confidential bool      `xorm:"not null default false"`
+	SecretHash   string    `xorm:"varchar(45) null"`
+	OwnerID      int64     `xorm:"bigint not null INDEX"`
+	Created      time.Time `xorm:"created not null"`
+	Updated      time.Time `xorm:"updated not null"`
+}
+
+func (oauth2Clients20210406103220) TableName() string {
+	return "oauth2_clients"
+}
+
+type oauth2Grants20210406103220 struct {
+	ID               int64     `xorm:"bigint autoincr not null unique pk"`
+	ClientID         int64     `xorm:"bigint not null INDEX"`
+	UserID           int64     `xorm:"bigint not null INDEX"`
+	Scope            string    `xorm:"varchar(250) not null"`
+	RefreshTokenHash string    `xorm:"varchar(45) null INDEX"`
+	LastUsed         time.Time `xorm:"datetime null"`
+	Created          time.Time `xorm:"created not null"`
+	Updated          time.Time `xorm:"updated not null"`
+}
+
+func (oauth2Grants20210406103220) TableName() string {
+	return "oauth2_grants"
+}
+
+func init() {
+	migrations = append(migrations, &xormigrate.Migration{
+		ID:          "20210406103220",
+		Description: "Add oauth2 clients and grants tables",
+		Migrate: func(tx *xorm.Engine) error {
+			return tx.Sync2(oauth2Clients20210406103220{}, oauth2Grants20210406103220{})
+		},
+		Rollback: func(tx *xorm.Engine) error {
+			return tx.DropTables(oauth2Clients20210406103220{}, oauth2Grants20210406103220{})
+		},
+	})
+}
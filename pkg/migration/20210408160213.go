@@ -0,0 +1,63 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"src.techknowlogick.com/xormigrate"
+	"xorm.io/xorm"
+)
+
+type users20210408160213 struct {
+	DeletionConfirmToken string    `xorm:"varchar(450) null"`
+	DeletionScheduledAt  time.Time `xorm:"datetime null"`
+}
+
+func (users20210408160213) TableName() string {
+	return "users"
+}
+
+type userDeletionTransfers20210408160213 struct {
+	ID          int64     `xorm:"bigint autoincr not null unique pk"`
+	UserID      int64     `xorm:"bigint not null INDEX"`
+	NamespaceID int64     `xorm:"bigint null INDEX"`
+	ListID      int64     `xorm:"bigint null INDEX"`
+	NewOwnerID  int64     `xorm:"bigint not null INDEX"`
+	Created     time.Time `xorm:"created not null"`
+}
+
+func (userDeletionTransfers20210408160213) TableName() string {
+	return "user_deletion_transfers"
+}
+
+func init() {
+	migrations = append(migrations, &xormigrate.Migration{
+		ID:          "20210408160213",
+		Description: "Add account deletion columns to users and the user deletion transfers table",
+		Migrate: func(tx *xorm.Engine) error {
+			err := tx.Sync2(users20210408160213{})
+			if err != nil {
+				return err
+			}
+			return tx.Sync2(userDeletionTransfers20210408160213{})
+		},
+		Rollback: func(tx *xorm.Engine) error {
+			return tx.DropTables(userDeletionTransfers20210408160213{})
+		},
+	})
+}
@@ -0,0 +1,57 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"src.techknowlogick.com/xormigrate"
+	"xorm.io/xorm"
+)
+
+type migrationStatus20210411161337 struct {
+	ListsImported int64     `xorm:"bigint not null default 0"`
+	TasksImported int64     `xorm:"bigint not null default 0"`
+	FilesImported int64     `xorm:"bigint not null default 0"`
+	Error         string    `xorm:"text null"`
+	StartedAt     time.Time `xorm:"DATETIME null"`
+	FinishedAt    time.Time `xorm:"DATETIME null"`
+}
+
+func (migrationStatus20210411161337) TableName() string {
+	return "migration_status"
+}
+
+func init() {
+	migrations = append(migrations, &xormigrate.Migration{
+		ID:          "20210411161337",
+		Description: "Add progress and report columns to migration status",
+		Migrate: func(tx *xorm.Engine) error {
+			err := tx.Sync2(migrationStatus20210411161337{})
+			if err != nil {
+				return err
+			}
+
+			// All migrations before this one ran synchronously, they were finished when their status was created
+			_, err = tx.Exec("UPDATE migration_status SET started_at = created, finished_at = created")
+			return err
+		},
+		Rollback: func(tx *xorm.Engine) error {
+			return nil
+		},
+	})
+}
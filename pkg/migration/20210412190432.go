@@ -0,0 +1,49 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"src.techknowlogick.com/xormigrate"
+	"xorm.io/xorm"
+)
+
+type taskCaldavProperties20210412190432 struct {
+	ID         int64     `xorm:"bigint autoincr not null unique pk"`
+	TaskID     int64     `xorm:"bigint not null unique"`
+	Properties string    `xorm:"longtext null"`
+	Created    time.Time `xorm:"created not null"`
+	Updated    time.Time `xorm:"updated not null"`
+}
+
+func (taskCaldavProperties20210412190432) TableName() string {
+	return "task_caldav_properties"
+}
+
+func init() {
+	migrations = append(migrations, &xormigrate.Migration{
+		ID:          "20210412190432",
+		Description: "Add task caldav properties table",
+		Migrate: func(tx *xorm.Engine) error {
+			return tx.Sync2(taskCaldavProperties20210412190432{})
+		},
+		Rollback: func(tx *xorm.Engine) error {
+			return tx.DropTables(taskCaldavProperties20210412190432{})
+		},
+	})
+}
@@ -0,0 +1,51 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"src.techknowlogick.com/xormigrate"
+	"xorm.io/xorm"
+)
+
+type icalFeeds20210415181210 struct {
+	ID            int64     `xorm:"bigint autoincr not null unique pk"`
+	Token         string    `xorm:"varchar(64) not null unique"`
+	Kind          string    `xorm:"varchar(20) not null"`
+	ListID        int64     `xorm:"bigint null"`
+	SavedFilterID int64     `xorm:"bigint null"`
+	OwnerID       int64     `xorm:"bigint not null INDEX"`
+	Created       time.Time `xorm:"created not null"`
+}
+
+func (icalFeeds20210415181210) TableName() string {
+	return "ical_feeds"
+}
+
+func init() {
+	migrations = append(migrations, &xormigrate.Migration{
+		ID:          "20210415181210",
+		Description: "Add ical feeds table",
+		Migrate: func(tx *xorm.Engine) error {
+			return tx.Sync2(icalFeeds20210415181210{})
+		},
+		Rollback: func(tx *xorm.Engine) error {
+			return tx.DropTables(icalFeeds20210415181210{})
+		},
+	})
+}
@@ -0,0 +1,119 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// AddressBook holds everyone a user can see in their address book
+type AddressBook struct {
+	// All users who share at least one list or team with the user, without the user itself.
+	// Names and email addresses are only set if the user made them discoverable.
+	Users []*user.User
+	// All teams the user is a member of.
+	Teams []*Team
+	// The ids of the members of every team which are part of the address book
+	TeamMemberIDs map[int64][]int64
+}
+
+// GetAddressBookForUser returns all users and teams the user shares a list or team with
+func GetAddressBookForUser(s *xorm.Session, u *user.User) (book *AddressBook, err error) {
+	listUserIDs, err := GetUserIDsSharingListsWithUser(s, u)
+	if err != nil {
+		return nil, err
+	}
+
+	book = &AddressBook{
+		Users:         []*user.User{},
+		Teams:         []*Team{},
+		TeamMemberIDs: make(map[int64][]int64),
+	}
+
+	err = s.
+		Select("teams.*").
+		Table("teams").
+		Join("INNER", "team_members", "team_members.team_id = teams.id").
+		Where("team_members.user_id = ?", u.ID).
+		OrderBy("teams.id").
+		Find(&book.Teams)
+	if err != nil {
+		return nil, err
+	}
+
+	teamIDs := make([]int64, 0, len(book.Teams))
+	for _, t := range book.Teams {
+		teamIDs = append(teamIDs, t.ID)
+	}
+
+	members := []*TeamMember{}
+	if len(teamIDs) > 0 {
+		err = s.
+			Where(builder.In("team_id", teamIDs)).
+			OrderBy("id").
+			Find(&members)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	uidmap := make(map[int64]bool)
+	for _, id := range listUserIDs {
+		uidmap[id] = true
+	}
+	for _, m := range members {
+		uidmap[m.UserID] = true
+	}
+	delete(uidmap, u.ID)
+
+	if len(uidmap) == 0 {
+		return book, nil
+	}
+
+	uids := make([]int64, 0, len(uidmap))
+	for id := range uidmap {
+		uids = append(uids, id)
+	}
+
+	err = s.
+		In("id", uids).
+		OrderBy("id").
+		Find(&book.Users)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bu := range book.Users {
+		if !bu.DiscoverableByName {
+			bu.Name = ""
+		}
+		if !bu.DiscoverableByEmail {
+			bu.Email = ""
+		}
+	}
+
+	for _, m := range members {
+		if !uidmap[m.UserID] {
+			continue
+		}
+		book.TeamMemberIDs[m.TeamID] = append(book.TeamMemberIDs[m.TeamID], m.UserID)
+	}
+
+	return book, nil
+}
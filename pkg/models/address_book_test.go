@@ -0,0 +1,68 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAddressBookForUser(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		book, err := GetAddressBookForUser(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+
+		users := make(map[int64]*user.User)
+		for _, u := range book.Users {
+			users[u.ID] = u
+		}
+		assert.NotContains(t, users, int64(1))
+		assert.Contains(t, users, int64(2))
+		assert.Empty(t, users[2].Email)
+		assert.Equal(t, []int64{2}, book.TeamMemberIDs[1])
+		assert.NotEmpty(t, book.Teams)
+		assert.Equal(t, int64(1), book.Teams[0].ID)
+	})
+	t.Run("discoverable", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.
+			Where("id = ?", 2).
+			Cols("name", "discoverable_by_name", "discoverable_by_email").
+			Update(&user.User{Name: "Some Name", DiscoverableByName: true, DiscoverableByEmail: true})
+		assert.NoError(t, err)
+
+		book, err := GetAddressBookForUser(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+
+		for _, u := range book.Users {
+			if u.ID == 2 {
+				assert.Equal(t, "Some Name", u.Name)
+				assert.Equal(t, "user2@example.com", u.Email)
+			}
+		}
+	})
+}
@@ -0,0 +1,449 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// IsInstanceAdmin checks if the doer is an active administrator of this instance.
+// Link shares are never administrators.
+func IsInstanceAdmin(s *xorm.Session, a web.Auth) (bool, error) {
+	if _, is := a.(*LinkSharing); is {
+		return false, nil
+	}
+
+	u, err := user.GetUserByID(s, a.GetID())
+	if err != nil {
+		if user.IsErrUserDoesNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return u.IsAdmin && u.IsActive, nil
+}
+
+// AdminUser represents a user with all the details only instance administrators can see
+type AdminUser struct {
+	// The unique, numeric id of this user.
+	ID int64 `json:"id" param:"user"`
+	// The username of the user. Is always unique.
+	Username string `json:"username" valid:"length(1|250)" minLength:"1" maxLength:"250"`
+	// The full name of the user.
+	Name string `json:"name"`
+	// The user's email address.
+	Email string `json:"email" valid:"email,length(0|250)" maxLength:"250"`
+	// The password of the user. Only used when creating a user, never returned.
+	Password string `json:"password,omitempty"`
+	// Whether the user is active and can log in.
+	IsActive bool `json:"is_active"`
+	// Whether the user is an administrator of this instance.
+	IsAdmin bool `json:"is_admin"`
+	// Where the user authenticates, either "local" or the issuer url of an openid provider.
+	Issuer string `json:"issuer"`
+
+	// A timestamp when this user was created. You cannot change this value.
+	Created time.Time `json:"created"`
+	// A timestamp when this user was last updated. You cannot change this value.
+	Updated time.Time `json:"updated"`
+
+	web.CRUDable `json:"-"`
+	web.Rights   `json:"-"`
+}
+
+func newAdminUser(u *user.User) *AdminUser {
+	return &AdminUser{
+		ID:       u.ID,
+		Username: u.Username,
+		Name:     u.Name,
+		Email:    u.Email,
+		IsActive: u.IsActive,
+		IsAdmin:  u.IsAdmin,
+		Issuer:   u.Issuer,
+		Created:  u.Created,
+		Updated:  u.Updated,
+	}
+}
+
+// ReadAll returns all users of this instance
+// @Summary Get all users
+// @Description Lists all users of this instance, including their email address and status. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Param page query int false "The page number. Used for pagination. If not provided, the first page of results is returned."
+// @Param per_page query int false "The maximum number of items per page. Note this parameter is limited by the configured maximum of items per page."
+// @Param s query string false "Search users by their username, name or email."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.AdminUser "The users."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /admin/users [get]
+func (au *AdminUser) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	isAdmin, err := IsInstanceAdmin(s, a)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if !isAdmin {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	limit, start := getLimitFromPageIndex(page, perPage)
+
+	cond := builder.Or(
+		builder.Like{"username", "%" + search + "%"},
+		builder.Like{"name", "%" + search + "%"},
+		builder.Like{"email", "%" + search + "%"},
+	)
+
+	users := []*user.User{}
+	query := s.
+		Where(cond).
+		OrderBy("id asc")
+	if limit > 0 {
+		query = query.Limit(limit, start)
+	}
+	err = query.Find(&users)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	all := make([]*AdminUser, 0, len(users))
+	for _, u := range users {
+		all = append(all, newAdminUser(u))
+	}
+
+	numberOfTotalItems, err = s.
+		Where(cond).
+		Count(&user.User{})
+	return all, len(all), numberOfTotalItems, err
+}
+
+// Create creates a new user together with their default namespace
+// @Summary Create a user
+// @Description Creates a new local user, the same way as the `user create` cli command. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param user body models.AdminUser true "The user you want to create."
+// @Success 201 {object} models.AdminUser "The created user."
+// @Failure 400 {object} web.HTTPError "Invalid user object provided."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /admin/users [put]
+func (au *AdminUser) Create(s *xorm.Session, a web.Auth) (err error) {
+	newUser, err := user.CreateUser(s, &user.User{
+		Username: au.Username,
+		Name:     au.Name,
+		Email:    au.Email,
+		Password: au.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = CreateNewNamespaceForUser(s, newUser)
+	if err != nil {
+		return err
+	}
+
+	if au.IsAdmin {
+		err = user.SetUserAdmin(s, newUser, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	newUser, err = user.GetUserWithEmail(s, &user.User{ID: newUser.ID})
+	if err != nil {
+		return err
+	}
+
+	*au = *newAdminUser(newUser)
+	return nil
+}
+
+// CanCreate checks if the user can create users
+func (au *AdminUser) CanCreate(s *xorm.Session, a web.Auth) (bool, error) {
+	return IsInstanceAdmin(s, a)
+}
+
+// AdminNamespace is used by instance administrators to see all namespaces
+type AdminNamespace struct {
+	web.CRUDable `json:"-"`
+	web.Rights   `json:"-"`
+}
+
+// ReadAll returns all namespaces of this instance
+// @Summary Get all namespaces
+// @Description Lists all namespaces of this instance with their owners. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Param page query int false "The page number. Used for pagination. If not provided, the first page of results is returned."
+// @Param per_page query int false "The maximum number of items per page. Note this parameter is limited by the configured maximum of items per page."
+// @Param s query string false "Search namespaces by their title."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.Namespace "The namespaces."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /admin/namespaces [get]
+func (an *AdminNamespace) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	isAdmin, err := IsInstanceAdmin(s, a)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if !isAdmin {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	limit, start := getLimitFromPageIndex(page, perPage)
+
+	namespaces := []*Namespace{}
+	query := s.
+		Where("title LIKE ?", "%"+search+"%").
+		OrderBy("id asc")
+	if limit > 0 {
+		query = query.Limit(limit, start)
+	}
+	err = query.Find(&namespaces)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	ownerIDs := make([]int64, 0, len(namespaces))
+	for _, n := range namespaces {
+		ownerIDs = append(ownerIDs, n.OwnerID)
+	}
+	owners, err := user.GetUsersByIDs(s, ownerIDs)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	for _, n := range namespaces {
+		n.Owner = owners[n.OwnerID]
+	}
+
+	numberOfTotalItems, err = s.
+		Where("title LIKE ?", "%"+search+"%").
+		Count(&Namespace{})
+	return namespaces, len(namespaces), numberOfTotalItems, err
+}
+
+// AdminList is used by instance administrators to see all lists
+type AdminList struct {
+	web.CRUDable `json:"-"`
+	web.Rights   `json:"-"`
+}
+
+// ReadAll returns all lists of this instance
+// @Summary Get all lists
+// @Description Lists all lists of this instance with their owners. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Param page query int false "The page number. Used for pagination. If not provided, the first page of results is returned."
+// @Param per_page query int false "The maximum number of items per page. Note this parameter is limited by the configured maximum of items per page."
+// @Param s query string false "Search lists by their title."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.List "The lists."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /admin/lists [get]
+func (al *AdminList) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	isAdmin, err := IsInstanceAdmin(s, a)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if !isAdmin {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	limit, start := getLimitFromPageIndex(page, perPage)
+
+	lists := []*List{}
+	query := s.
+		Where("title LIKE ?", "%"+search+"%").
+		OrderBy("id asc")
+	if limit > 0 {
+		query = query.Limit(limit, start)
+	}
+	err = query.Find(&lists)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	err = addListDetails(s, lists)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	numberOfTotalItems, err = s.
+		Where("title LIKE ?", "%"+search+"%").
+		Count(&List{})
+	return lists, len(lists), numberOfTotalItems, err
+}
+
+// OwnershipTransfer holds the new owner of a namespace or list
+type OwnershipTransfer struct {
+	// The id of the user who should become the new owner.
+	OwnerID int64 `json:"owner_id"`
+	// Only for lists: The id of a namespace of the new owner the list is moved into. If not provided,
+	// the list is moved into the oldest namespace of the new owner.
+	NamespaceID int64 `json:"namespace_id"`
+}
+
+// TransferNamespaceOwnership makes another user the owner of a namespace. All lists in the namespace which belonged
+// to the previous owner are transferred as well.
+func TransferNamespaceOwnership(s *xorm.Session, namespaceID int64, newOwnerID int64) (namespace *Namespace, err error) {
+	if namespaceID < 1 {
+		return nil, ErrNamespaceDoesNotExist{ID: namespaceID}
+	}
+
+	namespace, err = getNamespaceSimpleByID(s, namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	newOwner, err := user.GetUserByID(s, newOwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.
+		Where("namespace_id = ? AND owner_id = ?", namespace.ID, namespace.OwnerID).
+		Cols("owner_id").
+		Update(&List{OwnerID: newOwner.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	namespace.OwnerID = newOwner.ID
+	_, err = s.
+		ID(namespace.ID).
+		Cols("owner_id").
+		Update(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetNamespaceByID(s, namespace.ID)
+}
+
+// TransferListOwnership makes another user the owner of a list. The list is moved into a namespace of the new owner
+// so they can find and manage it. If namespaceID is 0, this is the oldest namespace of the new owner which is not
+// archived or a new one if they don't have any.
+func TransferListOwnership(s *xorm.Session, listID int64, newOwnerID int64, namespaceID int64) (list *List, err error) {
+	list, err = GetListSimpleByID(s, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	newOwner, err := user.GetUserByID(s, newOwnerID)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := getListTransferNamespace(s, newOwner, namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	list.OwnerID = newOwner.ID
+	list.NamespaceID = namespace.ID
+	_, err = s.
+		ID(list.ID).
+		Cols("owner_id", "namespace_id").
+		Update(list)
+	if err != nil {
+		return nil, err
+	}
+
+	list.Owner = newOwner
+	return list, nil
+}
+
+func getListTransferNamespace(s *xorm.Session, newOwner *user.User, namespaceID int64) (namespace *Namespace, err error) {
+	if namespaceID != 0 {
+		if namespaceID < 1 {
+			return nil, ErrNamespaceDoesNotExist{ID: namespaceID}
+		}
+		namespace, err = getNamespaceSimpleByID(s, namespaceID)
+		if err != nil {
+			return nil, err
+		}
+		if namespace.OwnerID != newOwner.ID {
+			return nil, ErrNamespaceNotOwnedByUser{NamespaceID: namespaceID, UserID: newOwner.ID}
+		}
+		return namespace, nil
+	}
+
+	namespace = &Namespace{}
+	exists, err := s.
+		Where("owner_id = ? AND is_archived = ?", newOwner.ID, false).
+		OrderBy("id asc").
+		Get(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return namespace, nil
+	}
+
+	return createNewNamespaceForUser(s, newOwner)
+}
+
+// InstanceStats holds the number of entities on this instance. These are the same numbers the metrics endpoint
+// exposes, but they are always available.
+type InstanceStats struct {
+	Users      int64 `json:"users"`
+	Namespaces int64 `json:"namespaces"`
+	Lists      int64 `json:"lists"`
+	Tasks      int64 `json:"tasks"`
+	Teams      int64 `json:"teams"`
+}
+
+// GetInstanceStats counts all users, namespaces, lists, tasks and teams.
+func GetInstanceStats(s *xorm.Session) (stats *InstanceStats, err error) {
+	stats = &InstanceStats{}
+
+	counts := []struct {
+		bean  interface{}
+		count *int64
+	}{
+		{&user.User{}, &stats.Users},
+		{&Namespace{}, &stats.Namespaces},
+		{&List{}, &stats.Lists},
+		{&Task{}, &stats.Tasks},
+		{&Team{}, &stats.Teams},
+	}
+
+	for _, c := range counts {
+		*c.count, err = s.Count(c.bean)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
@@ -0,0 +1,224 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInstanceAdmin(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	isAdmin, err := IsInstanceAdmin(s, &user.User{ID: 15})
+	assert.NoError(t, err)
+	assert.True(t, isAdmin)
+
+	isAdmin, err = IsInstanceAdmin(s, &user.User{ID: 1})
+	assert.NoError(t, err)
+	assert.False(t, isAdmin)
+
+	isAdmin, err = IsInstanceAdmin(s, &LinkSharing{ID: 1})
+	assert.NoError(t, err)
+	assert.False(t, isAdmin)
+}
+
+func TestAdminUser_ReadAll(t *testing.T) {
+	t.Run("search", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		au := &AdminUser{}
+		result, count, total, err := au.ReadAll(s, &user.User{ID: 15}, "user1", 1, 2)
+		assert.NoError(t, err)
+		// user1, user10 to user14
+		assert.Equal(t, 2, count)
+		assert.Equal(t, int64(6), total)
+		users := result.([]*AdminUser)
+		assert.Equal(t, "user1", users[0].Username)
+		assert.Equal(t, "user1@example.com", users[0].Email)
+		assert.False(t, users[0].IsAdmin)
+	})
+	t.Run("no admin", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		au := &AdminUser{}
+		_, _, _, err := au.ReadAll(s, &user.User{ID: 6}, "", 1, 50)
+		assert.Error(t, err)
+		assert.True(t, IsErrGenericForbidden(err))
+	})
+}
+
+func TestAdminUser_Create(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	au := &AdminUser{
+		Username: "newadmin",
+		Email:    "newadmin@example.com",
+		Password: "12345678",
+		IsAdmin:  true,
+	}
+	can, err := au.CanCreate(s, &user.User{ID: 15})
+	assert.NoError(t, err)
+	assert.True(t, can)
+	err = au.Create(s, &user.User{ID: 15})
+	assert.NoError(t, err)
+	assert.Empty(t, au.Password)
+	assert.True(t, au.IsAdmin)
+	err = s.Commit()
+	assert.NoError(t, err)
+
+	db.AssertExists(t, "users", map[string]interface{}{
+		"id":       au.ID,
+		"username": "newadmin",
+		"is_admin": true,
+	}, false)
+	db.AssertExists(t, "namespaces", map[string]interface{}{
+		"owner_id": au.ID,
+	}, false)
+}
+
+func TestTransferNamespaceOwnership(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		n, err := TransferNamespaceOwnership(s, 1, 6)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), n.Owner.ID)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "namespaces", map[string]interface{}{
+			"id":       1,
+			"owner_id": 6,
+		}, false)
+		db.AssertExists(t, "list", map[string]interface{}{
+			"id":       1,
+			"owner_id": 6,
+		}, false)
+		// Lists of other users in that namespace stay theirs
+		db.AssertExists(t, "list", map[string]interface{}{
+			"id":       2,
+			"owner_id": 3,
+		}, false)
+	})
+	t.Run("nonexisting user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := TransferNamespaceOwnership(s, 1, 9999)
+		assert.Error(t, err)
+		assert.True(t, user.IsErrUserDoesNotExist(err))
+	})
+	t.Run("pseudo namespace", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := TransferNamespaceOwnership(s, SharedListsPseudoNamespace.ID, 6)
+		assert.Error(t, err)
+		assert.True(t, IsErrNamespaceDoesNotExist(err))
+	})
+}
+
+func TestTransferListOwnership(t *testing.T) {
+	t.Run("into the oldest namespace of the new owner", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		l, err := TransferListOwnership(s, 2, 6, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), l.Owner.ID)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "list", map[string]interface{}{
+			"id":           2,
+			"owner_id":     6,
+			"namespace_id": 6,
+		}, false)
+	})
+	t.Run("into a namespace of the new owner", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := TransferListOwnership(s, 2, 6, 7)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "list", map[string]interface{}{
+			"id":           2,
+			"owner_id":     6,
+			"namespace_id": 7,
+		}, false)
+	})
+	t.Run("into a namespace of someone else", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := TransferListOwnership(s, 2, 6, 1)
+		assert.Error(t, err)
+		assert.True(t, IsErrNamespaceNotOwnedByUser(err))
+	})
+	t.Run("new owner without namespaces", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		l, err := TransferListOwnership(s, 2, 4, 0)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "namespaces", map[string]interface{}{
+			"id":       l.NamespaceID,
+			"owner_id": 4,
+			"title":    "user4",
+		}, false)
+	})
+}
+
+func TestGetInstanceStats(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	stats, err := GetInstanceStats(s)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15), stats.Users)
+	assert.Equal(t, int64(14), stats.Teams)
+	assert.NotZero(t, stats.Namespaces)
+	assert.NotZero(t, stats.Lists)
+	assert.NotZero(t, stats.Tasks)
+}
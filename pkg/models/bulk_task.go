@@ -115,6 +115,11 @@ func (bt *BulkTask) Update(s *xorm.Session, a web.Auth) (err error) {
 		if err != nil {
 			return err
 		}
+
+		// Caldav clients need to know about the change as well
+		if err := recordTaskChange(s, oldtask.ID); err != nil {
+			return err
+		}
 	}
 
 	return
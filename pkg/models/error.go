@@ -255,6 +255,52 @@ func (err ErrListIsArchived) HTTPError() web.HTTPError {
 	return web.HTTPError{HTTPCode: http.StatusPreconditionFailed, Code: ErrCodeListIsArchived, Message: "This lists is archived. Editing or creating new tasks is not possible."}
 }
 
+// ErrLinkSharePasswordRequired represents an error where a link share needs a password but none was provided
+type ErrLinkSharePasswordRequired struct {
+	ShareID int64
+}
+
+// IsErrLinkSharePasswordRequired checks if an error is a ErrLinkSharePasswordRequired.
+func IsErrLinkSharePasswordRequired(err error) bool {
+	_, ok := err.(ErrLinkSharePasswordRequired)
+	return ok
+}
+
+func (err ErrLinkSharePasswordRequired) Error() string {
+	return fmt.Sprintf("Link share needs a password [ShareID: %d]", err.ShareID)
+}
+
+// ErrCodeLinkSharePasswordRequired holds the unique world-error code of this error
+const ErrCodeLinkSharePasswordRequired = 3009
+
+// HTTPError holds the http error description
+func (err ErrLinkSharePasswordRequired) HTTPError() web.HTTPError {
+	return web.HTTPError{HTTPCode: http.StatusBadRequest, Code: ErrCodeLinkSharePasswordRequired, Message: "This link share needs a password."}
+}
+
+// ErrLinkSharePasswordInvalid represents an error where the password of a link share is wrong
+type ErrLinkSharePasswordInvalid struct {
+	ShareID int64
+}
+
+// IsErrLinkSharePasswordInvalid checks if an error is a ErrLinkSharePasswordInvalid.
+func IsErrLinkSharePasswordInvalid(err error) bool {
+	_, ok := err.(ErrLinkSharePasswordInvalid)
+	return ok
+}
+
+func (err ErrLinkSharePasswordInvalid) Error() string {
+	return fmt.Sprintf("Provided link share password is invalid [ShareID: %d]", err.ShareID)
+}
+
+// ErrCodeLinkSharePasswordInvalid holds the unique world-error code of this error
+const ErrCodeLinkSharePasswordInvalid = 3010
+
+// HTTPError holds the http error description
+func (err ErrLinkSharePasswordInvalid) HTTPError() web.HTTPError {
+	return web.HTTPError{HTTPCode: http.StatusForbidden, Code: ErrCodeLinkSharePasswordInvalid, Message: "The provided link share password is invalid."}
+}
+
 // ================
 // List task errors
 // ================
@@ -763,6 +809,60 @@ func (err ErrInvalidTaskFilterValue) HTTPError() web.HTTPError {
 	}
 }
 
+// ErrInvalidPreviewSize represents an error where the requested size of an attachment preview is invalid
+type ErrInvalidPreviewSize struct {
+	Size string
+}
+
+// IsErrInvalidPreviewSize checks if an error is ErrInvalidPreviewSize.
+func IsErrInvalidPreviewSize(err error) bool {
+	_, ok := err.(ErrInvalidPreviewSize)
+	return ok
+}
+
+func (err ErrInvalidPreviewSize) Error() string {
+	return fmt.Sprintf("Preview size is invalid [Size: %s]", err.Size)
+}
+
+// ErrCodeInvalidPreviewSize holds the unique world-error code of this error
+const ErrCodeInvalidPreviewSize = 4020
+
+// HTTPError holds the http error description
+func (err ErrInvalidPreviewSize) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeInvalidPreviewSize,
+		Message:  fmt.Sprintf("The preview size '%s' is invalid, it needs to be one of sm, md, lg or xl.", err.Size),
+	}
+}
+
+// ErrTaskAttachmentHasNoPreview represents an error where a preview of an attachment was requested which is not an image
+type ErrTaskAttachmentHasNoPreview struct {
+	AttachmentID int64
+}
+
+// IsErrTaskAttachmentHasNoPreview checks if an error is ErrTaskAttachmentHasNoPreview.
+func IsErrTaskAttachmentHasNoPreview(err error) bool {
+	_, ok := err.(ErrTaskAttachmentHasNoPreview)
+	return ok
+}
+
+func (err ErrTaskAttachmentHasNoPreview) Error() string {
+	return fmt.Sprintf("Task attachment has no preview [AttachmentID: %d]", err.AttachmentID)
+}
+
+// ErrCodeTaskAttachmentHasNoPreview holds the unique world-error code of this error
+const ErrCodeTaskAttachmentHasNoPreview = 4021
+
+// HTTPError holds the http error description
+func (err ErrTaskAttachmentHasNoPreview) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeTaskAttachmentHasNoPreview,
+		Message:  "There is no preview of this attachment, only images can be previewed.",
+	}
+}
+
 // =================
 // Namespace errors
 // =================
@@ -933,6 +1033,31 @@ func (err ErrNamespaceIsArchived) HTTPError() web.HTTPError {
 	return web.HTTPError{HTTPCode: http.StatusPreconditionFailed, Code: ErrCodeNamespaceIsArchived, Message: "This namespaces is archived. Editing or creating new lists is not possible."}
 }
 
+// ErrNamespaceNotOwnedByUser represents an error where a list should be moved into a namespace of a user which
+// belongs to someone else
+type ErrNamespaceNotOwnedByUser struct {
+	NamespaceID int64
+	UserID      int64
+}
+
+// IsErrNamespaceNotOwnedByUser checks if an error is a ErrNamespaceNotOwnedByUser.
+func IsErrNamespaceNotOwnedByUser(err error) bool {
+	_, ok := err.(ErrNamespaceNotOwnedByUser)
+	return ok
+}
+
+func (err ErrNamespaceNotOwnedByUser) Error() string {
+	return fmt.Sprintf("Namespace is not owned by the user [NamespaceID: %d, UserID: %d]", err.NamespaceID, err.UserID)
+}
+
+// ErrCodeNamespaceNotOwnedByUser holds the unique world-error code of this error
+const ErrCodeNamespaceNotOwnedByUser = 5013
+
+// HTTPError holds the http error description
+func (err ErrNamespaceNotOwnedByUser) HTTPError() web.HTTPError {
+	return web.HTTPError{HTTPCode: http.StatusPreconditionFailed, Code: ErrCodeNamespaceNotOwnedByUser, Message: "The namespace does not belong to the new owner of the list."}
+}
+
 // ============
 // Team errors
 // ============
@@ -1079,6 +1204,30 @@ func (err ErrTeamDoesNotHaveAccessToList) HTTPError() web.HTTPError {
 	return web.HTTPError{HTTPCode: http.StatusForbidden, Code: ErrCodeTeamDoesNotHaveAccessToList, Message: "This team does not have access to the list."}
 }
 
+// ErrCannotModifyExternalTeamMembers represents an error where a user tries to change the members of a team
+// which is managed by an external identity provider.
+type ErrCannotModifyExternalTeamMembers struct {
+	TeamID int64
+}
+
+// IsErrCannotModifyExternalTeamMembers checks if an error is ErrCannotModifyExternalTeamMembers.
+func IsErrCannotModifyExternalTeamMembers(err error) bool {
+	_, ok := err.(ErrCannotModifyExternalTeamMembers)
+	return ok
+}
+
+func (err ErrCannotModifyExternalTeamMembers) Error() string {
+	return fmt.Sprintf("Cannot modify the members of an externally managed team [TeamID: %d]", err.TeamID)
+}
+
+// ErrCodeCannotModifyExternalTeamMembers holds the unique world-error code of this error
+const ErrCodeCannotModifyExternalTeamMembers = 6008
+
+// HTTPError holds the http error description
+func (err ErrCannotModifyExternalTeamMembers) HTTPError() web.HTTPError {
+	return web.HTTPError{HTTPCode: http.StatusPreconditionFailed, Code: ErrCodeCannotModifyExternalTeamMembers, Message: "The members of this team are managed by an external identity provider and cannot be changed."}
+}
+
 // ====================
 // User <-> List errors
 // ====================
@@ -1483,3 +1632,514 @@ func (err ErrSubscriptionAlreadyExists) HTTPError() web.HTTPError {
 		Message:  "You're already subscribed.",
 	}
 }
+
+// ======
+// OAuth2
+// ======
+
+// ErrOAuth2ClientDoesNotExist represents an error where an oauth2 client does not exist
+type ErrOAuth2ClientDoesNotExist struct {
+	ID       int64
+	ClientID string
+}
+
+// IsErrOAuth2ClientDoesNotExist checks if an error is a ErrOAuth2ClientDoesNotExist.
+func IsErrOAuth2ClientDoesNotExist(err error) bool {
+	_, ok := err.(ErrOAuth2ClientDoesNotExist)
+	return ok
+}
+
+func (err ErrOAuth2ClientDoesNotExist) Error() string {
+	return fmt.Sprintf("OAuth2 client does not exist [ID: %d, ClientID: %s]", err.ID, err.ClientID)
+}
+
+// ErrCodeOAuth2ClientDoesNotExist holds the unique world-error code of this error
+const ErrCodeOAuth2ClientDoesNotExist = 13001
+
+// HTTPError holds the http error description
+func (err ErrOAuth2ClientDoesNotExist) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeOAuth2ClientDoesNotExist,
+		Message:  "The oauth2 client does not exist.",
+	}
+}
+
+// ErrOAuth2InvalidRedirectURI represents an error where a redirect uri of an oauth2 client is invalid or not registered
+type ErrOAuth2InvalidRedirectURI struct {
+	RedirectURI string
+}
+
+// IsErrOAuth2InvalidRedirectURI checks if an error is a ErrOAuth2InvalidRedirectURI.
+func IsErrOAuth2InvalidRedirectURI(err error) bool {
+	_, ok := err.(ErrOAuth2InvalidRedirectURI)
+	return ok
+}
+
+func (err ErrOAuth2InvalidRedirectURI) Error() string {
+	return fmt.Sprintf("OAuth2 redirect uri is invalid [RedirectURI: %s]", err.RedirectURI)
+}
+
+// ErrCodeOAuth2InvalidRedirectURI holds the unique world-error code of this error
+const ErrCodeOAuth2InvalidRedirectURI = 13002
+
+// HTTPError holds the http error description
+func (err ErrOAuth2InvalidRedirectURI) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeOAuth2InvalidRedirectURI,
+		Message:  "The redirect uri is invalid. Clients need at least one redirect uri, every uri needs to be absolute without a fragment and may only use http for localhost. During authorization, it needs to match one of the registered uris exactly.",
+	}
+}
+
+// ErrOAuth2InvalidScope represents an error where an oauth2 client requested an unknown scope
+type ErrOAuth2InvalidScope struct {
+	Scope string
+}
+
+// IsErrOAuth2InvalidScope checks if an error is a ErrOAuth2InvalidScope.
+func IsErrOAuth2InvalidScope(err error) bool {
+	_, ok := err.(ErrOAuth2InvalidScope)
+	return ok
+}
+
+func (err ErrOAuth2InvalidScope) Error() string {
+	return fmt.Sprintf("OAuth2 scope is invalid [Scope: %s]", err.Scope)
+}
+
+// ErrCodeOAuth2InvalidScope holds the unique world-error code of this error
+const ErrCodeOAuth2InvalidScope = 13003
+
+// HTTPError holds the http error description
+func (err ErrOAuth2InvalidScope) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeOAuth2InvalidScope,
+		Message:  "The requested scope is invalid.",
+	}
+}
+
+// ErrOAuth2GrantDoesNotExist represents an error where an oauth2 grant does not exist or was revoked
+type ErrOAuth2GrantDoesNotExist struct {
+	GrantID int64
+}
+
+// IsErrOAuth2GrantDoesNotExist checks if an error is a ErrOAuth2GrantDoesNotExist.
+func IsErrOAuth2GrantDoesNotExist(err error) bool {
+	_, ok := err.(ErrOAuth2GrantDoesNotExist)
+	return ok
+}
+
+func (err ErrOAuth2GrantDoesNotExist) Error() string {
+	return fmt.Sprintf("OAuth2 grant does not exist [GrantID: %d]", err.GrantID)
+}
+
+// ErrCodeOAuth2GrantDoesNotExist holds the unique world-error code of this error
+const ErrCodeOAuth2GrantDoesNotExist = 13004
+
+// HTTPError holds the http error description
+func (err ErrOAuth2GrantDoesNotExist) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeOAuth2GrantDoesNotExist,
+		Message:  "The access was revoked or never granted.",
+	}
+}
+
+// ErrOAuth2InsufficientScope represents an error where an oauth2 access token is used for something it was not granted for
+type ErrOAuth2InsufficientScope struct {
+	Scope string
+}
+
+// IsErrOAuth2InsufficientScope checks if an error is a ErrOAuth2InsufficientScope.
+func IsErrOAuth2InsufficientScope(err error) bool {
+	_, ok := err.(ErrOAuth2InsufficientScope)
+	return ok
+}
+
+func (err ErrOAuth2InsufficientScope) Error() string {
+	return fmt.Sprintf("OAuth2 access token does not have the required scope [Scope: %s]", err.Scope)
+}
+
+// ErrCodeOAuth2InsufficientScope holds the unique world-error code of this error
+const ErrCodeOAuth2InsufficientScope = 13005
+
+// HTTPError holds the http error description
+func (err ErrOAuth2InsufficientScope) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusForbidden,
+		Code:     ErrCodeOAuth2InsufficientScope,
+		Message:  "The access token does not have the scope needed to do this.",
+	}
+}
+
+// ErrOAuth2InvalidCodeChallenge represents an error where an authorization request does not contain a valid pkce code challenge
+type ErrOAuth2InvalidCodeChallenge struct {
+	Method string
+}
+
+// IsErrOAuth2InvalidCodeChallenge checks if an error is a ErrOAuth2InvalidCodeChallenge.
+func IsErrOAuth2InvalidCodeChallenge(err error) bool {
+	_, ok := err.(ErrOAuth2InvalidCodeChallenge)
+	return ok
+}
+
+func (err ErrOAuth2InvalidCodeChallenge) Error() string {
+	return fmt.Sprintf("OAuth2 code challenge is missing or invalid [Method: %s]", err.Method)
+}
+
+// ErrCodeOAuth2InvalidCodeChallenge holds the unique world-error code of this error
+const ErrCodeOAuth2InvalidCodeChallenge = 13006
+
+// HTTPError holds the http error description
+func (err ErrOAuth2InvalidCodeChallenge) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeOAuth2InvalidCodeChallenge,
+		Message:  "A code challenge with the method S256 is required.",
+	}
+}
+
+// ErrOAuth2UnsupportedResponseType represents an error where a client requested an unsupported response type
+type ErrOAuth2UnsupportedResponseType struct {
+	ResponseType string
+}
+
+// IsErrOAuth2UnsupportedResponseType checks if an error is a ErrOAuth2UnsupportedResponseType.
+func IsErrOAuth2UnsupportedResponseType(err error) bool {
+	_, ok := err.(ErrOAuth2UnsupportedResponseType)
+	return ok
+}
+
+func (err ErrOAuth2UnsupportedResponseType) Error() string {
+	return fmt.Sprintf("OAuth2 response type is not supported [ResponseType: %s]", err.ResponseType)
+}
+
+// ErrCodeOAuth2UnsupportedResponseType holds the unique world-error code of this error
+const ErrCodeOAuth2UnsupportedResponseType = 13007
+
+// HTTPError holds the http error description
+func (err ErrOAuth2UnsupportedResponseType) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeOAuth2UnsupportedResponseType,
+		Message:  "Only the response type code is supported.",
+	}
+}
+
+// ================
+// Account deletion
+// ================
+
+// ErrUserDeletionTransferNotAllowed is an error where a namespace or list cannot be transferred to a user when deleting an account
+type ErrUserDeletionTransferNotAllowed struct {
+	NamespaceID int64
+	ListID      int64
+	NewOwnerID  int64
+}
+
+// IsErrUserDeletionTransferNotAllowed checks if an error is a ErrUserDeletionTransferNotAllowed.
+func IsErrUserDeletionTransferNotAllowed(err error) bool {
+	_, ok := err.(ErrUserDeletionTransferNotAllowed)
+	return ok
+}
+
+func (err ErrUserDeletionTransferNotAllowed) Error() string {
+	return fmt.Sprintf("Namespace or list cannot be transferred to that user [NamespaceID: %d, ListID: %d, NewOwnerID: %d]", err.NamespaceID, err.ListID, err.NewOwnerID)
+}
+
+// ErrCodeUserDeletionTransferNotAllowed holds the unique world-error code of this error
+const ErrCodeUserDeletionTransferNotAllowed = 14001
+
+// HTTPError holds the http error description
+func (err ErrUserDeletionTransferNotAllowed) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeUserDeletionTransferNotAllowed,
+		Message:  "You can only transfer namespaces and lists you own to other active users who already have access to them.",
+	}
+}
+
+// ErrUserDeletionTransferInvalid is an error where a transfer does not contain exactly one namespace or list
+type ErrUserDeletionTransferInvalid struct{}
+
+// IsErrUserDeletionTransferInvalid checks if an error is a ErrUserDeletionTransferInvalid.
+func IsErrUserDeletionTransferInvalid(err error) bool {
+	_, ok := err.(ErrUserDeletionTransferInvalid)
+	return ok
+}
+
+func (err ErrUserDeletionTransferInvalid) Error() string {
+	return "A transfer needs exactly one namespace or list"
+}
+
+// ErrCodeUserDeletionTransferInvalid holds the unique world-error code of this error
+const ErrCodeUserDeletionTransferInvalid = 14002
+
+// HTTPError holds the http error description
+func (err ErrUserDeletionTransferInvalid) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeUserDeletionTransferInvalid,
+		Message:  "A transfer needs either a namespace or a list.",
+	}
+}
+
+// ================
+// User data export
+// ================
+
+// ErrUserDataExportDoesNotExist represents an error where a user has no data export which can be downloaded
+type ErrUserDataExportDoesNotExist struct {
+	UserID int64
+}
+
+// IsErrUserDataExportDoesNotExist checks if an error is a ErrUserDataExportDoesNotExist.
+func IsErrUserDataExportDoesNotExist(err error) bool {
+	_, ok := err.(ErrUserDataExportDoesNotExist)
+	return ok
+}
+
+func (err ErrUserDataExportDoesNotExist) Error() string {
+	return fmt.Sprintf("User data export does not exist [UserID: %d]", err.UserID)
+}
+
+// ErrCodeUserDataExportDoesNotExist holds the unique world-error code of this error
+const ErrCodeUserDataExportDoesNotExist = 15001
+
+// HTTPError holds the http error description
+func (err ErrUserDataExportDoesNotExist) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeUserDataExportDoesNotExist,
+		Message:  "There is no data export for you. Please request a new one.",
+	}
+}
+
+// =========
+// Migration
+// =========
+
+// ErrInvalidMigrationFile represents an error where an uploaded file cannot be migrated because it is not in the expected format
+type ErrInvalidMigrationFile struct {
+	Reason string
+}
+
+// IsErrInvalidMigrationFile checks if an error is a ErrInvalidMigrationFile.
+func IsErrInvalidMigrationFile(err error) bool {
+	_, ok := err.(ErrInvalidMigrationFile)
+	return ok
+}
+
+func (err ErrInvalidMigrationFile) Error() string {
+	return fmt.Sprintf("Invalid migration file [Reason: %s]", err.Reason)
+}
+
+// ErrCodeInvalidMigrationFile holds the unique world-error code of this error
+const ErrCodeInvalidMigrationFile = 16001
+
+// HTTPError holds the http error description
+func (err ErrInvalidMigrationFile) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeInvalidMigrationFile,
+		Message:  "The uploaded file cannot be imported because it is not in the expected format: " + err.Reason,
+	}
+}
+
+// ErrUnsupportedExportVersion represents an error where a Vikunja data export was created with a newer format than this instance supports
+type ErrUnsupportedExportVersion struct {
+	Version int
+}
+
+// IsErrUnsupportedExportVersion checks if an error is a ErrUnsupportedExportVersion.
+func IsErrUnsupportedExportVersion(err error) bool {
+	_, ok := err.(ErrUnsupportedExportVersion)
+	return ok
+}
+
+func (err ErrUnsupportedExportVersion) Error() string {
+	return fmt.Sprintf("Unsupported export version [Version: %d]", err.Version)
+}
+
+// ErrCodeUnsupportedExportVersion holds the unique world-error code of this error
+const ErrCodeUnsupportedExportVersion = 16002
+
+// HTTPError holds the http error description
+func (err ErrUnsupportedExportVersion) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeUnsupportedExportVersion,
+		Message:  "The export was created by a newer version of Vikunja. Please update this instance to import it.",
+	}
+}
+
+// ErrMigrationJobDoesNotExist represents an error where a migration job does not exist
+type ErrMigrationJobDoesNotExist struct {
+	JobID int64
+}
+
+// IsErrMigrationJobDoesNotExist checks if an error is a ErrMigrationJobDoesNotExist.
+func IsErrMigrationJobDoesNotExist(err error) bool {
+	_, ok := err.(ErrMigrationJobDoesNotExist)
+	return ok
+}
+
+func (err ErrMigrationJobDoesNotExist) Error() string {
+	return fmt.Sprintf("Migration job does not exist [JobID: %d]", err.JobID)
+}
+
+// ErrCodeMigrationJobDoesNotExist holds the unique world-error code of this error
+const ErrCodeMigrationJobDoesNotExist = 16003
+
+// HTTPError holds the http error description
+func (err ErrMigrationJobDoesNotExist) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeMigrationJobDoesNotExist,
+		Message:  "This migration job does not exist.",
+	}
+}
+
+// =====================
+// CSV import and export
+// =====================
+
+// ErrInvalidCSVColumn represents an error where a csv column does not exist
+type ErrInvalidCSVColumn struct {
+	Column string
+}
+
+// IsErrInvalidCSVColumn checks if an error is a ErrInvalidCSVColumn.
+func IsErrInvalidCSVColumn(err error) bool {
+	_, ok := err.(ErrInvalidCSVColumn)
+	return ok
+}
+
+func (err ErrInvalidCSVColumn) Error() string {
+	return fmt.Sprintf("CSV column does not exist [Column: %s]", err.Column)
+}
+
+// ErrCodeInvalidCSVColumn holds the unique world-error code of this error
+const ErrCodeInvalidCSVColumn = 17001
+
+// HTTPError holds the http error description
+func (err ErrInvalidCSVColumn) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeInvalidCSVColumn,
+		Message:  "The csv column '" + err.Column + "' does not exist.",
+	}
+}
+
+// ErrCSVImportTitleMissing represents an error where no csv column was mapped to the task title
+type ErrCSVImportTitleMissing struct{}
+
+// IsErrCSVImportTitleMissing checks if an error is a ErrCSVImportTitleMissing.
+func IsErrCSVImportTitleMissing(err error) bool {
+	_, ok := err.(ErrCSVImportTitleMissing)
+	return ok
+}
+
+func (err ErrCSVImportTitleMissing) Error() string {
+	return "No csv column is mapped to the task title"
+}
+
+// ErrCodeCSVImportTitleMissing holds the unique world-error code of this error
+const ErrCodeCSVImportTitleMissing = 17002
+
+// HTTPError holds the http error description
+func (err ErrCSVImportTitleMissing) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeCSVImportTitleMissing,
+		Message:  "You need to map a column to the task title.",
+	}
+}
+
+// ErrInvalidCSVValue represents an error where a value in a csv file could not be imported
+type ErrInvalidCSVValue struct {
+	Row    int
+	Column string
+	Value  string
+}
+
+// IsErrInvalidCSVValue checks if an error is a ErrInvalidCSVValue.
+func IsErrInvalidCSVValue(err error) bool {
+	_, ok := err.(ErrInvalidCSVValue)
+	return ok
+}
+
+func (err ErrInvalidCSVValue) Error() string {
+	return fmt.Sprintf("Invalid csv value [Row: %d, Column: %s, Value: %s]", err.Row, err.Column, err.Value)
+}
+
+// ErrCodeInvalidCSVValue holds the unique world-error code of this error
+const ErrCodeInvalidCSVValue = 17003
+
+// HTTPError holds the http error description
+func (err ErrInvalidCSVValue) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeInvalidCSVValue,
+		Message:  fmt.Sprintf("The value '%s' of the column '%s' in row %d is invalid.", err.Value, err.Column, err.Row),
+	}
+}
+
+// ==========
+// iCal feeds
+// ==========
+
+// ErrICalFeedDoesNotExist represents an error where an ical feed does not exist
+type ErrICalFeedDoesNotExist struct {
+	ID int64
+}
+
+// IsErrICalFeedDoesNotExist checks if an error is a ErrICalFeedDoesNotExist.
+func IsErrICalFeedDoesNotExist(err error) bool {
+	_, ok := err.(ErrICalFeedDoesNotExist)
+	return ok
+}
+
+func (err ErrICalFeedDoesNotExist) Error() string {
+	return fmt.Sprintf("iCal feed does not exist [ID: %d]", err.ID)
+}
+
+// ErrCodeICalFeedDoesNotExist holds the unique world-error code of this error
+const ErrCodeICalFeedDoesNotExist = 18001
+
+// HTTPError holds the http error description
+func (err ErrICalFeedDoesNotExist) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeICalFeedDoesNotExist,
+		Message:  "This ical feed does not exist.",
+	}
+}
+
+// ErrInvalidICalFeedKind represents an error where the kind of an ical feed is invalid
+type ErrInvalidICalFeedKind struct {
+	Kind ICalFeedKind
+}
+
+// IsErrInvalidICalFeedKind checks if an error is a ErrInvalidICalFeedKind.
+func IsErrInvalidICalFeedKind(err error) bool {
+	_, ok := err.(ErrInvalidICalFeedKind)
+	return ok
+}
+
+func (err ErrInvalidICalFeedKind) Error() string {
+	return fmt.Sprintf("Invalid ical feed kind [Kind: %s]", err.Kind)
+}
+
+// ErrCodeInvalidICalFeedKind holds the unique world-error code of this error
+const ErrCodeInvalidICalFeedKind = 18002
+
+// HTTPError holds the http error description
+func (err ErrInvalidICalFeedKind) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusBadRequest,
+		Code:     ErrCodeInvalidICalFeedKind,
+		Message:  "The feed kind '" + string(err.Kind) + "' is invalid. It needs to be one of list, saved_filter or assigned.",
+	}
+}
@@ -82,6 +82,16 @@ func (t *TaskCommentCreatedEvent) Name() string {
 	return "task.comment.created"
 }
 
+// TaskAttachmentCreatedEvent represents an event where an attachment has been added to a task
+type TaskAttachmentCreatedEvent struct {
+	Attachment *TaskAttachment
+}
+
+// Name defines the name for TaskAttachmentCreatedEvent
+func (t *TaskAttachmentCreatedEvent) Name() string {
+	return "task.attachment.created"
+}
+
 //////////////////////
 // Namespace Events //
 //////////////////////
@@ -245,3 +255,17 @@ type TeamDeletedEvent struct {
 func (t *TeamDeletedEvent) Name() string {
 	return "team.deleted"
 }
+
+/////////////////
+// User Events //
+/////////////////
+
+// UserDataExportRequestedEvent represents a UserDataExportRequestedEvent event
+type UserDataExportRequestedEvent struct {
+	User *user.User
+}
+
+// Name defines the name for UserDataExportRequestedEvent
+func (t *UserDataExportRequestedEvent) Name() string {
+	return "user.export.request"
+}
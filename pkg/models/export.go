@@ -0,0 +1,517 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"code.vikunja.io/api/pkg/cron"
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/api/pkg/version"
+	"xorm.io/xorm"
+)
+
+// UserDataExportValidity is how long a data export can be downloaded before it is deleted.
+const UserDataExportValidity = 7 * 24 * time.Hour
+
+// UserDataExportVersion is the version of the format of data exports. It is increased whenever the format changes
+// in a way importers need to know about.
+const UserDataExportVersion = 1
+
+// UserDataExportInfo is the content of info.json in a data export.
+type UserDataExportInfo struct {
+	// The version of the export format.
+	ExportVersion int `json:"export_version"`
+	// The version of Vikunja which created the export.
+	VikunjaVersion string `json:"vikunja_version"`
+	// When the export was created.
+	Exported time.Time `json:"exported"`
+	// The user the export belongs to.
+	User *user.User `json:"user"`
+}
+
+// NamespaceWithListsAndTasks is a namespace with all of its lists and their tasks, as it is saved in data.json.
+type NamespaceWithListsAndTasks struct {
+	Namespace
+	Lists []*ListWithTasksAndBuckets `xorm:"-" json:"lists"`
+}
+
+// ListWithTasksAndBuckets is a list with all of its tasks and kanban buckets.
+type ListWithTasksAndBuckets struct {
+	List
+	Tasks   []*TaskWithComments `xorm:"-" json:"tasks"`
+	Buckets []*Bucket           `xorm:"-" json:"buckets"`
+
+	// The id of the background file of the list. The file itself is saved in the files folder of the export.
+	BackgroundFileID int64 `xorm:"null" json:"background_file_id"`
+}
+
+// TaskWithComments is a task with all of its comments.
+type TaskWithComments struct {
+	Task
+	Comments []*TaskComment `xorm:"-" json:"comments"`
+}
+
+// TaskCommentWithTaskID is a comment of the user on a task of another user, as it is saved in comments.json.
+type TaskCommentWithTaskID struct {
+	*TaskComment
+	// The id of the task the comment belongs to.
+	TaskID int64 `json:"task_id"`
+}
+
+// ExportUserData creates a zip file with all data of a user and saves it as the user's current export.
+// A previous export is deleted.
+func ExportUserData(s *xorm.Session, u *user.User) (err error) {
+	tmpFile, err := ioutil.TempFile("", "vikunja-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	err = writeUserDataExport(s, u, tmpFile)
+	if err != nil {
+		return err
+	}
+
+	stat, err := tmpFile.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = tmpFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	exportFile, err := files.CreateWithoutSizeLimit(tmpFile, "vikunja-export.zip", uint64(stat.Size()), u, "application/zip")
+	if err != nil {
+		return err
+	}
+
+	err = deleteUserDataExport(s, u)
+	if err != nil {
+		return err
+	}
+
+	u.ExportFileID = exportFile.ID
+	_, err = s.
+		Where("id = ?", u.ID).
+		Cols("export_file_id").
+		Update(u)
+	return err
+}
+
+func writeUserDataExport(s *xorm.Session, u *user.User, w io.Writer) (err error) {
+	zw := zip.NewWriter(w)
+
+	info := &UserDataExportInfo{
+		ExportVersion:  UserDataExportVersion,
+		VikunjaVersion: version.Version,
+		Exported:       time.Now(),
+		User:           u,
+	}
+	err = writeJSONToZip(zw, "info.json", info)
+	if err != nil {
+		return err
+	}
+
+	namespaces, fileIDs, err := getUserDataForExport(s, u)
+	if err != nil {
+		return err
+	}
+	err = writeJSONToZip(zw, "data.json", namespaces)
+	if err != nil {
+		return err
+	}
+
+	comments, err := getCommentsOnOtherUsersTasksForExport(s, u)
+	if err != nil {
+		return err
+	}
+	err = writeJSONToZip(zw, "comments.json", comments)
+	if err != nil {
+		return err
+	}
+
+	filters, err := getSavedFiltersForUser(s, u)
+	if err != nil {
+		return err
+	}
+	err = writeJSONToZip(zw, "filters.json", filters)
+	if err != nil {
+		return err
+	}
+
+	labels := []*Label{}
+	err = s.
+		Where("created_by_id = ?", u.ID).
+		OrderBy("id asc").
+		Find(&labels)
+	if err != nil {
+		return err
+	}
+	err = writeJSONToZip(zw, "labels.json", labels)
+	if err != nil {
+		return err
+	}
+
+	for _, fileID := range fileIDs {
+		err = writeExportFileToZip(zw, fileID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// getUserDataForExport returns all namespaces and lists the user owns with all tasks and buckets in them.
+// Namespaces and lists of other users which are only shared with the user are not part of the export since they
+// contain personal data of these users. For the same reason, tasks only contain the comments and attachments of
+// the user. It also returns the ids of all files which belong to the exported lists and attachments.
+func getUserDataForExport(s *xorm.Session, u *user.User) (namespaces []*NamespaceWithListsAndTasks, fileIDs []int64, err error) {
+	n := &Namespace{IsArchived: true}
+	raw, _, _, err := n.ReadAll(s, u, "", -1, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Lists the user owns in namespaces of other users are exported without the namespace they are in
+	otherNamespaces := &NamespaceWithListsAndTasks{
+		Namespace: SharedListsPseudoNamespace,
+		Lists:     []*ListWithTasksAndBuckets{},
+	}
+	otherNamespaces.Description = "Your lists in namespaces of other users."
+
+	namespaces = []*NamespaceWithListsAndTasks{}
+	lists := []*List{}
+	listMap := make(map[int64]*ListWithTasksAndBuckets)
+	for _, ns := range raw.([]*NamespaceWithLists) {
+		// Favorites and saved filters only contain lists and tasks which are already part of the export
+		if ns.ID == FavoritesPseudoNamespace.ID || ns.ID == SavedFiltersPseudoNamespace.ID {
+			continue
+		}
+
+		exported := otherNamespaces
+		if ns.ID != SharedListsPseudoNamespace.ID && ns.OwnerID == u.ID {
+			exported = &NamespaceWithListsAndTasks{
+				Namespace: ns.Namespace,
+				Lists:     []*ListWithTasksAndBuckets{},
+			}
+			namespaces = append(namespaces, exported)
+		}
+
+		for _, l := range ns.Lists {
+			if l.ID < 1 || l.OwnerID != u.ID {
+				continue
+			}
+			el := &ListWithTasksAndBuckets{
+				List:             *l,
+				Tasks:            []*TaskWithComments{},
+				Buckets:          []*Bucket{},
+				BackgroundFileID: l.BackgroundFileID,
+			}
+			if l.BackgroundFileID != 0 {
+				fileIDs = append(fileIDs, l.BackgroundFileID)
+			}
+			exported.Lists = append(exported.Lists, el)
+			listMap[l.ID] = el
+			lists = append(lists, l)
+		}
+	}
+
+	if len(otherNamespaces.Lists) > 0 {
+		namespaces = append(namespaces, otherNamespaces)
+	}
+
+	if len(lists) == 0 {
+		return namespaces, fileIDs, nil
+	}
+
+	tasks, _, _, err := getTasksForLists(s, lists, u, &taskOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	taskMap := make(map[int64]*TaskWithComments, len(tasks))
+	taskIDs := make([]int64, 0, len(tasks))
+	for _, t := range tasks {
+		attachments := make([]*TaskAttachment, 0, len(t.Attachments))
+		for _, a := range t.Attachments {
+			if a.CreatedByID != u.ID {
+				continue
+			}
+			attachments = append(attachments, a)
+			fileIDs = append(fileIDs, a.FileID)
+		}
+		t.Attachments = attachments
+		tc := &TaskWithComments{
+			Task:     *t,
+			Comments: []*TaskComment{},
+		}
+		taskMap[t.ID] = tc
+		taskIDs = append(taskIDs, t.ID)
+		listMap[t.ListID].Tasks = append(listMap[t.ListID].Tasks, tc)
+	}
+
+	if len(taskIDs) > 0 {
+		comments := []*TaskComment{}
+		err = s.
+			In("task_id", taskIDs).
+			And("author_id = ?", u.ID).
+			OrderBy("id asc").
+			Find(&comments)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		authorIDs := make([]int64, 0, len(comments))
+		for _, c := range comments {
+			authorIDs = append(authorIDs, c.AuthorID)
+		}
+		authors, err := user.GetUsersByIDs(s, authorIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, c := range comments {
+			c.Author = authors[c.AuthorID]
+			taskMap[c.TaskID].Comments = append(taskMap[c.TaskID].Comments, c)
+		}
+	}
+
+	listIDs := make([]int64, 0, len(lists))
+	for _, l := range lists {
+		listIDs = append(listIDs, l.ID)
+	}
+	buckets := []*Bucket{}
+	err = s.
+		In("list_id", listIDs).
+		OrderBy("id asc").
+		Find(&buckets)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, b := range buckets {
+		listMap[b.ListID].Buckets = append(listMap[b.ListID].Buckets, b)
+	}
+
+	return namespaces, fileIDs, nil
+}
+
+// getCommentsOnOtherUsersTasksForExport returns all comments of the user on tasks in lists of other users.
+// These tasks are not part of the export, only the comments of the user are.
+func getCommentsOnOtherUsersTasksForExport(s *xorm.Session, u *user.User) (comments []*TaskCommentWithTaskID, err error) {
+	raw := []*TaskComment{}
+	err = s.
+		Select("task_comments.*").
+		Join("INNER", "tasks", "tasks.id = task_comments.task_id").
+		Join("INNER", "lists", "lists.id = tasks.list_id").
+		Where("task_comments.author_id = ? AND lists.owner_id != ?", u.ID, u.ID).
+		OrderBy("task_comments.id asc").
+		Find(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	authors, err := user.GetUsersByIDs(s, []int64{u.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	comments = make([]*TaskCommentWithTaskID, 0, len(raw))
+	for _, c := range raw {
+		c.Author = authors[u.ID]
+		comments = append(comments, &TaskCommentWithTaskID{
+			TaskComment: c,
+			TaskID:      c.TaskID,
+		})
+	}
+	return comments, nil
+}
+
+func writeJSONToZip(zw *zip.Writer, name string, data interface{}) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+	})
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(data)
+}
+
+func writeExportFileToZip(zw *zip.Writer, fileID int64) error {
+	f := &files.File{ID: fileID}
+	err := f.LoadFileByID()
+	if err != nil {
+		// Files which are referenced but missing in storage should not prevent the export
+		log.Warningf("Could not load file %d for data export: %s", fileID, err)
+		return nil
+	}
+	defer f.File.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:   "files/" + strconv.FormatInt(fileID, 10),
+		Method: zip.Deflate,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, f.File)
+	return err
+}
+
+// GetUserDataExport returns the current data export of a user if it has not expired yet.
+func GetUserDataExport(s *xorm.Session, u *user.User) (exportFile *files.File, err error) {
+	full, err := user.GetUserByID(s, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if full.ExportFileID == 0 {
+		return nil, ErrUserDataExportDoesNotExist{UserID: u.ID}
+	}
+
+	exportFile = &files.File{ID: full.ExportFileID}
+	err = exportFile.LoadFileMetaByID()
+	if err != nil {
+		if files.IsErrFileDoesNotExist(err) {
+			return nil, ErrUserDataExportDoesNotExist{UserID: u.ID}
+		}
+		return nil, err
+	}
+
+	if time.Since(exportFile.Created) > UserDataExportValidity {
+		return nil, ErrUserDataExportDoesNotExist{UserID: u.ID}
+	}
+
+	return exportFile, nil
+}
+
+func deleteUserDataExport(s *xorm.Session, u *user.User) (err error) {
+	full, err := user.GetUserByID(s, u.ID)
+	if err != nil {
+		return err
+	}
+
+	if full.ExportFileID == 0 {
+		return nil
+	}
+
+	err = (&files.File{ID: full.ExportFileID}).Delete()
+	if err != nil && !files.IsErrFileDoesNotExist(err) {
+		return err
+	}
+
+	full.ExportFileID = 0
+	_, err = s.
+		Where("id = ?", full.ID).
+		Cols("export_file_id").
+		Update(full)
+	return err
+}
+
+// RegisterOldExportCleanupCron deletes all data exports which can't be downloaded anymore.
+func RegisterOldExportCleanupCron() {
+	err := cron.Schedule("0 * * * *", deleteExpiredUserDataExports)
+	if err != nil {
+		log.Fatalf("Could not register old export cleanup cron: %s", err)
+	}
+}
+
+func deleteExpiredUserDataExports() {
+	s := db.NewSession()
+	defer s.Close()
+
+	users := []*user.User{}
+	err := s.
+		Where("export_file_id IS NOT NULL AND export_file_id != ?", 0).
+		Find(&users)
+	if err != nil {
+		log.Errorf("[Old Export Cleanup Cron] Could not get users with data exports: %s", err)
+		return
+	}
+
+	if len(users) == 0 {
+		return
+	}
+
+	fileIDs := make([]int64, 0, len(users))
+	for _, u := range users {
+		fileIDs = append(fileIDs, u.ExportFileID)
+	}
+	exportFiles := make(map[int64]*files.File)
+	err = s.In("id", fileIDs).Find(&exportFiles)
+	if err != nil {
+		log.Errorf("[Old Export Cleanup Cron] Could not get export files: %s", err)
+		return
+	}
+
+	for _, u := range users {
+		f, exists := exportFiles[u.ExportFileID]
+		if exists && time.Since(f.Created) <= UserDataExportValidity {
+			continue
+		}
+
+		err = deleteUserDataExport(s, u)
+		if err != nil {
+			_ = s.Rollback()
+			log.Errorf("[Old Export Cleanup Cron] Could not delete data export of user %d: %s", u.ID, err)
+			return
+		}
+		log.Debugf("[Old Export Cleanup Cron] Deleted data export of user %d", u.ID)
+	}
+
+	err = s.Commit()
+	if err != nil {
+		log.Errorf("[Old Export Cleanup Cron] Could not delete old data exports: %s", err)
+	}
+}
+
+// RequestUserDataExport checks the password of the user and starts the creation of a new data export in the background.
+func RequestUserDataExport(s *xorm.Session, u *user.User, password string) (err error) {
+	full, err := user.GetUserWithEmail(s, &user.User{ID: u.ID})
+	if err != nil {
+		return err
+	}
+
+	if full.IsLocalUser() {
+		err = user.CheckUserPassword(full, password)
+		if err != nil {
+			return err
+		}
+	}
+
+	return events.Dispatch(&UserDataExportRequestedEvent{
+		User: full,
+	})
+}
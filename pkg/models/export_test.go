@@ -0,0 +1,186 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportUserData(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	files.InitTestFileFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := user.GetUserWithEmail(s, &user.User{ID: 1})
+	assert.NoError(t, err)
+
+	// A comment of another user on a task of user 1 and a comment of user 1 on a task of another user
+	_, err = s.Insert(&TaskComment{Comment: "comment of user 2", AuthorID: 2, TaskID: 1})
+	assert.NoError(t, err)
+	ownComment := &TaskComment{Comment: "comment of user 1", AuthorID: 1, TaskID: 14}
+	_, err = s.Insert(ownComment)
+	assert.NoError(t, err)
+
+	err = ExportUserData(s, u)
+	assert.NoError(t, err)
+
+	exportFile, err := GetUserDataExport(s, u)
+	assert.NoError(t, err)
+	err = exportFile.LoadFileByID()
+	assert.NoError(t, err)
+	defer exportFile.File.Close()
+
+	zr, err := zip.NewReader(exportFile.File, int64(exportFile.Size))
+	assert.NoError(t, err)
+
+	zipFiles := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		zipFiles[f.Name] = f
+	}
+	assert.Contains(t, zipFiles, "info.json")
+	assert.Contains(t, zipFiles, "data.json")
+	assert.Contains(t, zipFiles, "comments.json")
+	assert.Contains(t, zipFiles, "filters.json")
+	assert.Contains(t, zipFiles, "labels.json")
+	// The file of attachment 1
+	assert.Contains(t, zipFiles, "files/1")
+
+	r, err := zipFiles["data.json"].Open()
+	assert.NoError(t, err)
+	defer r.Close()
+	namespaces := []*NamespaceWithListsAndTasks{}
+	err = json.NewDecoder(r).Decode(&namespaces)
+	assert.NoError(t, err)
+
+	var task1 *TaskWithComments
+	for _, n := range namespaces {
+		assert.NotEqual(t, FavoritesPseudoNamespace.ID, n.ID)
+		assert.NotEqual(t, SavedFiltersPseudoNamespace.ID, n.ID)
+		if n.ID != SharedListsPseudoNamespace.ID {
+			assert.Equal(t, int64(1), n.Owner.ID)
+		}
+		for _, l := range n.Lists {
+			// Lists of other users are not part of the export
+			assert.Equal(t, int64(1), l.Owner.ID)
+			for _, task := range l.Tasks {
+				if task.ID == 1 {
+					task1 = task
+				}
+				for _, comment := range task.Comments {
+					assert.Equal(t, int64(1), comment.Author.ID)
+				}
+			}
+		}
+	}
+	if assert.NotNil(t, task1) {
+		assert.Equal(t, "task #1", task1.Title)
+		assert.NotEmpty(t, task1.Attachments)
+		assert.Len(t, task1.Comments, 1)
+	}
+
+	cr, err := zipFiles["comments.json"].Open()
+	assert.NoError(t, err)
+	defer cr.Close()
+	comments := []*TaskCommentWithTaskID{}
+	err = json.NewDecoder(cr).Decode(&comments)
+	assert.NoError(t, err)
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, ownComment.ID, comments[0].ID)
+		assert.Equal(t, int64(14), comments[0].TaskID)
+	}
+}
+
+func TestGetUserDataExport(t *testing.T) {
+	t.Run("no export", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := GetUserDataExport(s, &user.User{ID: 1})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDataExportDoesNotExist(err))
+	})
+	t.Run("expired", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		// File 1 is older than the validity of exports
+		_, err := s.
+			Where("id = ?", 1).
+			Cols("export_file_id").
+			Update(&user.User{ExportFileID: 1})
+		assert.NoError(t, err)
+
+		_, err = GetUserDataExport(s, &user.User{ID: 1})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDataExportDoesNotExist(err))
+	})
+}
+
+func TestRequestUserDataExport(t *testing.T) {
+	t.Run("wrong password", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := RequestUserDataExport(s, &user.User{ID: 1}, "wrong")
+		assert.Error(t, err)
+		assert.True(t, user.IsErrWrongUsernameOrPassword(err))
+	})
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := RequestUserDataExport(s, &user.User{ID: 1}, "1234")
+		assert.NoError(t, err)
+	})
+}
+
+func TestDeleteExpiredUserDataExports(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	files.InitTestFileFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	_, err := s.
+		Where("id = ?", 1).
+		Cols("export_file_id").
+		Update(&user.User{ExportFileID: 1})
+	assert.NoError(t, err)
+	err = s.Commit()
+	assert.NoError(t, err)
+
+	deleteExpiredUserDataExports()
+
+	db.AssertExists(t, "users", map[string]interface{}{
+		"id":             1,
+		"export_file_id": 0,
+	}, false)
+	db.AssertMissing(t, "files", map[string]interface{}{
+		"id": 1,
+	})
+}
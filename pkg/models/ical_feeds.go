@@ -0,0 +1,263 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/api/pkg/utils"
+	"code.vikunja.io/web"
+	"xorm.io/xorm"
+)
+
+// ICalFeedKind defines which tasks an ical feed contains
+type ICalFeedKind string
+
+// All ical feed kinds
+const (
+	// All tasks of a list
+	ICalFeedKindList ICalFeedKind = `list`
+	// All tasks matching a saved filter
+	ICalFeedKindSavedFilter ICalFeedKind = `saved_filter`
+	// All tasks assigned to the owner of the feed
+	ICalFeedKindAssigned ICalFeedKind = `assigned`
+)
+
+// The maximum number of tasks in a feed
+const iCalFeedMaxTasks = 1000
+
+// ICalFeed is a read-only ical feed of tasks which can be subscribed to with calendar apps without caldav support.
+// Instead of a password, the feed is protected by a secret token in its url. Deleting the feed revokes the token.
+type ICalFeed struct {
+	// The unique, numeric id of this feed.
+	ID int64 `xorm:"bigint autoincr not null unique pk" json:"id" param:"feed"`
+	// The secret token of the feed. The feed is available at `/ical/<token>.ics`.
+	Token string `xorm:"varchar(64) not null unique" json:"token"`
+	// Which tasks the feed contains. Can be `list`, `saved_filter` or `assigned`.
+	Kind ICalFeedKind `xorm:"varchar(20) not null" json:"kind"`
+	// The list for feeds of kind `list`.
+	ListID int64 `xorm:"bigint null" json:"list_id"`
+	// The saved filter for feeds of kind `saved_filter`.
+	SavedFilterID int64 `xorm:"bigint null" json:"saved_filter_id"`
+	OwnerID       int64 `xorm:"bigint not null INDEX" json:"-"`
+
+	// A timestamp when this feed was created. You cannot change this value.
+	Created time.Time `xorm:"created not null" json:"created"`
+
+	web.CRUDable `xorm:"-" json:"-"`
+	web.Rights   `xorm:"-" json:"-"`
+}
+
+// TableName returns the table name for ical feeds
+func (*ICalFeed) TableName() string {
+	return "ical_feeds"
+}
+
+// GetICalFeedByToken returns a feed by its secret token
+func GetICalFeedByToken(s *xorm.Session, token string) (feed *ICalFeed, err error) {
+	feed = &ICalFeed{}
+	exists, err := s.
+		Where("token = ?", token).
+		Get(feed)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || token == "" {
+		return nil, ErrICalFeedDoesNotExist{}
+	}
+	return
+}
+
+func getICalFeedByID(s *xorm.Session, id int64) (feed *ICalFeed, err error) {
+	feed = &ICalFeed{}
+	exists, err := s.
+		Where("id = ?", id).
+		Get(feed)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrICalFeedDoesNotExist{ID: id}
+	}
+	return
+}
+
+// Checks the feed references something the user can see
+func (f *ICalFeed) validate(s *xorm.Session, a web.Auth) (err error) {
+	var can bool
+	switch f.Kind {
+	case ICalFeedKindList:
+		f.SavedFilterID = 0
+		can, _, err = (&List{ID: f.ListID}).CanRead(s, a)
+	case ICalFeedKindSavedFilter:
+		f.ListID = 0
+		can, _, err = (&SavedFilter{ID: f.SavedFilterID}).CanRead(s, a)
+	case ICalFeedKindAssigned:
+		f.ListID = 0
+		f.SavedFilterID = 0
+		return nil
+	default:
+		return ErrInvalidICalFeedKind{Kind: f.Kind}
+	}
+	if err != nil {
+		return err
+	}
+	if !can {
+		return ErrGenericForbidden{}
+	}
+	return nil
+}
+
+// GetTasks returns the title and all tasks of a feed. It checks the owner of the feed can still see the tasks,
+// in case their access was revoked since the feed was created. Feeds of disabled users don't exist anymore.
+func (f *ICalFeed) GetTasks(s *xorm.Session) (title string, tasks []*Task, err error) {
+	u, err := user.GetUserByID(s, f.OwnerID)
+	if err != nil {
+		return "", nil, err
+	}
+	if !u.IsActive {
+		return "", nil, ErrICalFeedDoesNotExist{ID: f.ID}
+	}
+
+	tc := &TaskCollection{}
+	switch f.Kind {
+	case ICalFeedKindList:
+		list := &List{ID: f.ListID}
+		can, _, err := list.CanRead(s, u)
+		if err != nil {
+			return "", nil, err
+		}
+		if !can {
+			return "", nil, ErrUserDoesNotHaveAccessToList{ListID: f.ListID, UserID: u.ID}
+		}
+		title = list.Title
+		tc.ListID = f.ListID
+	case ICalFeedKindSavedFilter:
+		sf := &SavedFilter{ID: f.SavedFilterID}
+		can, _, err := sf.CanRead(s, u)
+		if err != nil {
+			return "", nil, err
+		}
+		if !can {
+			return "", nil, ErrGenericForbidden{}
+		}
+		title = sf.Title
+		tc.ListID = getListIDFromSavedFilterID(f.SavedFilterID)
+	case ICalFeedKindAssigned:
+		title = "Assigned to " + u.GetName()
+		tc.FilterBy = []string{"assignees"}
+		tc.FilterValue = []string{strconv.FormatInt(u.ID, 10)}
+		tc.FilterComparator = []string{"equals"}
+	default:
+		return "", nil, ErrInvalidICalFeedKind{Kind: f.Kind}
+	}
+
+	result, _, _, err := tc.ReadAll(s, u, "", 1, iCalFeedMaxTasks)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return title, result.([]*Task), nil
+}
+
+// Create creates a new ical feed
+// @Summary Create an ical feed
+// @Description Creates a new read-only ical feed for a list, a saved filter or all tasks assigned to the current user. The feed can be subscribed to at `/ical/<token>.ics` without any further authentication.
+// @tags ical
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param feed body models.ICalFeed true "The feed you want to create."
+// @Success 200 {object} models.ICalFeed "The created feed."
+// @Failure 400 {object} web.HTTPError "Invalid feed object provided."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the list or saved filter."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /ical-feeds [put]
+func (f *ICalFeed) Create(s *xorm.Session, a web.Auth) (err error) {
+	err = f.validate(s, a)
+	if err != nil {
+		return
+	}
+
+	f.ID = 0
+	f.OwnerID = a.GetID()
+	f.Token, err = utils.MakeSecureRandomString(64)
+	if err != nil {
+		return
+	}
+
+	_, err = s.Insert(f)
+	return
+}
+
+// ReadAll returns all ical feeds of the current user
+// @Summary Get all ical feeds
+// @Description Returns all ical feeds the current user created.
+// @tags ical
+// @Accept json
+// @Produce json
+// @Param page query int false "The page number. Used for pagination. If not provided, the first page of results is returned."
+// @Param per_page query int false "The maximum number of items per page. Note this parameter is limited by the configured maximum of items per page."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.ICalFeed "The feeds."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /ical-feeds [get]
+func (f *ICalFeed) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	if _, is := a.(*LinkSharing); is {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	limit, start := getLimitFromPageIndex(page, perPage)
+
+	feeds := []*ICalFeed{}
+	query := s.
+		Where("owner_id = ?", a.GetID()).
+		OrderBy("id asc")
+	if limit > 0 {
+		query = query.Limit(limit, start)
+	}
+	err = query.Find(&feeds)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	numberOfTotalItems, err = s.
+		Where("owner_id = ?", a.GetID()).
+		Count(&ICalFeed{})
+	return feeds, len(feeds), numberOfTotalItems, err
+}
+
+// Delete revokes an ical feed
+// @Summary Delete an ical feed
+// @Description Deletes an ical feed. Calendar apps subscribed to it won't get any updates anymore.
+// @tags ical
+// @Produce json
+// @Security JWTKeyAuth
+// @Param feed path int true "Feed ID"
+// @Success 200 {object} models.Message "The feed was successfully deleted."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the feed."
+// @Failure 404 {object} web.HTTPError "The feed does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /ical-feeds/{feed} [delete]
+func (f *ICalFeed) Delete(s *xorm.Session, a web.Auth) (err error) {
+	_, err = s.
+		Where("id = ?", f.ID).
+		Delete(&ICalFeed{})
+	return
+}
@@ -0,0 +1,165 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICalFeed_Create(t *testing.T) {
+	u := &user.User{ID: 1}
+
+	t.Run("list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: ICalFeedKindList, ListID: 1, SavedFilterID: 1}
+		err := feed.Create(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		assert.Len(t, feed.Token, 64)
+		db.AssertExists(t, "ical_feeds", map[string]interface{}{
+			"id":              feed.ID,
+			"kind":            "list",
+			"list_id":         1,
+			"saved_filter_id": 0,
+			"owner_id":        1,
+		}, false)
+	})
+	t.Run("list without access", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: ICalFeedKindList, ListID: 2}
+		err := feed.Create(s, &user.User{ID: 2})
+		assert.Error(t, err)
+		assert.True(t, IsErrGenericForbidden(err))
+	})
+	t.Run("saved filter of another user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: ICalFeedKindSavedFilter, SavedFilterID: 1}
+		err := feed.Create(s, &user.User{ID: 2})
+		assert.Error(t, err)
+		assert.True(t, IsErrGenericForbidden(err))
+	})
+	t.Run("invalid kind", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: "invalid"}
+		err := feed.Create(s, u)
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidICalFeedKind(err))
+	})
+}
+
+func TestICalFeed_GetTasks(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed, err := GetICalFeedByToken(s, "d5c35b9e7b8a1c0f4e2a6d3b9c8f7e1a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e")
+		assert.NoError(t, err)
+		title, tasks, err := feed.GetTasks(s)
+		assert.NoError(t, err)
+		assert.Equal(t, "Test1", title)
+		assert.NotEmpty(t, tasks)
+		for _, task := range tasks {
+			assert.Equal(t, int64(1), task.ListID)
+		}
+	})
+	t.Run("saved filter", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: ICalFeedKindSavedFilter, SavedFilterID: 1, OwnerID: 1}
+		title, _, err := feed.GetTasks(s)
+		assert.NoError(t, err)
+		assert.Equal(t, "testfilter1", title)
+	})
+	t.Run("assigned", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: ICalFeedKindAssigned, OwnerID: 1}
+		_, tasks, err := feed.GetTasks(s)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 1)
+		assert.Equal(t, int64(30), tasks[0].ID)
+	})
+	t.Run("owner lost access", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		feed := &ICalFeed{Kind: ICalFeedKindList, ListID: 1, OwnerID: 2}
+		_, _, err := feed.GetTasks(s)
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDoesNotHaveAccessToList(err))
+	})
+	t.Run("owner disabled", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		// User 5 is not active
+		feed := &ICalFeed{Kind: ICalFeedKindAssigned, OwnerID: 5}
+		_, _, err := feed.GetTasks(s)
+		assert.Error(t, err)
+		assert.True(t, IsErrICalFeedDoesNotExist(err))
+	})
+}
+
+func TestGetICalFeedByToken(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	_, err := GetICalFeedByToken(s, "")
+	assert.True(t, IsErrICalFeedDoesNotExist(err))
+	_, err = GetICalFeedByToken(s, "does-not-exist")
+	assert.True(t, IsErrICalFeedDoesNotExist(err))
+}
+
+func TestICalFeed_CanDelete(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	can, err := (&ICalFeed{ID: 1}).CanDelete(s, &user.User{ID: 1})
+	assert.NoError(t, err)
+	assert.True(t, can)
+
+	can, err = (&ICalFeed{ID: 1}).CanDelete(s, &user.User{ID: 2})
+	assert.NoError(t, err)
+	assert.False(t, can)
+}
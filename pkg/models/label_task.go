@@ -63,7 +63,11 @@ func (LabelTask) TableName() string {
 // @Router /tasks/{task}/labels/{label} [delete]
 func (lt *LabelTask) Delete(s *xorm.Session, a web.Auth) (err error) {
 	_, err = s.Delete(&LabelTask{LabelID: lt.LabelID, TaskID: lt.TaskID})
-	return err
+	if err != nil {
+		return err
+	}
+
+	return recordTaskChange(s, lt.TaskID)
 }
 
 // Create adds a label to a task
@@ -97,6 +101,11 @@ func (lt *LabelTask) Create(s *xorm.Session, a web.Auth) (err error) {
 		return err
 	}
 
+	err = recordTaskChange(s, lt.TaskID)
+	if err != nil {
+		return err
+	}
+
 	err = updateListByTaskID(s, lt.TaskID)
 	return
 }
@@ -256,7 +265,10 @@ func (t *Task) updateTaskLabels(s *xorm.Session, creator web.Auth, labels []*Lab
 	if len(labels) == 0 && len(t.Labels) > 0 {
 		_, err = s.Where("task_id = ?", t.ID).
 			Delete(LabelTask{})
-		return err
+		if err != nil {
+			return err
+		}
+		return recordTaskChange(s, t.ID)
 	}
 
 	// If we didn't change anything (from 0 to zero) don't do anything.
@@ -335,6 +347,11 @@ func (t *Task) updateTaskLabels(s *xorm.Session, creator web.Auth, labels []*Lab
 		t.Labels = append(t.Labels, label)
 	}
 
+	err = recordTaskChange(s, t.ID)
+	if err != nil {
+		return err
+	}
+
 	err = updateListLastUpdated(s, &List{ID: t.ListID})
 	return
 }
@@ -0,0 +1,113 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkSharing_Create(t *testing.T) {
+	doer := &user.User{ID: 1}
+
+	t.Run("with password", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		share := &LinkSharing{
+			ListID:      1,
+			Right:       RightRead,
+			SharingType: SharingTypeWithPassword,
+			Password:    "somePassword",
+		}
+		err := share.Create(s, doer)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		assert.Empty(t, share.Password)
+		db.AssertExists(t, "link_sharing", map[string]interface{}{
+			"id":           share.ID,
+			"sharing_type": SharingTypeWithPassword,
+		}, false)
+
+		stored, err := GetLinkShareByHash(s, share.Hash)
+		assert.NoError(t, err)
+		assert.NotEqual(t, "somePassword", stored.Password)
+		assert.NoError(t, VerifyLinkSharePassword(stored, "somePassword"))
+	})
+	t.Run("with password but without providing one", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		share := &LinkSharing{
+			ListID:      1,
+			Right:       RightRead,
+			SharingType: SharingTypeWithPassword,
+		}
+		err := share.Create(s, doer)
+		assert.Error(t, err)
+		assert.True(t, IsErrLinkSharePasswordRequired(err))
+	})
+	t.Run("password is ignored without password sharing type", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		share := &LinkSharing{
+			ListID:      1,
+			Right:       RightRead,
+			SharingType: SharingTypeWithoutPassword,
+			Password:    "somePassword",
+		}
+		err := share.Create(s, doer)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "link_sharing", map[string]interface{}{
+			"id":       share.ID,
+			"password": "",
+		}, false)
+	})
+}
+
+func TestVerifyLinkSharePassword(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	share, err := GetLinkShareByHash(s, "testWithPassword")
+	assert.NoError(t, err)
+
+	assert.NoError(t, VerifyLinkSharePassword(share, "1234"))
+
+	err = VerifyLinkSharePassword(share, "wrong")
+	assert.True(t, IsErrLinkSharePasswordInvalid(err))
+
+	err = VerifyLinkSharePassword(share, "")
+	assert.True(t, IsErrLinkSharePasswordRequired(err))
+
+	share, err = GetLinkShareByHash(s, "test")
+	assert.NoError(t, err)
+	assert.NoError(t, VerifyLinkSharePassword(share, ""))
+}
@@ -133,6 +133,24 @@ func GetListsByNamespaceID(s *xorm.Session, nID int64, doer *user.User) (lists [
 	return lists, err
 }
 
+// GetPseudoListsForUser returns the favorites pseudo list and all saved filters of a user as lists.
+// It is used for clients which only know about lists, like caldav.
+func GetPseudoListsForUser(s *xorm.Session, doer *user.User) (lists []*List, err error) {
+	favorites := FavoritesPseudoList
+	favorites.Owner = doer
+	lists = append(lists, &favorites)
+
+	savedFilters, err := getSavedFilters(s, doer)
+	if err != nil {
+		return nil, err
+	}
+	if savedFilters != nil {
+		lists = append(lists, savedFilters.Lists...)
+	}
+
+	return
+}
+
 // ReadAll gets all lists a user has access to
 // @Summary Get all lists a user has access to
 // @Description Returns all lists a user has access to.
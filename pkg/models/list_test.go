@@ -216,6 +216,31 @@ func TestList_ReadAll(t *testing.T) {
 	})
 }
 
+func TestGetPseudoListsForUser(t *testing.T) {
+	t.Run("with saved filters", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		lists, err := GetPseudoListsForUser(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+		assert.Len(t, lists, 2)
+		assert.Equal(t, FavoritesPseudoList.ID, lists[0].ID)
+		assert.Equal(t, getListIDFromSavedFilterID(1), lists[1].ID)
+		assert.Equal(t, "testfilter1", lists[1].Title)
+	})
+	t.Run("without saved filters", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		lists, err := GetPseudoListsForUser(s, &user.User{ID: 2})
+		assert.NoError(t, err)
+		assert.Len(t, lists, 1)
+		assert.Equal(t, FavoritesPseudoList.ID, lists[0].ID)
+	})
+}
+
 func TestList_ReadOne(t *testing.T) {
 	t.Run("normal", func(t *testing.T) {
 		db.LoadAndAssertFixtures(t)
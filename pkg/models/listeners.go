@@ -21,10 +21,12 @@ import (
 
 	"code.vikunja.io/api/pkg/db"
 	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/files"
 	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/metrics"
 	"code.vikunja.io/api/pkg/modules/keyvalue"
 	"code.vikunja.io/api/pkg/notifications"
+	"code.vikunja.io/api/pkg/user"
 	"github.com/ThreeDotsLabs/watermill/message"
 )
 
@@ -44,6 +46,8 @@ func RegisterListeners() {
 	events.RegisterListener((&ListCreatedEvent{}).Name(), &SendListCreatedNotification{})
 	events.RegisterListener((&TaskAssigneeCreatedEvent{}).Name(), &SubscribeAssigneeToTask{})
 	events.RegisterListener((&TeamMemberAddedEvent{}).Name(), &SendTeamMemberAddedNotification{})
+	events.RegisterListener((&UserDataExportRequestedEvent{}).Name(), &HandleUserDataExport{})
+	events.RegisterListener((&TaskAttachmentCreatedEvent{}).Name(), &GenerateTaskAttachmentPreviews{})
 }
 
 //////
@@ -247,6 +251,44 @@ func (s *SubscribeAssigneeToTask) Handle(msg *message.Message) (err error) {
 	return sess.Commit()
 }
 
+// GenerateTaskAttachmentPreviews  represents a listener
+type GenerateTaskAttachmentPreviews struct {
+}
+
+// Name defines the name for the GenerateTaskAttachmentPreviews listener
+func (s *GenerateTaskAttachmentPreviews) Name() string {
+	return "task.attachment.previews.generate"
+}
+
+// Handle is executed when the event GenerateTaskAttachmentPreviews listens on is fired
+func (s *GenerateTaskAttachmentPreviews) Handle(msg *message.Message) (err error) {
+	event := &TaskAttachmentCreatedEvent{}
+	err = json.Unmarshal(msg.Payload, event)
+	if err != nil {
+		return err
+	}
+
+	// The file meta is loaded again because not all of it is part of the event
+	ta := event.Attachment
+	ta.File = &files.File{ID: ta.File.ID}
+	err = ta.File.LoadFileMetaByID()
+	if err != nil {
+		return err
+	}
+
+	_, err = generateAttachmentPreviews(ta, allPreviewSizes)
+	if IsErrTaskAttachmentHasNoPreview(err) {
+		log.Debugf("Attachment %d is not an image, not generating previews", ta.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Generated previews for attachment %d", ta.ID)
+	return nil
+}
+
 ///////
 // List Event Listeners
 
@@ -402,3 +444,51 @@ func (s *SendTeamMemberAddedNotification) Handle(msg *message.Message) (err erro
 		Team:   event.Team,
 	})
 }
+
+///////
+// User Events
+
+// HandleUserDataExport  represents a listener
+type HandleUserDataExport struct {
+}
+
+// Name defines the name for the HandleUserDataExport listener
+func (s *HandleUserDataExport) Name() string {
+	return "handle.user.data.export"
+}
+
+// Handle is executed when the event HandleUserDataExport listens on is fired
+func (s *HandleUserDataExport) Handle(msg *message.Message) (err error) {
+	event := &UserDataExportRequestedEvent{}
+	err = json.Unmarshal(msg.Payload, event)
+	if err != nil {
+		return err
+	}
+
+	sess := db.NewSession()
+	defer sess.Close()
+
+	u, err := user.GetUserWithEmail(sess, &user.User{ID: event.User.ID})
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Starting data export for user %d", u.ID)
+
+	err = ExportUserData(sess, u)
+	if err != nil {
+		_ = sess.Rollback()
+		return err
+	}
+
+	err = sess.Commit()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Finished data export for user %d", u.ID)
+
+	return notifications.Notify(u, &DataExportReadyNotification{
+		User: u,
+	})
+}
@@ -60,6 +60,12 @@ func GetTables() []interface{} {
 		&UnsplashPhoto{},
 		&SavedFilter{},
 		&Subscription{},
+		&OAuth2Client{},
+		&OAuth2Grant{},
+		&UserDeletionTransfer{},
+		&TaskCaldavProperties{},
+		&TaskChange{},
+		&ICalFeed{},
 	}
 }
 
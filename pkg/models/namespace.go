@@ -607,11 +607,17 @@ func (n *Namespace) Create(s *xorm.Session, a web.Auth) (err error) {
 // CreateNewNamespaceForUser creates a new namespace for a user. To prevent import cycles, we can't do that
 // directly in the user.Create function.
 func CreateNewNamespaceForUser(s *xorm.Session, user *user.User) (err error) {
-	newN := &Namespace{
+	_, err = createNewNamespaceForUser(s, user)
+	return
+}
+
+func createNewNamespaceForUser(s *xorm.Session, user *user.User) (newN *Namespace, err error) {
+	newN = &Namespace{
 		Title:       user.Username,
 		Description: user.Username + "'s namespace.",
 	}
-	return newN.Create(s, user)
+	err = newN.Create(s, user)
+	return
 }
 
 // Delete deletes a namespace
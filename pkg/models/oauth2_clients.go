@@ -0,0 +1,289 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"crypto/subtle"
+	"net/url"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/utils"
+	"code.vikunja.io/web"
+	"xorm.io/xorm"
+)
+
+// OAuth2Client represents a third-party application which can ask users for access to their account through OAuth2.
+type OAuth2Client struct {
+	// The unique, numeric id of this client.
+	ID int64 `xorm:"bigint autoincr not null unique pk" json:"id" param:"client"`
+	// The public identifier of the client. It needs to be passed as `client_id` during the authorization flow.
+	ClientID string `xorm:"varchar(40) not null unique" json:"client_id"`
+	// The name of the application. It is shown to users when they are asked to grant access.
+	Name string `xorm:"varchar(250) not null" json:"name" valid:"required,runelength(1|250)" minLength:"1" maxLength:"250"`
+	// All urls users may be redirected to after granting access. The redirect url used during the authorization flow
+	// needs to match one of these exactly.
+	RedirectURIs []string `xorm:"'redirect_uris' JSON not null" json:"redirect_uris"`
+	// Confidential clients are able to keep a secret, for example because they run on a server. They need to authenticate
+	// with their secret when requesting tokens. Mobile, desktop and browser apps should be public clients.
+	// This can only be set when creating a client.
+	Confidential bool   `xorm:"not null default false" json:"confidential"`
+	SecretHash   string `xorm:"varchar(45) null" json:"-"`
+	// The secret of a confidential client. Only returned once when the client is created.
+	Secret  string `xorm:"-" json:"secret,omitempty"`
+	OwnerID int64  `xorm:"bigint not null INDEX" json:"-"`
+
+	// A timestamp when this client was created. You cannot change this value.
+	Created time.Time `xorm:"created not null" json:"created"`
+	// A timestamp when this client was last updated. You cannot change this value.
+	Updated time.Time `xorm:"updated not null" json:"updated"`
+
+	web.CRUDable `xorm:"-" json:"-"`
+	web.Rights   `xorm:"-" json:"-"`
+}
+
+// TableName returns the table name for oauth2 clients
+func (*OAuth2Client) TableName() string {
+	return "oauth2_clients"
+}
+
+// HasRedirectURI checks if the uri is one of the registered redirect uris of the client
+func (c *OAuth2Client) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckSecret checks the secret of a confidential client. Public clients don't have a secret and always pass.
+func (c *OAuth2Client) CheckSecret(secret string) bool {
+	if !c.Confidential {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(c.SecretHash), []byte(utils.Sha256(secret))) == 1
+}
+
+func validateOAuth2RedirectURIs(uris []string) error {
+	if len(uris) == 0 {
+		return ErrOAuth2InvalidRedirectURI{}
+	}
+
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		// Fragments are not allowed in redirect uris, see https://tools.ietf.org/html/rfc6749#section-3.1.2
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return ErrOAuth2InvalidRedirectURI{RedirectURI: uri}
+		}
+		// Plain http is only allowed for local development, apps can use custom schemes
+		if u.Scheme == "http" && u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+			return ErrOAuth2InvalidRedirectURI{RedirectURI: uri}
+		}
+	}
+
+	return nil
+}
+
+// GetOAuth2ClientByClientID returns a client by its public client id
+func GetOAuth2ClientByClientID(s *xorm.Session, clientID string) (client *OAuth2Client, err error) {
+	client = &OAuth2Client{}
+	exists, err := s.
+		Where("client_id = ?", clientID).
+		Get(client)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOAuth2ClientDoesNotExist{ClientID: clientID}
+	}
+	return
+}
+
+func getOAuth2ClientByID(s *xorm.Session, id int64) (client *OAuth2Client, err error) {
+	client = &OAuth2Client{}
+	exists, err := s.
+		Where("id = ?", id).
+		Get(client)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOAuth2ClientDoesNotExist{ID: id}
+	}
+	return
+}
+
+// Create registers a new oauth2 client
+// @Summary Register an OAuth2 client
+// @Description Registers a new third-party application which can ask users for access to their account. If the client is confidential, the response contains its secret. It is only shown once.
+// @tags oauth2
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param client body models.OAuth2Client true "The client you want to register."
+// @Success 200 {object} models.OAuth2Client "The created client."
+// @Failure 400 {object} web.HTTPError "Invalid client object provided."
+// @Failure 403 {object} web.HTTPError "Link shares cannot register clients."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/clients [put]
+func (c *OAuth2Client) Create(s *xorm.Session, a web.Auth) (err error) {
+	err = validateOAuth2RedirectURIs(c.RedirectURIs)
+	if err != nil {
+		return
+	}
+
+	c.ID = 0
+	c.OwnerID = a.GetID()
+	c.ClientID, err = utils.MakeSecureRandomString(40)
+	if err != nil {
+		return
+	}
+	c.ClientID = strings.ToLower(c.ClientID)
+
+	c.SecretHash = ""
+	c.Secret = ""
+	if c.Confidential {
+		c.Secret, err = utils.MakeSecureRandomString(64)
+		if err != nil {
+			return
+		}
+		c.SecretHash = utils.Sha256(c.Secret)
+	}
+
+	_, err = s.Insert(c)
+	return
+}
+
+// ReadAll returns all oauth2 clients the current user registered
+// @Summary Get all OAuth2 clients
+// @Description Returns all OAuth2 clients the current user registered.
+// @tags oauth2
+// @Accept json
+// @Produce json
+// @Param page query int false "The page number. Used for pagination. If not provided, the first page of results is returned."
+// @Param per_page query int false "The maximum number of items per page. Note this parameter is limited by the configured maximum of items per page."
+// @Param s query string false "Search clients by their name."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.OAuth2Client "The clients."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/clients [get]
+func (c *OAuth2Client) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	if _, is := a.(*LinkSharing); is {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	limit, start := getLimitFromPageIndex(page, perPage)
+
+	clients := []*OAuth2Client{}
+	query := s.
+		Where("owner_id = ? AND name LIKE ?", a.GetID(), "%"+search+"%").
+		OrderBy("id asc")
+	if limit > 0 {
+		query = query.Limit(limit, start)
+	}
+	err = query.Find(&clients)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	numberOfTotalItems, err = s.
+		Where("owner_id = ? AND name LIKE ?", a.GetID(), "%"+search+"%").
+		Count(&OAuth2Client{})
+	return clients, len(clients), numberOfTotalItems, err
+}
+
+// ReadOne returns one oauth2 client
+// @Summary Get one OAuth2 client
+// @Description Returns one OAuth2 client the current user registered.
+// @tags oauth2
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param client path int true "Client ID"
+// @Success 200 {object} models.OAuth2Client "The client."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the client."
+// @Failure 404 {object} web.HTTPError "The client does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/clients/{client} [get]
+func (c *OAuth2Client) ReadOne(s *xorm.Session, a web.Auth) (err error) {
+	// The rights check already loaded the full client
+	return nil
+}
+
+// Update updates an oauth2 client
+// @Summary Update an OAuth2 client
+// @Description Updates the name and redirect uris of an OAuth2 client.
+// @tags oauth2
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param client path int true "Client ID"
+// @Param client body models.OAuth2Client true "The client with updated values you want to change."
+// @Success 200 {object} models.OAuth2Client "The updated client."
+// @Failure 400 {object} web.HTTPError "Invalid client object provided."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the client."
+// @Failure 404 {object} web.HTTPError "The client does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/clients/{client} [post]
+func (c *OAuth2Client) Update(s *xorm.Session, a web.Auth) (err error) {
+	err = validateOAuth2RedirectURIs(c.RedirectURIs)
+	if err != nil {
+		return
+	}
+
+	_, err = s.
+		Where("id = ?", c.ID).
+		Cols("name", "redirect_uris").
+		Update(c)
+	if err != nil {
+		return
+	}
+
+	updated, err := getOAuth2ClientByID(s, c.ID)
+	if err != nil {
+		return
+	}
+	*c = *updated
+	return
+}
+
+// Delete removes an oauth2 client and all access users granted to it
+// @Summary Delete an OAuth2 client
+// @Description Deletes an OAuth2 client. All tokens issued to it stop working.
+// @tags oauth2
+// @Produce json
+// @Security JWTKeyAuth
+// @Param client path int true "Client ID"
+// @Success 200 {object} models.Message "The client was successfully deleted."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the client."
+// @Failure 404 {object} web.HTTPError "The client does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/clients/{client} [delete]
+func (c *OAuth2Client) Delete(s *xorm.Session, a web.Auth) (err error) {
+	_, err = s.
+		Where("client_id = ?", c.ID).
+		Delete(&OAuth2Grant{})
+	if err != nil {
+		return
+	}
+
+	_, err = s.
+		Where("id = ?", c.ID).
+		Delete(&OAuth2Client{})
+	return
+}
@@ -0,0 +1,71 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"code.vikunja.io/web"
+	"xorm.io/xorm"
+)
+
+// CanCreate checks if the user can register an oauth2 client
+func (c *OAuth2Client) CanCreate(s *xorm.Session, a web.Auth) (bool, error) {
+	if _, is := a.(*LinkSharing); is {
+		return false, nil
+	}
+	return true, nil
+}
+
+// CanRead checks if the user can see an oauth2 client
+func (c *OAuth2Client) CanRead(s *xorm.Session, a web.Auth) (bool, int, error) {
+	can, err := c.isOwner(s, a)
+	return can, int(RightAdmin), err
+}
+
+// CanUpdate checks if the user can update an oauth2 client
+func (c *OAuth2Client) CanUpdate(s *xorm.Session, a web.Auth) (bool, error) {
+	// Checking on the passed struct would override the values we want to update
+	cc := &OAuth2Client{ID: c.ID}
+	can, err := cc.isOwner(s, a)
+	if err != nil || !can {
+		return can, err
+	}
+	c.OwnerID = cc.OwnerID
+	return true, nil
+}
+
+// CanDelete checks if the user can delete an oauth2 client
+func (c *OAuth2Client) CanDelete(s *xorm.Session, a web.Auth) (bool, error) {
+	return c.isOwner(s, a)
+}
+
+// Only the user who registered a client can do anything with it
+func (c *OAuth2Client) isOwner(s *xorm.Session, a web.Auth) (bool, error) {
+	if _, is := a.(*LinkSharing); is {
+		return false, nil
+	}
+
+	client, err := getOAuth2ClientByID(s, c.ID)
+	if err != nil {
+		return false, err
+	}
+	if client.OwnerID != a.GetID() {
+		return false, nil
+	}
+
+	*c = *client
+	return true, nil
+}
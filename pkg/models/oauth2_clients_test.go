@@ -0,0 +1,179 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth2Client_Create(t *testing.T) {
+	u := &user.User{ID: 1}
+
+	t.Run("public client", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{
+			Name:         "Test",
+			RedirectURIs: []string{"vikunja-test://callback"},
+		}
+		err := c.Create(s, u)
+		assert.NoError(t, err)
+		assert.Len(t, c.ClientID, 40)
+		assert.Empty(t, c.Secret)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "oauth2_clients", map[string]interface{}{
+			"id":           c.ID,
+			"client_id":    c.ClientID,
+			"name":         "Test",
+			"confidential": false,
+			"owner_id":     1,
+		}, false)
+	})
+	t.Run("confidential client", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{
+			Name:         "Test",
+			RedirectURIs: []string{"https://example.com/callback"},
+			Confidential: true,
+		}
+		err := c.Create(s, u)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, c.Secret)
+		assert.True(t, c.CheckSecret(c.Secret))
+		assert.False(t, c.CheckSecret("wrong"))
+	})
+	t.Run("no redirect uri", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{Name: "Test"}
+		err := c.Create(s, u)
+		assert.Error(t, err)
+		assert.True(t, IsErrOAuth2InvalidRedirectURI(err))
+	})
+	t.Run("insecure redirect uri", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{
+			Name:         "Test",
+			RedirectURIs: []string{"http://example.com/callback"},
+		}
+		err := c.Create(s, u)
+		assert.Error(t, err)
+		assert.True(t, IsErrOAuth2InvalidRedirectURI(err))
+	})
+}
+
+func TestOAuth2Client_CanRead(t *testing.T) {
+	t.Run("owner", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{ID: 1}
+		can, _, err := c.CanRead(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+		assert.True(t, can)
+		assert.Equal(t, "publicclient1", c.ClientID)
+	})
+	t.Run("other user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{ID: 2}
+		can, _, err := c.CanRead(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+		assert.False(t, can)
+	})
+	t.Run("nonexisting", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		c := &OAuth2Client{ID: 9999}
+		_, _, err := c.CanRead(s, &user.User{ID: 1})
+		assert.Error(t, err)
+		assert.True(t, IsErrOAuth2ClientDoesNotExist(err))
+	})
+}
+
+func TestOAuth2Client_Delete(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	c := &OAuth2Client{ID: 1}
+	err := c.Delete(s, &user.User{ID: 1})
+	assert.NoError(t, err)
+	err = s.Commit()
+	assert.NoError(t, err)
+
+	db.AssertMissing(t, "oauth2_clients", map[string]interface{}{
+		"id": 1,
+	})
+	db.AssertMissing(t, "oauth2_grants", map[string]interface{}{
+		"client_id": 1,
+	})
+}
+
+func TestParseOAuth2Scopes(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		scopes, err := ParseOAuth2Scopes("")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{OAuth2ScopeRead}, scopes)
+	})
+	t.Run("multiple", func(t *testing.T) {
+		scopes, err := ParseOAuth2Scopes("read write read")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{OAuth2ScopeRead, OAuth2ScopeWrite}, scopes)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseOAuth2Scopes("read admin")
+		assert.Error(t, err)
+		assert.True(t, IsErrOAuth2InvalidScope(err))
+	})
+}
+
+func TestOAuth2Grant_CanDelete(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	g := &OAuth2Grant{ID: 1}
+	can, err := g.CanDelete(s, &user.User{ID: 1})
+	assert.NoError(t, err)
+	assert.True(t, can)
+
+	can, err = g.CanDelete(s, &user.User{ID: 2})
+	assert.NoError(t, err)
+	assert.False(t, can)
+}
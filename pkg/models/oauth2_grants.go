@@ -0,0 +1,250 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/utils"
+	"code.vikunja.io/web"
+	"xorm.io/xorm"
+)
+
+// All scopes an oauth2 client can request
+const (
+	// OAuth2ScopeRead allows a client to read everything the user has access to.
+	OAuth2ScopeRead = "read"
+	// OAuth2ScopeWrite allows a client to read and modify everything the user has access to.
+	OAuth2ScopeWrite = "write"
+)
+
+// ParseOAuth2Scopes parses a space separated list of scopes as used in oauth2 requests.
+// If no scope is requested, clients only get read access.
+func ParseOAuth2Scopes(scope string) (scopes []string, err error) {
+	seen := make(map[string]bool)
+	for _, sc := range strings.Fields(scope) {
+		if sc != OAuth2ScopeRead && sc != OAuth2ScopeWrite {
+			return nil, ErrOAuth2InvalidScope{Scope: sc}
+		}
+		if seen[sc] {
+			continue
+		}
+		seen[sc] = true
+		scopes = append(scopes, sc)
+	}
+
+	if len(scopes) == 0 {
+		scopes = []string{OAuth2ScopeRead}
+	}
+
+	return
+}
+
+// OAuth2Grant represents the access a user granted to an oauth2 client.
+type OAuth2Grant struct {
+	// The unique, numeric id of this grant.
+	ID       int64 `xorm:"bigint autoincr not null unique pk" json:"id" param:"grant"`
+	ClientID int64 `xorm:"bigint not null INDEX" json:"-"`
+	UserID   int64 `xorm:"bigint not null INDEX" json:"-"`
+	// The scopes the user granted to the client, separated by spaces.
+	Scope            string `xorm:"varchar(250) not null" json:"scope"`
+	RefreshTokenHash string `xorm:"varchar(45) null INDEX" json:"-"`
+	// When the client last used this grant to get a new access token.
+	LastUsed time.Time `xorm:"datetime null" json:"last_used"`
+
+	// The client the user granted access to.
+	Client *OAuth2Client `xorm:"-" json:"client"`
+
+	// A timestamp when the access was granted. You cannot change this value.
+	Created time.Time `xorm:"created not null" json:"created"`
+	// A timestamp when this grant was last updated. You cannot change this value.
+	Updated time.Time `xorm:"updated not null" json:"updated"`
+
+	web.CRUDable `xorm:"-" json:"-"`
+	web.Rights   `xorm:"-" json:"-"`
+}
+
+// TableName returns the table name for oauth2 grants
+func (*OAuth2Grant) TableName() string {
+	return "oauth2_grants"
+}
+
+// HasScope checks if the user granted the scope to the client
+func (g *OAuth2Grant) HasScope(scope string) bool {
+	for _, sc := range strings.Fields(g.Scope) {
+		if sc == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func newOAuth2RefreshToken() (token string, hash string, err error) {
+	token, err = utils.MakeSecureRandomString(64)
+	if err != nil {
+		return
+	}
+	return token, utils.Sha256(token), nil
+}
+
+// CreateOAuth2Grant saves the access a user granted to a client and returns the refresh token for it.
+// The refresh token is only stored hashed and can not be retrieved later.
+func CreateOAuth2Grant(s *xorm.Session, client *OAuth2Client, userID int64, scopes []string) (grant *OAuth2Grant, refreshToken string, err error) {
+	refreshToken, hash, err := newOAuth2RefreshToken()
+	if err != nil {
+		return
+	}
+
+	grant = &OAuth2Grant{
+		ClientID:         client.ID,
+		UserID:           userID,
+		Scope:            strings.Join(scopes, " "),
+		RefreshTokenHash: hash,
+		LastUsed:         time.Now(),
+	}
+	_, err = s.Insert(grant)
+	return
+}
+
+// GetOAuth2GrantByID returns a grant by its id
+func GetOAuth2GrantByID(s *xorm.Session, id int64) (grant *OAuth2Grant, err error) {
+	grant = &OAuth2Grant{}
+	exists, err := s.
+		Where("id = ?", id).
+		Get(grant)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOAuth2GrantDoesNotExist{GrantID: id}
+	}
+	return
+}
+
+// GetOAuth2GrantByRefreshToken returns the grant a refresh token belongs to
+func GetOAuth2GrantByRefreshToken(s *xorm.Session, refreshToken string) (grant *OAuth2Grant, err error) {
+	grant = &OAuth2Grant{}
+	exists, err := s.
+		Where("refresh_token_hash = ?", utils.Sha256(refreshToken)).
+		Get(grant)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || refreshToken == "" {
+		return nil, ErrOAuth2GrantDoesNotExist{}
+	}
+	return
+}
+
+// RotateRefreshToken replaces the refresh token of a grant with a new one so every refresh token can only be used once.
+// If the refresh token was already rotated in the meantime, for example by a parallel request with the same token,
+// it returns ErrOAuth2GrantDoesNotExist.
+func (g *OAuth2Grant) RotateRefreshToken(s *xorm.Session) (refreshToken string, err error) {
+	refreshToken, hash, err := newOAuth2RefreshToken()
+	if err != nil {
+		return
+	}
+
+	oldHash := g.RefreshTokenHash
+	g.RefreshTokenHash = hash
+	g.LastUsed = time.Now()
+	affected, err := s.
+		Where("id = ? AND refresh_token_hash = ?", g.ID, oldHash).
+		Cols("refresh_token_hash", "last_used").
+		Update(g)
+	if err != nil {
+		return "", err
+	}
+	if affected == 0 {
+		return "", ErrOAuth2GrantDoesNotExist{GrantID: g.ID}
+	}
+	return
+}
+
+// ReadAll returns all clients the current user granted access to
+// @Summary Get all authorized OAuth2 applications
+// @Description Returns all applications the current user granted access to their account through OAuth2.
+// @tags oauth2
+// @Accept json
+// @Produce json
+// @Param page query int false "The page number. Used for pagination. If not provided, the first page of results is returned."
+// @Param per_page query int false "The maximum number of items per page. Note this parameter is limited by the configured maximum of items per page."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.OAuth2Grant "The grants."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/grants [get]
+func (g *OAuth2Grant) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	if _, is := a.(*LinkSharing); is {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	limit, start := getLimitFromPageIndex(page, perPage)
+
+	grants := []*OAuth2Grant{}
+	query := s.
+		Where("user_id = ?", a.GetID()).
+		OrderBy("id asc")
+	if limit > 0 {
+		query = query.Limit(limit, start)
+	}
+	err = query.Find(&grants)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	clientIDs := make([]int64, 0, len(grants))
+	for _, grant := range grants {
+		clientIDs = append(clientIDs, grant.ClientID)
+	}
+
+	clients := make(map[int64]*OAuth2Client, len(clientIDs))
+	if len(clientIDs) > 0 {
+		err = s.In("id", clientIDs).Find(&clients)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	for _, grant := range grants {
+		grant.Client = clients[grant.ClientID]
+	}
+
+	numberOfTotalItems, err = s.
+		Where("user_id = ?", a.GetID()).
+		Count(&OAuth2Grant{})
+	return grants, len(grants), numberOfTotalItems, err
+}
+
+// Delete revokes the access of a client
+// @Summary Revoke access of an OAuth2 application
+// @Description Revokes the access the current user granted to an application. All tokens issued to it with this grant stop working.
+// @tags oauth2
+// @Produce json
+// @Security JWTKeyAuth
+// @Param grant path int true "Grant ID"
+// @Success 200 {object} models.Message "The access was successfully revoked."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the grant."
+// @Failure 404 {object} web.HTTPError "The grant does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/grants/{grant} [delete]
+func (g *OAuth2Grant) Delete(s *xorm.Session, a web.Auth) (err error) {
+	_, err = s.
+		Where("id = ?", g.ID).
+		Delete(&OAuth2Grant{})
+	return
+}
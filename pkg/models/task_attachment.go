@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
 	"code.vikunja.io/api/pkg/user"
 	"code.vikunja.io/web"
 	"xorm.io/xorm"
@@ -49,6 +50,7 @@ func (TaskAttachment) TableName() string {
 }
 
 // NewAttachment creates a new task attachment
+// Dispatch a TaskAttachmentCreatedEvent once the session is committed to generate previews of the attachment.
 // Note: I'm not sure if only accepting an io.ReadCloser and not an afero.File or os.File instead is a good way of doing things.
 func (ta *TaskAttachment) NewAttachment(s *xorm.Session, f io.ReadCloser, realname string, realsize uint64, a web.Auth) error {
 
@@ -195,6 +197,11 @@ func (ta *TaskAttachment) Delete(s *xorm.Session, a web.Auth) error {
 		return err
 	}
 
+	err = keyvalue.Del(getNoPreviewKey(ta.FileID))
+	if err != nil {
+		return err
+	}
+
 	// Delete the underlying file
 	err = ta.File.Delete()
 	// If the file does not exist, we don't want to error out
@@ -0,0 +1,170 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	// Formats which can be previewed in addition to the ones imaging supports
+	_ "golang.org/x/image/webp"
+
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"github.com/disintegration/imaging"
+)
+
+// PreviewSize is the size of a preview of an image attachment
+type PreviewSize string
+
+// All sizes attachment previews are available in
+const (
+	PreviewSizeSmall      PreviewSize = `sm`
+	PreviewSizeMedium     PreviewSize = `md`
+	PreviewSizeLarge      PreviewSize = `lg`
+	PreviewSizeExtraLarge PreviewSize = `xl`
+)
+
+// The maximum width and height of a preview in pixels
+var previewSizes = map[PreviewSize]int{
+	PreviewSizeSmall:      100,
+	PreviewSizeMedium:     200,
+	PreviewSizeLarge:      400,
+	PreviewSizeExtraLarge: 800,
+}
+
+var allPreviewSizes = []PreviewSize{
+	PreviewSizeSmall,
+	PreviewSizeMedium,
+	PreviewSizeLarge,
+	PreviewSizeExtraLarge,
+}
+
+// Images with more pixels than this are not previewed because decoding them would take up too much memory
+const maxPreviewSourcePixels = 50 * 1000 * 1000
+
+// GetPreviewSizeFromString returns the preview size of a string like "md"
+func GetPreviewSizeFromString(size string) (PreviewSize, error) {
+	if _, exists := previewSizes[PreviewSize(size)]; !exists {
+		return "", ErrInvalidPreviewSize{Size: size}
+	}
+	return PreviewSize(size), nil
+}
+
+func (p PreviewSize) getDerivedFileKind() string {
+	return "preview_" + string(p)
+}
+
+// GetPreview returns the preview of an image attachment in a size. Previews are usually generated when an
+// attachment is uploaded, if one does not exist yet it is generated now.
+// The attachment needs to be loaded with its file.
+func (ta *TaskAttachment) GetPreview(size PreviewSize) (preview *files.File, err error) {
+	_, noPreview, err := keyvalue.Get(getNoPreviewKey(ta.File.ID))
+	if err != nil {
+		return nil, err
+	}
+	if noPreview {
+		return nil, ErrTaskAttachmentHasNoPreview{AttachmentID: ta.ID}
+	}
+
+	preview, err = files.GetDerivedFile(ta.File.ID, size.getDerivedFileKind())
+	if err == nil {
+		return preview, nil
+	}
+	if !files.IsErrFileDoesNotExist(err) {
+		return nil, err
+	}
+
+	previews, err := generateAttachmentPreviews(ta, []PreviewSize{size})
+	if err != nil {
+		return nil, err
+	}
+	return previews[size], nil
+}
+
+// Files which can't be previewed are remembered to not read them again every time their preview is requested
+func getNoPreviewKey(fileID int64) string {
+	return "attachment_no_preview_" + strconv.FormatInt(fileID, 10)
+}
+
+func markAsWithoutPreview(ta *TaskAttachment) error {
+	err := keyvalue.Put(getNoPreviewKey(ta.File.ID), true)
+	if err != nil {
+		return err
+	}
+	return ErrTaskAttachmentHasNoPreview{AttachmentID: ta.ID}
+}
+
+// generateAttachmentPreviews creates a preview of an image attachment in every size.
+// Multi-page images like animated gifs or tiffs are previewed with their first page.
+func generateAttachmentPreviews(ta *TaskAttachment, sizes []PreviewSize) (previews map[PreviewSize]*files.File, err error) {
+	original := ta.File
+	if err := original.LoadFileByID(); err != nil {
+		return nil, err
+	}
+	defer original.File.Close()
+
+	// Only the header of the file is read to check if it is an image
+	config, format, err := image.DecodeConfig(original.File)
+	if err != nil || config.Width*config.Height > maxPreviewSourcePixels {
+		return nil, markAsWithoutPreview(ta)
+	}
+	if _, err := original.File.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, err := imaging.Decode(original.File, imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, markAsWithoutPreview(ta)
+	}
+
+	// Photos are kept as jpeg, everything else could have transparent parts
+	encodeFormat, mime, ext := imaging.PNG, "image/png", ".png"
+	if format == "jpeg" {
+		encodeFormat, mime, ext = imaging.JPEG, "image/jpeg", ".jpg"
+	}
+	// The extension of the name is used to determine the content type when serving a preview
+	name := strings.TrimSuffix(original.Name, path.Ext(original.Name)) + ext
+
+	previews = make(map[PreviewSize]*files.File, len(sizes))
+	for _, size := range sizes {
+		// Images which are smaller than the preview size stay as they are
+		resized := imaging.Fit(img, previewSizes[size], previewSizes[size], imaging.Lanczos)
+
+		buf := &bytes.Buffer{}
+		if err := imaging.Encode(buf, resized, encodeFormat, imaging.JPEGQuality(85)); err != nil {
+			return nil, err
+		}
+
+		// If the preview was generated concurrently, the existing one is returned
+		preview, err := files.CreateDerived(&files.File{
+			ID:          original.ID,
+			Name:        name,
+			CreatedByID: original.CreatedByID,
+		}, size.getDerivedFileKind(), buf, uint64(buf.Len()), mime)
+		if err != nil {
+			return nil, err
+		}
+		previews[size] = preview
+	}
+
+	return previews, nil
+}
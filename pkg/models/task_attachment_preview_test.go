@@ -0,0 +1,113 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskAttachment_GetPreview(t *testing.T) {
+	u := &user.User{ID: 1}
+
+	t.Run("image", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		files.InitTestFileFixtures(t)
+
+		img := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+		for x := 0; x < 1000; x++ {
+			img.Set(x, x/2, color.RGBA{R: 255, A: 255})
+		}
+		buf := &bytes.Buffer{}
+		err := png.Encode(buf, img)
+		assert.NoError(t, err)
+
+		ta := &TaskAttachment{TaskID: 1}
+		err = ta.NewAttachment(s, ioutil.NopCloser(bytes.NewReader(buf.Bytes())), "image.png", uint64(buf.Len()), u)
+		assert.NoError(t, err)
+
+		preview, err := ta.GetPreview(PreviewSizeSmall)
+		assert.NoError(t, err)
+		assert.Equal(t, "image.png", preview.Name)
+		assert.Equal(t, "image/png", preview.Mime)
+
+		err = preview.LoadFileByID()
+		assert.NoError(t, err)
+		config, _, err := image.DecodeConfig(preview.File)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, config.Width)
+		assert.Equal(t, 50, config.Height)
+
+		// The second time the stored preview is returned
+		again, err := ta.GetPreview(PreviewSizeSmall)
+		assert.NoError(t, err)
+		assert.Equal(t, preview.ID, again.ID)
+
+		// Generating the previews again, like the listener does, does not create another one
+		previews, err := generateAttachmentPreviews(ta, allPreviewSizes)
+		assert.NoError(t, err)
+		assert.Equal(t, preview.ID, previews[PreviewSizeSmall].ID)
+		count, err := s.Where("derived_from_id = ?", ta.File.ID).Count(&files.File{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(allPreviewSizes)), count)
+	})
+	t.Run("not an image", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		files.InitTestFileFixtures(t)
+
+		ta := &TaskAttachment{ID: 1}
+		err := ta.ReadOne(s, u)
+		assert.NoError(t, err)
+		_, err = ta.GetPreview(PreviewSizeSmall)
+		assert.Error(t, err)
+		assert.True(t, IsErrTaskAttachmentHasNoPreview(err))
+
+		// The result is cached
+		_, exists, err := keyvalue.Get(getNoPreviewKey(ta.File.ID))
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		_, err = ta.GetPreview(PreviewSizeMedium)
+		assert.True(t, IsErrTaskAttachmentHasNoPreview(err))
+
+		err = (&TaskAttachment{TaskID: ta.TaskID, ID: ta.ID}).Delete(s, u)
+		assert.NoError(t, err)
+		_, exists, err = keyvalue.Get(getNoPreviewKey(ta.File.ID))
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+	t.Run("invalid size", func(t *testing.T) {
+		_, err := GetPreviewSizeFromString("huge")
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidPreviewSize(err))
+	})
+}
@@ -0,0 +1,278 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"code.vikunja.io/web"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// TaskCaldavProperties holds all properties of a task's caldav representation Vikunja does not know about.
+// They are stored verbatim and sent back to caldav clients so that a sync through Vikunja does not lose anything.
+type TaskCaldavProperties struct {
+	ID     int64 `xorm:"bigint autoincr not null unique pk"`
+	TaskID int64 `xorm:"bigint not null unique"`
+	// The raw ical lines, separated by a line break
+	Properties string `xorm:"longtext null"`
+
+	Created time.Time `xorm:"created not null"`
+	Updated time.Time `xorm:"updated not null"`
+}
+
+// TableName returns the table name for caldav task properties
+func (TaskCaldavProperties) TableName() string {
+	return "task_caldav_properties"
+}
+
+// caldavRelationKinds are all relation kinds which have an equivalent in RELATED-TO
+var caldavRelationKinds = []RelationKind{
+	RelationKindParenttask,
+	RelationKindSubtask,
+	RelationKindRelated,
+}
+
+var inverseCaldavRelationKinds = map[RelationKind]RelationKind{
+	RelationKindParenttask: RelationKindSubtask,
+	RelationKindSubtask:    RelationKindParenttask,
+	RelationKindRelated:    RelationKindRelated,
+}
+
+// GetCaldavPropertiesForTasks returns the stored caldav properties of a bunch of tasks, keyed by their task id.
+func GetCaldavPropertiesForTasks(s *xorm.Session, taskIDs []int64) (properties map[int64]string, err error) {
+	properties = make(map[int64]string, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return
+	}
+
+	props := []*TaskCaldavProperties{}
+	err = s.In("task_id", taskIDs).Find(&props)
+	if err != nil {
+		return
+	}
+
+	for _, p := range props {
+		properties[p.TaskID] = p.Properties
+	}
+	return
+}
+
+// SetCaldavProperties saves the unknown caldav properties of a task, replacing all previously saved ones.
+func (t *Task) SetCaldavProperties(s *xorm.Session, properties string) (err error) {
+	if properties == "" {
+		_, err = s.Where("task_id = ?", t.ID).Delete(&TaskCaldavProperties{})
+		return
+	}
+
+	existing := &TaskCaldavProperties{}
+	exists, err := s.Where("task_id = ?", t.ID).Get(existing)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		_, err = s.Insert(&TaskCaldavProperties{TaskID: t.ID, Properties: properties})
+		return
+	}
+
+	existing.Properties = properties
+	_, err = s.ID(existing.ID).Cols("properties").Update(existing)
+	return
+}
+
+// UpdateLabelsByTitle replaces all labels of a task with the labels matching the titles.
+// Labels which don't exist yet or which the user does not have access to are created.
+func (t *Task) UpdateLabelsByTitle(s *xorm.Session, a web.Auth, titles []string) (err error) {
+	existing, _, _, err := getLabelsByTaskIDs(s, &LabelByTaskIDsOptions{
+		TaskIDs: []int64{t.ID},
+		Page:    -1,
+	})
+	if err != nil {
+		return err
+	}
+	t.Labels = make([]*Label, 0, len(existing))
+	for _, l := range existing {
+		label := l.Label
+		t.Labels = append(t.Labels, &label)
+	}
+
+	labelsByTitle := make(map[string]*Label)
+	if len(titles) > 0 {
+		accessible, _, _, err := (&Label{}).ReadAll(s, a, "", -1, 0)
+		if err != nil {
+			return err
+		}
+		for _, l := range accessible.([]*labelWithTaskID) {
+			label := l.Label
+			if _, has := labelsByTitle[label.Title]; !has {
+				labelsByTitle[label.Title] = &label
+			}
+		}
+	}
+
+	labels := make([]*Label, 0, len(titles))
+	for _, title := range titles {
+		label, exists := labelsByTitle[title]
+		if !exists {
+			label = &Label{Title: title}
+			err = label.Create(s, a)
+			if err != nil {
+				return err
+			}
+			labelsByTitle[title] = label
+		}
+		labels = append(labels, label)
+	}
+
+	return t.updateTaskLabels(s, a, labels)
+}
+
+// findRelatedTasksByUID finds the tasks with the uids the user has access to.
+// Uids are only unique per list, tasks of other users or imported tasks might have the same uid. If there is more
+// than one task with a uid, a task in the same list as t is preferred.
+func (t *Task) findRelatedTasksByUID(s *xorm.Session, a web.Auth, uids []string) (tasksByUID map[string]*Task, err error) {
+	tasksByUID = make(map[string]*Task, len(uids))
+	if len(uids) == 0 {
+		return
+	}
+
+	others := []*Task{}
+	err = s.
+		In("uid", uids).
+		OrderBy("id asc").
+		Find(&others)
+	if err != nil {
+		return nil, err
+	}
+
+	canReadList := make(map[int64]bool)
+	for _, o := range others {
+		if current, has := tasksByUID[o.UID]; has && current.ListID == t.ListID {
+			continue
+		}
+
+		canRead, has := canReadList[o.ListID]
+		if !has {
+			canRead, _, err = (&List{ID: o.ListID}).CanRead(s, a)
+			if err != nil {
+				return nil, err
+			}
+			canReadList[o.ListID] = canRead
+		}
+		if !canRead {
+			continue
+		}
+
+		if _, has := tasksByUID[o.UID]; !has || o.ListID == t.ListID {
+			tasksByUID[o.UID] = o
+		}
+	}
+
+	return
+}
+
+// SyncCaldavRelations replaces all parent, sub and related task relations of a task with the ones passed in.
+// The other tasks are referenced by their uid. All relations where the other task does not exist (yet) or
+// the user does not have access to are returned so they can be kept around until the other task shows up.
+func (t *Task) SyncCaldavRelations(s *xorm.Session, a web.Auth, related map[RelationKind][]string) (unresolved map[RelationKind][]string, err error) {
+	unresolved = make(map[RelationKind][]string)
+
+	var uids []string
+	for _, kind := range caldavRelationKinds {
+		uids = append(uids, related[kind]...)
+	}
+
+	tasksByUID, err := t.findRelatedTasksByUID(s, a, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := []*TaskRelation{}
+	err = s.
+		Where("task_id = ?", t.ID).
+		And(builder.In("relation_kind", caldavRelationKinds)).
+		Find(&existing)
+	if err != nil {
+		return nil, err
+	}
+
+	existingRelations := make(map[RelationKind]map[int64]bool)
+	for _, kind := range caldavRelationKinds {
+		existingRelations[kind] = make(map[int64]bool)
+	}
+	for _, rel := range existing {
+		existingRelations[rel.RelationKind][rel.OtherTaskID] = true
+	}
+
+	for _, kind := range caldavRelationKinds {
+		wanted := make(map[int64]bool)
+		for _, uid := range related[kind] {
+			other, exists := tasksByUID[uid]
+			if !exists || other.ID == t.ID {
+				unresolved[kind] = append(unresolved[kind], uid)
+				continue
+			}
+			wanted[other.ID] = true
+
+			if existingRelations[kind][other.ID] {
+				continue
+			}
+
+			rel := &TaskRelation{
+				TaskID:       t.ID,
+				OtherTaskID:  other.ID,
+				RelationKind: kind,
+			}
+			can, err := rel.CanCreate(s, a)
+			if err != nil {
+				return nil, err
+			}
+			if !can {
+				unresolved[kind] = append(unresolved[kind], uid)
+				continue
+			}
+			err = rel.Create(s, a)
+			if err != nil {
+				return nil, err
+			}
+			existingRelations[kind][other.ID] = true
+		}
+
+		for otherID := range existingRelations[kind] {
+			if wanted[otherID] {
+				continue
+			}
+
+			// Relations always exist in both directions, so we need to remove both
+			_, err = s.
+				Where("(task_id = ? AND other_task_id = ? AND relation_kind = ?) OR (task_id = ? AND other_task_id = ? AND relation_kind = ?)",
+					t.ID, otherID, kind, otherID, t.ID, inverseCaldavRelationKinds[kind]).
+				Delete(&TaskRelation{})
+			if err != nil {
+				return nil, err
+			}
+
+			err = recordTaskChange(s, otherID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,161 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_UpdateLabelsByTitle(t *testing.T) {
+	t.Run("existing and new labels", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		task := &Task{ID: 1, ListID: 1}
+		err := task.UpdateLabelsByTitle(s, &user.User{ID: 1}, []string{"Label #1", "A new label"})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		assert.Len(t, task.Labels, 2)
+		db.AssertExists(t, "label_task", map[string]interface{}{
+			"task_id":  1,
+			"label_id": 1,
+		}, false)
+		db.AssertMissing(t, "label_task", map[string]interface{}{
+			"task_id":  1,
+			"label_id": 4,
+		})
+		db.AssertExists(t, "labels", map[string]interface{}{
+			"title":         "A new label",
+			"created_by_id": 1,
+		}, false)
+	})
+	t.Run("no labels", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		task := &Task{ID: 1, ListID: 1}
+		err := task.UpdateLabelsByTitle(s, &user.User{ID: 1}, nil)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "label_task", map[string]interface{}{
+			"task_id": 1,
+		})
+	})
+}
+
+func TestTask_SyncCaldavRelations(t *testing.T) {
+	t.Run("add, keep and remove relations", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.Exec("UPDATE tasks SET uid = 'uid-2' WHERE id = 2")
+		assert.NoError(t, err)
+
+		task := &Task{ID: 1, ListID: 1}
+		unresolved, err := task.SyncCaldavRelations(s, &user.User{ID: 1}, map[RelationKind][]string{
+			RelationKindSubtask: {"uid-2", "does-not-exist"},
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		assert.Equal(t, []string{"does-not-exist"}, unresolved[RelationKindSubtask])
+		db.AssertExists(t, "task_relations", map[string]interface{}{
+			"task_id":       1,
+			"other_task_id": 2,
+			"relation_kind": RelationKindSubtask,
+		}, false)
+		db.AssertExists(t, "task_relations", map[string]interface{}{
+			"task_id":       2,
+			"other_task_id": 1,
+			"relation_kind": RelationKindParenttask,
+		}, false)
+		db.AssertMissing(t, "task_relations", map[string]interface{}{
+			"task_id":       1,
+			"other_task_id": 29,
+			"relation_kind": RelationKindSubtask,
+		})
+		db.AssertMissing(t, "task_relations", map[string]interface{}{
+			"task_id":       29,
+			"other_task_id": 1,
+			"relation_kind": RelationKindParenttask,
+		})
+	})
+	t.Run("same uid in a list of another user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.Exec("UPDATE tasks SET uid = 'uid-2' WHERE id IN (2, 14)")
+		assert.NoError(t, err)
+
+		task := &Task{ID: 1, ListID: 1}
+		unresolved, err := task.SyncCaldavRelations(s, &user.User{ID: 1}, map[RelationKind][]string{
+			RelationKindSubtask: {"uid-2"},
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		assert.Empty(t, unresolved[RelationKindSubtask])
+		db.AssertExists(t, "task_relations", map[string]interface{}{
+			"task_id":       1,
+			"other_task_id": 2,
+			"relation_kind": RelationKindSubtask,
+		}, false)
+		db.AssertMissing(t, "task_relations", map[string]interface{}{
+			"task_id":       1,
+			"other_task_id": 14,
+		})
+	})
+}
+
+func TestTask_SetCaldavProperties(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	task := &Task{ID: 1}
+	err := task.SetCaldavProperties(s, "CLASS:PRIVATE")
+	assert.NoError(t, err)
+	err = task.SetCaldavProperties(s, "X-CUSTOM:value")
+	assert.NoError(t, err)
+
+	props, err := GetCaldavPropertiesForTasks(s, []int64{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]string{1: "X-CUSTOM:value"}, props)
+
+	err = task.SetCaldavProperties(s, "")
+	assert.NoError(t, err)
+	err = s.Commit()
+	assert.NoError(t, err)
+
+	db.AssertMissing(t, "task_caldav_properties", map[string]interface{}{
+		"task_id": 1,
+	})
+}
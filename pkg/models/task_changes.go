@@ -0,0 +1,92 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"xorm.io/xorm"
+)
+
+// TaskChange is an entry in the change log of a list. Caldav clients use it to only get the tasks which changed
+// since their last sync instead of all tasks of a list.
+// Only the latest change of each task in a list is kept, older ones are removed when a new change is recorded.
+type TaskChange struct {
+	// The id is increasing with every change and is used as sync token.
+	ID      int64  `xorm:"bigint autoincr not null unique pk"`
+	ListID  int64  `xorm:"bigint not null INDEX"`
+	TaskID  int64  `xorm:"bigint not null INDEX"`
+	TaskUID string `xorm:"varchar(250) null"`
+	// If true, the task was deleted or moved to another list.
+	Deleted bool `xorm:"not null default false"`
+
+	Created time.Time `xorm:"created not null"`
+}
+
+// TableName returns the table name for task changes
+func (TaskChange) TableName() string {
+	return "task_changes"
+}
+
+func addTaskChange(s *xorm.Session, listID, taskID int64, uid string, deleted bool) (err error) {
+	_, err = s.
+		Where("list_id = ? AND task_id = ?", listID, taskID).
+		Delete(&TaskChange{})
+	if err != nil {
+		return
+	}
+
+	_, err = s.Insert(&TaskChange{
+		ListID:  listID,
+		TaskID:  taskID,
+		TaskUID: uid,
+		Deleted: deleted,
+	})
+	return
+}
+
+// recordTaskChange records a change of a task which does not change its list, like updated labels or relations
+func recordTaskChange(s *xorm.Session, taskID int64) (err error) {
+	t := &Task{}
+	exists, err := s.ID(taskID).Cols("id", "list_id", "uid").Get(t)
+	if err != nil || !exists {
+		return
+	}
+
+	return addTaskChange(s, t.ListID, t.ID, t.UID, false)
+}
+
+// GetLatestTaskChangeID returns the id of the latest change of a list. Every change increases it, it is therefore
+// a good sync token for the list.
+func GetLatestTaskChangeID(s *xorm.Session, listID int64) (id int64, err error) {
+	latest := &TaskChange{}
+	_, err = s.
+		Where("list_id = ?", listID).
+		OrderBy("id desc").
+		Get(latest)
+	return latest.ID, err
+}
+
+// GetTaskChangesSince returns all changes of tasks in a list which happened after the change with the provided id.
+func GetTaskChangesSince(s *xorm.Session, listID int64, sinceID int64) (changes []*TaskChange, err error) {
+	changes = []*TaskChange{}
+	err = s.
+		Where("list_id = ? AND id > ?", listID, sinceID).
+		OrderBy("id asc").
+		Find(&changes)
+	return
+}
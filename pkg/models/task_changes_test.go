@@ -0,0 +1,188 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLatestTaskChangeID(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	id, err := GetLatestTaskChangeID(s, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), id)
+
+	id, err = GetLatestTaskChangeID(s, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), id)
+}
+
+func TestGetTaskChangesSince(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	changes, err := GetTaskChangesSince(s, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, int64(2), changes[0].ID)
+	assert.True(t, changes[0].Deleted)
+	assert.Equal(t, "uid-deleted-task", changes[0].TaskUID)
+}
+
+func TestTaskChanges(t *testing.T) {
+	u := &user.User{ID: 1}
+
+	t.Run("create", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		task := &Task{
+			Title:  "Lorem",
+			ListID: 1,
+		}
+		err := task.Create(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "task_changes", map[string]interface{}{
+			"list_id":  1,
+			"task_id":  task.ID,
+			"task_uid": task.UID,
+			"deleted":  false,
+		}, false)
+	})
+	t.Run("update replaces the old change", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		task := &Task{
+			ID:     1,
+			Title:  "Lorem",
+			ListID: 1,
+		}
+		err := task.Update(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "task_changes", map[string]interface{}{
+			"id": 1,
+		})
+		db.AssertExists(t, "task_changes", map[string]interface{}{
+			"list_id": 1,
+			"task_id": 1,
+			"deleted": false,
+		}, false)
+	})
+	t.Run("bulk update", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		bt := &BulkTask{
+			IDs:  []int64{10, 11},
+			Task: Task{Title: "bulkupdated"},
+		}
+		allowed, err := bt.CanUpdate(s, u)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		err = bt.Update(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		changes, err := GetTaskChangesSince(s, 1, 2)
+		assert.NoError(t, err)
+		assert.Len(t, changes, 2)
+		assert.Equal(t, int64(10), changes[0].TaskID)
+		assert.Equal(t, int64(11), changes[1].TaskID)
+		assert.False(t, changes[0].Deleted)
+	})
+	t.Run("moving to another list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		task := &Task{
+			ID:     1,
+			Title:  "Lorem",
+			ListID: 2,
+		}
+		err := task.Update(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "task_changes", map[string]interface{}{
+			"list_id": 1,
+			"task_id": 1,
+			"deleted": true,
+		}, false)
+		db.AssertExists(t, "task_changes", map[string]interface{}{
+			"list_id": 2,
+			"task_id": 1,
+			"deleted": false,
+		}, false)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		task := &Task{ID: 1}
+		err := task.Delete(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "task_changes", map[string]interface{}{
+			"list_id": 1,
+			"task_id": 1,
+			"deleted": true,
+		}, false)
+	})
+	t.Run("adding a label", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		lt := &LabelTask{TaskID: 1, LabelID: 1}
+		err := lt.Create(s, u)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "task_changes", map[string]interface{}{
+			"id": 1,
+		})
+		db.AssertExists(t, "task_changes", map[string]interface{}{
+			"list_id": 1,
+			"task_id": 1,
+		}, false)
+	})
+}
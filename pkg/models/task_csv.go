@@ -0,0 +1,609 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web"
+	"xorm.io/xorm"
+)
+
+// Columns of the csv export which are not task properties
+const (
+	taskCSVColumnLabels    string = "labels"
+	taskCSVColumnAssignees string = "assignees"
+	taskCSVColumnBucket    string = "bucket"
+	taskCSVColumnCreatedBy string = "created_by"
+)
+
+// All columns of a csv export in their default order
+var taskCSVExportColumns = []string{
+	taskPropertyID,
+	taskPropertyTitle,
+	taskPropertyDescription,
+	taskPropertyDone,
+	taskPropertyDoneAt,
+	taskPropertyDueDate,
+	taskPropertyStartDate,
+	taskPropertyEndDate,
+	taskPropertyPriority,
+	taskPropertyPercentDone,
+	taskPropertyRepeatAfter,
+	taskPropertyHexColor,
+	taskCSVColumnLabels,
+	taskCSVColumnAssignees,
+	taskCSVColumnBucket,
+	taskCSVColumnCreatedBy,
+	taskPropertyCreated,
+	taskPropertyUpdated,
+}
+
+// The columns of a csv file which can be imported as task fields
+var taskCSVImportFields = map[string]bool{
+	taskPropertyTitle:       true,
+	taskPropertyDescription: true,
+	taskPropertyDone:        true,
+	taskPropertyDoneAt:      true,
+	taskPropertyDueDate:     true,
+	taskPropertyStartDate:   true,
+	taskPropertyEndDate:     true,
+	taskPropertyPriority:    true,
+	taskPropertyPercentDone: true,
+	taskPropertyRepeatAfter: true,
+	taskPropertyHexColor:    true,
+	taskCSVColumnLabels:     true,
+	taskCSVColumnAssignees:  true,
+	taskCSVColumnBucket:     true,
+}
+
+// Other common names of columns, used to guess the mapping of a csv file
+var taskCSVColumnAliases = map[string]string{
+	"name":        taskPropertyTitle,
+	"task":        taskPropertyTitle,
+	"summary":     taskPropertyTitle,
+	"notes":       taskPropertyDescription,
+	"completed":   taskPropertyDone,
+	"due":         taskPropertyDueDate,
+	"start":       taskPropertyStartDate,
+	"end":         taskPropertyEndDate,
+	"color":       taskPropertyHexColor,
+	"progress":    taskPropertyPercentDone,
+	"label":       taskCSVColumnLabels,
+	"tags":        taskCSVColumnLabels,
+	"assignee":    taskCSVColumnAssignees,
+	"assigned_to": taskCSVColumnAssignees,
+	"column":      taskCSVColumnBucket,
+	"section":     taskCSVColumnBucket,
+	"stage":       taskCSVColumnBucket,
+}
+
+const (
+	// Spreadsheet applications like Excel add a byte order mark at the beginning of csv files
+	csvByteOrderMark = "\ufeff"
+	// The number of rows shown in the preview of a csv import
+	taskCSVPreviewRows = 5
+	// The default separator of multiple labels or assignees in one column
+	taskCSVDefaultSeparator = ","
+)
+
+// TaskCSVImportPreview is the preview of a csv file before importing it
+type TaskCSVImportPreview struct {
+	// The names of all columns in the csv file, taken from its first row.
+	Columns []string `json:"columns"`
+	// The first rows of the csv file.
+	Rows [][]string `json:"rows"`
+	// A suggested mapping of csv columns to task fields, based on the name of the columns.
+	Mapping map[string]string `json:"mapping"`
+}
+
+// TaskCSVImportOptions holds everything needed to import a csv file
+type TaskCSVImportOptions struct {
+	// Maps the names of the csv columns to the task fields they should be imported as. Columns which are not mapped are ignored.
+	Mapping map[string]string `json:"mapping"`
+	// The format of all dates in the csv file as go time layout, for example `2006-01-02` or `02.01.2006 15:04`. Defaults to RFC 3339.
+	DateFormat string `json:"date_format"`
+	// The separator of multiple labels or assignees in one column. Defaults to `,`.
+	Separator string `json:"separator"`
+}
+
+func normalizeCSVColumnName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, csvByteOrderMark)))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// Spreadsheet applications run cells starting with one of these as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+func csvValueNeedsEscaping(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return true
+	}
+	// Values which already look escaped are escaped again so they are imported unchanged
+	return value[0] == '\'' && csvValueNeedsEscaping(value[1:])
+}
+
+// escapeCSVValue prefixes values which would be run as formula by spreadsheet applications with a '
+// so that they are shown as text instead.
+func escapeCSVValue(value string) string {
+	if csvValueNeedsEscaping(value) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeCSVValue removes the ' escapeCSVValue added so that an exported file can be imported again unchanged.
+func unescapeCSVValue(value string) string {
+	if value != "" && value[0] == '\'' && csvValueNeedsEscaping(value[1:]) {
+		return value[1:]
+	}
+	return value
+}
+
+func formatCSVDate(t time.Time, dateFormat string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(config.GetTimeZone()).Format(dateFormat)
+}
+
+// ExportTasksToCSV writes all tasks of a task collection to w as csv. It uses the same filters and sorting as
+// the task collection. The first row contains the names of the columns.
+// If no columns are provided, all columns are exported.
+// Texts which spreadsheet applications would run as formula are prefixed with a '.
+func ExportTasksToCSV(s *xorm.Session, tf *TaskCollection, a web.Auth, search string, columns []string, dateFormat string, w io.Writer) (err error) {
+	if len(columns) == 0 {
+		columns = taskCSVExportColumns
+	}
+	for _, c := range columns {
+		valid := false
+		for _, col := range taskCSVExportColumns {
+			if c == col {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrInvalidTaskField{TaskField: c}
+		}
+	}
+
+	if dateFormat == "" {
+		dateFormat = time.RFC3339
+	}
+
+	result, _, _, err := tf.ReadAll(s, a, search, -1, 0)
+	if err != nil {
+		return err
+	}
+	tasks := result.([]*Task)
+
+	bucketIDs := make([]int64, 0, len(tasks))
+	for _, t := range tasks {
+		bucketIDs = append(bucketIDs, t.BucketID)
+	}
+	buckets := make(map[int64]*Bucket, len(bucketIDs))
+	if len(bucketIDs) > 0 {
+		err = s.In("id", bucketIDs).Find(&buckets)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(columns)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		record := make([]string, 0, len(columns))
+		for _, c := range columns {
+			var value string
+			switch c {
+			case taskPropertyID:
+				value = strconv.FormatInt(t.ID, 10)
+			case taskPropertyTitle:
+				value = escapeCSVValue(t.Title)
+			case taskPropertyDescription:
+				value = escapeCSVValue(t.Description)
+			case taskPropertyDone:
+				value = strconv.FormatBool(t.Done)
+			case taskPropertyDoneAt:
+				value = formatCSVDate(t.DoneAt, dateFormat)
+			case taskPropertyDueDate:
+				value = formatCSVDate(t.DueDate, dateFormat)
+			case taskPropertyStartDate:
+				value = formatCSVDate(t.StartDate, dateFormat)
+			case taskPropertyEndDate:
+				value = formatCSVDate(t.EndDate, dateFormat)
+			case taskPropertyPriority:
+				value = strconv.FormatInt(t.Priority, 10)
+			case taskPropertyPercentDone:
+				value = strconv.FormatFloat(t.PercentDone, 'f', -1, 64)
+			case taskPropertyRepeatAfter:
+				value = strconv.FormatInt(t.RepeatAfter, 10)
+			case taskPropertyHexColor:
+				value = escapeCSVValue(t.HexColor)
+			case taskCSVColumnLabels:
+				titles := make([]string, 0, len(t.Labels))
+				for _, l := range t.Labels {
+					titles = append(titles, l.Title)
+				}
+				value = escapeCSVValue(strings.Join(titles, taskCSVDefaultSeparator))
+			case taskCSVColumnAssignees:
+				usernames := make([]string, 0, len(t.Assignees))
+				for _, u := range t.Assignees {
+					usernames = append(usernames, u.Username)
+				}
+				value = escapeCSVValue(strings.Join(usernames, taskCSVDefaultSeparator))
+			case taskCSVColumnBucket:
+				if b, exists := buckets[t.BucketID]; exists {
+					value = escapeCSVValue(b.Title)
+				}
+			case taskCSVColumnCreatedBy:
+				if t.CreatedBy != nil {
+					value = escapeCSVValue(t.CreatedBy.Username)
+				}
+			case taskPropertyCreated:
+				value = formatCSVDate(t.Created, dateFormat)
+			case taskPropertyUpdated:
+				value = formatCSVDate(t.Updated, dateFormat)
+			}
+			record = append(record, value)
+		}
+
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func newTaskCSVReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// PreviewTaskCSVImport reads the first rows of a csv file and guesses which columns should be imported as which
+// task fields. Nothing is created.
+func PreviewTaskCSVImport(r io.Reader) (preview *TaskCSVImportPreview, err error) {
+	reader := newTaskCSVReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidCSVValue{Row: 1, Value: err.Error()}
+	}
+
+	preview = &TaskCSVImportPreview{
+		Columns: make([]string, 0, len(header)),
+		Rows:    [][]string{},
+		Mapping: make(map[string]string),
+	}
+
+	for _, c := range header {
+		c = strings.TrimPrefix(c, csvByteOrderMark)
+		preview.Columns = append(preview.Columns, c)
+
+		field := normalizeCSVColumnName(c)
+		if alias, exists := taskCSVColumnAliases[field]; exists {
+			field = alias
+		}
+		if taskCSVImportFields[field] {
+			preview.Mapping[c] = field
+		}
+	}
+
+	for i := 0; i < taskCSVPreviewRows; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidCSVValue{Row: i + 2, Value: err.Error()}
+		}
+		preview.Rows = append(preview.Rows, record)
+	}
+
+	return
+}
+
+// taskCSVImporter keeps track of all labels and buckets used during an import so they are only created once
+type taskCSVImporter struct {
+	s       *xorm.Session
+	a       web.Auth
+	listID  int64
+	opts    *TaskCSVImportOptions
+	labels  map[string]*Label
+	buckets map[string]*Bucket
+}
+
+func (i *taskCSVImporter) getLabels(titles []string) (labels []*Label, err error) {
+	if i.labels == nil {
+		i.labels = make(map[string]*Label)
+		existing, _, _, err := (&Label{}).ReadAll(i.s, i.a, "", -1, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range existing.([]*labelWithTaskID) {
+			label := l.Label
+			i.labels[label.Title] = &label
+		}
+	}
+
+	for _, title := range titles {
+		label, exists := i.labels[title]
+		if !exists {
+			label = &Label{Title: title}
+			err = label.Create(i.s, i.a)
+			if err != nil {
+				return nil, err
+			}
+			i.labels[title] = label
+		}
+		labels = append(labels, label)
+	}
+
+	return
+}
+
+func (i *taskCSVImporter) getBucket(title string) (bucket *Bucket, err error) {
+	if i.buckets == nil {
+		i.buckets = make(map[string]*Bucket)
+		existing := []*Bucket{}
+		err = i.s.Where("list_id = ?", i.listID).Find(&existing)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range existing {
+			i.buckets[b.Title] = b
+		}
+	}
+
+	bucket, exists := i.buckets[title]
+	if !exists {
+		bucket = &Bucket{
+			Title:  title,
+			ListID: i.listID,
+		}
+		err = bucket.Create(i.s, i.a)
+		if err != nil {
+			return nil, err
+		}
+		i.buckets[title] = bucket
+	}
+
+	return
+}
+
+func (i *taskCSVImporter) splitValues(value string) (values []string) {
+	for _, v := range strings.Split(value, i.opts.Separator) {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// setField parses the value of a csv column and sets it as the task field it is mapped to.
+func (i *taskCSVImporter) setField(t *Task, field, value string) (labels []*Label, err error) {
+	switch field {
+	case taskPropertyTitle:
+		t.Title = value
+	case taskPropertyDescription:
+		t.Description = value
+	case taskPropertyDone:
+		switch strings.ToLower(value) {
+		case "", "0", "false", "no", "n":
+			t.Done = false
+		case "1", "true", "yes", "y", "x":
+			t.Done = true
+		default:
+			return nil, ErrInvalidCSVValue{}
+		}
+	case taskPropertyDoneAt, taskPropertyDueDate, taskPropertyStartDate, taskPropertyEndDate:
+		date, err := time.ParseInLocation(i.opts.DateFormat, value, config.GetTimeZone())
+		if err != nil {
+			return nil, ErrInvalidCSVValue{}
+		}
+		switch field {
+		case taskPropertyDoneAt:
+			t.DoneAt = date
+		case taskPropertyDueDate:
+			t.DueDate = date
+		case taskPropertyStartDate:
+			t.StartDate = date
+		case taskPropertyEndDate:
+			t.EndDate = date
+		}
+	case taskPropertyPriority:
+		t.Priority, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCSVValue{}
+		}
+	case taskPropertyPercentDone:
+		// Spreadsheets usually show percentages as "50%", the api uses values between 0 and 1
+		percent := strings.HasSuffix(value, "%")
+		t.PercentDone, err = strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+		if err != nil {
+			return nil, ErrInvalidCSVValue{}
+		}
+		if percent {
+			t.PercentDone /= 100
+		}
+	case taskPropertyRepeatAfter:
+		repeatAfter, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCSVValue{}
+		}
+		t.RepeatAfter = repeatAfter
+	case taskPropertyHexColor:
+		t.HexColor = strings.TrimPrefix(value, "#")
+	case taskCSVColumnLabels:
+		return i.getLabels(i.splitValues(value))
+	case taskCSVColumnAssignees:
+		for _, username := range i.splitValues(value) {
+			u, err := user.GetUserByUsername(i.s, username)
+			if err != nil {
+				if user.IsErrUserDoesNotExist(err) {
+					return nil, ErrInvalidCSVValue{}
+				}
+				return nil, err
+			}
+			t.Assignees = append(t.Assignees, u)
+		}
+	case taskCSVColumnBucket:
+		bucket, err := i.getBucket(value)
+		if err != nil {
+			return nil, err
+		}
+		t.BucketID = bucket.ID
+	}
+
+	return
+}
+
+// ImportTasksFromCSV creates a task in a list for every row in a csv file. The first row of the file needs to
+// contain the names of the columns. Labels and buckets are matched by their title and created if they don't exist yet,
+// assignees are matched by their username.
+func ImportTasksFromCSV(s *xorm.Session, listID int64, a web.Auth, r io.Reader, opts *TaskCSVImportOptions) (tasks []*Task, err error) {
+	if opts.DateFormat == "" {
+		opts.DateFormat = time.RFC3339
+	}
+	if opts.Separator == "" {
+		opts.Separator = taskCSVDefaultSeparator
+	}
+
+	hasTitle := false
+	for _, field := range opts.Mapping {
+		if field == "" {
+			continue
+		}
+		if !taskCSVImportFields[field] {
+			return nil, ErrInvalidTaskField{TaskField: field}
+		}
+		if field == taskPropertyTitle {
+			hasTitle = true
+		}
+	}
+	if !hasTitle {
+		return nil, ErrCSVImportTitleMissing{}
+	}
+
+	reader := newTaskCSVReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, ErrInvalidCSVValue{Row: 1, Value: err.Error()}
+	}
+	for i := range header {
+		header[i] = strings.TrimPrefix(header[i], csvByteOrderMark)
+	}
+
+	columns := make(map[string]bool, len(header))
+	for _, c := range header {
+		columns[c] = true
+	}
+	for c := range opts.Mapping {
+		if !columns[c] {
+			return nil, ErrInvalidCSVColumn{Column: c}
+		}
+	}
+
+	importer := &taskCSVImporter{
+		s:      s,
+		a:      a,
+		listID: listID,
+		opts:   opts,
+	}
+
+	tasks = []*Task{}
+	// The first row is the header, the row numbers are the same as in a spreadsheet application
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidCSVValue{Row: row, Value: err.Error()}
+		}
+
+		t := &Task{ListID: listID}
+		var labels []*Label
+		empty := true
+		for i, value := range record {
+			if i >= len(header) {
+				break
+			}
+			field := opts.Mapping[header[i]]
+			value = unescapeCSVValue(strings.TrimSpace(value))
+			if field == "" || value == "" {
+				continue
+			}
+			empty = false
+
+			l, err := importer.setField(t, field, value)
+			if IsErrInvalidCSVValue(err) {
+				return nil, ErrInvalidCSVValue{Row: row, Column: header[i], Value: value}
+			}
+			if err != nil {
+				return nil, err
+			}
+			if l != nil {
+				labels = l
+			}
+		}
+
+		// Skip empty lines
+		if empty {
+			continue
+		}
+
+		err = createTask(s, t, a, true)
+		if err != nil {
+			if IsErrTaskCannotBeEmpty(err) {
+				return nil, ErrInvalidCSVValue{Row: row, Column: taskPropertyTitle}
+			}
+			return nil, err
+		}
+
+		if len(labels) > 0 {
+			err = t.updateTaskLabels(s, a, labels)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,228 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTasksToCSV(t *testing.T) {
+	u := &user.User{ID: 1}
+
+	t.Run("all columns", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		buf := &bytes.Buffer{}
+		err := ExportTasksToCSV(s, &TaskCollection{ListID: 1}, u, "", nil, "", buf)
+		assert.NoError(t, err)
+
+		records, err := csv.NewReader(buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, taskCSVExportColumns, records[0])
+		assert.Equal(t, "1", records[1][0])
+		assert.Equal(t, "task #1", records[1][1])
+		assert.Equal(t, "testbucket1", records[1][14])
+		assert.Equal(t, "user1", records[1][15])
+	})
+	t.Run("columns, filter and sorting", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tf := &TaskCollection{
+			ListID:      1,
+			SortBy:      []string{"id"},
+			OrderBy:     []string{"desc"},
+			FilterBy:    []string{"done"},
+			FilterValue: []string{"true"},
+		}
+		buf := &bytes.Buffer{}
+		err := ExportTasksToCSV(s, tf, u, "", []string{"title", "done"}, "", buf)
+		assert.NoError(t, err)
+
+		records, err := csv.NewReader(buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"title", "done"}, records[0])
+		assert.Greater(t, len(records), 1)
+		for _, r := range records[1:] {
+			assert.Equal(t, "true", r[1])
+		}
+		assert.Equal(t, "task #2 done", records[len(records)-1][0])
+	})
+	t.Run("invalid column", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := ExportTasksToCSV(s, &TaskCollection{ListID: 1}, u, "", []string{"lorem"}, "", &bytes.Buffer{})
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidTaskField(err))
+	})
+	t.Run("no access", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := ExportTasksToCSV(s, &TaskCollection{ListID: 2}, &user.User{ID: 2}, "", nil, "", &bytes.Buffer{})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDoesNotHaveAccessToList(err))
+	})
+}
+
+func TestEscapeCSVValue(t *testing.T) {
+	values := map[string]string{
+		"Lorem Ipsum": "Lorem Ipsum",
+		"":            "",
+		"=1+1":        "'=1+1",
+		"+1":          "'+1",
+		"-1":          "'-1",
+		"@SUM(A1)":    "'@SUM(A1)",
+		"\t=1":        "'\t=1",
+		"\r=1":        "'\r=1",
+		"'=1":         "''=1",
+		"'quoted'":    "'quoted'",
+	}
+	for value, escaped := range values {
+		assert.Equal(t, escaped, escapeCSVValue(value))
+		assert.Equal(t, value, unescapeCSVValue(escaped))
+	}
+}
+
+const testTaskCSV = `Name,Notes,Due,Tags,Assigned to,Stage,Ignored
+Imported task,Some notes,10.04.2021,"Label #1, New label",user1,testbucket3,lorem
+Second task,,,,,New bucket,
+
+`
+
+func TestPreviewTaskCSVImport(t *testing.T) {
+	preview, err := PreviewTaskCSVImport(strings.NewReader(testTaskCSV))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Name", "Notes", "Due", "Tags", "Assigned to", "Stage", "Ignored"}, preview.Columns)
+	assert.Len(t, preview.Rows, 2)
+	assert.Equal(t, map[string]string{
+		"Name":        "title",
+		"Notes":       "description",
+		"Due":         "due_date",
+		"Tags":        "labels",
+		"Assigned to": "assignees",
+		"Stage":       "bucket",
+	}, preview.Mapping)
+}
+
+func TestImportTasksFromCSV(t *testing.T) {
+	u := &user.User{ID: 1}
+	mapping := map[string]string{
+		"Name":        "title",
+		"Notes":       "description",
+		"Due":         "due_date",
+		"Tags":        "labels",
+		"Assigned to": "assignees",
+		"Stage":       "bucket",
+	}
+
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tasks, err := ImportTasksFromCSV(s, 1, u, strings.NewReader(testTaskCSV), &TaskCSVImportOptions{
+			Mapping:    mapping,
+			DateFormat: "02.01.2006",
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 2)
+
+		assert.Equal(t, "Imported task", tasks[0].Title)
+		assert.Equal(t, "Some notes", tasks[0].Description)
+		assert.Equal(t, int64(3), tasks[0].BucketID)
+		assert.Equal(t, 2021, tasks[0].DueDate.Year())
+		assert.Equal(t, time.April, tasks[0].DueDate.Month())
+		assert.Len(t, tasks[0].Assignees, 1)
+		assert.Equal(t, int64(1), tasks[0].Assignees[0].ID)
+		db.AssertExists(t, "label_task", map[string]interface{}{
+			"task_id":  tasks[0].ID,
+			"label_id": 1,
+		}, false)
+		db.AssertExists(t, "labels", map[string]interface{}{
+			"title":         "New label",
+			"created_by_id": 1,
+		}, false)
+		db.AssertExists(t, "buckets", map[string]interface{}{
+			"id":      tasks[1].BucketID,
+			"title":   "New bucket",
+			"list_id": 1,
+		}, false)
+	})
+	t.Run("no title mapped", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := ImportTasksFromCSV(s, 1, u, strings.NewReader(testTaskCSV), &TaskCSVImportOptions{
+			Mapping: map[string]string{"Notes": "description"},
+		})
+		assert.Error(t, err)
+		assert.True(t, IsErrCSVImportTitleMissing(err))
+	})
+	t.Run("mapped column does not exist", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := ImportTasksFromCSV(s, 1, u, strings.NewReader(testTaskCSV), &TaskCSVImportOptions{
+			Mapping: map[string]string{"Title": "title"},
+		})
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidCSVColumn(err))
+	})
+	t.Run("invalid date", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := ImportTasksFromCSV(s, 1, u, strings.NewReader(testTaskCSV), &TaskCSVImportOptions{
+			Mapping: mapping,
+		})
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidCSVValue(err))
+		assert.Equal(t, 2, err.(ErrInvalidCSVValue).Row)
+		assert.Equal(t, "Due", err.(ErrInvalidCSVValue).Column)
+	})
+	t.Run("unknown assignee", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := ImportTasksFromCSV(s, 1, u, strings.NewReader("Title,Assignees\nTask,doesnotexist\n"), &TaskCSVImportOptions{
+			Mapping: map[string]string{"Title": "title", "Assignees": "assignees"},
+		})
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidCSVValue(err))
+	})
+}
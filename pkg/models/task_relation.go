@@ -185,7 +185,15 @@ func (rel *TaskRelation) Create(s *xorm.Session, a web.Auth) error {
 		rel,
 		otherRelation,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Both tasks now have a new relation
+	if err := recordTaskChange(s, rel.TaskID); err != nil {
+		return err
+	}
+	return recordTaskChange(s, rel.OtherTaskID)
 }
 
 // Delete removes a task relation
@@ -220,5 +228,12 @@ func (rel *TaskRelation) Delete(s *xorm.Session, a web.Auth) error {
 	}
 
 	_, err = s.Delete(rel)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := recordTaskChange(s, rel.TaskID); err != nil {
+		return err
+	}
+	return recordTaskChange(s, rel.OtherTaskID)
 }
@@ -835,6 +835,10 @@ func createTask(s *xorm.Session, t *Task, a web.Auth, updateAssignees bool) (err
 		return err
 	}
 
+	if err := addTaskChange(s, t.ListID, t.ID, t.UID, false); err != nil {
+		return err
+	}
+
 	// Update the assignees
 	if updateAssignees {
 		if err := t.updateTaskAssignees(s, t.Assignees, a); err != nil {
@@ -982,6 +986,9 @@ func (t *Task) Update(s *xorm.Session, a web.Auth) (err error) {
 	// We also set this here to prevent it being overwritten later on.
 	// t.Labels = ot.Labels
 
+	// Saved to record the task as deleted in its old list if it was moved
+	oldListID := ot.ListID
+
 	// For whatever reason, xorm dont detect if done is updated, so we need to update this every time by hand
 	// Which is why we merge the actual task struct with the one we got from the db
 	// The user struct overrides values in the actual one.
@@ -1058,6 +1065,15 @@ func (t *Task) Update(s *xorm.Session, a web.Auth) (err error) {
 	}
 	t.Updated = nt.Updated
 
+	if oldListID != t.ListID {
+		if err := addTaskChange(s, oldListID, t.ID, t.UID, true); err != nil {
+			return err
+		}
+	}
+	if err := addTaskChange(s, t.ListID, t.ID, t.UID, false); err != nil {
+		return err
+	}
+
 	doer, _ := user.GetFromAuth(a)
 	err = events.Dispatch(&TaskUpdatedEvent{
 		Task: t,
@@ -1207,15 +1223,30 @@ func (t *Task) updateReminders(s *xorm.Session, reminders []time.Time) (err erro
 // @Router /tasks/{id} [delete]
 func (t *Task) Delete(s *xorm.Session, a web.Auth) (err error) {
 
+	// We need the list and uid of the task to record its deletion
+	deleted := &Task{}
+	if _, err = s.ID(t.ID).Cols("id", "list_id", "uid").Get(deleted); err != nil {
+		return err
+	}
+
 	if _, err = s.ID(t.ID).Delete(Task{}); err != nil {
 		return err
 	}
 
+	if err = addTaskChange(s, deleted.ListID, deleted.ID, deleted.UID, true); err != nil {
+		return err
+	}
+
 	// Delete assignees
 	if _, err = s.Where("task_id = ?", t.ID).Delete(TaskAssginee{}); err != nil {
 		return err
 	}
 
+	// Delete all caldav properties we kept around
+	if _, err = s.Where("task_id = ?", t.ID).Delete(TaskCaldavProperties{}); err != nil {
+		return err
+	}
+
 	doer, _ := user.GetFromAuth(a)
 	err = events.Dispatch(&TaskDeletedEvent{
 		Task: t,
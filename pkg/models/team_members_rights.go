@@ -23,16 +23,29 @@ import (
 
 // CanCreate checks if the user can add a new tem member
 func (tm *TeamMember) CanCreate(s *xorm.Session, a web.Auth) (bool, error) {
-	return tm.IsAdmin(s, a)
+	return tm.canModify(s, a)
 }
 
 // CanDelete checks if the user can delete a new team member
 func (tm *TeamMember) CanDelete(s *xorm.Session, a web.Auth) (bool, error) {
-	return tm.IsAdmin(s, a)
+	return tm.canModify(s, a)
 }
 
 // CanUpdate checks if the user can modify a team member's right
 func (tm *TeamMember) CanUpdate(s *xorm.Session, a web.Auth) (bool, error) {
+	return tm.canModify(s, a)
+}
+
+// canModify checks if the user is team admin and the members of the team are not managed externally
+func (tm *TeamMember) canModify(s *xorm.Session, a web.Auth) (bool, error) {
+	team, err := GetTeamByID(s, tm.TeamID)
+	if err != nil {
+		return false, err
+	}
+	if team.IsExternallyManaged() {
+		return false, ErrCannotModifyExternalTeamMembers{TeamID: team.ID}
+	}
+
 	return tm.IsAdmin(s, a)
 }
 
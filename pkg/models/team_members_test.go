@@ -163,3 +163,38 @@ func TestTeamMember_Update(t *testing.T) {
 		}, false)
 	})
 }
+
+func TestTeamMember_CanModify(t *testing.T) {
+	t.Run("team admin", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tm := &TeamMember{
+			TeamID:   1,
+			Username: "user3",
+		}
+		can, err := tm.CanCreate(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+		assert.True(t, can)
+	})
+	t.Run("externally managed team", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tm := &TeamMember{
+			TeamID:   14,
+			Username: "user3",
+		}
+		can, err := tm.CanCreate(s, &user.User{ID: 14})
+		assert.Error(t, err)
+		assert.True(t, IsErrCannotModifyExternalTeamMembers(err))
+		assert.False(t, can)
+
+		can, err = tm.CanDelete(s, &user.User{ID: 14})
+		assert.Error(t, err)
+		assert.True(t, IsErrCannotModifyExternalTeamMembers(err))
+		assert.False(t, can)
+	})
+}
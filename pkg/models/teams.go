@@ -38,6 +38,12 @@ type Team struct {
 	Description string `xorm:"longtext null" json:"description"`
 	CreatedByID int64  `xorm:"bigint not null INDEX" json:"-"`
 
+	// The id of the group this team is synced from in an external identity provider.
+	// If set, the team's members are managed by that provider and cannot be changed in Vikunja.
+	ExternalID string `xorm:"varchar(250) null INDEX" json:"external_id"`
+	// The issuer of the OpenID Connect provider this team was synced from.
+	Issuer string `xorm:"text null" json:"-"`
+
 	// The user who created this team.
 	CreatedBy *user.User `xorm:"-" json:"created_by"`
 	// An array of all members in this team.
@@ -57,6 +63,11 @@ func (Team) TableName() string {
 	return "teams"
 }
 
+// IsExternallyManaged returns true if the team is synced from an external identity provider.
+func (t *Team) IsExternallyManaged() bool {
+	return t.ExternalID != ""
+}
+
 // TeamMember defines the relationship between a user and a team
 type TeamMember struct {
 	// The unique, numeric id of this team member relation.
@@ -265,6 +276,10 @@ func (t *Team) Create(s *xorm.Session, a web.Auth) (err error) {
 	t.CreatedByID = doer.ID
 	t.CreatedBy = doer
 
+	// Teams created through the api are never managed externally
+	t.ExternalID = ""
+	t.Issuer = ""
+
 	_, err = s.Insert(t)
 	if err != nil {
 		return
@@ -350,6 +365,10 @@ func (t *Team) Update(s *xorm.Session, a web.Auth) (err error) {
 		return
 	}
 
+	// The external id and issuer can only be set through the sync from an identity provider
+	t.ExternalID = ""
+	t.Issuer = ""
+
 	_, err = s.ID(t.ID).Update(t)
 	if err != nil {
 		return
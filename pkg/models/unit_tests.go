@@ -21,6 +21,7 @@ import (
 	"code.vikunja.io/api/pkg/db"
 	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/mail"
+	"code.vikunja.io/api/pkg/notifications"
 )
 
 // SetupTests takes care of seting up the db, fixtures etc.
@@ -37,6 +38,12 @@ func SetupTests() {
 		log.Fatal(err)
 	}
 
+	// Deleting users also deletes their notifications
+	err = x.Sync2(notifications.GetTables()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	err = db.InitTestFixtures(
 		"files",
 		"label_task",
@@ -60,6 +67,11 @@ func SetupTests() {
 		"buckets",
 		"saved_filters",
 		"subscriptions",
+		"oauth2_clients",
+		"oauth2_grants",
+		"user_deletion_transfers",
+		"task_changes",
+		"ical_feeds",
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,366 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/cron"
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/notifications"
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/xorm"
+)
+
+// RegisterUserDeletionCron deletes all users whose deletion grace period is over.
+func RegisterUserDeletionCron() {
+	if !config.ServiceEnableUserDeletion.GetBool() {
+		return
+	}
+
+	err := cron.Schedule("0 * * * *", deleteUsersScheduledForDeletion)
+	if err != nil {
+		log.Fatalf("Could not register user deletion cron: %s", err)
+	}
+}
+
+func deleteUsersScheduledForDeletion() {
+	s := db.NewSession()
+	users := []*user.User{}
+	err := s.
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", time.Now()).
+		Find(&users)
+	s.Close()
+	if err != nil {
+		log.Errorf("[User Deletion Cron] Could not get users scheduled for deletion: %s", err)
+		return
+	}
+
+	if len(users) == 0 {
+		return
+	}
+
+	log.Debugf("[User Deletion Cron] Deleting %d users", len(users))
+
+	for _, u := range users {
+		// Every user is deleted in its own transaction so one failing deletion does not prevent the others.
+		s := db.NewSession()
+		err = DeleteUser(s, u)
+		if err != nil {
+			_ = s.Rollback()
+			s.Close()
+			log.Errorf("[User Deletion Cron] Could not delete user %d: %s", u.ID, err)
+			continue
+		}
+		err = s.Commit()
+		s.Close()
+		if err != nil {
+			log.Errorf("[User Deletion Cron] Could not delete user %d: %s", u.ID, err)
+			continue
+		}
+		log.Debugf("[User Deletion Cron] Deleted user %d", u.ID)
+
+		NotifyUserDeleted(u)
+	}
+}
+
+// DeleteUser deletes a user and everything they own. Namespaces and lists the user chose to transfer are given to
+// their new owners. Comments, tasks and everything else the user created in namespaces and lists which are not
+// deleted is kept but not associated with the user anymore.
+// The user is not notified, use NotifyUserDeleted for that once the session is committed.
+func DeleteUser(s *xorm.Session, u *user.User) (err error) {
+	transfers, err := getUserDeletionTransfers(s, u.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range transfers {
+		if t.NamespaceID != 0 {
+			_, err = TransferNamespaceOwnership(s, t.NamespaceID, t.NewOwnerID)
+		} else {
+			_, err = TransferListOwnership(s, t.ListID, t.NewOwnerID, 0)
+		}
+		if err != nil && !IsErrNamespaceDoesNotExist(err) && !IsErrListDoesNotExist(err) && !user.IsErrUserDoesNotExist(err) {
+			return err
+		}
+	}
+
+	namespaces := []*Namespace{}
+	err = s.Where("owner_id = ?", u.ID).Find(&namespaces)
+	if err != nil {
+		return err
+	}
+	for _, n := range namespaces {
+		err = moveForeignListsOutOfNamespace(s, n)
+		if err != nil {
+			return err
+		}
+		err = n.Delete(s, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	lists := []*List{}
+	err = s.Where("owner_id = ?", u.ID).Find(&lists)
+	if err != nil {
+		return err
+	}
+	for _, l := range lists {
+		err = l.Delete(s, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = anonymizeUserContent(s, u)
+	if err != nil {
+		return err
+	}
+
+	err = deleteUserRelations(s, u)
+	if err != nil {
+		return err
+	}
+
+	err = deleteUserDataExport(s, u)
+	if err != nil {
+		return err
+	}
+
+	if u.AvatarFileID != 0 {
+		err = (&files.File{ID: u.AvatarFileID}).Delete()
+		if err != nil && !files.IsErrFileDoesNotExist(err) {
+			return err
+		}
+	}
+
+	return user.DeleteUser(s, u)
+}
+
+// NotifyUserDeleted tells a user their account was deleted. Call it only after the session used to delete the user
+// was committed, to make sure nobody gets that mail while their account still exists.
+// Because the account is already gone at that point, failing to send the mail is only logged.
+func NotifyUserDeleted(u *user.User) {
+	if !config.MailerEnabled.GetBool() || u.Email == "" {
+		return
+	}
+
+	err := notifications.Notify(u, &user.AccountDeletedNotification{
+		User: u,
+	})
+	if err != nil {
+		log.Errorf("Could not notify user %d about the deletion of their account: %s", u.ID, err)
+	}
+}
+
+// moveForeignListsOutOfNamespace moves all lists in a namespace which are not owned by the namespace owner to the
+// first namespace of their owners. This makes sure transferred lists are not deleted together with the namespace.
+func moveForeignListsOutOfNamespace(s *xorm.Session, n *Namespace) (err error) {
+	lists := []*List{}
+	err = s.
+		Where("namespace_id = ? AND owner_id != ?", n.ID, n.OwnerID).
+		Find(&lists)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range lists {
+		target := &Namespace{}
+		exists, err := s.
+			Where("owner_id = ?", l.OwnerID).
+			OrderBy("id asc").
+			Get(target)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			owner, err := user.GetUserByID(s, l.OwnerID)
+			if err != nil {
+				return err
+			}
+			target = &Namespace{
+				Title:       owner.Username,
+				Description: owner.Username + "'s namespace.",
+			}
+			err = target.Create(s, owner)
+			if err != nil {
+				return err
+			}
+		}
+
+		l.NamespaceID = target.ID
+		_, err = s.
+			ID(l.ID).
+			Cols("namespace_id").
+			Update(l)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// anonymizeUserContent removes the user from everything they created which stays when they are deleted.
+func anonymizeUserContent(s *xorm.Session, u *user.User) (err error) {
+	updates := []struct {
+		column string
+		bean   interface{}
+	}{
+		{"author_id", &TaskComment{}},
+		{"created_by_id", &Task{}},
+		{"created_by_id", &TaskAttachment{}},
+		{"created_by_id", &TaskRelation{}},
+		{"created_by_id", &Bucket{}},
+		{"created_by_id", &Team{}},
+	}
+
+	for _, up := range updates {
+		_, err = s.
+			Where(up.column+" = ?", u.ID).
+			Cols(up.column).
+			NoAutoTime().
+			Update(up.bean)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteUserRelations removes everything which only makes sense together with the user.
+func deleteUserRelations(s *xorm.Session, u *user.User) (err error) {
+	err = reassignOrDeleteUserLabels(s, u)
+	if err != nil {
+		return err
+	}
+
+	deletes := []struct {
+		column string
+		bean   interface{}
+	}{
+		{"user_id", &TaskAssginee{}},
+		{"user_id", &TeamMember{}},
+		{"user_id", &ListUser{}},
+		{"user_id", &NamespaceUser{}},
+		{"user_id", &Subscription{}},
+		{"owner_id", &SavedFilter{}},
+		{"owner_id", &ICalFeed{}},
+		{"shared_by_id", &LinkSharing{}},
+		{"user_id", &OAuth2Grant{}},
+		{"user_id", &UserDeletionTransfer{}},
+		{"new_owner_id", &UserDeletionTransfer{}},
+		{"notifiable_id", &notifications.DatabaseNotification{}},
+	}
+
+	for _, d := range deletes {
+		_, err = s.
+			Where(d.column+" = ?", u.ID).
+			Delete(d.bean)
+		if err != nil {
+			return err
+		}
+	}
+
+	clients := []*OAuth2Client{}
+	err = s.Where("owner_id = ?", u.ID).Find(&clients)
+	if err != nil {
+		return err
+	}
+	for _, c := range clients {
+		err = c.Delete(s, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reassignOrDeleteUserLabels gives all labels the user created which are still used on tasks of other users to the
+// owner of the list of the first of these tasks. All other labels of the user are deleted.
+// This needs to run after the lists of the user were deleted so that only tasks which are kept are taken into account.
+func reassignOrDeleteUserLabels(s *xorm.Session, u *user.User) (err error) {
+	labelIDs := []int64{}
+	err = s.
+		Table("labels").
+		Where("created_by_id = ?", u.ID).
+		Cols("id").
+		Find(&labelIDs)
+	if err != nil {
+		return err
+	}
+	if len(labelIDs) == 0 {
+		return nil
+	}
+
+	usages := []*struct {
+		LabelID int64
+		OwnerID int64
+	}{}
+	err = s.
+		Table("label_task").
+		Select("label_task.label_id, list.owner_id").
+		Join("INNER", "tasks", "tasks.id = label_task.task_id").
+		Join("INNER", "list", "list.id = tasks.list_id").
+		In("label_task.label_id", labelIDs).
+		OrderBy("label_task.id asc").
+		Find(&usages)
+	if err != nil {
+		return err
+	}
+
+	newOwners := make(map[int64]int64, len(usages))
+	for _, usage := range usages {
+		if _, has := newOwners[usage.LabelID]; !has {
+			newOwners[usage.LabelID] = usage.OwnerID
+		}
+	}
+
+	unused := []int64{}
+	for _, id := range labelIDs {
+		ownerID, used := newOwners[id]
+		if !used {
+			unused = append(unused, id)
+			continue
+		}
+		_, err = s.
+			Where("id = ?", id).
+			Cols("created_by_id").
+			NoAutoTime().
+			Update(&Label{CreatedByID: ownerID})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(unused) == 0 {
+		return nil
+	}
+
+	_, err = s.In("label_id", unused).Delete(&LabelTask{})
+	if err != nil {
+		return err
+	}
+	_, err = s.In("id", unused).Delete(&Label{})
+	return err
+}
@@ -0,0 +1,127 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteUser(t *testing.T) {
+	t.Run("with transfer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := user.GetUserByID(s, 13)
+		assert.NoError(t, err)
+		err = DeleteUser(s, u)
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "users", map[string]interface{}{
+			"id": 13,
+		})
+		db.AssertMissing(t, "namespaces", map[string]interface{}{
+			"id": 15,
+		})
+		db.AssertMissing(t, "users_namespace", map[string]interface{}{
+			"user_id": 13,
+		})
+		db.AssertMissing(t, "user_deletion_transfers", map[string]interface{}{
+			"user_id": 13,
+		})
+		// List 20 was transferred to user6 and moved out of the deleted namespace
+		db.AssertExists(t, "list", map[string]interface{}{
+			"id":           20,
+			"owner_id":     6,
+			"namespace_id": 6,
+		}, false)
+		db.AssertExists(t, "tasks", map[string]interface{}{
+			"id":            34,
+			"created_by_id": 0,
+		}, false)
+	})
+	t.Run("without transfer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.Where("user_id = ?", 13).Delete(&UserDeletionTransfer{})
+		assert.NoError(t, err)
+
+		u, err := user.GetUserByID(s, 13)
+		assert.NoError(t, err)
+		err = DeleteUser(s, u)
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "list", map[string]interface{}{
+			"id": 20,
+		})
+		db.AssertMissing(t, "tasks", map[string]interface{}{
+			"id": 34,
+		})
+	})
+	t.Run("anonymizes comments", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := user.GetUserByID(s, 6)
+		assert.NoError(t, err)
+		err = DeleteUser(s, u)
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "users", map[string]interface{}{
+			"id": 6,
+		})
+		db.AssertMissing(t, "namespaces", map[string]interface{}{
+			"owner_id": 6,
+		})
+		db.AssertMissing(t, "task_comments", map[string]interface{}{
+			"author_id": 6,
+		})
+		db.AssertMissing(t, "task_assignees", map[string]interface{}{
+			"user_id": 6,
+		})
+	})
+	t.Run("keeps labels used on other tasks", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := user.GetUserByID(s, 2)
+		assert.NoError(t, err)
+		err = DeleteUser(s, u)
+		assert.NoError(t, err)
+
+		// Label 4 is used on task 1 in list 1 which belongs to user 1
+		db.AssertExists(t, "labels", map[string]interface{}{
+			"id":            4,
+			"created_by_id": 1,
+		}, false)
+		db.AssertExists(t, "label_task", map[string]interface{}{
+			"task_id":  1,
+			"label_id": 4,
+		}, false)
+		db.AssertMissing(t, "labels", map[string]interface{}{
+			"id": 3,
+		})
+	})
+}
@@ -0,0 +1,215 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// UserDeletionTransfer holds the choice of a user who scheduled the deletion of their account to give one of their
+// namespaces or lists to another user instead of deleting it.
+type UserDeletionTransfer struct {
+	// The unique, numeric id of this transfer.
+	ID     int64 `xorm:"bigint autoincr not null unique pk" json:"id" param:"transfer"`
+	UserID int64 `xorm:"bigint not null INDEX" json:"-"`
+	// The namespace which should be transferred. Either this or the list id needs to be set.
+	NamespaceID int64 `xorm:"bigint null INDEX" json:"namespace_id"`
+	// The list which should be transferred. Either this or the namespace id needs to be set.
+	ListID int64 `xorm:"bigint null INDEX" json:"list_id"`
+	// The id of the user who should become the new owner.
+	NewOwnerID int64 `xorm:"bigint not null INDEX" json:"new_owner_id"`
+	// The user who will become the new owner.
+	NewOwner *user.User `xorm:"-" json:"new_owner"`
+
+	// A timestamp when this transfer was created. You cannot change this value.
+	Created time.Time `xorm:"created not null" json:"created"`
+
+	web.CRUDable `xorm:"-" json:"-"`
+	web.Rights   `xorm:"-" json:"-"`
+}
+
+// TableName returns the table name for user deletion transfers
+func (*UserDeletionTransfer) TableName() string {
+	return "user_deletion_transfers"
+}
+
+// Create saves which user should get a namespace or list once the account of the current user is deleted
+// @Summary Transfer a namespace or list when the account is deleted
+// @Description Gives a namespace or list to another user instead of deleting it when the account of the current user is deleted. The deletion needs to be scheduled and the new owner needs to have access to the namespace or list already. An earlier choice for the same namespace or list is replaced.
+// @tags user
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param transfer body models.UserDeletionTransfer true "The namespace or list and the new owner."
+// @Success 200 {object} models.UserDeletionTransfer "The created transfer."
+// @Failure 400 {object} web.HTTPError "The transfer does not contain exactly one namespace or list."
+// @Failure 412 {object} web.HTTPError "The deletion is not scheduled or the namespace or list cannot be transferred to that user."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /user/deletion/transfers [put]
+func (t *UserDeletionTransfer) Create(s *xorm.Session, a web.Auth) (err error) {
+	if (t.NamespaceID == 0) == (t.ListID == 0) {
+		return ErrUserDeletionTransferInvalid{}
+	}
+
+	doer, err := user.GetUserByID(s, a.GetID())
+	if err != nil {
+		return err
+	}
+	if doer.DeletionScheduledAt.IsZero() {
+		return user.ErrAccountDeletionNotScheduled{UserID: doer.ID}
+	}
+
+	notAllowed := ErrUserDeletionTransferNotAllowed{
+		NamespaceID: t.NamespaceID,
+		ListID:      t.ListID,
+		NewOwnerID:  t.NewOwnerID,
+	}
+
+	if t.NewOwnerID == doer.ID {
+		return notAllowed
+	}
+
+	newOwner, err := user.GetUserByID(s, t.NewOwnerID)
+	if err != nil {
+		if user.IsErrUserDoesNotExist(err) {
+			return notAllowed
+		}
+		return err
+	}
+	if !newOwner.IsActive {
+		return notAllowed
+	}
+
+	var ownerID int64
+	var canRead bool
+	if t.NamespaceID != 0 {
+		n, err := getNamespaceSimpleByID(s, t.NamespaceID)
+		if err != nil {
+			return err
+		}
+		ownerID = n.OwnerID
+		canRead, _, err = (&Namespace{ID: n.ID}).CanRead(s, newOwner)
+		if err != nil {
+			return err
+		}
+	} else {
+		l, err := GetListSimpleByID(s, t.ListID)
+		if err != nil {
+			return err
+		}
+		ownerID = l.OwnerID
+		canRead, _, err = (&List{ID: l.ID}).CanRead(s, newOwner)
+		if err != nil {
+			return err
+		}
+	}
+
+	if ownerID != doer.ID || !canRead {
+		return notAllowed
+	}
+
+	// The column which is not set can be null, that's why we only filter by the one which is set.
+	cond := builder.Eq{"user_id": doer.ID}
+	if t.NamespaceID != 0 {
+		cond["namespace_id"] = t.NamespaceID
+	} else {
+		cond["list_id"] = t.ListID
+	}
+	_, err = s.
+		Where(cond).
+		Delete(&UserDeletionTransfer{})
+	if err != nil {
+		return err
+	}
+
+	t.ID = 0
+	t.UserID = doer.ID
+	_, err = s.Insert(t)
+	if err != nil {
+		return err
+	}
+
+	t.NewOwner = newOwner
+	return nil
+}
+
+// ReadAll returns all transfers the current user chose
+// @Summary Get all transfers for the account deletion
+// @Description Returns all namespaces and lists the current user chose to give to other users when their account is deleted.
+// @tags user
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {array} models.UserDeletionTransfer "The transfers."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /user/deletion/transfers [get]
+func (t *UserDeletionTransfer) ReadAll(s *xorm.Session, a web.Auth, search string, page int, perPage int) (result interface{}, resultCount int, numberOfTotalItems int64, err error) {
+	if _, is := a.(*LinkSharing); is {
+		return nil, 0, 0, ErrGenericForbidden{}
+	}
+
+	transfers, err := getUserDeletionTransfers(s, a.GetID())
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	ownerIDs := make([]int64, 0, len(transfers))
+	for _, tr := range transfers {
+		ownerIDs = append(ownerIDs, tr.NewOwnerID)
+	}
+	owners, err := user.GetUsersByIDs(s, ownerIDs)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	for _, tr := range transfers {
+		tr.NewOwner = owners[tr.NewOwnerID]
+	}
+
+	return transfers, len(transfers), int64(len(transfers)), nil
+}
+
+// Delete removes a transfer so the namespace or list is deleted together with the account again
+// @Summary Remove a transfer for the account deletion
+// @Description Removes a transfer. The namespace or list will be deleted together with the account again.
+// @tags user
+// @Produce json
+// @Security JWTKeyAuth
+// @Param transfer path int true "Transfer ID"
+// @Success 200 {object} models.Message "The transfer was successfully deleted."
+// @Failure 403 {object} web.HTTPError "The transfer does not belong to the user."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /user/deletion/transfers/{transfer} [delete]
+func (t *UserDeletionTransfer) Delete(s *xorm.Session, a web.Auth) (err error) {
+	_, err = s.
+		Where("id = ?", t.ID).
+		Delete(&UserDeletionTransfer{})
+	return
+}
+
+func getUserDeletionTransfers(s *xorm.Session, userID int64) (transfers []*UserDeletionTransfer, err error) {
+	transfers = []*UserDeletionTransfer{}
+	err = s.
+		Where("user_id = ?", userID).
+		OrderBy("id asc").
+		Find(&transfers)
+	return
+}
@@ -0,0 +1,206 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func scheduleUserDeletionForTest(t *testing.T, s *xorm.Session, userID int64) {
+	_, err := s.
+		Where("id = ?", userID).
+		Cols("deletion_scheduled_at").
+		Update(&user.User{DeletionScheduledAt: time.Now().Add(time.Hour)})
+	assert.NoError(t, err)
+}
+
+func TestUserDeletionTransfer_Create(t *testing.T) {
+	t.Run("namespace", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		_, err := s.Insert(&NamespaceUser{UserID: 6, NamespaceID: 15, Right: RightRead})
+		assert.NoError(t, err)
+
+		tr := &UserDeletionTransfer{NamespaceID: 15, NewOwnerID: 6}
+		can, err := tr.CanCreate(s, &user.User{ID: 13})
+		assert.NoError(t, err)
+		assert.True(t, can)
+		err = tr.Create(s, &user.User{ID: 13})
+		assert.NoError(t, err)
+		assert.Equal(t, "user6", tr.NewOwner.Username)
+		db.AssertExists(t, "user_deletion_transfers", map[string]interface{}{
+			"id":           tr.ID,
+			"user_id":      13,
+			"namespace_id": 15,
+			"new_owner_id": 6,
+		}, false)
+	})
+	t.Run("replaces an earlier transfer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		_, err := s.Insert(&ListUser{UserID: 1, ListID: 20, Right: RightRead})
+		assert.NoError(t, err)
+
+		tr := &UserDeletionTransfer{ListID: 20, NewOwnerID: 1}
+		err = tr.Create(s, &user.User{ID: 13})
+		assert.NoError(t, err)
+		db.AssertMissing(t, "user_deletion_transfers", map[string]interface{}{
+			"id": 1,
+		})
+		db.AssertExists(t, "user_deletion_transfers", map[string]interface{}{
+			"id":           tr.ID,
+			"user_id":      13,
+			"list_id":      20,
+			"new_owner_id": 1,
+		}, false)
+	})
+	t.Run("no namespace or list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		tr := &UserDeletionTransfer{NewOwnerID: 6}
+		err := tr.Create(s, &user.User{ID: 13})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDeletionTransferInvalid(err))
+	})
+	t.Run("namespace and list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		tr := &UserDeletionTransfer{NamespaceID: 15, ListID: 20, NewOwnerID: 6}
+		err := tr.Create(s, &user.User{ID: 13})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDeletionTransferInvalid(err))
+	})
+	t.Run("deletion not scheduled", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tr := &UserDeletionTransfer{NamespaceID: 15, NewOwnerID: 6}
+		err := tr.Create(s, &user.User{ID: 13})
+		assert.Error(t, err)
+		assert.True(t, user.IsErrAccountDeletionNotScheduled(err))
+	})
+	t.Run("new owner without access", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		tr := &UserDeletionTransfer{NamespaceID: 15, NewOwnerID: 6}
+		err := tr.Create(s, &user.User{ID: 13})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDeletionTransferNotAllowed(err))
+	})
+	t.Run("not the owner", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		// User 13 has admin access to namespace 14 but does not own it
+		tr := &UserDeletionTransfer{NamespaceID: 14, NewOwnerID: 11}
+		err := tr.Create(s, &user.User{ID: 13})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDeletionTransferNotAllowed(err))
+	})
+	t.Run("to themselves", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		scheduleUserDeletionForTest(t, s, 13)
+		tr := &UserDeletionTransfer{NamespaceID: 15, NewOwnerID: 13}
+		err := tr.Create(s, &user.User{ID: 13})
+		assert.Error(t, err)
+		assert.True(t, IsErrUserDeletionTransferNotAllowed(err))
+	})
+	t.Run("link share", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tr := &UserDeletionTransfer{NamespaceID: 15, NewOwnerID: 6}
+		can, err := tr.CanCreate(s, &LinkSharing{ID: 1})
+		assert.NoError(t, err)
+		assert.False(t, can)
+	})
+}
+
+func TestUserDeletionTransfer_ReadAll(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	tr := &UserDeletionTransfer{}
+	result, count, _, err := tr.ReadAll(s, &user.User{ID: 13}, "", 1, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	transfers := result.([]*UserDeletionTransfer)
+	assert.Equal(t, int64(20), transfers[0].ListID)
+	assert.Equal(t, "user6", transfers[0].NewOwner.Username)
+
+	result, count, _, err = tr.ReadAll(s, &user.User{ID: 1}, "", 1, 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Len(t, result, 0)
+}
+
+func TestUserDeletionTransfer_Delete(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tr := &UserDeletionTransfer{ID: 1}
+		can, err := tr.CanDelete(s, &user.User{ID: 13})
+		assert.NoError(t, err)
+		assert.True(t, can)
+		err = tr.Delete(s, &user.User{ID: 13})
+		assert.NoError(t, err)
+		db.AssertMissing(t, "user_deletion_transfers", map[string]interface{}{
+			"id": 1,
+		})
+	})
+	t.Run("forbidden", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		tr := &UserDeletionTransfer{ID: 1}
+		can, err := tr.CanDelete(s, &user.User{ID: 1})
+		assert.NoError(t, err)
+		assert.False(t, can)
+	})
+}
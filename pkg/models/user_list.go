@@ -35,6 +35,64 @@ type ListUIDs struct {
 // ListUsersFromList returns a list with all users who have access to a list, regardless of the method which gave them access
 func ListUsersFromList(s *xorm.Session, l *List, search string) (users []*user.User, err error) {
 
+	uids, err := getUserIDsWithAccessToLists(s, []*List{l})
+	if err != nil {
+		return
+	}
+
+	if len(uids) == 0 {
+		return []*user.User{}, nil
+	}
+
+	cond := builder.And(
+		builder.In("id", uids),
+		builder.Like{"username", "%" + search + "%"},
+	)
+
+	// Get all users
+	err = s.
+		Table("users").
+		Select("*").
+		Where(cond).
+		GroupBy("id").
+		OrderBy("id").
+		Find(&users)
+
+	// Obfuscate all user emails
+	for _, u := range users {
+		u.Email = ""
+	}
+
+	return
+}
+
+// GetUserIDsSharingListsWithUser returns the ids of all users who have access to at least one of the lists
+// the user has access to, including the user itself.
+func GetUserIDsSharingListsWithUser(s *xorm.Session, u *user.User) (uids []int64, err error) {
+	lists, _, _, err := getRawListsForUser(s, &listOptions{
+		user:       u,
+		page:       -1,
+		isArchived: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getUserIDsWithAccessToLists(s, lists)
+}
+
+// getUserIDsWithAccessToLists returns the ids of all users who have access to at least one of the lists,
+// regardless of the method which gave them access
+func getUserIDsWithAccessToLists(s *xorm.Session, lists []*List) (uids []int64, err error) {
+	if len(lists) == 0 {
+		return nil, nil
+	}
+
+	listIDs := make([]int64, 0, len(lists))
+	for _, l := range lists {
+		listIDs = append(listIDs, l.ID)
+	}
+
 	userids := []*ListUIDs{}
 
 	err = s.
@@ -73,7 +131,7 @@ func ListUsersFromList(s *xorm.Session, l *List, search string) (users []*user.U
 				builder.Or(builder.Eq{"tl.right": RightAdmin}),
 				builder.Or(builder.Eq{"tn.right": RightAdmin}),
 			),
-			builder.Eq{"l.id": l.ID},
+			builder.In("l.id", listIDs),
 		).
 		Find(&userids)
 	if err != nil {
@@ -82,7 +140,9 @@ func ListUsersFromList(s *xorm.Session, l *List, search string) (users []*user.U
 
 	// Remove duplicates from the list of ids and make it a slice
 	uidmap := make(map[int64]bool)
-	uidmap[l.OwnerID] = true
+	for _, l := range lists {
+		uidmap[l.OwnerID] = true
+	}
 	for _, u := range userids {
 		uidmap[u.ListUserID] = true
 		uidmap[u.NamespaceOwnerUserID] = true
@@ -90,34 +150,12 @@ func ListUsersFromList(s *xorm.Session, l *List, search string) (users []*user.U
 		uidmap[u.TeamListUserID] = true
 		uidmap[u.TeamNamespaceUserID] = true
 	}
+	delete(uidmap, 0)
 
-	uids := make([]int64, len(uidmap))
+	uids = make([]int64, 0, len(uidmap))
 	for id := range uidmap {
 		uids = append(uids, id)
 	}
 
-	var cond builder.Cond = builder.Like{"username", "%" + search + "%"}
-
-	if len(uids) > 0 {
-		cond = builder.And(
-			builder.In("id", uids),
-			cond,
-		)
-	}
-
-	// Get all users
-	err = s.
-		Table("users").
-		Select("*").
-		Where(cond).
-		GroupBy("id").
-		OrderBy("id").
-		Find(&users)
-
-	// Obfuscate all user emails
-	for _, u := range users {
-		u.Email = ""
-	}
-
 	return
 }
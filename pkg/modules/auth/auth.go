@@ -56,16 +56,38 @@ func NewUserJWTAuthtoken(user *user.User) (token string, err error) {
 
 	// Set claims
 	claims := t.Claims.(jwt.MapClaims)
+	setUserClaims(claims, user, time.Hour*72)
+
+	// Generate encoded token and send it as response.
+	return t.SignedString([]byte(config.ServiceJWTSecret.GetString()))
+}
+
+// NewOAuth2AccessToken creates a new jwt token for a user which was issued to an oauth2 client. It is only valid as long
+// as the grant exists and only for the scopes the user granted.
+func NewOAuth2AccessToken(user *user.User, grant *models.OAuth2Grant, expiresIn time.Duration) (token string, err error) {
+	t := jwt.New(jwt.SigningMethodHS256)
+
+	// Set claims
+	claims := t.Claims.(jwt.MapClaims)
+	setUserClaims(claims, user, expiresIn)
+	claims["oauth2_grant_id"] = grant.ID
+	claims["scope"] = grant.Scope
+
+	// Generate encoded token and send it as response.
+	return t.SignedString([]byte(config.ServiceJWTSecret.GetString()))
+}
+
+// setUserClaims sets all claims every token of a user contains
+func setUserClaims(claims jwt.MapClaims, user *user.User, expiresIn time.Duration) {
 	claims["type"] = AuthTypeUser
 	claims["id"] = user.ID
 	claims["username"] = user.Username
 	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
+	claims["exp"] = time.Now().Add(expiresIn).Unix()
 	claims["name"] = user.Name
 	claims["emailRemindersEnabled"] = user.EmailRemindersEnabled
-
-	// Generate encoded token and send it as response.
-	return t.SignedString([]byte(config.ServiceJWTSecret.GetString()))
+	claims["discoverableByName"] = user.DiscoverableByName
+	claims["discoverableByEmail"] = user.DiscoverableByEmail
 }
 
 // NewLinkShareJWTAuthtoken creates a new jwt token from a link share
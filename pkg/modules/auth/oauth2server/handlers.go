@@ -0,0 +1,236 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2server
+
+import (
+	"net/http"
+	"net/url"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/auth"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+)
+
+func getCurrentUser(c echo.Context) (*user.User, error) {
+	a, err := auth.GetAuthFromClaims(c)
+	if err != nil {
+		return nil, err
+	}
+	u, is := a.(*user.User)
+	if !is {
+		return nil, models.ErrGenericForbidden{}
+	}
+	return u, nil
+}
+
+// GetAuthorizationConsent returns the details of an authorization request to show them to the user
+// @Summary Get the details of an OAuth2 authorization request
+// @Description Validates an authorization request of a third-party client and returns everything needed to ask the user if they want to grant access. The frontend should call this with the query parameters the client sent the user with.
+// @tags oauth2
+// @Produce json
+// @Security JWTKeyAuth
+// @Param response_type query string true "Needs to be `code`."
+// @Param client_id query string true "The public id of the client."
+// @Param redirect_uri query string false "The url to redirect the user to afterwards. Can be omitted if the client only has one."
+// @Param scope query string false "The requested scopes, separated by spaces. Possible values are `read` and `write`."
+// @Param state query string false "An opaque value which is passed back to the client."
+// @Param code_challenge query string true "The PKCE code challenge."
+// @Param code_challenge_method query string true "Needs to be `S256`."
+// @Success 200 {object} oauth2server.Consent "The details of the request."
+// @Failure 400 {object} web.HTTPError "The request is invalid."
+// @Failure 404 {object} web.HTTPError "The client does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/authorize [get]
+func GetAuthorizationConsent(c echo.Context) error {
+	_, err := getCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	req := &AuthorizationRequest{}
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Message{Message: "Invalid authorization request."})
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	consent, err := getConsent(s, req)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, consent)
+}
+
+// Authorize grants a client access to the current user's account
+// @Summary Grant an OAuth2 client access
+// @Description Grants a third-party client access to the current user's account after they agreed to it and returns the url to redirect the user back to the client. The url contains an authorization code which the client can exchange for tokens at `/oauth2/token`.
+// @tags oauth2
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param request body oauth2server.AuthorizationRequest true "The parameters of the authorization request as sent by the client."
+// @Success 200 {object} oauth2server.AuthorizationResponse "Where to redirect the user."
+// @Failure 400 {object} web.HTTPError "The request is invalid."
+// @Failure 404 {object} web.HTTPError "The client does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/authorize [post]
+func Authorize(c echo.Context) error {
+	u, err := getCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	req := &AuthorizationRequest{}
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Message{Message: "Invalid authorization request."})
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	res, err := authorize(s, u.ID, req)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// Some clients pass their credentials through http basic auth instead of the request body.
+// These need to be url encoded, see https://tools.ietf.org/html/rfc6749#section-2.3.1
+func getClientCredentialsFromBasicAuth(c echo.Context) (clientID, secret string, ok bool) {
+	clientID, secret, ok = c.Request().BasicAuth()
+	if !ok {
+		return
+	}
+
+	clientID, err := url.QueryUnescape(clientID)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err = url.QueryUnescape(secret)
+	if err != nil {
+		return "", "", false
+	}
+	return clientID, secret, true
+}
+
+func handleTokenError(c echo.Context, err error) error {
+	if te, is := err.(*tokenError); is {
+		return c.JSON(te.status, ErrorResponse{
+			Error:            te.code,
+			ErrorDescription: te.description,
+		})
+	}
+	return handler.HandleHTTPError(err, c)
+}
+
+// Token issues tokens to oauth2 clients
+// @Summary Get OAuth2 tokens
+// @Description Exchanges an authorization code or a refresh token for a new access and refresh token. Every refresh token can only be used once. The parameters need to be sent form encoded, errors are returned as specified in RFC 6749.
+// @tags oauth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param request body oauth2server.TokenRequest true "The token request."
+// @Success 200 {object} oauth2server.TokenResponse "The new tokens."
+// @Failure 400 {object} oauth2server.ErrorResponse "The request is invalid."
+// @Failure 401 {object} oauth2server.ErrorResponse "The client could not be authenticated."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/token [post]
+func Token(c echo.Context) error {
+	req := &TokenRequest{}
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_request"})
+	}
+	if clientID, secret, ok := getClientCredentialsFromBasicAuth(c); ok {
+		req.ClientID = clientID
+		req.ClientSecret = secret
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	res, err := exchangeToken(s, req)
+	if err != nil {
+		_ = s.Rollback()
+		return handleTokenError(c, err)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+	return c.JSON(http.StatusOK, res)
+}
+
+// Revoke revokes oauth2 tokens
+// @Summary Revoke an OAuth2 token
+// @Description Revokes an access or refresh token and with it all other tokens issued for the same grant. Responds with 200 even if the token was invalid, as specified in RFC 7009. The parameters need to be sent form encoded.
+// @tags oauth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param request body oauth2server.RevocationRequest true "The revocation request."
+// @Success 200 {object} models.Message "The token was revoked."
+// @Failure 401 {object} oauth2server.ErrorResponse "The client could not be authenticated."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /oauth2/revoke [post]
+func Revoke(c echo.Context) error {
+	req := &RevocationRequest{}
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid_request"})
+	}
+	if clientID, secret, ok := getClientCredentialsFromBasicAuth(c); ok {
+		req.ClientID = clientID
+		req.ClientSecret = secret
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	err := revokeToken(s, req)
+	if err != nil {
+		_ = s.Rollback()
+		return handleTokenError(c, err)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "The token was revoked."})
+}
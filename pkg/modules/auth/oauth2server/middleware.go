@@ -0,0 +1,109 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2server
+
+import (
+	"net/http"
+	"strings"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/web/handler"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+)
+
+// Access tokens of oauth2 clients can never be used for these routes, regardless of their scope.
+// They allow to take over or delete the account, to get access for other clients or to administrate the instance.
+var restrictedRoutePrefixes = []string{
+	"/api/v1/user/password",
+	"/api/v1/user/token",
+	"/api/v1/user/settings",
+	"/api/v1/user/deletion",
+	"/api/v1/user/export",
+	// Feed tokens and link share hashes would keep working after the grant was revoked.
+	// These are route paths, the list id is not replaced with the actual id.
+	"/api/v1/ical-feeds",
+	"/api/v1/lists/:list/shares",
+	"/api/v1/oauth2",
+	"/api/v1/admin",
+}
+
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return models.OAuth2ScopeRead
+	default:
+		return models.OAuth2ScopeWrite
+	}
+}
+
+// CheckAccessToken makes sure access tokens issued to oauth2 clients are only used while the user did not revoke
+// them and only within the scopes the user granted. It needs to run after the jwt middleware.
+// Tokens of users and link shares are not affected.
+func CheckAccessToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		jwtinf, is := c.Get("user").(*jwt.Token)
+		if !is {
+			return next(c)
+		}
+		claims, is := jwtinf.Claims.(jwt.MapClaims)
+		if !is {
+			return next(c)
+		}
+		grantID, is := claims["oauth2_grant_id"].(float64)
+		if !is {
+			return next(c)
+		}
+
+		for _, prefix := range restrictedRoutePrefixes {
+			if strings.HasPrefix(c.Path(), prefix) {
+				return handler.HandleHTTPError(models.ErrOAuth2InsufficientScope{}, c)
+			}
+		}
+
+		s := db.NewSession()
+		defer s.Close()
+
+		grant, err := models.GetOAuth2GrantByID(s, int64(grantID))
+		if err != nil {
+			_ = s.Rollback()
+			if models.IsErrOAuth2GrantDoesNotExist(err) {
+				return echo.NewHTTPError(http.StatusUnauthorized, models.Message{Message: "The access token was revoked."})
+			}
+			return handler.HandleHTTPError(err, c)
+		}
+
+		if err := s.Commit(); err != nil {
+			_ = s.Rollback()
+			return handler.HandleHTTPError(err, c)
+		}
+
+		userID, _ := claims["id"].(float64)
+		if grant.UserID != int64(userID) {
+			return echo.NewHTTPError(http.StatusUnauthorized, models.Message{Message: "Invalid JWT token."})
+		}
+
+		// Write access includes read access
+		scope := requiredScope(c.Request().Method)
+		if !grant.HasScope(scope) && !grant.HasScope(models.OAuth2ScopeWrite) {
+			return handler.HandleHTTPError(models.ErrOAuth2InsufficientScope{Scope: scope}, c)
+		}
+
+		return next(c)
+	}
+}
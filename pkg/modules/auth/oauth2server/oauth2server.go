@@ -0,0 +1,257 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/url"
+	"time"
+
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"code.vikunja.io/api/pkg/utils"
+	"xorm.io/xorm"
+)
+
+const (
+	authorizationCodeTimeout = 10 * time.Minute
+	accessTokenLifetime      = time.Hour
+
+	codeChallengeMethodS256 = "S256"
+	// Verifiers need to be between 43 and 128 characters, see https://tools.ietf.org/html/rfc7636#section-4.1
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// AuthorizationRequest holds the parameters a client sends the user to the frontend with to ask for access.
+type AuthorizationRequest struct {
+	// Needs to be `code`, other flows are not supported.
+	ResponseType string `query:"response_type" json:"response_type"`
+	// The public id of the client.
+	ClientID string `query:"client_id" json:"client_id"`
+	// The url to redirect the user to after granting access. Can be omitted if the client only has one.
+	RedirectURI string `query:"redirect_uri" json:"redirect_uri"`
+	// The requested scopes, separated by spaces. Possible values are `read` and `write`. Defaults to `read`.
+	Scope string `query:"scope" json:"scope"`
+	// An opaque value which is passed back to the client unchanged.
+	State string `query:"state" json:"state"`
+	// The PKCE code challenge, required for all clients.
+	CodeChallenge string `query:"code_challenge" json:"code_challenge"`
+	// Needs to be `S256`.
+	CodeChallengeMethod string `query:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// Consent holds everything the frontend needs to ask the user if they want to grant access to a client.
+type Consent struct {
+	// The name of the client asking for access.
+	ClientName string `json:"client_name"`
+	// The scopes the client asks for.
+	Scopes []string `json:"scopes"`
+	// The url the user will be redirected to after granting access.
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// AuthorizationResponse holds where the frontend should send the user after they granted access.
+type AuthorizationResponse struct {
+	// The redirect url of the client including the authorization code and state.
+	RedirectURL string `json:"redirect_url"`
+}
+
+// TokenRequest holds the parameters of a request to the token endpoint. They need to be sent form encoded.
+type TokenRequest struct {
+	// Either `authorization_code` or `refresh_token`.
+	GrantType string `form:"grant_type" json:"grant_type"`
+	// The authorization code the client got after the user granted access.
+	Code string `form:"code" json:"code"`
+	// The redirect uri used when requesting the authorization code.
+	RedirectURI string `form:"redirect_uri" json:"redirect_uri"`
+	// The PKCE code verifier belonging to the code challenge used when requesting the authorization code.
+	CodeVerifier string `form:"code_verifier" json:"code_verifier"`
+	// The refresh token to get a new access token with.
+	RefreshToken string `form:"refresh_token" json:"refresh_token"`
+	// The public id of the client. Can also be passed via http basic auth.
+	ClientID string `form:"client_id" json:"client_id"`
+	// The secret of confidential clients. Can also be passed via http basic auth.
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+}
+
+// TokenResponse is returned by the token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// RevocationRequest holds the parameters of a request to the revocation endpoint. They need to be sent form encoded.
+type RevocationRequest struct {
+	// Either an access or a refresh token. Revoking any of them revokes the whole grant.
+	Token string `form:"token" json:"token"`
+	// The public id of the client. Can also be passed via http basic auth.
+	ClientID string `form:"client_id" json:"client_id"`
+	// The secret of confidential clients. Can also be passed via http basic auth.
+	ClientSecret string `form:"client_secret" json:"client_secret"`
+}
+
+// ErrorResponse is returned by the token and revocation endpoints as specified in https://tools.ietf.org/html/rfc6749#section-5.2
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// authorizationCode holds everything needed to exchange an authorization code for tokens.
+type authorizationCode struct {
+	ClientID      int64     `json:"client_id"`
+	UserID        int64     `json:"user_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes"`
+	CodeChallenge string    `json:"code_challenge"`
+	Expires       time.Time `json:"expires"`
+}
+
+func authorizationCodeKey(code string) string {
+	return "oauth2_code_" + utils.Sha256(code)
+}
+
+// validateAuthorizationRequest checks an authorization request and returns the client and scopes it asks for.
+func validateAuthorizationRequest(s *xorm.Session, req *AuthorizationRequest) (client *models.OAuth2Client, scopes []string, err error) {
+	if req.ResponseType != "code" {
+		return nil, nil, models.ErrOAuth2UnsupportedResponseType{ResponseType: req.ResponseType}
+	}
+
+	client, err = models.GetOAuth2ClientByClientID(s, req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if req.RedirectURI == "" && len(client.RedirectURIs) == 1 {
+		req.RedirectURI = client.RedirectURIs[0]
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, nil, models.ErrOAuth2InvalidRedirectURI{RedirectURI: req.RedirectURI}
+	}
+
+	scopes, err = models.ParseOAuth2Scopes(req.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The challenge is the base64 encoded sha256 hash of the verifier and therefore always 43 characters long
+	if req.CodeChallengeMethod != codeChallengeMethodS256 || len(req.CodeChallenge) != base64.RawURLEncoding.EncodedLen(sha256.Size) {
+		return nil, nil, models.ErrOAuth2InvalidCodeChallenge{Method: req.CodeChallengeMethod}
+	}
+
+	return
+}
+
+// getConsent returns everything a user needs to know to decide if they want to grant access to a client.
+func getConsent(s *xorm.Session, req *AuthorizationRequest) (consent *Consent, err error) {
+	client, scopes, err := validateAuthorizationRequest(s, req)
+	if err != nil {
+		return
+	}
+
+	return &Consent{
+		ClientName:  client.Name,
+		Scopes:      scopes,
+		RedirectURI: req.RedirectURI,
+	}, nil
+}
+
+// authorize creates an authorization code for the client after the user granted access and returns the url to
+// redirect the user back to the client.
+func authorize(s *xorm.Session, userID int64, req *AuthorizationRequest) (res *AuthorizationResponse, err error) {
+	client, scopes, err := validateAuthorizationRequest(s, req)
+	if err != nil {
+		return
+	}
+
+	code, err := utils.MakeSecureRandomString(40)
+	if err != nil {
+		return
+	}
+
+	err = keyvalue.PutWithExpiration(authorizationCodeKey(code), &authorizationCode{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		Expires:       time.Now().Add(authorizationCodeTimeout),
+	}, authorizationCodeTimeout)
+	if err != nil {
+		return
+	}
+
+	redirect, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		return
+	}
+	query := redirect.Query()
+	query.Set("code", code)
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	redirect.RawQuery = query.Encode()
+
+	return &AuthorizationResponse{RedirectURL: redirect.String()}, nil
+}
+
+// redeemAuthorizationCode returns the data of an authorization code and removes it so every code can only be used once.
+func redeemAuthorizationCode(code string) (ac *authorizationCode, err error) {
+	if code == "" {
+		return nil, nil
+	}
+
+	key := authorizationCodeKey(code)
+	ac = &authorizationCode{}
+	exists, err := keyvalue.GetWithValue(key, ac)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	// Only the one who actually removed the code may use it, even if the same code is redeemed in parallel
+	removed, err := keyvalue.DelIfExists(key)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, nil
+	}
+
+	if ac.Expires.Before(time.Now()) {
+		return nil, nil
+	}
+
+	return ac, nil
+}
+
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	h := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(h[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
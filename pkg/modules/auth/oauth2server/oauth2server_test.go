@@ -0,0 +1,299 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func getTestCodeChallenge() string {
+	h := sha256.Sum256([]byte(testCodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func getTestAuthorizationCode(t *testing.T, req *AuthorizationRequest) string {
+	s := db.NewSession()
+	defer s.Close()
+
+	res, err := authorize(s, 1, req)
+	assert.NoError(t, err)
+	u, err := url.Parse(res.RedirectURL)
+	assert.NoError(t, err)
+	assert.Equal(t, req.State, u.Query().Get("state"))
+	return u.Query().Get("code")
+}
+
+func TestValidateAuthorizationRequest(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		consent, err := getConsent(s, &AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            "publicclient1",
+			RedirectURI:         "vikunja-app://callback",
+			Scope:               "read write",
+			CodeChallenge:       getTestCodeChallenge(),
+			CodeChallengeMethod: "S256",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Public Client", consent.ClientName)
+		assert.Equal(t, []string{"read", "write"}, consent.Scopes)
+	})
+	t.Run("unregistered redirect uri", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := getConsent(s, &AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            "publicclient1",
+			RedirectURI:         "https://evil.example.com",
+			CodeChallenge:       getTestCodeChallenge(),
+			CodeChallengeMethod: "S256",
+		})
+		assert.Error(t, err)
+		assert.True(t, models.IsErrOAuth2InvalidRedirectURI(err))
+	})
+	t.Run("without pkce", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := getConsent(s, &AuthorizationRequest{
+			ResponseType: "code",
+			ClientID:     "publicclient1",
+			RedirectURI:  "vikunja-app://callback",
+		})
+		assert.Error(t, err)
+		assert.True(t, models.IsErrOAuth2InvalidCodeChallenge(err))
+	})
+	t.Run("implicit flow", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := getConsent(s, &AuthorizationRequest{
+			ResponseType: "token",
+			ClientID:     "publicclient1",
+		})
+		assert.Error(t, err)
+		assert.True(t, models.IsErrOAuth2UnsupportedResponseType(err))
+	})
+}
+
+func TestExchangeToken(t *testing.T) {
+	authReq := &AuthorizationRequest{
+		ResponseType:        "code",
+		ClientID:            "publicclient1",
+		RedirectURI:         "vikunja-app://callback",
+		Scope:               "write",
+		State:               "somestate",
+		CodeChallenge:       getTestCodeChallenge(),
+		CodeChallengeMethod: "S256",
+	}
+
+	t.Run("authorization code", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		code := getTestAuthorizationCode(t, authReq)
+
+		s := db.NewSession()
+		defer s.Close()
+
+		res, err := exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeAuthorizationCode,
+			Code:         code,
+			RedirectURI:  "vikunja-app://callback",
+			CodeVerifier: testCodeVerifier,
+			ClientID:     "publicclient1",
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.AccessToken)
+		assert.NotEmpty(t, res.RefreshToken)
+		assert.Equal(t, "write", res.Scope)
+
+		// Codes can only be used once
+		_, err = exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeAuthorizationCode,
+			Code:         code,
+			CodeVerifier: testCodeVerifier,
+			ClientID:     "publicclient1",
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*tokenError).code)
+	})
+	t.Run("wrong code verifier", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		code := getTestAuthorizationCode(t, authReq)
+
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeAuthorizationCode,
+			Code:         code,
+			CodeVerifier: "wrongwrongwrongwrongwrongwrongwrongwrongwrongwrong",
+			ClientID:     "publicclient1",
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*tokenError).code)
+	})
+	t.Run("code of another client", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		code := getTestAuthorizationCode(t, authReq)
+
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeAuthorizationCode,
+			Code:         code,
+			CodeVerifier: testCodeVerifier,
+			ClientID:     "confidentialclient2",
+			ClientSecret: "clientsecret",
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*tokenError).code)
+	})
+	t.Run("refresh token", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		res, err := exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeRefreshToken,
+			RefreshToken: "refreshtoken1",
+			ClientID:     "publicclient1",
+		})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.AccessToken)
+		assert.NotEqual(t, "refreshtoken1", res.RefreshToken)
+		assert.Equal(t, "read", res.Scope)
+
+		// The old refresh token is invalid after it was used once
+		_, err = exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeRefreshToken,
+			RefreshToken: "refreshtoken1",
+			ClientID:     "publicclient1",
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_grant", err.(*tokenError).code)
+	})
+	t.Run("refresh token used in parallel", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		// Both requests get the grant before any of them rotated the refresh token
+		first, err := models.GetOAuth2GrantByRefreshToken(s, "refreshtoken1")
+		assert.NoError(t, err)
+		second, err := models.GetOAuth2GrantByRefreshToken(s, "refreshtoken1")
+		assert.NoError(t, err)
+
+		_, err = first.RotateRefreshToken(s)
+		assert.NoError(t, err)
+		_, err = second.RotateRefreshToken(s)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrOAuth2GrantDoesNotExist(err))
+	})
+	t.Run("wrong client secret", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeRefreshToken,
+			RefreshToken: "refreshtoken2",
+			ClientID:     "confidentialclient2",
+			ClientSecret: "wrong",
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "invalid_client", err.(*tokenError).code)
+	})
+}
+
+func TestRevokeToken(t *testing.T) {
+	t.Run("refresh token", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := revokeToken(s, &RevocationRequest{
+			Token:    "refreshtoken1",
+			ClientID: "publicclient1",
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "oauth2_grants", map[string]interface{}{
+			"id": 1,
+		})
+	})
+	t.Run("access token", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		res, err := exchangeToken(s, &TokenRequest{
+			GrantType:    grantTypeRefreshToken,
+			RefreshToken: "refreshtoken1",
+			ClientID:     "publicclient1",
+		})
+		assert.NoError(t, err)
+
+		err = revokeToken(s, &RevocationRequest{
+			Token:    res.AccessToken,
+			ClientID: "publicclient1",
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "oauth2_grants", map[string]interface{}{
+			"id": 1,
+		})
+	})
+	t.Run("token of another client", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := revokeToken(s, &RevocationRequest{
+			Token:        "refreshtoken1",
+			ClientID:     "confidentialclient2",
+			ClientSecret: "clientsecret",
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "oauth2_grants", map[string]interface{}{
+			"id": 1,
+		}, false)
+	})
+}
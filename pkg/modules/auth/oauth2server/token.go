@@ -0,0 +1,200 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package oauth2server
+
+import (
+	"net/http"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/auth"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/dgrijalva/jwt-go"
+	"xorm.io/xorm"
+)
+
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+)
+
+// tokenError is an error of the token or revocation endpoint as specified in https://tools.ietf.org/html/rfc6749#section-5.2
+// These endpoints are used by third-party clients which expect this format instead of the usual vikunja errors.
+type tokenError struct {
+	code        string
+	description string
+	status      int
+}
+
+func (err *tokenError) Error() string {
+	return err.code + ": " + err.description
+}
+
+func newInvalidGrantError(description string) *tokenError {
+	return &tokenError{code: "invalid_grant", description: description, status: http.StatusBadRequest}
+}
+
+// authenticateClient checks the client id and, for confidential clients, the secret of a token or revocation request.
+func authenticateClient(s *xorm.Session, clientID, secret string) (client *models.OAuth2Client, err error) {
+	client, err = models.GetOAuth2ClientByClientID(s, clientID)
+	if err != nil {
+		if models.IsErrOAuth2ClientDoesNotExist(err) {
+			return nil, &tokenError{code: "invalid_client", description: "The client does not exist.", status: http.StatusUnauthorized}
+		}
+		return nil, err
+	}
+
+	if !client.CheckSecret(secret) {
+		return nil, &tokenError{code: "invalid_client", description: "The client secret is invalid.", status: http.StatusUnauthorized}
+	}
+
+	return
+}
+
+// exchangeToken issues a new access and refresh token for an authorization code or a refresh token.
+func exchangeToken(s *xorm.Session, req *TokenRequest) (res *TokenResponse, err error) {
+	client, err := authenticateClient(s, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	var grant *models.OAuth2Grant
+	var refreshToken string
+
+	switch req.GrantType {
+	case grantTypeAuthorizationCode:
+		ac, err := redeemAuthorizationCode(req.Code)
+		if err != nil {
+			return nil, err
+		}
+		if ac == nil || ac.ClientID != client.ID {
+			return nil, newInvalidGrantError("The authorization code is invalid or expired.")
+		}
+		if req.RedirectURI != "" && req.RedirectURI != ac.RedirectURI {
+			return nil, newInvalidGrantError("The redirect uri does not match the one used to request the authorization code.")
+		}
+		if !verifyCodeChallenge(ac.CodeChallenge, req.CodeVerifier) {
+			return nil, newInvalidGrantError("The code verifier does not match the code challenge.")
+		}
+
+		grant, refreshToken, err = models.CreateOAuth2Grant(s, client, ac.UserID, ac.Scopes)
+		if err != nil {
+			return nil, err
+		}
+	case grantTypeRefreshToken:
+		grant, err = models.GetOAuth2GrantByRefreshToken(s, req.RefreshToken)
+		if err != nil && !models.IsErrOAuth2GrantDoesNotExist(err) {
+			return nil, err
+		}
+		if err != nil || grant.ClientID != client.ID {
+			return nil, newInvalidGrantError("The refresh token is invalid or was revoked.")
+		}
+
+		refreshToken, err = grant.RotateRefreshToken(s)
+		if models.IsErrOAuth2GrantDoesNotExist(err) {
+			return nil, newInvalidGrantError("The refresh token is invalid or was revoked.")
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, &tokenError{code: "unsupported_grant_type", description: "Only authorization_code and refresh_token are supported.", status: http.StatusBadRequest}
+	}
+
+	u, err := user.GetUserWithEmail(s, &user.User{ID: grant.UserID})
+	if err != nil {
+		if user.IsErrUserDoesNotExist(err) {
+			return nil, newInvalidGrantError("The user does not exist anymore.")
+		}
+		return nil, err
+	}
+	if !u.IsActive {
+		return nil, newInvalidGrantError("The user is disabled.")
+	}
+
+	accessToken, err := auth.NewOAuth2AccessToken(u, grant, accessTokenLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "bearer",
+		ExpiresIn:    int64(accessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        grant.Scope,
+	}, nil
+}
+
+// getGrantFromToken returns the grant an access or refresh token belongs to or nil if the token is invalid.
+func getGrantFromToken(s *xorm.Session, token string) (grant *models.OAuth2Grant, err error) {
+	grant, err = models.GetOAuth2GrantByRefreshToken(s, token)
+	if err == nil {
+		return grant, nil
+	}
+	if !models.IsErrOAuth2GrantDoesNotExist(err) {
+		return nil, err
+	}
+
+	// The token might be an access token
+	t, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(config.ServiceJWTSecret.GetString()), nil
+	})
+	if err != nil || !t.Valid {
+		return nil, nil
+	}
+
+	claims, is := t.Claims.(jwt.MapClaims)
+	if !is {
+		return nil, nil
+	}
+	grantID, is := claims["oauth2_grant_id"].(float64)
+	if !is {
+		return nil, nil
+	}
+
+	grant, err = models.GetOAuth2GrantByID(s, int64(grantID))
+	if models.IsErrOAuth2GrantDoesNotExist(err) {
+		return nil, nil
+	}
+	return grant, err
+}
+
+// revokeToken removes the grant an access or refresh token belongs to. Invalid tokens are ignored as specified in
+// https://tools.ietf.org/html/rfc7009#section-2.2
+func revokeToken(s *xorm.Session, req *RevocationRequest) (err error) {
+	client, err := authenticateClient(s, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	grant, err := getGrantFromToken(s, req.Token)
+	if err != nil {
+		return err
+	}
+	if grant == nil || grant.ClientID != client.ID {
+		return nil
+	}
+
+	_, err = s.
+		Where("id = ?", grant.ID).
+		Delete(&models.OAuth2Grant{})
+	return
+}
@@ -49,6 +49,7 @@ type Provider struct {
 	AuthURL        string         `json:"auth_url"`
 	ClientID       string         `json:"client_id"`
 	ClientSecret   string         `json:"-"`
+	GroupsClaim    string         `json:"-"`
 	OpenIDProvider *oidc.Provider `json:"-"`
 	Oauth2Config   *oauth2.Config `json:"-"`
 }
@@ -143,6 +144,22 @@ func HandleCallback(c echo.Context) error {
 		return err
 	}
 
+	// Sync the user's teams if the provider is configured to send groups
+	if provider.GroupsClaim != "" {
+		rawClaims := make(map[string]interface{})
+		err = idToken.Claims(&rawClaims)
+		if err != nil {
+			_ = s.Rollback()
+			return err
+		}
+
+		err = syncUserTeams(s, u, idToken.Issuer, getGroupsFromClaims(rawClaims, provider.GroupsClaim))
+		if err != nil {
+			_ = s.Rollback()
+			return err
+		}
+	}
+
 	err = s.Commit()
 	if err != nil {
 		return err
@@ -208,13 +225,8 @@ func getOrCreateUser(s *xorm.Session, cl *claims, issuer, subject string) (u *us
 		if cl.Name != u.Name {
 			u.Name = cl.Name
 		}
-		u, err = user.UpdateUser(s, &user.User{
-			ID:      u.ID,
-			Email:   u.Email,
-			Name:    u.Name,
-			Issuer:  issuer,
-			Subject: subject,
-		})
+		// The user is updated as a whole to keep all other settings of the user
+		u, err = user.UpdateUser(s, u)
 		if err != nil {
 			return nil, err
 		}
@@ -20,6 +20,8 @@ import (
 	"testing"
 
 	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -93,4 +95,133 @@ func TestGetOrCreateUser(t *testing.T) {
 			"email": cl.Email,
 		}, false)
 	})
+	t.Run("existing user, keeps settings", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.
+			Where("id = ?", 14).
+			Cols("discoverable_by_name", "discoverable_by_email").
+			Update(&user.User{DiscoverableByName: true, DiscoverableByEmail: true})
+		assert.NoError(t, err)
+
+		cl := &claims{
+			Email: "other-email-address@some.service.com",
+			Name:  "Some other name",
+		}
+		u, err := getOrCreateUser(s, cl, "https://some.service.com", "12345")
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "users", map[string]interface{}{
+			"id":                    u.ID,
+			"email":                 cl.Email,
+			"name":                  cl.Name,
+			"is_active":             true,
+			"discoverable_by_name":  true,
+			"discoverable_by_email": true,
+		}, false)
+	})
+}
+
+func TestGetGroupsFromClaims(t *testing.T) {
+	t.Run("list of groups", func(t *testing.T) {
+		groups := getGroupsFromClaims(map[string]interface{}{
+			"groups": []interface{}{"group1", "", 42, "group2"},
+		}, "groups")
+		assert.Equal(t, []string{"group1", "group2"}, groups)
+	})
+	t.Run("single group", func(t *testing.T) {
+		groups := getGroupsFromClaims(map[string]interface{}{
+			"groups": "group1",
+		}, "groups")
+		assert.Equal(t, []string{"group1"}, groups)
+	})
+	t.Run("claim missing", func(t *testing.T) {
+		groups := getGroupsFromClaims(map[string]interface{}{}, "groups")
+		assert.Empty(t, groups)
+	})
+}
+
+func TestSyncUserTeams(t *testing.T) {
+	u := &user.User{ID: 14}
+	issuer := "https://some.service.com"
+
+	t.Run("new group", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := syncUserTeams(s, u, issuer, []string{"vikunja-admins", "new-group"})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		team := &models.Team{}
+		exists, err := s.Where("external_id = ? AND issuer = ?", "new-group", issuer).Get(team)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, "new-group", team.Name)
+
+		db.AssertExists(t, "team_members", map[string]interface{}{
+			"team_id": team.ID,
+			"user_id": 14,
+		}, false)
+		db.AssertExists(t, "team_members", map[string]interface{}{
+			"team_id": 14,
+			"user_id": 14,
+		}, false)
+	})
+	t.Run("existing group of another user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := syncUserTeams(s, &user.User{ID: 1}, issuer, []string{"vikunja-admins"})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "team_members", map[string]interface{}{
+			"team_id": 14,
+			"user_id": 1,
+		}, false)
+	})
+	t.Run("removed from group", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := syncUserTeams(s, u, issuer, []string{})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "team_members", map[string]interface{}{
+			"team_id": 14,
+			"user_id": 14,
+		})
+	})
+	t.Run("same group from another issuer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := syncUserTeams(s, u, "https://other.service.com", []string{"vikunja-admins"})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		// The membership in the team of the first issuer must stay untouched
+		db.AssertExists(t, "team_members", map[string]interface{}{
+			"team_id": 14,
+			"user_id": 14,
+		}, false)
+		db.AssertExists(t, "teams", map[string]interface{}{
+			"external_id": "vikunja-admins",
+			"issuer":      "https://other.service.com",
+		}, false)
+	})
 }
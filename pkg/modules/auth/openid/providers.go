@@ -109,6 +109,11 @@ func getProviderFromMap(pi map[interface{}]interface{}) (*Provider, error) {
 		ClientSecret: pi["clientsecret"].(string),
 	}
 
+	groupsClaim, is := pi["groupsclaim"].(string)
+	if is {
+		provider.GroupsClaim = groupsClaim
+	}
+
 	cl, is := pi["clientid"].(int)
 	if is {
 		provider.ClientID = strconv.Itoa(cl)
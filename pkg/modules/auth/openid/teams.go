@@ -0,0 +1,156 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package openid
+
+import (
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/xorm"
+)
+
+const maxGroupNameLength = 250
+
+// getGroupsFromClaims returns all group names contained in the configured groups claim.
+// Providers either send a list of groups or a single group as a string.
+func getGroupsFromClaims(rawClaims map[string]interface{}, groupsClaim string) (groups []string) {
+	switch g := rawClaims[groupsClaim].(type) {
+	case []interface{}:
+		for _, group := range g {
+			if name, is := group.(string); is && name != "" {
+				groups = append(groups, name)
+			}
+		}
+	case string:
+		if g != "" {
+			groups = append(groups, g)
+		}
+	}
+
+	return
+}
+
+// syncUserTeams makes sure the user is a member of exactly the teams of the issuer which correspond to the groups
+// passed. Teams which do not exist yet are created and marked as externally managed.
+func syncUserTeams(s *xorm.Session, u *user.User, issuer string, groups []string) (err error) {
+
+	wanted := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		if len(g) > maxGroupNameLength {
+			log.Debugf("Ignoring OpenID group %s of user %d because its name is too long", g, u.ID)
+			continue
+		}
+		wanted[g] = true
+	}
+
+	// Get all teams of the issuer the user should be a member of
+	teams := make(map[string]*models.Team, len(wanted))
+	if len(wanted) > 0 {
+		externalIDs := make([]string, 0, len(wanted))
+		for g := range wanted {
+			externalIDs = append(externalIDs, g)
+		}
+
+		existing := []*models.Team{}
+		err = s.
+			Where("issuer = ?", issuer).
+			In("external_id", externalIDs).
+			Find(&existing)
+		if err != nil {
+			return
+		}
+		for _, t := range existing {
+			teams[t.ExternalID] = t
+		}
+	}
+
+	// Create all teams we have not seen before
+	for g := range wanted {
+		if _, exists := teams[g]; exists {
+			continue
+		}
+
+		t := &models.Team{
+			Name:        g,
+			ExternalID:  g,
+			Issuer:      issuer,
+			CreatedByID: u.ID,
+		}
+		_, err = s.Insert(t)
+		if err != nil {
+			return
+		}
+		teams[g] = t
+
+		err = events.Dispatch(&models.TeamCreatedEvent{
+			Team: t,
+			Doer: u,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	// Get all memberships the user currently has in teams of that issuer
+	memberships := []*models.TeamMember{}
+	err = s.
+		Select("team_members.*").
+		Join("INNER", "teams", "teams.id = team_members.team_id").
+		Where("team_members.user_id = ? AND teams.issuer = ?", u.ID, issuer).
+		Find(&memberships)
+	if err != nil {
+		return
+	}
+
+	isMember := make(map[int64]bool, len(memberships))
+	for _, m := range memberships {
+		isMember[m.TeamID] = true
+	}
+
+	wantedTeamIDs := make(map[int64]bool, len(teams))
+	for _, t := range teams {
+		wantedTeamIDs[t.ID] = true
+		if isMember[t.ID] {
+			continue
+		}
+
+		_, err = s.Insert(&models.TeamMember{
+			TeamID: t.ID,
+			UserID: u.ID,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	// Remove the user from all teams they are no longer part of
+	for _, m := range memberships {
+		if wantedTeamIDs[m.TeamID] {
+			continue
+		}
+
+		_, err = s.
+			Where("team_id = ? AND user_id = ?", m.TeamID, u.ID).
+			Delete(&models.TeamMember{})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -17,6 +17,8 @@
 package keyvalue
 
 import (
+	"time"
+
 	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/modules/keyvalue/memory"
 	"code.vikunja.io/api/pkg/modules/keyvalue/redis"
@@ -25,8 +27,11 @@ import (
 // Storage defines an interface for saving key-value pairs
 type Storage interface {
 	Put(key string, value interface{}) (err error)
+	PutWithExpiration(key string, value interface{}, expiration time.Duration) (err error)
 	Get(key string) (value interface{}, exists bool, err error)
+	GetWithValue(key string, value interface{}) (exists bool, err error)
 	Del(key string) (err error)
+	DelIfExists(key string) (existed bool, err error)
 	IncrBy(key string, update int64) (err error)
 	DecrBy(key string, update int64) (err error)
 }
@@ -50,16 +55,32 @@ func Put(key string, value interface{}) error {
 	return store.Put(key, value)
 }
 
+// PutWithExpiration puts a value in the storage backend which is removed once the expiration passed
+func PutWithExpiration(key string, value interface{}, expiration time.Duration) error {
+	return store.PutWithExpiration(key, value, expiration)
+}
+
 // Get returns a value from a storage backend
 func Get(key string) (value interface{}, exists bool, err error) {
 	return store.Get(key)
 }
 
+// GetWithValue gets a value from a storage backend and stores it in the value parameter, which needs to be a pointer.
+func GetWithValue(key string, value interface{}) (exists bool, err error) {
+	return store.GetWithValue(key, value)
+}
+
 // Del removes a save value from a storage backend
 func Del(key string) (err error) {
 	return store.Del(key)
 }
 
+// DelIfExists removes a saved value from a storage backend and returns whether it existed.
+// When called concurrently for the same key, only one of the callers gets true.
+func DelIfExists(key string) (existed bool, err error) {
+	return store.DelIfExists(key)
+}
+
 // IncrBy increases a value at key by the amount in update
 func IncrBy(key string, update int64) (err error) {
 	return store.IncrBy(key, update)
@@ -17,29 +17,73 @@
 package memory
 
 import (
+	"reflect"
 	"sync"
+	"time"
 
 	e "code.vikunja.io/api/pkg/modules/keyvalue/error"
 )
 
+// How often expired values are removed from the memory storage
+const sweepInterval = time.Minute
+
 // Storage is the memory implementation of a storage backend
 type Storage struct {
 	store map[string]interface{}
-	mutex sync.Mutex
+	// The time when a value expires for all values which were put with an expiration
+	expirations map[string]time.Time
+	lastSweep   time.Time
+	mutex       sync.Mutex
 }
 
 // NewStorage creates a new memory storage
 func NewStorage() *Storage {
 	s := &Storage{}
 	s.store = make(map[string]interface{})
+	s.expirations = make(map[string]time.Time)
 	return s
 }
 
+// expire removes the value saved at key if it is expired. The mutex needs to be locked when calling this.
+func (s *Storage) expire(key string, now time.Time) {
+	expiration, has := s.expirations[key]
+	if has && !expiration.After(now) {
+		delete(s.store, key)
+		delete(s.expirations, key)
+	}
+}
+
+// sweep removes all expired values so values which are never retrieved again don't stay in memory forever.
+// It only checks all values once per sweep interval. The mutex needs to be locked when calling this.
+func (s *Storage) sweep(now time.Time) {
+	if s.lastSweep.Add(sweepInterval).After(now) {
+		return
+	}
+	s.lastSweep = now
+
+	for key := range s.expirations {
+		s.expire(key, now)
+	}
+}
+
 // Put puts a value into the memory storage
 func (s *Storage) Put(key string, value interface{}) (err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.store[key] = value
+	delete(s.expirations, key)
+	return nil
+}
+
+// PutWithExpiration puts a value into the memory storage which is removed once the expiration passed
+func (s *Storage) PutWithExpiration(key string, value interface{}, expiration time.Duration) (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+	s.store[key] = value
+	s.expirations[key] = now.Add(expiration)
 	return nil
 }
 
@@ -48,24 +92,67 @@ func (s *Storage) Get(key string) (value interface{}, exists bool, err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.expire(key, time.Now())
 	value, exists = s.store[key]
 	return
 }
 
+// GetWithValue retrieves a saved value from memory storage and stores it in the value parameter.
+// The saved value needs to have the same type as the value parameter points to or be a pointer to that type.
+func (s *Storage) GetWithValue(key string, value interface{}) (exists bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expire(key, time.Now())
+	v, exists := s.store[key]
+	if !exists {
+		return false, nil
+	}
+
+	target := reflect.ValueOf(value)
+	if target.Kind() != reflect.Ptr {
+		return false, &e.ErrValueHasWrongType{Key: key, ExpectedValue: "pointer"}
+	}
+
+	saved := reflect.ValueOf(v)
+	if saved.Kind() == reflect.Ptr && saved.Type() != target.Elem().Type() {
+		saved = saved.Elem()
+	}
+	if !saved.Type().AssignableTo(target.Elem().Type()) {
+		return false, &e.ErrValueHasWrongType{Key: key, ExpectedValue: target.Elem().Type().String()}
+	}
+
+	target.Elem().Set(saved)
+	return true, nil
+}
+
 // Del removes a saved value from a memory storage
 func (s *Storage) Del(key string) (err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	delete(s.store, key)
+	delete(s.expirations, key)
 	return nil
 }
 
+// DelIfExists removes a saved value from a memory storage and returns whether it existed
+func (s *Storage) DelIfExists(key string) (existed bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expire(key, time.Now())
+	_, existed = s.store[key]
+	delete(s.store, key)
+	return existed, nil
+}
+
 // IncrBy increases the value saved at key by the amount provided through update
 // It assumes the value saved for the key either does not exist or has a type of int64
 func (s *Storage) IncrBy(key string, update int64) (err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.expire(key, time.Now())
 	_, exists := s.store[key]
 	if !exists {
 		s.store[key] = int64(0)
@@ -85,6 +172,7 @@ func (s *Storage) DecrBy(key string, update int64) (err error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.expire(key, time.Now())
 	_, exists := s.store[key]
 	if !exists {
 		s.store[key] = int64(0)
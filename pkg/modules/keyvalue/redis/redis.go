@@ -19,6 +19,8 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"time"
 
 	"code.vikunja.io/api/pkg/red"
 	"github.com/go-redis/redis/v8"
@@ -48,6 +50,16 @@ func (s *Storage) Put(key string, value interface{}) (err error) {
 	return s.client.Set(context.Background(), key, v, 0).Err()
 }
 
+// PutWithExpiration puts a value into redis which redis removes once the expiration passed
+func (s *Storage) PutWithExpiration(key string, value interface{}, expiration time.Duration) (err error) {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.client.Set(context.Background(), key, v, expiration).Err()
+}
+
 // Get retrieves a saved value from redis
 func (s *Storage) Get(key string) (value interface{}, exists bool, err error) {
 	b, err := s.client.Get(context.Background(), key).Bytes()
@@ -59,11 +71,31 @@ func (s *Storage) Get(key string) (value interface{}, exists bool, err error) {
 	return
 }
 
+// GetWithValue retrieves a saved value from redis and unmarshals it into the value parameter
+func (s *Storage) GetWithValue(key string, value interface{}) (exists bool, err error) {
+	b, err := s.client.Get(context.Background(), key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	err = json.Unmarshal(b, value)
+	return true, err
+}
+
 // Del removed a value from redis
 func (s *Storage) Del(key string) (err error) {
 	return s.client.Del(context.Background(), key).Err()
 }
 
+// DelIfExists removes a value from redis and returns whether it existed
+func (s *Storage) DelIfExists(key string) (existed bool, err error) {
+	deleted, err := s.client.Del(context.Background(), key).Result()
+	return deleted > 0, err
+}
+
 // IncrBy increases the value saved at key by the amount provided through update
 func (s *Storage) IncrBy(key string, update int64) (err error) {
 	return s.client.IncrBy(context.Background(), key, update).Err()
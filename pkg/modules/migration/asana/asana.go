@@ -0,0 +1,331 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package asana
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// Migration represents the asana migration struct
+type Migration struct {
+	// If true, the imported lists are shared with write access with all assignees of their tasks.
+	// Otherwise only tasks assigned to the importing user keep their assignee.
+	ShareWithAssignees bool `json:"share_with_assignees" form:"share_with_assignees"`
+}
+
+// The title of the list for all tasks without a project
+const defaultListTitle = "Inbox"
+
+type reference struct {
+	GID   string `json:"gid"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type membership struct {
+	Project *reference `json:"project"`
+	Section *reference `json:"section"`
+}
+
+type story struct {
+	Type      string     `json:"type"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy *reference `json:"created_by"`
+}
+
+type attachment struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"download_url"`
+}
+
+type task struct {
+	GID         string        `json:"gid"`
+	Name        string        `json:"name"`
+	Notes       string        `json:"notes"`
+	Completed   bool          `json:"completed"`
+	CompletedAt *time.Time    `json:"completed_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	DueAt       *time.Time    `json:"due_at"`
+	DueOn       string        `json:"due_on"`
+	StartOn     string        `json:"start_on"`
+	Assignee    *reference    `json:"assignee"`
+	Memberships []*membership `json:"memberships"`
+	Projects    []*reference  `json:"projects"`
+	Tags        []*reference  `json:"tags"`
+	Subtasks    []*task       `json:"subtasks"`
+	Stories     []*story      `json:"stories"`
+	Attachments []*attachment `json:"attachments"`
+}
+
+type export struct {
+	Data []*task `json:"data"`
+}
+
+// Name is used to get the name of the asana migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/asana/status [get]
+func (m *Migration) Name() string {
+	return "asana"
+}
+
+func parseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", date, config.GetTimeZone())
+}
+
+// converter holds everything needed to find the lists, buckets and labels of tasks
+type converter struct {
+	namespace *models.NamespaceWithLists
+	lists     map[string]*models.List
+	buckets   map[*models.List]map[string]*models.Bucket
+	labels    map[string]*models.Label
+	comments  migration.TaskComments
+	bucketID  int64
+	status    *migration.Status
+}
+
+func (c *converter) getList(title string) *models.List {
+	list, exists := c.lists[title]
+	if !exists {
+		list = &models.List{Title: title}
+		c.lists[title] = list
+		c.buckets[list] = make(map[string]*models.Bucket)
+		c.namespace.Lists = append(c.namespace.Lists, list)
+	}
+	return list
+}
+
+func (c *converter) getBucketID(list *models.List, title string) int64 {
+	bucket, exists := c.buckets[list][title]
+	if !exists {
+		c.bucketID++
+		bucket = &models.Bucket{
+			ID:    c.bucketID,
+			Title: title,
+		}
+		c.buckets[list][title] = bucket
+		list.Buckets = append(list.Buckets, bucket)
+	}
+	return bucket.ID
+}
+
+// convertTask converts an asana task with all its subtasks and adds them to the list.
+// Subtasks are put in the list of their parent.
+func (c *converter) convertTask(t *task, list *models.List, bucketID int64) (vikunjaTask *models.Task, err error) {
+	vikunjaTask = &models.Task{
+		Title:       t.Name,
+		Description: t.Notes,
+		Done:        t.Completed,
+		Created:     t.CreatedAt.In(config.GetTimeZone()),
+		BucketID:    bucketID,
+	}
+
+	if t.CompletedAt != nil {
+		vikunjaTask.DoneAt = t.CompletedAt.In(config.GetTimeZone())
+	}
+
+	// A due date with time is more precise than one without
+	if t.DueAt != nil {
+		vikunjaTask.DueDate = t.DueAt.In(config.GetTimeZone())
+	} else {
+		vikunjaTask.DueDate, err = parseDate(t.DueOn)
+		if err != nil {
+			return nil, models.ErrInvalidMigrationFile{Reason: "invalid due date of task " + t.GID}
+		}
+	}
+	vikunjaTask.StartDate, err = parseDate(t.StartOn)
+	if err != nil {
+		return nil, models.ErrInvalidMigrationFile{Reason: "invalid start date of task " + t.GID}
+	}
+
+	if t.Assignee != nil && t.Assignee.Email != "" {
+		vikunjaTask.Assignees = []*user.User{{Email: t.Assignee.Email}}
+	}
+
+	for _, tag := range t.Tags {
+		label, exists := c.labels[tag.Name]
+		if !exists {
+			label = &models.Label{Title: tag.Name}
+			c.labels[tag.Name] = label
+		}
+		vikunjaTask.Labels = append(vikunjaTask.Labels, label)
+	}
+
+	for _, s := range t.Stories {
+		if s.Type != "comment" {
+			continue
+		}
+		c.comments[vikunjaTask] = append(c.comments[vikunjaTask], &models.TaskComment{
+			Comment: s.Text,
+			Created: s.CreatedAt.In(config.GetTimeZone()),
+		})
+	}
+
+	// The download urls come from the uploaded file, fetching them would let anyone make the server request
+	// arbitrary urls. The attachments are added as links to the description instead.
+	if len(t.Attachments) > 0 {
+		vikunjaTask.Description += "\n\n## Attachments\n"
+		for _, a := range t.Attachments {
+			if a.DownloadURL == "" {
+				vikunjaTask.Description += "\n* " + a.Name
+			} else {
+				vikunjaTask.Description += "\n* [" + a.Name + "](" + a.DownloadURL + ")"
+			}
+			c.status.AddWarning("Attachment " + a.Name + " of task " + t.Name + " was not imported, it was added as a link to the task description instead.")
+		}
+	}
+
+	list.Tasks = append(list.Tasks, vikunjaTask)
+
+	for _, st := range t.Subtasks {
+		subtask, err := c.convertTask(st, list, bucketID)
+		if err != nil {
+			return nil, err
+		}
+		if vikunjaTask.RelatedTasks == nil {
+			vikunjaTask.RelatedTasks = make(models.RelatedTaskMap)
+		}
+		vikunjaTask.RelatedTasks[models.RelationKindSubtask] = append(vikunjaTask.RelatedTasks[models.RelationKindSubtask], subtask)
+	}
+
+	return vikunjaTask, nil
+}
+
+// convertAsanaToVikunja converts the tasks of asana json exports into the vikunja structure.
+// Projects are converted to lists, sections to buckets, tags to labels and comments to comments.
+// Assignees only have their email set.
+func convertAsanaToVikunja(tasks []*task, status *migration.Status) (fullVikunjaHierachie []*models.NamespaceWithLists, comments migration.TaskComments, err error) {
+	c := &converter{
+		namespace: &models.NamespaceWithLists{
+			Namespace: models.Namespace{
+				Title: "Imported from Asana",
+			},
+		},
+		lists:    make(map[string]*models.List),
+		buckets:  make(map[*models.List]map[string]*models.Bucket),
+		labels:   make(map[string]*models.Label),
+		comments: make(migration.TaskComments),
+		status:   status,
+	}
+
+	// Subtasks might be part of the export twice, nested in their parent and as their own task
+	subtasks := make(map[string]bool)
+	var collectSubtasks func(t *task)
+	collectSubtasks = func(t *task) {
+		for _, st := range t.Subtasks {
+			subtasks[st.GID] = true
+			collectSubtasks(st)
+		}
+	}
+	for _, t := range tasks {
+		collectSubtasks(t)
+	}
+
+	for _, t := range tasks {
+		if subtasks[t.GID] {
+			continue
+		}
+
+		var list *models.List
+		var bucketID int64
+		switch {
+		case len(t.Memberships) > 0 && t.Memberships[0].Project != nil:
+			list = c.getList(t.Memberships[0].Project.Name)
+			if t.Memberships[0].Section != nil {
+				bucketID = c.getBucketID(list, t.Memberships[0].Section.Name)
+			}
+		case len(t.Projects) > 0:
+			list = c.getList(t.Projects[0].Name)
+		default:
+			list = c.getList(defaultListTitle)
+		}
+
+		_, err = c.convertTask(t, list, bucketID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return []*models.NamespaceWithLists{c.namespace}, c.comments, nil
+}
+
+// Migrate imports all tasks from an asana json export
+// @Summary Import all tasks from an asana json export
+// @Description Imports all tasks from the json export of an asana project. Projects are converted to lists, sections to kanban buckets, tags to labels. Subtasks and comments are imported as well, attachments are added as links to the task description. Assignees are matched by their email address with existing users who already have access to one of your lists. Other users are only assigned and get write access to the imported lists if share_with_assignees is true.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The json export of an asana project."
+// @Param share_with_assignees formData bool false "If true, the imported lists are shared with the assignees of their tasks. Otherwise only tasks assigned to you keep their assignee."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not an asana export."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/asana/migrate [put]
+func (m *Migration) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[Asana Migration] Starting migration for user %d", u.ID)
+
+	e := &export{}
+	err := json.NewDecoder(io.NewSectionReader(file, 0, size)).Decode(e)
+	if err != nil || e.Data == nil {
+		return models.ErrInvalidMigrationFile{Reason: "not an asana export"}
+	}
+
+	fullVikunjaHierachie, comments, err := convertAsanaToVikunja(e.Data, status)
+	if err != nil {
+		return err
+	}
+
+	err = migration.MatchAssigneesByEmail(fullVikunjaHierachie, u, m.ShareWithAssignees)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Asana Migration] Start inserting data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	err = migration.InsertTaskComments(comments, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Asana Migration] Migration done for user %d", u.ID)
+
+	return nil
+}
@@ -0,0 +1,175 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package asana
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+const testExport = `{
+  "data": [
+    {
+      "gid": "1",
+      "name": "Plan the launch",
+      "notes": "Some notes",
+      "completed": false,
+      "completed_at": null,
+      "created_at": "2021-04-01T10:00:00.000Z",
+      "due_at": null,
+      "due_on": "2021-04-10",
+      "start_on": "2021-04-05",
+      "assignee": {"gid": "100", "name": "User 1", "email": "user1@example.com"},
+      "memberships": [{"project": {"gid": "10", "name": "Launch"}, "section": {"gid": "20", "name": "Doing"}}],
+      "tags": [{"gid": "30", "name": "marketing"}],
+      "subtasks": [
+        {
+          "gid": "2",
+          "name": "Write the announcement",
+          "completed": true,
+          "completed_at": "2021-04-04T15:00:00.000Z",
+          "created_at": "2021-04-02T10:00:00.000Z",
+          "due_at": "2021-04-06T12:00:00.000Z",
+          "memberships": [],
+          "tags": [{"gid": "30", "name": "marketing"}]
+        }
+      ],
+      "stories": [
+        {"type": "system", "text": "User 1 added this task", "created_at": "2021-04-01T10:00:00.000Z"},
+        {"type": "comment", "text": "Let's do this", "created_at": "2021-04-01T11:00:00.000Z", "created_by": {"gid": "100", "name": "User 1"}}
+      ]
+    },
+    {
+      "gid": "2",
+      "name": "Write the announcement",
+      "completed": true,
+      "created_at": "2021-04-02T10:00:00.000Z",
+      "memberships": []
+    },
+    {
+      "gid": "3",
+      "name": "Without a project",
+      "completed": false,
+      "created_at": "2021-04-03T10:00:00.000Z",
+      "memberships": [],
+      "attachments": [
+        {"gid": "40", "name": "metadata.txt", "download_url": "http://169.254.169.254/latest/meta-data/"}
+      ]
+    }
+  ]
+}`
+
+func TestConvertAsanaToVikunja(t *testing.T) {
+	config.InitConfig()
+
+	parse := func(date string) time.Time {
+		d, err := time.Parse(time.RFC3339, date)
+		assert.NoError(t, err)
+		return d.In(config.GetTimeZone())
+	}
+	parseDay := func(date string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", date, config.GetTimeZone())
+		assert.NoError(t, err)
+		return d
+	}
+
+	e := &export{}
+	err := json.Unmarshal([]byte(testExport), e)
+	assert.NoError(t, err)
+
+	marketing := &models.Label{Title: "marketing"}
+	subtask := &models.Task{
+		Title:    "Write the announcement",
+		Done:     true,
+		DoneAt:   parse("2021-04-04T15:00:00Z"),
+		Created:  parse("2021-04-02T10:00:00Z"),
+		DueDate:  parse("2021-04-06T12:00:00Z"),
+		BucketID: 1,
+		Labels:   []*models.Label{marketing},
+	}
+
+	expectedHierachie := []*models.NamespaceWithLists{
+		{
+			Namespace: models.Namespace{
+				Title: "Imported from Asana",
+			},
+			Lists: []*models.List{
+				{
+					Title: "Launch",
+					Buckets: []*models.Bucket{
+						{ID: 1, Title: "Doing"},
+					},
+					Tasks: []*models.Task{
+						{
+							Title:       "Plan the launch",
+							Description: "Some notes",
+							Created:     parse("2021-04-01T10:00:00Z"),
+							DueDate:     parseDay("2021-04-10"),
+							StartDate:   parseDay("2021-04-05"),
+							BucketID:    1,
+							Assignees:   []*user.User{{Email: "user1@example.com"}},
+							Labels:      []*models.Label{marketing},
+							RelatedTasks: models.RelatedTaskMap{
+								models.RelationKindSubtask: {subtask},
+							},
+						},
+						subtask,
+					},
+				},
+				{
+					Title: "Inbox",
+					Tasks: []*models.Task{
+						{
+							Title:       "Without a project",
+							Description: "\n\n## Attachments\n\n* [metadata.txt](http://169.254.169.254/latest/meta-data/)",
+							Created:     parse("2021-04-03T10:00:00Z"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	status := &migration.Status{}
+	hierachie, comments, err := convertAsanaToVikunja(e.Data, status)
+	assert.NoError(t, err)
+	// Attachments are never downloaded from the urls of the uploaded file
+	assert.Len(t, status.Warnings, 1)
+	if diff, equal := messagediff.PrettyDiff(hierachie, expectedHierachie); !equal {
+		t.Errorf("converted asana data = %v, want %v, diff: %v", hierachie, expectedHierachie, diff)
+	}
+
+	expectedComments := migration.TaskComments{
+		hierachie[0].Lists[0].Tasks[0]: {
+			{
+				Comment: "Let's do this",
+				Created: parse("2021-04-01T11:00:00Z"),
+			},
+		},
+	}
+	if diff, equal := messagediff.PrettyDiff(comments, expectedComments); !equal {
+		t.Errorf("converted asana comments = %v, want %v, diff: %v", comments, expectedComments, diff)
+	}
+}
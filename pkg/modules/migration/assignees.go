@@ -0,0 +1,168 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/xorm"
+)
+
+// MatchAssigneesByEmail replaces the assignees of all tasks in the structure with the existing users who have the same
+// email address. Migrators only know the email addresses of assignees, so they should only set the email of
+// every assignee.
+// To not make the import a way to find out who has an account, only users who already have access to at least one
+// of the lists of the importing user are matched. Because the imported lists are new, other users only get access to
+// them if shareLists is true, in which case every list is shared with the assignees of its tasks when the structure
+// is created. Otherwise only the importing user is kept as an assignee.
+// Assignees without a matching user are removed.
+func MatchAssigneesByEmail(str []*models.NamespaceWithLists, doer *user.User, shareLists bool) (err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	allowed := map[int64]bool{doer.ID: true}
+	if shareLists {
+		uids, err := models.GetUserIDsSharingListsWithUser(s, doer)
+		if err != nil {
+			return err
+		}
+		for _, id := range uids {
+			allowed[id] = true
+		}
+	}
+
+	users := make(map[string]*user.User)
+	matchAssignees := func(t *models.Task) error {
+		assignees := make([]*user.User, 0, len(t.Assignees))
+		for _, a := range t.Assignees {
+			if a.Email == "" {
+				continue
+			}
+
+			u, exists := users[a.Email]
+			if !exists {
+				found, err := user.GetUserWithEmail(s, &user.User{Email: a.Email})
+				if err != nil && !user.IsErrUserDoesNotExist(err) {
+					return err
+				}
+				if err == nil && allowed[found.ID] {
+					u = found
+				} else {
+					log.Debugf("[creating structure] No user with email %s the importing user shares a list with exists, not assigning it", a.Email)
+				}
+				users[a.Email] = u
+			}
+
+			if u != nil {
+				assignees = append(assignees, u)
+			}
+		}
+		t.Assignees = assignees
+		return nil
+	}
+
+	for _, n := range str {
+		for _, l := range n.Lists {
+			for _, t := range l.Tasks {
+				err = matchAssignees(t)
+				if err != nil {
+					return err
+				}
+				for _, related := range t.RelatedTasks {
+					for _, rt := range related {
+						err = matchAssignees(rt)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Assignees need access to the list of their task. Since the list was just created,
+// it is shared with all assignees of its tasks. MatchAssigneesByEmail only keeps other users than the importing one
+// as assignees if the importing user chose to share the lists with them.
+func shareListWithAssignees(s *xorm.Session, l *models.List, tasks []*models.Task, doer *user.User) (err error) {
+	shared := make(map[int64]bool)
+	share := func(t *models.Task) error {
+		for _, a := range t.Assignees {
+			if a.ID == doer.ID || shared[a.ID] {
+				continue
+			}
+
+			lu := &models.ListUser{
+				ListID:   l.ID,
+				Username: a.Username,
+				Right:    models.RightWrite,
+			}
+			err := lu.Create(s, doer)
+			if err != nil && !models.IsErrUserAlreadyHasAccess(err) {
+				return err
+			}
+			shared[a.ID] = true
+
+			log.Debugf("[creating structure] Shared list %d with assignee %d", l.ID, a.ID)
+		}
+		return nil
+	}
+
+	for _, t := range tasks {
+		err = share(t)
+		if err != nil {
+			return
+		}
+		for _, related := range t.RelatedTasks {
+			for _, rt := range related {
+				err = share(rt)
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// onlyShowAssigneeNames replaces all assignees in the structure with only their display name.
+// The structure of a dry run is shown to the importing user who should not see any account details of the assignees.
+func onlyShowAssigneeNames(str []*models.NamespaceWithLists) {
+	hide := func(t *models.Task) {
+		for i, a := range t.Assignees {
+			t.Assignees[i] = &user.User{Name: a.GetName()}
+		}
+	}
+
+	for _, n := range str {
+		for _, l := range n.Lists {
+			for _, t := range l.Tasks {
+				hide(t)
+				for _, related := range t.RelatedTasks {
+					for _, rt := range related {
+						hide(rt)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchAssigneesByEmail(t *testing.T) {
+	u := &user.User{ID: 1}
+	getStructure := func() []*models.NamespaceWithLists {
+		return []*models.NamespaceWithLists{
+			{
+				Lists: []*models.List{
+					{
+						Tasks: []*models.Task{
+							{
+								Title: "Task",
+								Assignees: []*user.User{
+									{Email: "user1@example.com"},
+									{Email: "user2@example.com"},
+									{Email: "user15@some.service.com"},
+									{Email: "doesnotexist@example.com"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	assigneeIDs := func(str []*models.NamespaceWithLists) (ids []int64) {
+		for _, a := range str[0].Lists[0].Tasks[0].Assignees {
+			ids = append(ids, a.ID)
+		}
+		return
+	}
+
+	t.Run("without sharing", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		str := getStructure()
+		err := MatchAssigneesByEmail(str, u, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{1}, assigneeIDs(str))
+	})
+	t.Run("with sharing", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		str := getStructure()
+		err := MatchAssigneesByEmail(str, u, true)
+		assert.NoError(t, err)
+		// User 14 exists but does not share any list with user 1
+		assert.Equal(t, []int64{1, 2}, assigneeIDs(str))
+	})
+}
@@ -0,0 +1,72 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"time"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// TaskComments holds all comments of tasks which should be migrated. The tasks are the same ones passed to
+// InsertFromStructure, which sets their id once they were created.
+type TaskComments map[*models.Task][]*models.TaskComment
+
+// InsertTaskComments creates all comments for tasks which were previously created with InsertFromStructure.
+// Since the authors of the comments don't exist in Vikunja, the migrating user is set as author of all comments.
+// The original creation date of a comment is kept. Dry runs don't create any comments.
+func InsertTaskComments(comments TaskComments, u *user.User, status *Status) (err error) {
+
+	if status.IsDryRun() {
+		return nil
+	}
+
+	log.Debugf("[creating structure] Creating comments for %d tasks", len(comments))
+
+	s := db.NewSession()
+	defer s.Close()
+
+	for t, cs := range comments {
+		if t.ID == 0 {
+			log.Debugf("[creating structure] Task %s was not created, not creating its comments", t.Title)
+			continue
+		}
+
+		for _, c := range cs {
+			c.ID = 0
+			c.TaskID = t.ID
+			c.AuthorID = u.ID
+			if c.Created.IsZero() {
+				c.Created = time.Now()
+			}
+			c.Updated = c.Created
+
+			_, err = s.NoAutoTime().Insert(c)
+			if err != nil {
+				_ = s.Rollback()
+				return
+			}
+		}
+
+		log.Debugf("[creating structure] Created %d comments for task %d", len(cs), t.ID)
+	}
+
+	return s.Commit()
+}
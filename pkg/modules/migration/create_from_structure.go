@@ -26,15 +26,26 @@ import (
 	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/models"
 	"code.vikunja.io/api/pkg/user"
+	"xorm.io/xorm"
 )
 
 // InsertFromStructure takes a fully nested Vikunja data structure and a user and then creates everything for this user
 // (Namespaces, tasks, etc. Even attachments and relations.)
-func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err error) {
+// The progress is saved in the status as lists are created. If the status is a dry run, nothing is created and the
+// structure is only kept in the status.
+func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User, status *Status) (err error) {
+
+	if status.IsDryRun() {
+		onlyShowAssigneeNames(str)
+		status.Structure = append(status.Structure, str...)
+		return nil
+	}
 
 	log.Debugf("[creating structure] Creating %d namespaces", len(str))
 
 	labels := make(map[string]*models.Label)
+	// Relations are created once all tasks exist, a related task might be created after the task it is related to
+	var tasksWithRelations []*models.Task
 
 	s := db.NewSession()
 	defer s.Close()
@@ -55,6 +66,7 @@ func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err
 			// The tasks and bucket slices are going to be reset during the creation of the list so we rescue it here
 			// to be able to still loop over them aftere the list was created.
 			tasks := l.Tasks
+			var filesCreated int64
 			originalBuckets := l.Buckets
 			originalBackgroundInformation := l.BackgroundInformation
 			needsDefaultBucket := false
@@ -68,6 +80,12 @@ func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err
 
 			log.Debugf("[creating structure] Created list %d", l.ID)
 
+			err = shareListWithAssignees(s, l, tasks, user)
+			if err != nil {
+				_ = s.Rollback()
+				return
+			}
+
 			backgroundFile, is := originalBackgroundInformation.(*bytes.Buffer)
 			if is {
 				log.Debugf("[creating structure] Creating a background file for list %d", l.ID)
@@ -85,6 +103,7 @@ func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err
 				}
 
 				log.Debugf("[creating structure] Created a background file as new file %d for list %d", file.ID, l.ID)
+				filesCreated++
 			}
 
 			// Create all buckets
@@ -129,43 +148,7 @@ func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err
 
 				log.Debugf("[creating structure] Created task %d", t.ID)
 				if len(t.RelatedTasks) > 0 {
-					log.Debugf("[creating structure] Creating %d related task kinds", len(t.RelatedTasks))
-				}
-
-				// Create all relation for each task
-				for kind, tasks := range t.RelatedTasks {
-
-					if len(tasks) > 0 {
-						log.Debugf("[creating structure] Creating %d related tasks for kind %v", len(tasks), kind)
-					}
-
-					for _, rt := range tasks {
-						// First create the related tasks if they do not exist
-						if rt.ID == 0 {
-							rt.ListID = t.ListID
-							err = rt.Create(s, user)
-							if err != nil {
-								_ = s.Rollback()
-								return
-							}
-							log.Debugf("[creating structure] Created related task %d", rt.ID)
-						}
-
-						// Then create the relation
-						taskRel := &models.TaskRelation{
-							TaskID:       t.ID,
-							OtherTaskID:  rt.ID,
-							RelationKind: kind,
-						}
-						err = taskRel.Create(s, user)
-						if err != nil {
-							_ = s.Rollback()
-							return
-						}
-
-						log.Debugf("[creating structure] Created task relation between task %d and %d", t.ID, rt.ID)
-
-					}
+					tasksWithRelations = append(tasksWithRelations, t)
 				}
 
 				// Create all attachments for each task
@@ -183,6 +166,7 @@ func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err
 							return
 						}
 						log.Debugf("[creating structure] Created new attachment %d", a.ID)
+						filesCreated++
 					}
 				}
 
@@ -235,10 +219,66 @@ func InsertFromStructure(str []*models.NamespaceWithLists, user *user.User) (err
 
 			l.Tasks = tasks
 			l.Buckets = originalBuckets
+
+			status.addProgress(1, int64(len(tasks)), filesCreated)
 		}
 	}
 
+	var relatedTasksCreated int64
+	for _, t := range tasksWithRelations {
+		created, err := createTaskRelations(s, t, user)
+		if err != nil {
+			_ = s.Rollback()
+			return err
+		}
+		relatedTasksCreated += created
+	}
+	if relatedTasksCreated > 0 {
+		status.addProgress(0, relatedTasksCreated, 0)
+	}
+
 	log.Debugf("[creating structure] Done inserting new task structure")
 
 	return s.Commit()
 }
+
+// Creates all relations of a task. Related tasks which were not created yet because they are not part of a list
+// are created in the list of the task. Returns how many of those tasks were created.
+func createTaskRelations(s *xorm.Session, t *models.Task, user *user.User) (tasksCreated int64, err error) {
+	log.Debugf("[creating structure] Creating %d related task kinds for task %d", len(t.RelatedTasks), t.ID)
+
+	for kind, tasks := range t.RelatedTasks {
+
+		if len(tasks) > 0 {
+			log.Debugf("[creating structure] Creating %d related tasks for kind %v", len(tasks), kind)
+		}
+
+		for _, rt := range tasks {
+			// First create the related tasks if they do not exist
+			if rt.ID == 0 {
+				rt.ListID = t.ListID
+				err = rt.Create(s, user)
+				if err != nil {
+					return
+				}
+				log.Debugf("[creating structure] Created related task %d", rt.ID)
+				tasksCreated++
+			}
+
+			// Then create the relation
+			taskRel := &models.TaskRelation{
+				TaskID:       t.ID,
+				OtherTaskID:  rt.ID,
+				RelationKind: kind,
+			}
+			err = taskRel.Create(s, user)
+			if err != nil {
+				return
+			}
+
+			log.Debugf("[creating structure] Created task relation between task %d and %d", t.ID, rt.ID)
+		}
+	}
+
+	return
+}
@@ -111,7 +111,7 @@ func TestInsertFromStructure(t *testing.T) {
 				},
 			},
 		}
-		err := InsertFromStructure(testStructure, u)
+		err := InsertFromStructure(testStructure, u, nil)
 		assert.NoError(t, err)
 		db.AssertExists(t, "namespaces", map[string]interface{}{
 			"title":       testStructure[0].Namespace.Title,
@@ -132,4 +132,86 @@ func TestInsertFromStructure(t *testing.T) {
 		assert.NotEqual(t, 0, testStructure[0].Lists[0].Tasks[0].BucketID) // Should get the default bucket
 		assert.NotEqual(t, 0, testStructure[0].Lists[0].Tasks[6].BucketID) // Should get the default bucket
 	})
+	t.Run("related task in the same list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		subtask := &models.Task{
+			Title:    "Subtask created after its parent",
+			BucketID: 1,
+		}
+		testStructure := []*models.NamespaceWithLists{
+			{
+				Namespace: models.Namespace{
+					Title: "Test2",
+				},
+				Lists: []*models.List{
+					{
+						Title: "Testlist2",
+						Buckets: []*models.Bucket{
+							{
+								ID:    1,
+								Title: "Done",
+							},
+						},
+						Tasks: []*models.Task{
+							{
+								Title: "Parent task",
+								RelatedTasks: map[models.RelationKind][]*models.Task{
+									models.RelationKindSubtask: {subtask},
+								},
+							},
+							subtask,
+						},
+					},
+				},
+			},
+		}
+		err := InsertFromStructure(testStructure, u, nil)
+		assert.NoError(t, err)
+		parent := testStructure[0].Lists[0].Tasks[0]
+		assert.Equal(t, testStructure[0].Lists[0].Buckets[0].ID, subtask.BucketID)
+		s := db.NewSession()
+		count, err := s.Where("title = ?", subtask.Title).Count(&models.Task{})
+		s.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		db.AssertExists(t, "task_relations", map[string]interface{}{
+			"task_id":       parent.ID,
+			"other_task_id": subtask.ID,
+			"relation_kind": models.RelationKindSubtask,
+		}, false)
+	})
+	t.Run("dry run", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		testStructure := []*models.NamespaceWithLists{
+			{
+				Namespace: models.Namespace{
+					Title: "Dry run namespace",
+				},
+				Lists: []*models.List{
+					{
+						Title: "Dry run list",
+						Tasks: []*models.Task{
+							{
+								Title: "Dry run task",
+								Assignees: []*user.User{
+									{ID: 2, Username: "user2", Email: "user2@example.com"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		status := &Status{DryRun: true}
+		err := InsertFromStructure(testStructure, u, status)
+		assert.NoError(t, err)
+		assert.Equal(t, testStructure, status.Structure)
+		assert.Equal(t, &user.User{Name: "user2"}, status.Structure[0].Lists[0].Tasks[0].Assignees[0])
+		db.AssertMissing(t, "namespaces", map[string]interface{}{
+			"title": testStructure[0].Namespace.Title,
+		})
+		db.AssertMissing(t, "tasks", map[string]interface{}{
+			"title": testStructure[0].Lists[0].Tasks[0].Title,
+		})
+	})
 }
@@ -17,9 +17,10 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
 
-	"code.vikunja.io/api/pkg/models"
 	"code.vikunja.io/api/pkg/modules/migration"
 	user2 "code.vikunja.io/api/pkg/user"
 	"code.vikunja.io/web/handler"
@@ -39,6 +40,7 @@ type AuthURL struct {
 // RegisterRoutes registers all routes for migration
 func (mw *MigrationWeb) RegisterRoutes(g *echo.Group) {
 	ms := mw.MigrationStruct()
+	migration.RegisterMigrator(mw.MigrationStruct)
 	g.GET("/"+ms.Name()+"/auth", mw.AuthURL)
 	g.GET("/"+ms.Name()+"/status", mw.Status)
 	g.POST("/"+ms.Name()+"/migrate", mw.Migrate)
@@ -50,7 +52,7 @@ func (mw *MigrationWeb) AuthURL(c echo.Context) error {
 	return c.JSON(http.StatusOK, &AuthURL{URL: ms.AuthURL()})
 }
 
-// Migrate calls the migration method
+// Migrate starts the migration in the background or, for dry runs, returns the converted data
 func (mw *MigrationWeb) Migrate(c echo.Context) error {
 	ms := mw.MigrationStruct()
 
@@ -66,24 +68,37 @@ func (mw *MigrationWeb) Migrate(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "No or invalid model provided: "+err.Error())
 	}
 
-	// Do the migration
-	err = ms.Migrate(user)
+	if isDryRun(c) {
+		status := &migration.Status{DryRun: true}
+		err = ms.Migrate(user, status)
+		if err != nil {
+			return handler.HandleHTTPError(err, c)
+		}
+		return c.JSON(http.StatusOK, status.Structure)
+	}
+
+	// The migrator needs to be recreated with everything bound from the request when the job is run
+	migrator, err := json.Marshal(ms)
 	if err != nil {
 		return handler.HandleHTTPError(err, c)
 	}
 
-	err = migration.SetMigrationStatus(ms, user)
+	status, err := migration.StartMigrationJob(ms, user, migrator, 0)
 	if err != nil {
 		return handler.HandleHTTPError(err, c)
 	}
 
-	return c.JSON(http.StatusOK, models.Message{Message: "Everything was migrated successfully."})
+	return c.JSON(http.StatusOK, status)
 }
 
 // Status returns whether or not a user has already done this migration
 func (mw *MigrationWeb) Status(c echo.Context) error {
 	ms := mw.MigrationStruct()
 
+	return status(ms, c)
+}
+
+func status(ms migration.MigratorName, c echo.Context) error {
 	user, err := user2.GetCurrentUser(c)
 	if err != nil {
 		return handler.HandleHTTPError(err, c)
@@ -96,3 +111,38 @@ func (mw *MigrationWeb) Status(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, status)
 }
+
+// JobStatus returns the status of a single migration job
+// @Summary Get the status of a migration job
+// @Description Returns the progress of a migration job which runs in the background. Once the job is finished, this contains the final report with everything that was imported and, if the migration failed, the reason why.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Param job path int true "The id of the migration job."
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 404 {object} web.HTTPError "The migration job does not exist."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/jobs/{job} [get]
+func JobStatus(c echo.Context) error {
+	user, err := user2.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	jobID, err := strconv.ParseInt(c.Param("job"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid job id.")
+	}
+
+	status, err := migration.GetMigrationStatusByID(jobID, user)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+func isDryRun(c echo.Context) bool {
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dry_run"))
+	return dryRun
+}
@@ -0,0 +1,104 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/modules/migration"
+	user2 "code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// FileMigratorWeb holds the web handler for migrators which import an uploaded file
+type FileMigratorWeb struct {
+	MigrationStruct func() migration.FileMigrator
+}
+
+// RegisterRoutes registers all routes for a file migration
+func (fw *FileMigratorWeb) RegisterRoutes(g *echo.Group) {
+	ms := fw.MigrationStruct()
+	migration.RegisterFileMigrator(fw.MigrationStruct)
+	g.GET("/"+ms.Name()+"/status", fw.Status)
+	g.PUT("/"+ms.Name()+"/migrate", fw.Migrate)
+}
+
+// Migrate saves the uploaded file and starts the migration in the background or, for dry runs, returns the converted data
+func (fw *FileMigratorWeb) Migrate(c echo.Context) error {
+	ms := fw.MigrationStruct()
+
+	// Get the user from context
+	user, err := user2.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	// File migrators can have options, they are sent as form values together with the file
+	err = c.Bind(ms)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No or invalid model provided: "+err.Error())
+	}
+
+	file, err := c.FormFile("import")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No file provided. Please upload it as the form field 'import'.")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+	defer src.Close()
+
+	if isDryRun(c) {
+		status := &migration.Status{DryRun: true}
+		err = ms.Migrate(user, src, file.Size, status)
+		if err != nil {
+			return handler.HandleHTTPError(err, c)
+		}
+		return c.JSON(http.StatusOK, status.Structure)
+	}
+
+	// The migrator needs to be recreated with its options when the job is run
+	migrator, err := json.Marshal(ms)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	// The upload is kept until the job ran, the job deletes it afterwards
+	upload, err := files.CreateWithoutSizeLimit(src, file.Filename, uint64(file.Size), user, "")
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	status, err := migration.StartMigrationJob(ms, user, migrator, upload.ID)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// Status returns whether or not a user has already done this migration
+func (fw *FileMigratorWeb) Status(c echo.Context) error {
+	ms := fw.MigrationStruct()
+
+	return status(ms, c)
+}
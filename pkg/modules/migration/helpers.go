@@ -19,26 +19,53 @@ package migration
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/files"
 )
 
-// DownloadFile downloads a file and returns its contents
+// The maximum time a download of a single file may take
+const downloadTimeout = 5 * time.Minute
+
+// DownloadFile downloads a file and returns its contents.
+// Only use this for urls returned by the api of a service, never for urls from an uploaded file.
+// Files larger than the configured maximum file size are not downloaded.
 func DownloadFile(url string) (buf *bytes.Buffer, err error) {
+	maxSize, err := files.MaxSize()
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
-	hc := http.Client{}
+	hc := http.Client{Timeout: downloadTimeout}
 	resp, err := hc.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("downloading %s failed with status %d", url, resp.StatusCode)
+	}
+
 	buf = &bytes.Buffer{}
-	_, err = buf.ReadFrom(resp.Body)
-	return
+	// Reading one byte more than allowed tells us if the file is too large
+	_, err = buf.ReadFrom(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(buf.Len()) > maxSize {
+		return nil, files.ErrFileIsTooLarge{Size: uint64(buf.Len())}
+	}
+	return buf, nil
 }
 
 // DoPost makes a form encoded post request
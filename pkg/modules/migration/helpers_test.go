@@ -0,0 +1,61 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/files"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file":
+			_, _ = w.Write([]byte("content"))
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("a", 2048)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("normal", func(t *testing.T) {
+		buf, err := DownloadFile(server.URL + "/file")
+		assert.NoError(t, err)
+		assert.Equal(t, "content", buf.String())
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := DownloadFile(server.URL + "/nonexisting")
+		assert.Error(t, err)
+	})
+	t.Run("too large", func(t *testing.T) {
+		maxSize := config.FilesMaxSize.GetString()
+		defer config.FilesMaxSize.Set(maxSize)
+		config.FilesMaxSize.Set("1KB")
+
+		_, err := DownloadFile(server.URL + "/large")
+		assert.Error(t, err)
+		assert.True(t, files.IsErrFileIsTooLarge(err))
+	})
+}
@@ -0,0 +1,119 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ical
+
+import (
+	"io"
+
+	"code.vikunja.io/api/pkg/caldav"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// Migration represents the ical migration struct
+type Migration struct {
+	// If true, events are imported as tasks as well.
+	Events bool `json:"events" form:"events"`
+}
+
+// The title of the list if the calendar does not have a name
+const defaultListTitle = "Calendar"
+
+// Name is used to get the name of the ical migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/ical/status [get]
+func (m *Migration) Name() string {
+	return "ical"
+}
+
+// convertICalToVikunja puts all tasks of an ical file into one list named after the calendar
+func convertICalToVikunja(imp *caldav.ICalImport) (fullVikunjaHierachie []*models.NamespaceWithLists, err error) {
+	if len(imp.Tasks) == 0 {
+		return nil, models.ErrInvalidMigrationFile{Reason: "the file does not contain any tasks"}
+	}
+
+	title := imp.Title
+	if title == "" {
+		title = defaultListTitle
+	}
+
+	return []*models.NamespaceWithLists{
+		{
+			Namespace: models.Namespace{
+				Title: "Imported from iCalendar",
+			},
+			Lists: []*models.List{
+				{
+					Title: title,
+					Tasks: imp.Tasks,
+				},
+			},
+		},
+	}, nil
+}
+
+// Migrate imports all tasks from an ical file
+// @Summary Import all tasks from an ical file
+// @Description Imports all VTODOs and, if enabled, all VEVENTs of an ical file as tasks into a new list. The uids are kept so caldav clients which synced the same tasks before recognize them. Components which can't be imported, like recurring events or journal entries, are listed as warnings in the status of the migration job.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The ical file."
+// @Param events formData bool false "If true, events are imported as tasks as well."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a valid ical file."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/ical/migrate [put]
+func (m *Migration) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[ICal Migration] Starting migration for user %d", u.ID)
+
+	imp, err := caldav.ParseICalImport(io.NewSectionReader(file, 0, size), m.Events)
+	if err != nil {
+		return err
+	}
+
+	for _, skipped := range imp.Skipped {
+		log.Debugf("[ICal Migration] %s", skipped)
+		status.AddWarning(skipped.String())
+	}
+
+	fullVikunjaHierachie, err := convertICalToVikunja(imp)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[ICal Migration] Start inserting data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[ICal Migration] Migration done for user %d", u.ID)
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package ical
+
+import (
+	"strings"
+	"testing"
+
+	"code.vikunja.io/api/pkg/caldav"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertICalToVikunja(t *testing.T) {
+	t.Run("named calendar", func(t *testing.T) {
+		imp, err := caldav.ParseICalImport(strings.NewReader(`BEGIN:VCALENDAR
+VERSION:2.0
+X-WR-CALNAME:Groceries
+BEGIN:VTODO
+UID:milk
+SUMMARY:Buy milk
+END:VTODO
+BEGIN:VEVENT
+UID:market
+SUMMARY:Farmers market
+DTSTART:20210301T100000Z
+END:VEVENT
+END:VCALENDAR`), false)
+		assert.NoError(t, err)
+
+		hierachie, err := convertICalToVikunja(imp)
+		assert.NoError(t, err)
+		assert.Len(t, hierachie, 1)
+		assert.Len(t, hierachie[0].Lists, 1)
+		assert.Equal(t, "Groceries", hierachie[0].Lists[0].Title)
+		assert.Len(t, hierachie[0].Lists[0].Tasks, 1)
+		assert.Equal(t, "milk", hierachie[0].Lists[0].Tasks[0].UID)
+		assert.Len(t, imp.Skipped, 1)
+	})
+	t.Run("no tasks", func(t *testing.T) {
+		imp, err := caldav.ParseICalImport(strings.NewReader(`BEGIN:VCALENDAR
+VERSION:2.0
+END:VCALENDAR`), false)
+		assert.NoError(t, err)
+
+		_, err = convertICalToVikunja(imp)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+}
@@ -0,0 +1,346 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package jira
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// Migration represents the jira migration struct
+type Migration struct {
+	// If true, the imported lists are shared with write access with all assignees of their tasks.
+	// Otherwise only tasks assigned to the importing user keep their assignee.
+	ShareWithAssignees bool `json:"share_with_assignees" form:"share_with_assignees"`
+}
+
+// The format of all timestamps in jira
+const dateTimeFormat = "2006-01-02T15:04:05.000-0700"
+
+type jiraUser struct {
+	EmailAddress string `json:"emailAddress"`
+	DisplayName  string `json:"displayName"`
+}
+
+type statusCategory struct {
+	Key string `json:"key"`
+}
+
+type status struct {
+	Name           string          `json:"name"`
+	StatusCategory *statusCategory `json:"statusCategory"`
+}
+
+type priority struct {
+	Name string `json:"name"`
+}
+
+type project struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+type comment struct {
+	// Only the api v2 returns a plain text body, v3 returns it as document which is not imported.
+	Body    json.RawMessage `json:"body"`
+	Created string          `json:"created"`
+}
+
+type commentPage struct {
+	Comments []*comment `json:"comments"`
+}
+
+type attachment struct {
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType"`
+	Content  string `json:"content"`
+}
+
+type fields struct {
+	Summary        string          `json:"summary"`
+	Description    json.RawMessage `json:"description"`
+	Status         *status         `json:"status"`
+	Priority       *priority       `json:"priority"`
+	Labels         []string        `json:"labels"`
+	Assignee       *jiraUser       `json:"assignee"`
+	Created        string          `json:"created"`
+	ResolutionDate string          `json:"resolutiondate"`
+	DueDate        string          `json:"duedate"`
+	Parent         *issue          `json:"parent"`
+	Project        *project        `json:"project"`
+	Comment        *commentPage    `json:"comment"`
+	Attachment     []*attachment   `json:"attachment"`
+}
+
+type issue struct {
+	ID     string  `json:"id"`
+	Key    string  `json:"key"`
+	Fields *fields `json:"fields"`
+}
+
+type searchResult struct {
+	Issues []*issue `json:"issues"`
+}
+
+// Name is used to get the name of the jira migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/jira/status [get]
+func (m *Migration) Name() string {
+	return "jira"
+}
+
+func parseDateTime(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(dateTimeFormat, date)
+	if err != nil {
+		return t, err
+	}
+	return t.In(config.GetTimeZone()), nil
+}
+
+// getText returns a text field from the api v2. The api v3 returns text as document in its own format which is ignored.
+func getText(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return ""
+	}
+	return text
+}
+
+func convertPriority(priority string) int64 {
+	switch priority {
+	case "Highest", "Blocker":
+		return 4
+	case "High", "Critical":
+		return 3
+	case "Medium", "Major":
+		return 2
+	case "Low", "Lowest", "Minor", "Trivial":
+		return 1
+	}
+	return 0
+}
+
+// convertJiraToVikunja converts jira issues into the vikunja structure.
+// Projects are converted to lists, statuses to buckets and labels to labels.
+// Assignees only have their email set.
+func convertJiraToVikunja(issues []*issue, status *migration.Status) (fullVikunjaHierachie []*models.NamespaceWithLists, comments migration.TaskComments, err error) {
+	namespace := &models.NamespaceWithLists{
+		Namespace: models.Namespace{
+			Title: "Imported from Jira",
+		},
+	}
+
+	lists := make(map[string]*models.List)
+	buckets := make(map[*models.List]map[string]*models.Bucket)
+	labels := make(map[string]*models.Label)
+	// All tasks with the issue key as key to find the parents of subtasks
+	tasks := make(map[string]*models.Task, len(issues))
+	comments = make(migration.TaskComments)
+	var bucketID int64
+
+	for _, i := range issues {
+		if i.Fields == nil {
+			continue
+		}
+		f := i.Fields
+
+		listTitle := "Jira"
+		if f.Project != nil {
+			listTitle = f.Project.Name
+		}
+		list, exists := lists[listTitle]
+		if !exists {
+			list = &models.List{Title: listTitle}
+			lists[listTitle] = list
+			buckets[list] = make(map[string]*models.Bucket)
+			namespace.Lists = append(namespace.Lists, list)
+		}
+
+		task := &models.Task{
+			Title:       i.Key + " " + f.Summary,
+			Description: getText(f.Description),
+		}
+
+		if f.Status != nil {
+			bucket, exists := buckets[list][f.Status.Name]
+			if !exists {
+				bucketID++
+				bucket = &models.Bucket{
+					ID:    bucketID,
+					Title: f.Status.Name,
+				}
+				buckets[list][f.Status.Name] = bucket
+				list.Buckets = append(list.Buckets, bucket)
+			}
+			task.BucketID = bucket.ID
+			task.Done = f.Status.StatusCategory != nil && f.Status.StatusCategory.Key == "done"
+		}
+
+		if f.Priority != nil {
+			task.Priority = convertPriority(f.Priority.Name)
+		}
+
+		task.Created, err = parseDateTime(f.Created)
+		if err != nil {
+			return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid created date of issue " + i.Key}
+		}
+		if task.Done {
+			task.DoneAt, err = parseDateTime(f.ResolutionDate)
+			if err != nil {
+				return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid resolution date of issue " + i.Key}
+			}
+		}
+		if f.DueDate != "" {
+			task.DueDate, err = time.ParseInLocation("2006-01-02", f.DueDate, config.GetTimeZone())
+			if err != nil {
+				return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid due date of issue " + i.Key}
+			}
+		}
+
+		if f.Assignee != nil && f.Assignee.EmailAddress != "" {
+			task.Assignees = []*user.User{{Email: f.Assignee.EmailAddress}}
+		}
+
+		for _, l := range f.Labels {
+			label, exists := labels[l]
+			if !exists {
+				label = &models.Label{Title: l}
+				labels[l] = label
+			}
+			task.Labels = append(task.Labels, label)
+		}
+
+		if f.Comment != nil {
+			for _, c := range f.Comment.Comments {
+				text := getText(c.Body)
+				if text == "" {
+					continue
+				}
+				created, err := parseDateTime(c.Created)
+				if err != nil {
+					return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid comment date of issue " + i.Key}
+				}
+				comments[task] = append(comments[task], &models.TaskComment{
+					Comment: text,
+					Created: created,
+				})
+			}
+		}
+
+		// The attachment urls come from the uploaded file, fetching them would let anyone make the server request
+		// arbitrary urls. The attachments are added as links to the description instead.
+		if len(f.Attachment) > 0 {
+			task.Description += "\n\n## Attachments\n"
+			for _, a := range f.Attachment {
+				if a.Content == "" {
+					task.Description += "\n* " + a.Filename
+				} else {
+					task.Description += "\n* [" + a.Filename + "](" + a.Content + ")"
+				}
+				status.AddWarning("Attachment " + a.Filename + " of issue " + i.Key + " was not imported, it was added as a link to the task description instead.")
+			}
+		}
+
+		tasks[i.Key] = task
+		list.Tasks = append(list.Tasks, task)
+	}
+
+	// Subtasks are converted to relations once all tasks exist since the parent might be after the subtask
+	for _, i := range issues {
+		if i.Fields == nil || i.Fields.Parent == nil {
+			continue
+		}
+
+		parent, exists := tasks[i.Fields.Parent.Key]
+		if !exists {
+			log.Debugf("[Jira Migration] Could not find parent %s of issue %s", i.Fields.Parent.Key, i.Key)
+			continue
+		}
+		if parent.RelatedTasks == nil {
+			parent.RelatedTasks = make(models.RelatedTaskMap)
+		}
+		parent.RelatedTasks[models.RelationKindSubtask] = append(parent.RelatedTasks[models.RelationKindSubtask], tasks[i.Key])
+	}
+
+	return []*models.NamespaceWithLists{namespace}, comments, nil
+}
+
+// Migrate imports all issues from a jira export
+// @Summary Import all issues from a jira export
+// @Description Imports all issues from the json result of the jira search api (`/rest/api/2/search`). Projects are converted to lists, statuses to kanban buckets. Labels, subtasks and comments are imported as well, attachments are added as links to the task description. Assignees are matched by their email address with existing users who already have access to one of your lists. Other users are only assigned and get write access to the imported lists if share_with_assignees is true.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The json result of the jira search api."
+// @Param share_with_assignees formData bool false "If true, the imported lists are shared with the assignees of their tasks. Otherwise only tasks assigned to you keep their assignee."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a jira export."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/jira/migrate [put]
+func (m *Migration) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[Jira Migration] Starting migration for user %d", u.ID)
+
+	result := &searchResult{}
+	err := json.NewDecoder(io.NewSectionReader(file, 0, size)).Decode(result)
+	if err != nil || result.Issues == nil {
+		return models.ErrInvalidMigrationFile{Reason: "not a jira export"}
+	}
+
+	fullVikunjaHierachie, comments, err := convertJiraToVikunja(result.Issues, status)
+	if err != nil {
+		return err
+	}
+
+	err = migration.MatchAssigneesByEmail(fullVikunjaHierachie, u, m.ShareWithAssignees)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Jira Migration] Start inserting data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	err = migration.InsertTaskComments(comments, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Jira Migration] Migration done for user %d", u.ID)
+
+	return nil
+}
@@ -0,0 +1,147 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package jira
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+const testSearchResult = `{
+  "issues": [
+    {
+      "id": "10001",
+      "key": "VIK-2",
+      "fields": {
+        "summary": "Write docs",
+        "parent": {"id": "10000", "key": "VIK-1"},
+        "status": {"name": "Done", "statusCategory": {"key": "done"}},
+        "priority": {"name": "Low"},
+        "labels": [],
+        "created": "2021-04-02T10:00:00.000+0000",
+        "resolutiondate": "2021-04-05T12:00:00.000+0000",
+        "project": {"key": "VIK", "name": "Vikunja"}
+      }
+    },
+    {
+      "id": "10000",
+      "key": "VIK-1",
+      "fields": {
+        "summary": "Release the new version",
+        "description": "All the things",
+        "status": {"name": "In Progress", "statusCategory": {"key": "indeterminate"}},
+        "priority": {"name": "High"},
+        "labels": ["release"],
+        "assignee": {"emailAddress": "user1@example.com", "displayName": "User 1"},
+        "created": "2021-04-01T10:00:00.000+0000",
+        "duedate": "2021-04-10",
+        "project": {"key": "VIK", "name": "Vikunja"},
+        "comment": {
+          "comments": [
+            {"body": "Looks good", "created": "2021-04-03T08:00:00.000+0000"},
+            {"body": {"type": "doc", "content": []}, "created": "2021-04-03T09:00:00.000+0000"}
+          ]
+        }
+      }
+    }
+  ]
+}`
+
+func TestConvertJiraToVikunja(t *testing.T) {
+	config.InitConfig()
+
+	parse := func(date string) time.Time {
+		d, err := time.Parse(dateTimeFormat, date)
+		assert.NoError(t, err)
+		return d.In(config.GetTimeZone())
+	}
+	dueDate, err := time.ParseInLocation("2006-01-02", "2021-04-10", config.GetTimeZone())
+	assert.NoError(t, err)
+
+	result := &searchResult{}
+	err = json.Unmarshal([]byte(testSearchResult), result)
+	assert.NoError(t, err)
+
+	subtask := &models.Task{
+		Title:    "VIK-2 Write docs",
+		Done:     true,
+		DoneAt:   parse("2021-04-05T12:00:00.000+0000"),
+		Priority: 1,
+		Created:  parse("2021-04-02T10:00:00.000+0000"),
+		BucketID: 1,
+	}
+	parent := &models.Task{
+		Title:       "VIK-1 Release the new version",
+		Description: "All the things",
+		Priority:    3,
+		Created:     parse("2021-04-01T10:00:00.000+0000"),
+		DueDate:     dueDate,
+		BucketID:    2,
+		Assignees:   []*user.User{{Email: "user1@example.com"}},
+		Labels:      []*models.Label{{Title: "release"}},
+		RelatedTasks: models.RelatedTaskMap{
+			models.RelationKindSubtask: {subtask},
+		},
+	}
+
+	expectedHierachie := []*models.NamespaceWithLists{
+		{
+			Namespace: models.Namespace{
+				Title: "Imported from Jira",
+			},
+			Lists: []*models.List{
+				{
+					Title: "Vikunja",
+					Buckets: []*models.Bucket{
+						{ID: 1, Title: "Done"},
+						{ID: 2, Title: "In Progress"},
+					},
+					Tasks: []*models.Task{subtask, parent},
+				},
+			},
+		},
+	}
+
+	hierachie, comments, err := convertJiraToVikunja(result.Issues, nil)
+	assert.NoError(t, err)
+	if diff, equal := messagediff.PrettyDiff(hierachie, expectedHierachie); !equal {
+		t.Errorf("converted jira data = %v, want %v, diff: %v", hierachie, expectedHierachie, diff)
+	}
+
+	// The subtask needs to be the same task as the one in the list to only create it once
+	assert.Same(t, hierachie[0].Lists[0].Tasks[0], hierachie[0].Lists[0].Tasks[1].RelatedTasks[models.RelationKindSubtask][0])
+
+	expectedComments := migration.TaskComments{
+		hierachie[0].Lists[0].Tasks[1]: {
+			{
+				Comment: "Looks good",
+				Created: parse("2021-04-03T08:00:00.000+0000"),
+			},
+		},
+	}
+	if diff, equal := messagediff.PrettyDiff(comments, expectedComments); !equal {
+		t.Errorf("converted jira comments = %v, want %v, diff: %v", comments, expectedComments, diff)
+	}
+}
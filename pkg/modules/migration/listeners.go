@@ -0,0 +1,207 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// RegisterListeners registers all event listeners
+func RegisterListeners() {
+	events.RegisterListener((&MigrationRequestedEvent{}).Name(), &HandleMigration{})
+}
+
+// HandleMigration  represents a listener
+type HandleMigration struct {
+}
+
+// Name defines the name for the HandleMigration listener
+func (s *HandleMigration) Name() string {
+	return "handle.migration"
+}
+
+// Handle is executed when the event HandleMigration listens on is fired
+func (s *HandleMigration) Handle(msg *message.Message) (err error) {
+	event := &MigrationRequestedEvent{}
+	err = json.Unmarshal(msg.Payload, event)
+	if err != nil {
+		return err
+	}
+
+	sess := db.NewSession()
+	u, err := user.GetUserByID(sess, event.User.ID)
+	sess.Close()
+	if err != nil {
+		return err
+	}
+
+	status, err := GetMigrationStatusByID(event.StatusID, u)
+	if err != nil {
+		return err
+	}
+
+	return runMigrationJob(status, u)
+}
+
+// ResumeMigrationJobs takes care of all migration jobs which were not finished when Vikunja was stopped.
+// Jobs which were not started yet are started again, jobs which were interrupted while running are marked as
+// failed since they might have imported a part of the data already.
+// It needs to be called once all migrators are registered and before any new job can be requested.
+func ResumeMigrationJobs() {
+	s := db.NewSession()
+	unfinished := []*Status{}
+	err := s.
+		Where("finished_at IS NULL").
+		OrderBy("id asc").
+		Find(&unfinished)
+	s.Close()
+	if err != nil {
+		log.Errorf("[Migration] Could not get unfinished migration jobs: %s", err)
+		return
+	}
+
+	pending := make([]*Status, 0, len(unfinished))
+	for _, status := range unfinished {
+		if status.StartedAt.IsZero() {
+			pending = append(pending, status)
+			continue
+		}
+
+		log.Infof("[Migration] Migration job %d was interrupted, marking it as failed", status.ID)
+		err = status.finish(errMigrationJobInterrupted)
+		if err != nil {
+			log.Errorf("[Migration] Could not mark migration job %d as failed: %s", status.ID, err)
+		}
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Infof("[Migration] Resuming %d migration jobs", len(pending))
+
+	go func() {
+		for _, status := range pending {
+			s := db.NewSession()
+			u, err := user.GetUserByID(s, status.UserID)
+			s.Close()
+			if err != nil {
+				log.Errorf("[Migration] Could not get the user of migration job %d: %s", status.ID, err)
+				continue
+			}
+
+			err = runMigrationJob(status, u)
+			if err != nil {
+				log.Errorf("[Migration] Could not run migration job %d: %s", status.ID, err)
+			}
+		}
+	}()
+}
+
+// errMigrationJobInterrupted is the reason saved for jobs which were running while Vikunja was stopped
+var errMigrationJobInterrupted = errors.New("the migration was interrupted because Vikunja was restarted, please start it again")
+
+// errMigratorDataExpired is the reason saved for jobs whose migrator data is not in the keyvalue store anymore
+var errMigratorDataExpired = errors.New("the authorization for the migration expired or was lost when Vikunja was restarted, please start it again")
+
+func runMigrationJob(status *Status, u *user.User) (err error) {
+	started, err := status.start()
+	if err != nil {
+		return err
+	}
+	if !started {
+		log.Debugf("[Migration] Migration job %d was already started, not starting it again", status.ID)
+		return nil
+	}
+
+	log.Debugf("[Migration] Starting migration job %d (%s) for user %d", status.ID, status.MigratorName, u.ID)
+
+	migrationErr := runMigration(status, u)
+	if migrationErr != nil {
+		log.Errorf("[Migration] Migration job %d (%s) for user %d failed: %s", status.ID, status.MigratorName, u.ID, migrationErr)
+	} else {
+		log.Debugf("[Migration] Migration job %d (%s) for user %d is done", status.ID, status.MigratorName, u.ID)
+	}
+
+	// A failed migration is not retried, the error is part of the final report of the job instead.
+	return status.finish(migrationErr)
+}
+
+func runMigration(status *Status, u *user.User) (err error) {
+	if status.FileID != 0 {
+		m, exists := fileMigrators[status.MigratorName]
+		if !exists {
+			return fmt.Errorf("file migrator %s does not exist", status.MigratorName)
+		}
+
+		file := &files.File{ID: status.FileID}
+		err = file.LoadFileMetaByID()
+		if err != nil {
+			return err
+		}
+		err = file.LoadFileByID()
+		if err != nil {
+			return err
+		}
+		// The upload itself is deleted when the job is finished
+		defer func() {
+			_ = file.File.Close()
+		}()
+
+		fm := m()
+		if status.Migrator != "" {
+			err = json.Unmarshal([]byte(status.Migrator), fm)
+			if err != nil {
+				return err
+			}
+		}
+
+		return fm.Migrate(u, file.File, int64(file.Size), status)
+	}
+
+	m, exists := migrators[status.MigratorName]
+	if !exists {
+		return fmt.Errorf("migrator %s does not exist", status.MigratorName)
+	}
+
+	var migrator string
+	exists, err = keyvalue.GetWithValue(migratorDataKey(status.ID), &migrator)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errMigratorDataExpired
+	}
+
+	ms := m()
+	err = json.Unmarshal([]byte(migrator), ms)
+	if err != nil {
+		return err
+	}
+
+	return ms.Migrate(u, status)
+}
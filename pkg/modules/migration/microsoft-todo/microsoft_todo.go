@@ -139,7 +139,7 @@ func (m *Migration) AuthURL() string {
 
 // Name is used to get the name of the Microsoft Todo migration - we're using the docs here to annotate the status route.
 // @Summary Get migration status
-// @Description Returns if the current user already did the migation or not. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
 // @tags migration
 // @Produce json
 // @Security JWTKeyAuth
@@ -359,10 +359,11 @@ func convertMicrosoftTodoData(todoData []*list) (vikunjsStructure []*models.Name
 // @Produce json
 // @Security JWTKeyAuth
 // @Param migrationCode body microsofttodo.Migration true "The auth token previously obtained from the auth url. See the docs for /migration/microsoft-todo/auth."
-// @Success 200 {object} models.Message "A message telling you everything was migrated successfully."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
 // @Failure 500 {object} models.Message "Internal server error"
 // @Router /migration/microsoft-todo/migrate [post]
-func (m *Migration) Migrate(user *user.User) (err error) {
+func (m *Migration) Migrate(user *user.User, status *migration.Status) (err error) {
 
 	log.Debugf("[Microsoft Todo Migration] Start Microsoft Todo migration for user %d", user.ID)
 	log.Debugf("[Microsoft Todo Migration] Getting Microsoft Graph api token")
@@ -394,7 +395,7 @@ func (m *Migration) Migrate(user *user.User) (err error) {
 	log.Debugf("[Microsoft Todo Migration] Done converting Microsoft Todo data")
 	log.Debugf("[Microsoft Todo Migration] Creating new structure")
 
-	err = migration.InsertFromStructure(vikunjaStructure, user)
+	err = migration.InsertFromStructure(vikunjaStructure, user, status)
 	if err != nil {
 		log.Debugf("[Microsoft Todo Migration] Error while creating new structure: %s", err)
 		return
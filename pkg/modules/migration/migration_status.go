@@ -17,18 +17,55 @@
 package migration
 
 import (
+	"strconv"
 	"time"
 
 	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
 	"code.vikunja.io/api/pkg/user"
 )
 
-// Status represents this migration status
+// Status represents this migration status.
+// Every migration runs as a background job, its status holds the progress and the final report of that job.
 type Status struct {
-	ID           int64     `xorm:"bigint autoincr not null unique pk" json:"id"`
-	UserID       int64     `xorm:"bigint not null" json:"-"`
-	MigratorName string    `xorm:"varchar(255)" json:"migrator_name"`
-	Created      time.Time `xorm:"created not null 'created'" json:"time"`
+	// The id of the migration job.
+	ID           int64  `xorm:"bigint autoincr not null unique pk" json:"id"`
+	UserID       int64  `xorm:"bigint not null" json:"-"`
+	MigratorName string `xorm:"varchar(255)" json:"migrator_name"`
+
+	// How many lists were imported so far.
+	ListsImported int64 `xorm:"bigint not null default 0" json:"lists_imported"`
+	// How many tasks were imported so far.
+	TasksImported int64 `xorm:"bigint not null default 0" json:"tasks_imported"`
+	// How many files like attachments or list backgrounds were imported so far.
+	FilesImported int64 `xorm:"bigint not null default 0" json:"files_imported"`
+	// If the migration failed, this holds the reason why.
+	Error string `xorm:"text null" json:"error"`
+	// Everything which could not be migrated without failing the whole migration.
+	Warnings []string `xorm:"json null" json:"warnings"`
+
+	// A timestamp when the migration job was picked up and started.
+	StartedAt time.Time `xorm:"DATETIME null" json:"started_at"`
+	// A timestamp when the migration job finished, regardless of whether it was successful.
+	FinishedAt time.Time `xorm:"DATETIME null" json:"finished_at"`
+	// A timestamp when the migration was requested.
+	Created time.Time `xorm:"created not null 'created'" json:"time"`
+
+	// The request data a file migrator was bound to, it is used to recreate the migrator when the job is run.
+	// Removed once the job is finished. Other migrators are bound to credentials like an oauth code, their
+	// data is only kept in the keyvalue store and never saved in the database.
+	Migrator string `xorm:"text null" json:"-"`
+	// The uploaded file of file migrators. Deleted once the job is finished.
+	FileID int64 `xorm:"bigint null" json:"-"`
+
+	// A dry run only converts everything without creating anything.
+	DryRun bool `xorm:"-" json:"-"`
+	// The converted structure of a dry run.
+	Structure []*models.NamespaceWithLists `xorm:"-" json:"-"`
 }
 
 // TableName holds the table name for the migration status table
@@ -36,28 +73,175 @@ func (s *Status) TableName() string {
 	return "migration_status"
 }
 
-// SetMigrationStatus sets the migration status for a user
-func SetMigrationStatus(m Migrator, u *user.User) (err error) {
-	s := db.NewSession()
-	defer s.Close()
+// migratorDataExpiration is how long the data of a migrator which is not a file migrator is kept for a job
+// which was not started yet. The oauth codes in there are not valid for much longer anyway.
+const migratorDataExpiration = time.Hour
+
+func migratorDataKey(statusID int64) string {
+	return "migration_job_" + strconv.FormatInt(statusID, 10)
+}
 
-	status := &Status{
+// StartMigrationJob saves everything needed to run a migration job and starts it in the background.
+// The migrator is the json of everything the migrator was bound to from the request. File migrators pass
+// the id of the uploaded file, it is deleted once the job is finished.
+// Because the job is saved, it is resumed when Vikunja is restarted before the job was started. The migrator
+// data of other migrators holds credentials and is only kept in the keyvalue store, these jobs can only be
+// resumed if it survived the restart.
+func StartMigrationJob(m MigratorName, u *user.User, migrator []byte, fileID int64) (status *Status, err error) {
+	s := db.NewSession()
+	status = &Status{
 		UserID:       u.ID,
 		MigratorName: m.Name(),
+		FileID:       fileID,
+	}
+	if fileID != 0 {
+		status.Migrator = string(migrator)
 	}
 	_, err = s.Insert(status)
-	return
+	s.Close()
+	if err != nil {
+		deleteUpload(fileID, 0)
+		return nil, err
+	}
+
+	if fileID == 0 {
+		err = keyvalue.PutWithExpiration(migratorDataKey(status.ID), string(migrator), migratorDataExpiration)
+		if err != nil {
+			if finishErr := status.finish(err); finishErr != nil {
+				log.Errorf("[Migration] Could not save the failure of migration job %d: %s", status.ID, finishErr)
+			}
+			return nil, err
+		}
+	}
+
+	err = events.Dispatch(&MigrationRequestedEvent{
+		StatusID: status.ID,
+		User:     u,
+	})
+	if err != nil {
+		if finishErr := status.finish(err); finishErr != nil {
+			log.Errorf("[Migration] Could not save the failure of migration job %d: %s", status.ID, finishErr)
+		}
+		return nil, err
+	}
+
+	return status, nil
 }
 
-// GetMigrationStatus returns the migration status for a migration and a user
-func GetMigrationStatus(m Migrator, u *user.User) (status *Status, err error) {
+// GetMigrationStatus returns the status of the latest successful migration for a migration and a user.
+// Jobs which are still pending or running and jobs which failed are not taken into account, use
+// GetMigrationStatusByID to get their progress.
+func GetMigrationStatus(m MigratorName, u *user.User) (status *Status, err error) {
 	s := db.NewSession()
 	defer s.Close()
 
 	status = &Status{}
 	_, err = s.
-		Where("user_id = ? and migrator_name = ?", u.ID, m.Name()).
+		Where("user_id = ? AND migrator_name = ? AND finished_at IS NOT NULL AND (error IS NULL OR error = '')", u.ID, m.Name()).
 		Desc("id").
 		Get(status)
 	return
 }
+
+// GetMigrationStatusByID returns the status of a migration job of a user
+func GetMigrationStatusByID(id int64, u *user.User) (status *Status, err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	status = &Status{}
+	exists, err := s.
+		Where("id = ? and user_id = ?", id, u.ID).
+		Get(status)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrMigrationJobDoesNotExist{JobID: id}
+	}
+	return
+}
+
+func (status *Status) update(cols ...string) error {
+	s := db.NewSession()
+	defer s.Close()
+
+	_, err := s.
+		Where("id = ?", status.ID).
+		Cols(cols...).
+		Update(status)
+	return err
+}
+
+// start marks the job as started. It returns false if the job was already started before, for example by another
+// event for the same job, to make sure every job only runs once.
+func (status *Status) start() (started bool, err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	status.StartedAt = time.Now()
+	affected, err := s.
+		Where("id = ? AND started_at IS NULL", status.ID).
+		Cols("started_at").
+		Update(status)
+	return affected == 1, err
+}
+
+// finish saves the final report of a job and removes everything which was only needed to run it.
+func (status *Status) finish(migrationErr error) error {
+	deleteUpload(status.FileID, status.ID)
+	if status.FileID == 0 {
+		if err := keyvalue.Del(migratorDataKey(status.ID)); err != nil {
+			log.Errorf("[Migration] Could not delete the migrator data of migration job %d: %s", status.ID, err)
+		}
+	}
+
+	status.Migrator = ""
+	status.FileID = 0
+	status.FinishedAt = time.Now()
+	if migrationErr != nil {
+		status.Error = migrationErr.Error()
+	}
+	return status.update("lists_imported", "tasks_imported", "files_imported", "error", "warnings", "finished_at", "migrator", "file_id")
+}
+
+func deleteUpload(fileID, statusID int64) {
+	if fileID == 0 {
+		return
+	}
+
+	err := (&files.File{ID: fileID}).Delete()
+	if err != nil && !files.IsErrFileDoesNotExist(err) {
+		log.Errorf("[Migration] Could not delete the uploaded file %d of migration job %d: %s", fileID, statusID, err)
+	}
+}
+
+// addProgress adds newly imported lists, tasks and files to the progress of a migration job.
+// Migrations run without a status, for example in tests, don't track any progress.
+func (status *Status) addProgress(lists, tasks, files int64) {
+	if status == nil || status.ID == 0 {
+		return
+	}
+
+	status.ListsImported += lists
+	status.TasksImported += tasks
+	status.FilesImported += files
+
+	// The progress is only informational, a migration should not fail because it could not be saved
+	err := status.update("lists_imported", "tasks_imported", "files_imported")
+	if err != nil {
+		log.Errorf("[Migration] Could not save the progress of migration job %d: %s", status.ID, err)
+	}
+}
+
+// AddWarning adds something which could not be migrated to the final report of a migration job.
+func (status *Status) AddWarning(warning string) {
+	if status == nil {
+		return
+	}
+	status.Warnings = append(status.Warnings, warning)
+}
+
+// IsDryRun returns whether a migration should only convert everything without creating anything.
+func (status *Status) IsDryRun() bool {
+	return status != nil && status.DryRun
+}
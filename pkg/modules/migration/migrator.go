@@ -17,14 +17,24 @@
 package migration
 
 import (
+	"io"
+
 	"code.vikunja.io/api/pkg/user"
 )
 
+// MigratorName holds the name of a migrator. It is shared among all kinds of migrators to keep track of users who already migrated.
+type MigratorName interface {
+	// Name holds the name of the migration.
+	// This is used to show the name to users and to keep track of users who already migrated.
+	Name() string
+}
+
 // Migrator is the basic migrator interface which is shared among all migrators
 type Migrator interface {
 	// Migrate is the interface used to migrate a user's tasks from another platform to vikunja.
 	// The user object is the user who's tasks will be migrated.
-	Migrate(user *user.User) error
+	// The status needs to be passed on to InsertFromStructure to keep track of the progress.
+	Migrate(user *user.User, status *Status) error
 	// AuthURL returns a url for clients to authenticate against.
 	// The use case for this are Oauth flows, where the server token should remain hidden and not
 	// known to the frontend.
@@ -33,3 +43,27 @@ type Migrator interface {
 	// This is used to show the name to users and to keep track of users who already migrated.
 	Name() string
 }
+
+// FileMigrator is the interface for migrators which import an uploaded file instead of fetching the data from a service.
+type FileMigrator interface {
+	MigratorName
+	// Migrate is the interface used to migrate a user's tasks from a file to vikunja.
+	// The user object is the user who's tasks will be migrated.
+	// The status needs to be passed on to InsertFromStructure to keep track of the progress.
+	Migrate(user *user.User, file io.ReaderAt, size int64, status *Status) error
+}
+
+var (
+	migrators     = make(map[string]func() Migrator)
+	fileMigrators = make(map[string]func() FileMigrator)
+)
+
+// RegisterMigrator makes a migrator available to migration jobs which run in the background
+func RegisterMigrator(m func() Migrator) {
+	migrators[m().Name()] = m
+}
+
+// RegisterFileMigrator makes a file migrator available to migration jobs which run in the background
+func RegisterFileMigrator(m func() FileMigrator) {
+	fileMigrators[m().Name()] = m
+}
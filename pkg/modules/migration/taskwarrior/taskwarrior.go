@@ -0,0 +1,316 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package taskwarrior
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// Migration represents the taskwarrior migration struct
+type Migration struct {
+}
+
+// The title of the list for all tasks without a project
+const defaultListTitle = "Inbox"
+
+// The format of all dates in a taskwarrior export
+const dateFormat = "20060102T150405Z"
+
+type annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+type task struct {
+	ID          int64         `json:"id"`
+	UUID        string        `json:"uuid"`
+	Description string        `json:"description"`
+	Status      string        `json:"status"`
+	Project     string        `json:"project"`
+	Tags        []string      `json:"tags"`
+	Priority    string        `json:"priority"`
+	Entry       string        `json:"entry"`
+	Modified    string        `json:"modified"`
+	End         string        `json:"end"`
+	Due         string        `json:"due"`
+	Scheduled   string        `json:"scheduled"`
+	Until       string        `json:"until"`
+	Recur       string        `json:"recur"`
+	Parent      string        `json:"parent"`
+	Annotations []*annotation `json:"annotations"`
+}
+
+const day = 24 * 60 * 60
+
+// Named recurrences of taskwarrior and their length in seconds
+var namedRecurrences = map[string]int64{
+	"hourly":     60 * 60,
+	"daily":      day,
+	"day":        day,
+	"weekdays":   day,
+	"weekly":     7 * day,
+	"week":       7 * day,
+	"biweekly":   14 * day,
+	"fortnight":  14 * day,
+	"monthly":    30 * day,
+	"month":      30 * day,
+	"bimonthly":  60 * day,
+	"quarterly":  91 * day,
+	"semiannual": 182 * day,
+	"annual":     365 * day,
+	"yearly":     365 * day,
+	"year":       365 * day,
+	"biannual":   2 * 365 * day,
+	"biyearly":   2 * 365 * day,
+	"sennight":   7 * day,
+	"quarter":    91 * day,
+}
+
+var recurrenceRegex = regexp.MustCompile(`^(\d+)\s*([a-z]+)$`)
+
+// The units of recurrences like "2wks" and their length in seconds
+var recurrenceUnits = map[string]int64{
+	"h":        60 * 60,
+	"hr":       60 * 60,
+	"hrs":      60 * 60,
+	"hour":     60 * 60,
+	"hours":    60 * 60,
+	"d":        day,
+	"day":      day,
+	"days":     day,
+	"w":        7 * day,
+	"wk":       7 * day,
+	"wks":      7 * day,
+	"week":     7 * day,
+	"weeks":    7 * day,
+	"mo":       30 * day,
+	"mos":      30 * day,
+	"month":    30 * day,
+	"months":   30 * day,
+	"q":        91 * day,
+	"qtr":      91 * day,
+	"qtrs":     91 * day,
+	"quarter":  91 * day,
+	"quarters": 91 * day,
+	"y":        365 * day,
+	"yr":       365 * day,
+	"yrs":      365 * day,
+	"year":     365 * day,
+	"years":    365 * day,
+}
+
+// Name is used to get the name of the taskwarrior migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/taskwarrior/status [get]
+func (m *Migration) Name() string {
+	return "taskwarrior"
+}
+
+func parseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(dateFormat, date)
+	if err != nil {
+		return t, err
+	}
+	return t.In(config.GetTimeZone()), nil
+}
+
+// parseRecurrence converts a taskwarrior recurrence like "weekly" or "3days" to seconds.
+// It returns 0 if the recurrence could not be parsed.
+func parseRecurrence(recur string) int64 {
+	recur = strings.ToLower(strings.TrimSpace(recur))
+	if seconds, exists := namedRecurrences[recur]; exists {
+		return seconds
+	}
+
+	match := recurrenceRegex.FindStringSubmatch(recur)
+	if match == nil {
+		return 0
+	}
+	amount, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return amount * recurrenceUnits[match[2]]
+}
+
+func convertPriority(priority string) int64 {
+	switch priority {
+	case "H":
+		return 3
+	case "M":
+		return 2
+	case "L":
+		return 1
+	}
+	return 0
+}
+
+// convertTaskwarriorToVikunja converts the tasks of a taskwarrior export into the vikunja structure.
+// Projects are converted to lists and tags to labels. Annotations are returned as comments of their tasks.
+func convertTaskwarriorToVikunja(tasks []*task) (fullVikunjaHierachie []*models.NamespaceWithLists, comments migration.TaskComments, err error) {
+	namespace := &models.NamespaceWithLists{
+		Namespace: models.Namespace{
+			Title: "Imported from Taskwarrior",
+		},
+	}
+
+	lists := make(map[string]*models.List)
+	labels := make(map[string]*models.Label)
+	comments = make(migration.TaskComments)
+
+	for _, t := range tasks {
+		// Recurring tasks are exported as a template with the status "recurring" and one task for every
+		// instance with the template as parent. We only import the template as repeating task.
+		if t.Status == "deleted" || t.Parent != "" {
+			continue
+		}
+
+		vikunjaTask := &models.Task{
+			Title:       t.Description,
+			Done:        t.Status == "completed",
+			Priority:    convertPriority(t.Priority),
+			RepeatAfter: parseRecurrence(t.Recur),
+		}
+
+		if t.Recur != "" && vikunjaTask.RepeatAfter == 0 {
+			log.Debugf("[Taskwarrior Migration] Could not parse recurrence %s of task %s", t.Recur, t.UUID)
+		}
+
+		vikunjaTask.Created, err = parseDate(t.Entry)
+		if err != nil {
+			return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid entry date of task " + t.UUID}
+		}
+		vikunjaTask.DueDate, err = parseDate(t.Due)
+		if err != nil {
+			return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid due date of task " + t.UUID}
+		}
+		vikunjaTask.StartDate, err = parseDate(t.Scheduled)
+		if err != nil {
+			return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid scheduled date of task " + t.UUID}
+		}
+		vikunjaTask.EndDate, err = parseDate(t.Until)
+		if err != nil {
+			return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid until date of task " + t.UUID}
+		}
+		if vikunjaTask.Done {
+			vikunjaTask.DoneAt, err = parseDate(t.End)
+			if err != nil {
+				return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid end date of task " + t.UUID}
+			}
+		}
+
+		for _, tag := range t.Tags {
+			label, exists := labels[tag]
+			if !exists {
+				label = &models.Label{Title: tag}
+				labels[tag] = label
+			}
+			vikunjaTask.Labels = append(vikunjaTask.Labels, label)
+		}
+
+		for _, a := range t.Annotations {
+			created, err := parseDate(a.Entry)
+			if err != nil {
+				return nil, nil, models.ErrInvalidMigrationFile{Reason: "invalid annotation date of task " + t.UUID}
+			}
+			comments[vikunjaTask] = append(comments[vikunjaTask], &models.TaskComment{
+				Comment: a.Description,
+				Created: created,
+			})
+		}
+
+		listTitle := t.Project
+		if listTitle == "" {
+			listTitle = defaultListTitle
+		}
+		list, exists := lists[listTitle]
+		if !exists {
+			list = &models.List{Title: listTitle}
+			lists[listTitle] = list
+			namespace.Lists = append(namespace.Lists, list)
+		}
+		list.Tasks = append(list.Tasks, vikunjaTask)
+	}
+
+	return []*models.NamespaceWithLists{namespace}, comments, nil
+}
+
+// Migrate imports all tasks from a taskwarrior export
+// @Summary Import all tasks from a taskwarrior export
+// @Description Imports all tasks from the json file created by `task export`. Projects are converted to lists, tags to labels and annotations to comments. Priorities, dates and recurrences are imported as well. Deleted tasks are not imported.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The json file created by `task export`."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a taskwarrior export."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/taskwarrior/migrate [put]
+func (m *Migration) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[Taskwarrior Migration] Starting migration for user %d", u.ID)
+
+	tasks := []*task{}
+	err := json.NewDecoder(io.NewSectionReader(file, 0, size)).Decode(&tasks)
+	if err != nil {
+		return models.ErrInvalidMigrationFile{Reason: "not a taskwarrior export"}
+	}
+
+	log.Debugf("[Taskwarrior Migration] Converting %d tasks for user %d", len(tasks), u.ID)
+
+	fullVikunjaHierachie, comments, err := convertTaskwarriorToVikunja(tasks)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Taskwarrior Migration] Start inserting data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	err = migration.InsertTaskComments(comments, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Taskwarrior Migration] Migration done for user %d", u.ID)
+
+	return nil
+}
@@ -0,0 +1,131 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package taskwarrior
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+const testExport = `[
+{"id":1,"description":"Buy milk","entry":"20210401T100000Z","modified":"20210401T100000Z","project":"Home","status":"pending","tags":["shopping","errand"],"priority":"H","due":"20210410T220000Z","uuid":"a4b1a1cd-0f53-4d8f-a7c3-2b6d8e0c7b11","annotations":[{"entry":"20210402T080000Z","description":"Oat milk"}],"urgency":11.2},
+{"id":0,"description":"Water the plants","entry":"20210401T100000Z","end":"20210405T120000Z","modified":"20210405T120000Z","project":"Home","status":"completed","uuid":"0b0b5b7d-4a0c-4bf1-9d5b-77ad1f4c2d22","urgency":0},
+{"id":2,"description":"Standup","entry":"20210401T100000Z","modified":"20210401T100000Z","status":"recurring","recur":"weekdays","due":"20210405T070000Z","scheduled":"20210405T060000Z","until":"20211231T230000Z","priority":"L","tags":["errand"],"uuid":"6a1e2f46-5a14-4a0c-8f7b-c1dc7b1ae333","urgency":2},
+{"id":3,"description":"Standup","entry":"20210405T000000Z","modified":"20210405T000000Z","status":"pending","recur":"weekdays","due":"20210405T070000Z","parent":"6a1e2f46-5a14-4a0c-8f7b-c1dc7b1ae333","uuid":"a6b6f4c9-1ab2-4e1e-9d8c-95ad2e5d4444","urgency":2},
+{"id":0,"description":"Deleted task","entry":"20210401T100000Z","modified":"20210401T100000Z","status":"deleted","uuid":"b8f6bd1a-3f5e-4ab8-a6f1-1f1d1f1f5555","urgency":0}
+]`
+
+func TestParseRecurrence(t *testing.T) {
+	assert.Equal(t, int64(day), parseRecurrence("daily"))
+	assert.Equal(t, int64(7*day), parseRecurrence("weekly"))
+	assert.Equal(t, int64(3*day), parseRecurrence("3days"))
+	assert.Equal(t, int64(14*day), parseRecurrence("2 wks"))
+	assert.Equal(t, int64(365*day), parseRecurrence("1y"))
+	assert.Equal(t, int64(0), parseRecurrence("sometimes"))
+}
+
+func TestConvertTaskwarriorToVikunja(t *testing.T) {
+	config.InitConfig()
+
+	parse := func(date string) time.Time {
+		d, err := time.Parse(dateFormat, date)
+		assert.NoError(t, err)
+		return d.In(config.GetTimeZone())
+	}
+
+	tasks := []*task{}
+	err := json.Unmarshal([]byte(testExport), &tasks)
+	assert.NoError(t, err)
+
+	errandLabel := &models.Label{Title: "errand"}
+	buyMilk := &models.Task{
+		Title:    "Buy milk",
+		Priority: 3,
+		Created:  parse("20210401T100000Z"),
+		DueDate:  parse("20210410T220000Z"),
+		Labels: []*models.Label{
+			{Title: "shopping"},
+			errandLabel,
+		},
+	}
+
+	expectedHierachie := []*models.NamespaceWithLists{
+		{
+			Namespace: models.Namespace{
+				Title: "Imported from Taskwarrior",
+			},
+			Lists: []*models.List{
+				{
+					Title: "Home",
+					Tasks: []*models.Task{
+						buyMilk,
+						{
+							Title:   "Water the plants",
+							Done:    true,
+							DoneAt:  parse("20210405T120000Z"),
+							Created: parse("20210401T100000Z"),
+						},
+					},
+				},
+				{
+					Title: "Inbox",
+					Tasks: []*models.Task{
+						{
+							Title:       "Standup",
+							Priority:    1,
+							RepeatAfter: day,
+							Created:     parse("20210401T100000Z"),
+							DueDate:     parse("20210405T070000Z"),
+							StartDate:   parse("20210405T060000Z"),
+							EndDate:     parse("20211231T230000Z"),
+							Labels:      []*models.Label{errandLabel},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hierachie, comments, err := convertTaskwarriorToVikunja(tasks)
+	assert.NoError(t, err)
+	if diff, equal := messagediff.PrettyDiff(hierachie, expectedHierachie); !equal {
+		t.Errorf("converted taskwarrior data = %v, want %v, diff: %v", hierachie, expectedHierachie, diff)
+	}
+
+	// The labels need to be the same so they are only created once
+	assert.Same(t, hierachie[0].Lists[0].Tasks[0].Labels[1], hierachie[0].Lists[1].Tasks[0].Labels[0])
+
+	assert.Len(t, comments, 1)
+	expectedComments := migration.TaskComments{
+		hierachie[0].Lists[0].Tasks[0]: {
+			{
+				Comment: "Oat milk",
+				Created: parse("20210402T080000Z"),
+			},
+		},
+	}
+	if diff, equal := messagediff.PrettyDiff(comments, expectedComments); !equal {
+		t.Errorf("converted taskwarrior comments = %v, want %v, diff: %v", comments, expectedComments, diff)
+	}
+}
@@ -0,0 +1,273 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package todotxt
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// Migration represents the todo.txt migration struct
+type Migration struct {
+}
+
+// The title of the list for all tasks without a project
+const defaultListTitle = "Inbox"
+
+var (
+	priorityRegex   = regexp.MustCompile(`^\(([A-Z])\)$`)
+	dateRegex       = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	recurrenceRegex = regexp.MustCompile(`^\+?(\d+)([dbwmy])$`)
+)
+
+const day = 24 * 60 * 60
+
+// The length of the units of a todo.txt recurrence in seconds. Business days are treated as normal days.
+var recurrenceUnits = map[string]int64{
+	"d": day,
+	"b": day,
+	"w": 7 * day,
+	"m": 30 * day,
+	"y": 365 * day,
+}
+
+// Name is used to get the name of the todo.txt migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/todo-txt/status [get]
+func (m *Migration) Name() string {
+	return "todo-txt"
+}
+
+// todo.txt priorities range from A (highest) to Z (lowest), everything below C is treated as low priority.
+func convertPriority(priority string) int64 {
+	switch priority {
+	case "A":
+		return 4
+	case "B":
+		return 3
+	case "C":
+		return 2
+	default:
+		return 1
+	}
+}
+
+func parseDate(date string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", date, config.GetTimeZone())
+}
+
+// parseLine converts a single line of a todo.txt file into a task.
+// It returns the projects of the task separately since they are converted to lists.
+func parseLine(line string, labels map[string]*models.Label) (task *models.Task, projects []string, err error) {
+	fields := strings.Fields(line)
+	task = &models.Task{}
+
+	i := 0
+	if len(fields) > 0 && fields[0] == "x" {
+		task.Done = true
+		i++
+		// A done task can have a completion date, it is always followed by the creation date
+		if len(fields) > i && dateRegex.MatchString(fields[i]) {
+			task.DoneAt, err = parseDate(fields[i])
+			if err != nil {
+				return
+			}
+			i++
+		}
+	} else if len(fields) > 0 && priorityRegex.MatchString(fields[0]) {
+		task.Priority = convertPriority(priorityRegex.FindStringSubmatch(fields[0])[1])
+		i++
+	}
+
+	if len(fields) > i && dateRegex.MatchString(fields[i]) {
+		task.Created, err = parseDate(fields[i])
+		if err != nil {
+			return
+		}
+		i++
+	}
+
+	title := make([]string, 0, len(fields)-i)
+	for _, field := range fields[i:] {
+		switch {
+		case len(field) > 1 && strings.HasPrefix(field, "+"):
+			projects = append(projects, field[1:])
+			continue
+		case len(field) > 1 && strings.HasPrefix(field, "@"):
+			name := field[1:]
+			label, exists := labels[name]
+			if !exists {
+				label = &models.Label{Title: name}
+				labels[name] = label
+			}
+			task.Labels = append(task.Labels, label)
+			continue
+		}
+
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			switch parts[0] {
+			case "due":
+				task.DueDate, err = parseDate(parts[1])
+				if err != nil {
+					return
+				}
+				continue
+			case "t":
+				task.StartDate, err = parseDate(parts[1])
+				if err != nil {
+					return
+				}
+				continue
+			case "rec":
+				match := recurrenceRegex.FindStringSubmatch(parts[1])
+				if match == nil {
+					log.Debugf("[Todo.txt Migration] Could not parse recurrence %s", parts[1])
+					break
+				}
+				amount, err := strconv.ParseInt(match[1], 10, 64)
+				if err != nil {
+					return nil, nil, err
+				}
+				task.RepeatAfter = amount * recurrenceUnits[match[2]]
+				continue
+			case "pri":
+				// Done tasks keep their priority as tag
+				if len(parts[1]) == 1 {
+					task.Priority = convertPriority(parts[1])
+					continue
+				}
+			}
+		}
+
+		title = append(title, field)
+	}
+
+	task.Title = strings.Join(title, " ")
+	return
+}
+
+// convertTodoTxtToVikunja converts the content of a todo.txt file into the vikunja structure.
+// The first project of a task is used as its list, all other projects and contexts are converted to labels.
+func convertTodoTxtToVikunja(r io.Reader) (fullVikunjaHierachie []*models.NamespaceWithLists, err error) {
+	namespace := &models.NamespaceWithLists{
+		Namespace: models.Namespace{
+			Title: "Imported from todo.txt",
+		},
+	}
+
+	lists := make(map[string]*models.List)
+	labels := make(map[string]*models.Label)
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		task, projects, err := parseLine(line, labels)
+		if err != nil {
+			return nil, models.ErrInvalidMigrationFile{Reason: "line " + strconv.Itoa(lineNumber) + " is invalid: " + err.Error()}
+		}
+		if task.Title == "" {
+			log.Debugf("[Todo.txt Migration] Line %d does not contain a title, skipping", lineNumber)
+			continue
+		}
+
+		listTitle := defaultListTitle
+		if len(projects) > 0 {
+			listTitle = projects[0]
+			for _, p := range projects[1:] {
+				label, exists := labels[p]
+				if !exists {
+					label = &models.Label{Title: p}
+					labels[p] = label
+				}
+				task.Labels = append(task.Labels, label)
+			}
+		}
+
+		list, exists := lists[listTitle]
+		if !exists {
+			list = &models.List{Title: listTitle}
+			lists[listTitle] = list
+			namespace.Lists = append(namespace.Lists, list)
+		}
+		list.Tasks = append(list.Tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(namespace.Lists) == 0 {
+		return nil, models.ErrInvalidMigrationFile{Reason: "the file does not contain any tasks"}
+	}
+
+	return []*models.NamespaceWithLists{namespace}, nil
+}
+
+// Migrate imports all tasks from a todo.txt file
+// @Summary Import all tasks from a todo.txt file
+// @Description Imports all tasks from a todo.txt file. Projects are converted to lists, contexts to labels. Priorities, due dates (`due:`), threshold dates (`t:`) and recurrences (`rec:`) are imported as well.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The todo.txt file."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a valid todo.txt file."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/todo-txt/migrate [put]
+func (m *Migration) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[Todo.txt Migration] Starting migration for user %d", u.ID)
+
+	fullVikunjaHierachie, err := convertTodoTxtToVikunja(io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Todo.txt Migration] Start inserting data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Todo.txt Migration] Migration done for user %d", u.ID)
+
+	return nil
+}
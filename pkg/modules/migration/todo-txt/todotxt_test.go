@@ -0,0 +1,116 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package todotxt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/d4l3k/messagediff.v1"
+)
+
+const testTodoTxt = `(A) 2021-04-01 Call mom +Family @phone due:2021-04-10
+x 2021-04-05 2021-04-01 Water the plants +Home +Garden pri:B
+(D) Pay rent @finance rec:1m t:2021-04-25 https://bank.example.com
+
+Read a book
+`
+
+func TestConvertTodoTxtToVikunja(t *testing.T) {
+	config.InitConfig()
+
+	parse := func(date string) time.Time {
+		d, err := time.ParseInLocation("2006-01-02", date, config.GetTimeZone())
+		assert.NoError(t, err)
+		return d
+	}
+
+	expectedHierachie := []*models.NamespaceWithLists{
+		{
+			Namespace: models.Namespace{
+				Title: "Imported from todo.txt",
+			},
+			Lists: []*models.List{
+				{
+					Title: "Family",
+					Tasks: []*models.Task{
+						{
+							Title:    "Call mom",
+							Priority: 4,
+							Created:  parse("2021-04-01"),
+							DueDate:  parse("2021-04-10"),
+							Labels:   []*models.Label{{Title: "phone"}},
+						},
+					},
+				},
+				{
+					Title: "Home",
+					Tasks: []*models.Task{
+						{
+							Title:    "Water the plants",
+							Done:     true,
+							DoneAt:   parse("2021-04-05"),
+							Created:  parse("2021-04-01"),
+							Priority: 3,
+							Labels:   []*models.Label{{Title: "Garden"}},
+						},
+					},
+				},
+				{
+					Title: "Inbox",
+					Tasks: []*models.Task{
+						{
+							Title:       "Pay rent https://bank.example.com",
+							Priority:    1,
+							RepeatAfter: 30 * day,
+							StartDate:   parse("2021-04-25"),
+							Labels:      []*models.Label{{Title: "finance"}},
+						},
+						{
+							Title: "Read a book",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hierachie, err := convertTodoTxtToVikunja(strings.NewReader(testTodoTxt))
+	assert.NoError(t, err)
+	if diff, equal := messagediff.PrettyDiff(hierachie, expectedHierachie); !equal {
+		t.Errorf("converted todo.txt data = %v, want %v, diff: %v", hierachie, expectedHierachie, diff)
+	}
+}
+
+func TestConvertTodoTxtToVikunjaInvalid(t *testing.T) {
+	config.InitConfig()
+
+	t.Run("empty file", func(t *testing.T) {
+		_, err := convertTodoTxtToVikunja(strings.NewReader("\n\n"))
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+	t.Run("invalid date", func(t *testing.T) {
+		_, err := convertTodoTxtToVikunja(strings.NewReader("Task due:2021-13-45"))
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+}
@@ -199,7 +199,7 @@ func init() {
 
 // Name is used to get the name of the todoist migration - we're using the docs here to annotate the status route.
 // @Summary Get migration status
-// @Description Returns if the current user already did the migation or not. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
 // @tags migration
 // @Produce json
 // @Security JWTKeyAuth
@@ -238,7 +238,8 @@ func parseDate(dateString string) (date time.Time, err error) {
 	return date, err
 }
 
-func convertTodoistToVikunja(sync *sync) (fullVikunjaHierachie []*models.NamespaceWithLists, err error) {
+// Attachments are only downloaded if downloadFiles is true. Only pass true if the sync data was returned by the todoist api.
+func convertTodoistToVikunja(sync *sync, downloadFiles bool) (fullVikunjaHierachie []*models.NamespaceWithLists, err error) {
 
 	newNamespace := &models.NamespaceWithLists{
 		Namespace: models.Namespace{
@@ -377,8 +378,9 @@ func convertTodoistToVikunja(sync *sync) (fullVikunjaHierachie []*models.Namespa
 			continue
 		}
 
-		// Only add the attachment if there's something to download
-		if len(n.FileAttachment.FileURL) > 0 {
+		// Only add the attachment if there's something to download.
+		// The urls of files can only be trusted if they were returned by the todoist api.
+		if len(n.FileAttachment.FileURL) > 0 && downloadFiles {
 			// Download the attachment and put it in the file
 			buf, err := migration.DownloadFile(n.FileAttachment.FileURL)
 			if err != nil {
@@ -466,10 +468,11 @@ func getAccessTokenFromAuthToken(authToken string) (accessToken string, err erro
 // @Produce json
 // @Security JWTKeyAuth
 // @Param migrationCode body todoist.Migration true "The auth code previously obtained from the auth url. See the docs for /migration/todoist/auth."
-// @Success 200 {object} models.Message "A message telling you everything was migrated successfully."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
 // @Failure 500 {object} models.Message "Internal server error"
 // @Router /migration/todoist/migrate [post]
-func (m *Migration) Migrate(u *user.User) (err error) {
+func (m *Migration) Migrate(u *user.User, status *migration.Status) (err error) {
 
 	log.Debugf("[Todoist Migration] Starting migration for user %d", u.ID)
 
@@ -508,7 +511,7 @@ func (m *Migration) Migrate(u *user.User) (err error) {
 	log.Debugf("[Todoist Migration] Got all todoist user data for user %d", u.ID)
 	log.Debugf("[Todoist Migration] Start converting data for user %d", u.ID)
 
-	fullVikunjaHierachie, err := convertTodoistToVikunja(syncResponse)
+	fullVikunjaHierachie, err := convertTodoistToVikunja(syncResponse, true)
 	if err != nil {
 		return
 	}
@@ -516,7 +519,7 @@ func (m *Migration) Migrate(u *user.User) (err error) {
 	log.Debugf("[Todoist Migration] Done converting data for user %d", u.ID)
 	log.Debugf("[Todoist Migration] Start inserting data for user %d", u.ID)
 
-	err = migration.InsertFromStructure(fullVikunjaHierachie, u)
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
 	if err != nil {
 		return
 	}
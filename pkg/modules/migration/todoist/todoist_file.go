@@ -0,0 +1,262 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package todoist
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+)
+
+// FileMigrator imports a todoist csv export of a single project or a backup with all projects
+type FileMigrator struct {
+}
+
+// The files in a backup are named like "Project name [123456].csv"
+var backupFileNameRegex = regexp.MustCompile(`\s*\[\d+\]$`)
+
+// Name is used to get the name of the todoist file migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/todoist-file/status [get]
+func (m *FileMigrator) Name() string {
+	return "todoist-file"
+}
+
+// csvConverter puts the rows of one or more todoist csv exports into the same structure the sync api returns
+// to be able to use the same conversion for both.
+type csvConverter struct {
+	sync   *sync
+	lastID int64
+}
+
+func (c *csvConverter) nextID() int64 {
+	c.lastID++
+	return c.lastID
+}
+
+// addProject parses the csv export of a single project.
+// The export contains tasks, sections and notes in the order they appear in todoist.
+// Every note belongs to the task before it, every task to the section before it.
+func (c *csvConverter) addProject(name string, r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return models.ErrInvalidMigrationFile{Reason: "not a todoist csv export"}
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.TrimPrefix(strings.TrimSpace(h), "\ufeff")] = i
+	}
+	if _, has := columns["TYPE"]; !has {
+		return models.ErrInvalidMigrationFile{Reason: "not a todoist csv export"}
+	}
+	if _, has := columns["CONTENT"]; !has {
+		return models.ErrInvalidMigrationFile{Reason: "not a todoist csv export"}
+	}
+
+	p := &project{
+		ID:   c.nextID(),
+		Name: name,
+	}
+	c.sync.Projects = append(c.sync.Projects, p)
+
+	var sectionID int64
+	var lastItem *item
+	// The ids of all parent tasks, the last one is the task with the highest indentation
+	var parents []int64
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return models.ErrInvalidMigrationFile{Reason: err.Error()}
+		}
+
+		get := func(column string) string {
+			i, has := columns[column]
+			if !has || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		switch get("TYPE") {
+		case "section":
+			sectionID = c.nextID()
+			c.sync.Sections = append(c.sync.Sections, &section{
+				ID:           sectionID,
+				Name:         get("CONTENT"),
+				ProjectID:    p.ID,
+				SectionOrder: int64(len(c.sync.Sections)),
+			})
+			parents = nil
+		case "task":
+			i := &item{
+				ID:        c.nextID(),
+				ProjectID: p.ID,
+				Content:   get("CONTENT"),
+				SectionID: sectionID,
+			}
+
+			// The csv export uses the priorities as shown in todoist, 1 is the highest and 4 the lowest priority.
+			priority, err := strconv.ParseInt(get("PRIORITY"), 10, 64)
+			if err == nil && priority >= 1 && priority <= 4 {
+				i.Priority = 5 - priority
+			}
+
+			// Recurring and other dates can only be set in natural language, we can only import fixed dates.
+			if date := get("DATE"); date != "" {
+				if _, err := parseDate(date); err == nil {
+					i.Due = &dueDate{Date: date}
+				} else {
+					log.Debugf("[Todoist File Migration] Could not parse due date %s of task %s, not importing it", date, i.Content)
+				}
+			}
+
+			indent, err := strconv.Atoi(get("INDENT"))
+			if err != nil || indent < 1 {
+				indent = 1
+			}
+			if indent-1 < len(parents) {
+				parents = parents[:indent-1]
+			}
+			if len(parents) > 0 {
+				i.ParentID = parents[len(parents)-1]
+			}
+			parents = append(parents, i.ID)
+
+			c.sync.Items = append(c.sync.Items, i)
+			lastItem = i
+
+			if description := get("DESCRIPTION"); description != "" {
+				c.sync.Notes = append(c.sync.Notes, &note{
+					ID:      c.nextID(),
+					ItemID:  i.ID,
+					Content: description,
+				})
+			}
+		case "note":
+			if lastItem == nil {
+				c.sync.ProjectNotes = append(c.sync.ProjectNotes, &projectNote{
+					ID:        c.nextID(),
+					ProjectID: p.ID,
+					Content:   get("CONTENT"),
+				})
+				continue
+			}
+			c.sync.Notes = append(c.sync.Notes, &note{
+				ID:      c.nextID(),
+				ItemID:  lastItem.ID,
+				Content: get("CONTENT"),
+			})
+		}
+	}
+
+	return nil
+}
+
+// convertFileToSync takes either a single csv export or a zip backup with one csv export per project.
+func convertFileToSync(file io.ReaderAt, size int64) (*sync, error) {
+	c := &csvConverter{sync: &sync{}}
+
+	r, err := zip.NewReader(file, size)
+	if err != nil {
+		// Not a backup, so it has to be the export of a single project
+		err = c.addProject("Imported from todoist", io.NewSectionReader(file, 0, size))
+		return c.sync, err
+	}
+
+	for _, f := range r.File {
+		if path.Ext(f.Name) != ".csv" {
+			continue
+		}
+
+		name := backupFileNameRegex.ReplaceAllString(strings.TrimSuffix(path.Base(f.Name), ".csv"), "")
+		fr, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = c.addProject(name, fr)
+		fr.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(c.sync.Projects) == 0 {
+		return nil, models.ErrInvalidMigrationFile{Reason: "the backup does not contain any projects"}
+	}
+
+	return c.sync, nil
+}
+
+// Migrate imports a todoist csv export or backup
+// @Summary Import all projects and tasks from a todoist csv export or backup
+// @Description Imports all tasks, sections, notes and subtasks from the csv export of a todoist project or all projects from a todoist backup zip file.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The csv export or zip backup from todoist."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a todoist export or backup."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/todoist-file/migrate [put]
+func (m *FileMigrator) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[Todoist File Migration] Starting migration for user %d", u.ID)
+
+	syncData, err := convertFileToSync(file, size)
+	if err != nil {
+		return err
+	}
+
+	fullVikunjaHierachie, err := convertTodoistToVikunja(syncData, false)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Todoist File Migration] Start inserting data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Todoist File Migration] Todoist migration done for user %d", u.ID)
+
+	return nil
+}
@@ -0,0 +1,98 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package todoist
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCSVExport = `TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE
+task,Task 1,Some description,1,1,User (1),,2021-04-10,en,Europe/Berlin
+note,A note,,,,User (1),,,en,Europe/Berlin
+task,Subtask,,4,2,User (1),,every day,en,Europe/Berlin
+,,,,,,,,,
+section,Section 1,,,,,,,,
+task,Task 2,,4,1,User (1),,,en,Europe/Berlin
+`
+
+func TestConvertFileToSync(t *testing.T) {
+	t.Run("csv export", func(t *testing.T) {
+		r := strings.NewReader(testCSVExport)
+		s, err := convertFileToSync(r, r.Size())
+		assert.NoError(t, err)
+
+		assert.Len(t, s.Projects, 1)
+		assert.Equal(t, "Imported from todoist", s.Projects[0].Name)
+
+		assert.Len(t, s.Sections, 1)
+		assert.Equal(t, "Section 1", s.Sections[0].Name)
+		assert.Equal(t, s.Projects[0].ID, s.Sections[0].ProjectID)
+
+		assert.Len(t, s.Items, 3)
+		assert.Equal(t, "Task 1", s.Items[0].Content)
+		assert.Equal(t, int64(4), s.Items[0].Priority)
+		assert.Equal(t, "2021-04-10", s.Items[0].Due.Date)
+		assert.Equal(t, int64(0), s.Items[0].SectionID)
+		assert.Equal(t, "Subtask", s.Items[1].Content)
+		assert.Equal(t, s.Items[0].ID, s.Items[1].ParentID)
+		assert.Nil(t, s.Items[1].Due)
+		assert.Equal(t, int64(1), s.Items[1].Priority)
+		assert.Equal(t, "Task 2", s.Items[2].Content)
+		assert.Equal(t, int64(0), s.Items[2].ParentID)
+		assert.Equal(t, s.Sections[0].ID, s.Items[2].SectionID)
+
+		assert.Len(t, s.Notes, 2)
+		assert.Equal(t, "Some description", s.Notes[0].Content)
+		assert.Equal(t, "A note", s.Notes[1].Content)
+		assert.Equal(t, s.Items[0].ID, s.Notes[1].ItemID)
+
+		_, err = convertTodoistToVikunja(s, false)
+		assert.NoError(t, err)
+	})
+	t.Run("backup", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		w := zip.NewWriter(buf)
+		for _, name := range []string{"Inbox [1234].csv", "Project [5678].csv"} {
+			f, err := w.Create(name)
+			assert.NoError(t, err)
+			_, err = f.Write([]byte(testCSVExport))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, w.Close())
+
+		r := bytes.NewReader(buf.Bytes())
+		s, err := convertFileToSync(r, r.Size())
+		assert.NoError(t, err)
+		assert.Len(t, s.Projects, 2)
+		assert.Equal(t, "Inbox", s.Projects[0].Name)
+		assert.Equal(t, "Project", s.Projects[1].Name)
+		assert.Len(t, s.Items, 6)
+		assert.NotEqual(t, s.Items[0].ID, s.Items[3].ID)
+	})
+	t.Run("invalid file", func(t *testing.T) {
+		r := strings.NewReader("lorem,ipsum\ndolor,sit")
+		_, err := convertFileToSync(r, r.Size())
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+}
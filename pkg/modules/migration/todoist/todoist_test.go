@@ -586,7 +586,7 @@ func TestConvertTodoistToVikunja(t *testing.T) {
 		},
 	}
 
-	hierachie, err := convertTodoistToVikunja(testSync)
+	hierachie, err := convertTodoistToVikunja(testSync, true)
 	assert.NoError(t, err)
 	assert.NotNil(t, hierachie)
 	if diff, equal := messagediff.PrettyDiff(hierachie, expectedHierachie); !equal {
@@ -52,7 +52,7 @@ func init() {
 
 // Name is used to get the name of the trello migration - we're using the docs here to annotate the status route.
 // @Summary Get migration status
-// @Description Returns if the current user already did the migation or not. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
 // @tags migration
 // @Produce json
 // @Security JWTKeyAuth
@@ -144,7 +144,9 @@ func getTrelloData(token string) (trelloData []*trello.Board, err error) {
 
 // Converts all previously obtained data from trello into the vikunja format.
 // `trelloData` should contain all boards with their lists and cards respectively.
-func convertTrelloDataToVikunja(trelloData []*trello.Board) (fullVikunjaHierachie []*models.NamespaceWithLists, err error) {
+// Background images and attachments are only downloaded if downloadFiles is true. Only pass true if their
+// urls were returned by the trello api, never for urls from an uploaded file.
+func convertTrelloDataToVikunja(trelloData []*trello.Board, downloadFiles bool) (fullVikunjaHierachie []*models.NamespaceWithLists, err error) {
 
 	log.Debugf("[Trello Migration] ")
 
@@ -170,7 +172,7 @@ func convertTrelloDataToVikunja(trelloData []*trello.Board) (fullVikunjaHierachi
 
 		// Background
 		// We're pretty much abusing the backgroundinformation field here - not sure if this is really better than adding a new property to the list
-		if board.Prefs.BackgroundImage != "" {
+		if board.Prefs.BackgroundImage != "" && downloadFiles {
 			log.Debugf("[Trello Migration] Downloading background %s for board %s", board.Prefs.BackgroundImage, board.ID)
 			buf, err := migration.DownloadFile(board.Prefs.BackgroundImage)
 			if err != nil {
@@ -179,7 +181,7 @@ func convertTrelloDataToVikunja(trelloData []*trello.Board) (fullVikunjaHierachi
 			log.Debugf("[Trello Migration] Downloaded background %s for board %s", board.Prefs.BackgroundImage, board.ID)
 			list.BackgroundInformation = buf
 		} else {
-			log.Debugf("[Trello Migration] Board %s does not have a background image, not copying...", board.ID)
+			log.Debugf("[Trello Migration] Board %s does not have a background image or it can't be downloaded, not copying...", board.ID)
 		}
 
 		for _, l := range board.Lists {
@@ -249,6 +251,10 @@ func convertTrelloDataToVikunja(trelloData []*trello.Board) (fullVikunjaHierachi
 						log.Debugf("[Trello Migration] Attachment %s does not have a mime type, not downloading", attachment.ID)
 						continue
 					}
+					if !downloadFiles {
+						log.Debugf("[Trello Migration] Not downloading attachment %s", attachment.ID)
+						continue
+					}
 
 					log.Debugf("[Trello Migration] Downloading card attachment %s", attachment.ID)
 
@@ -292,10 +298,11 @@ func convertTrelloDataToVikunja(trelloData []*trello.Board) (fullVikunjaHierachi
 // @Produce json
 // @Security JWTKeyAuth
 // @Param migrationCode body trello.Migration true "The auth token previously obtained from the auth url. See the docs for /migration/trello/auth."
-// @Success 200 {object} models.Message "A message telling you everything was migrated successfully."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
 // @Failure 500 {object} models.Message "Internal server error"
 // @Router /migration/trello/migrate [post]
-func (m *Migration) Migrate(u *user.User) (err error) {
+func (m *Migration) Migrate(u *user.User, status *migration.Status) (err error) {
 	log.Debugf("[Trello Migration] Starting migration for user %d", u.ID)
 	log.Debugf("[Trello Migration] Getting all trello data for user %d", u.ID)
 
@@ -307,7 +314,7 @@ func (m *Migration) Migrate(u *user.User) (err error) {
 	log.Debugf("[Trello Migration] Got all trello data for user %d", u.ID)
 	log.Debugf("[Trello Migration] Start converting trello data for user %d", u.ID)
 
-	fullVikunjaHierachie, err := convertTrelloDataToVikunja(trelloData)
+	fullVikunjaHierachie, err := convertTrelloDataToVikunja(trelloData, true)
 	if err != nil {
 		return
 	}
@@ -315,7 +322,7 @@ func (m *Migration) Migrate(u *user.User) (err error) {
 	log.Debugf("[Trello Migration] Done migrating trello data for user %d", u.ID)
 	log.Debugf("[Trello Migration] Start inserting trello data for user %d", u.ID)
 
-	err = migration.InsertFromStructure(fullVikunjaHierachie, u)
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,138 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package trello
+
+import (
+	"encoding/json"
+	"io"
+
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/adlio/trello"
+)
+
+// FileMigrator imports the json export of a trello board
+type FileMigrator struct {
+}
+
+// boardExport is the json export of a single board. Unlike the api, the export contains
+// all cards and checklists of a board at the top level.
+type boardExport struct {
+	trello.Board
+	Cards      []*trello.Card      `json:"cards"`
+	Checklists []*trello.Checklist `json:"checklists"`
+}
+
+// Name is used to get the name of the trello file migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/trello-file/status [get]
+func (m *FileMigrator) Name() string {
+	return "trello-file"
+}
+
+// Puts all cards and checklists of a board export into their lists, the same way the api returns them.
+// Attachments uploaded to trello can only be downloaded with an api token, they are added as links to
+// the description of their card instead. The background image is not imported for the same reason.
+func convertBoardExport(export *boardExport) *trello.Board {
+	board := &export.Board
+	board.Prefs.BackgroundImage = ""
+
+	listMap := make(map[string]*trello.List, len(board.Lists))
+	for _, list := range board.Lists {
+		list.Cards = nil
+		listMap[list.ID] = list
+	}
+
+	cardMap := make(map[string]*trello.Card, len(export.Cards))
+	for _, card := range export.Cards {
+		list, exists := listMap[card.IDList]
+		if !exists {
+			log.Debugf("[Trello File Migration] Could not find list %s for card %s", card.IDList, card.ID)
+			continue
+		}
+
+		if len(card.Attachments) > 0 {
+			card.Desc += "\n\n## Attachments\n"
+			for _, attachment := range card.Attachments {
+				card.Desc += "\n* [" + attachment.Name + "](" + attachment.URL + ")"
+			}
+			card.Attachments = nil
+		}
+
+		card.Checklists = nil
+		cardMap[card.ID] = card
+		list.Cards = append(list.Cards, card)
+	}
+
+	for _, checklist := range export.Checklists {
+		card, exists := cardMap[checklist.IDCard]
+		if !exists {
+			log.Debugf("[Trello File Migration] Could not find card %s for checklist %s", checklist.IDCard, checklist.ID)
+			continue
+		}
+		card.Checklists = append(card.Checklists, checklist)
+	}
+
+	return board
+}
+
+// Migrate imports a trello board from its json export
+// @Summary Import a trello board from its json export
+// @Description Imports all lists, cards, checklists and labels of a trello board from its json export. Every board needs to be imported separately.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The json export of a trello board."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a trello board export."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/trello-file/migrate [put]
+func (m *FileMigrator) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	log.Debugf("[Trello File Migration] Starting migration for user %d", u.ID)
+
+	export := &boardExport{}
+	err := json.NewDecoder(io.NewSectionReader(file, 0, size)).Decode(export)
+	if err != nil || export.Board.ID == "" {
+		return models.ErrInvalidMigrationFile{Reason: "not a trello board export"}
+	}
+
+	fullVikunjaHierachie, err := convertTrelloDataToVikunja([]*trello.Board{convertBoardExport(export)}, false)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Trello File Migration] Start inserting trello data for user %d", u.ID)
+
+	err = migration.InsertFromStructure(fullVikunjaHierachie, u, status)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("[Trello File Migration] Migration done for user %d", u.ID)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package trello
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adlio/trello"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBoardExport = `{
+  "id": "board1",
+  "name": "Test Board",
+  "desc": "Board description",
+  "closed": false,
+  "prefs": {"backgroundImage": "https://trello-backgrounds.s3.amazonaws.com/test.jpg"},
+  "lists": [
+    {"id": "list1", "name": "Todo", "closed": false},
+    {"id": "list2", "name": "Done", "closed": false}
+  ],
+  "cards": [
+    {
+      "id": "card1",
+      "name": "Card 1",
+      "desc": "Card description",
+      "idList": "list1",
+      "pos": 1,
+      "labels": [{"id": "label1", "name": "Label", "color": "green"}],
+      "attachments": [{"id": "att1", "name": "file.pdf", "url": "https://trello.com/1/cards/card1/attachments/att1/download/file.pdf", "isUpload": true}]
+    },
+    {"id": "card2", "name": "Card 2", "idList": "list2", "pos": 2},
+    {"id": "card3", "name": "Card without list", "idList": "list3", "pos": 3}
+  ],
+  "checklists": [
+    {"id": "checklist1", "idCard": "card2", "name": "Checklist", "checkItems": [{"name": "Item", "state": "complete"}]}
+  ]
+}`
+
+func TestConvertBoardExport(t *testing.T) {
+	export := &boardExport{}
+	err := json.Unmarshal([]byte(testBoardExport), export)
+	assert.NoError(t, err)
+
+	board := convertBoardExport(export)
+	assert.Equal(t, "Test Board", board.Name)
+	assert.Empty(t, board.Prefs.BackgroundImage)
+	assert.Len(t, board.Lists, 2)
+
+	assert.Len(t, board.Lists[0].Cards, 1)
+	card := board.Lists[0].Cards[0]
+	assert.Equal(t, "Card 1", card.Name)
+	assert.Empty(t, card.Attachments)
+	assert.Equal(t, "Card description\n\n## Attachments\n\n* [file.pdf](https://trello.com/1/cards/card1/attachments/att1/download/file.pdf)", card.Desc)
+
+	assert.Len(t, board.Lists[1].Cards, 1)
+	assert.Len(t, board.Lists[1].Cards[0].Checklists, 1)
+	assert.Equal(t, "Checklist", board.Lists[1].Cards[0].Checklists[0].Name)
+
+	hierachie, err := convertTrelloDataToVikunja([]*trello.Board{board}, false)
+	assert.NoError(t, err)
+	assert.Len(t, hierachie[0].Lists, 1)
+	assert.Len(t, hierachie[0].Lists[0].Tasks, 2)
+	assert.Len(t, hierachie[0].Lists[0].Buckets, 2)
+}
@@ -0,0 +1,365 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package vikunjafile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/xorm"
+)
+
+// FileMigrator imports a data export of Vikunja, created with the data export of another (or the same) instance.
+type FileMigrator struct {
+}
+
+// Name is used to get the name of the vikunja-file migration - we're using the docs here to annotate the status route.
+// @Summary Get migration status
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @tags migration
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} migration.Status "The migration status"
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/vikunja-file/status [get]
+func (v *FileMigrator) Name() string {
+	return "vikunja-file"
+}
+
+// exportData holds everything from an export which needs to be created after the main structure
+type exportData struct {
+	// The tasks with their original relations and comments, keyed by the task id in the export
+	tasks   map[int64]*models.TaskWithComments
+	related map[int64]models.RelatedTaskMap
+	filters []*models.SavedFilter
+	labels  []*models.Label
+}
+
+// Migrate takes a vikunja data export and imports everything in it for the user.
+// @Summary Import all lists, tasks etc. from a Vikunja data export
+// @Description Imports all namespaces, lists, tasks, comments, labels, saved filters and files from a zip file created with the Vikunja data export.
+// @tags migration
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security JWTKeyAuth
+// @Param import formData string true "The Vikunja export zip file."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
+// @Failure 400 {object} web.HTTPError "The file is not a Vikunja data export or was created by a newer version of Vikunja."
+// @Failure 500 {object} models.Message "Internal server error"
+// @Router /migration/vikunja-file/migrate [put]
+func (v *FileMigrator) Migrate(u *user.User, file io.ReaderAt, size int64, status *migration.Status) error {
+	r, err := zip.NewReader(file, size)
+	if err != nil {
+		return models.ErrInvalidMigrationFile{Reason: "not a zip file"}
+	}
+
+	zipFiles := make(map[string]*zip.File, len(r.File))
+	for _, f := range r.File {
+		zipFiles[f.Name] = f
+	}
+
+	info := &models.UserDataExportInfo{}
+	err = readJSONFromZip(zipFiles, "info.json", info)
+	if err != nil {
+		return err
+	}
+	if info.ExportVersion > models.UserDataExportVersion {
+		return models.ErrUnsupportedExportVersion{Version: info.ExportVersion}
+	}
+
+	log.Debugf("[Vikunja File Migration] Importing export version %d from Vikunja %s for user %d", info.ExportVersion, info.VikunjaVersion, u.ID)
+
+	namespaces := []*models.NamespaceWithListsAndTasks{}
+	err = readJSONFromZip(zipFiles, "data.json", &namespaces)
+	if err != nil {
+		return err
+	}
+
+	data := &exportData{
+		tasks:   make(map[int64]*models.TaskWithComments),
+		related: make(map[int64]models.RelatedTaskMap),
+	}
+	err = readJSONFromZip(zipFiles, "filters.json", &data.filters)
+	if err != nil {
+		return err
+	}
+	err = readJSONFromZip(zipFiles, "labels.json", &data.labels)
+	if err != nil {
+		return err
+	}
+
+	structure, err := convertExport(namespaces, zipFiles, data)
+	if err != nil {
+		return err
+	}
+
+	s := db.NewSession()
+	err = removeExistingIdentifiers(s, structure)
+	s.Close()
+	if err != nil {
+		return err
+	}
+
+	err = migration.InsertFromStructure(structure, u, status)
+	if err != nil {
+		return err
+	}
+
+	// Nothing was created in a dry run, the extras would not have anything to refer to
+	if status.IsDryRun() {
+		return nil
+	}
+
+	s = db.NewSession()
+	defer s.Close()
+
+	err = createExtras(s, u, data)
+	if err != nil {
+		_ = s.Rollback()
+		return err
+	}
+
+	return s.Commit()
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openFromZip opens a file of the export. Files larger than the configured maximum file size are rejected
+// since their content is read into memory. The size in the zip is not trusted, reading stops once the maximum
+// file size is reached.
+func openFromZip(f *zip.File) (io.ReadCloser, error) {
+	maxSize, err := files.MaxSize()
+	if err != nil {
+		return nil, err
+	}
+	if f.UncompressedSize64 > maxSize {
+		return nil, models.ErrInvalidMigrationFile{Reason: f.Name + " is larger than the maximum file size"}
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedReadCloser{
+		Reader: io.LimitReader(r, int64(maxSize)),
+		Closer: r,
+	}, nil
+}
+
+func readJSONFromZip(zipFiles map[string]*zip.File, name string, v interface{}) error {
+	f, exists := zipFiles[name]
+	if !exists {
+		return models.ErrInvalidMigrationFile{Reason: name + " is missing"}
+	}
+
+	r, err := openFromZip(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	err = json.NewDecoder(r).Decode(v)
+	if err != nil {
+		return models.ErrInvalidMigrationFile{Reason: name + " is not valid json"}
+	}
+	return nil
+}
+
+func readFileFromZip(zipFiles map[string]*zip.File, fileID int64) ([]byte, error) {
+	f, exists := zipFiles["files/"+strconv.FormatInt(fileID, 10)]
+	if !exists {
+		return nil, nil
+	}
+
+	r, err := openFromZip(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// convertExport turns the exported namespaces into the structure used by all migrators.
+// Everything which can't be created by it is saved in data.
+func convertExport(namespaces []*models.NamespaceWithListsAndTasks, zipFiles map[string]*zip.File, data *exportData) (structure []*models.NamespaceWithLists, err error) {
+	structure = make([]*models.NamespaceWithLists, 0, len(namespaces))
+
+	for _, n := range namespaces {
+		namespace := &models.NamespaceWithLists{
+			Namespace: n.Namespace,
+		}
+		namespace.ID = 0
+
+		for _, l := range n.Lists {
+			list := l.List
+			list.ID = 0
+			list.Buckets = l.Buckets
+
+			if l.BackgroundFileID != 0 {
+				content, err := readFileFromZip(zipFiles, l.BackgroundFileID)
+				if err != nil {
+					return nil, err
+				}
+				list.BackgroundInformation = nil
+				if content != nil {
+					list.BackgroundInformation = bytes.NewBuffer(content)
+				}
+			}
+
+			for _, t := range l.Tasks {
+				oldID := t.ID
+				data.tasks[oldID] = t
+				if len(t.RelatedTasks) > 0 {
+					data.related[oldID] = t.RelatedTasks
+				}
+
+				// Relations are created once all tasks exist, see createExtras.
+				t.RelatedTasks = nil
+				// The users of another instance don't exist here
+				t.Assignees = nil
+
+				for _, label := range t.Labels {
+					label.ID = 0
+				}
+
+				for _, a := range t.Attachments {
+					a.ID = 0
+					if a.File == nil {
+						continue
+					}
+					content, err := readFileFromZip(zipFiles, a.File.ID)
+					if err != nil {
+						return nil, err
+					}
+					a.File.FileContent = content
+				}
+
+				list.Tasks = append(list.Tasks, &t.Task)
+			}
+
+			namespace.Lists = append(namespace.Lists, &list)
+		}
+
+		structure = append(structure, namespace)
+	}
+
+	return structure, nil
+}
+
+// removeExistingIdentifiers clears all list identifiers which are already used by another list
+// since they need to be unique.
+func removeExistingIdentifiers(s *xorm.Session, structure []*models.NamespaceWithLists) error {
+	for _, n := range structure {
+		for _, l := range n.Lists {
+			if l.Identifier == "" {
+				continue
+			}
+			exists, err := s.
+				Where("identifier = ?", l.Identifier).
+				Exist(&models.List{})
+			if err != nil {
+				return err
+			}
+			if exists {
+				l.Identifier = ""
+			}
+		}
+	}
+	return nil
+}
+
+// createExtras creates everything from the export which is not part of the structure all migrators use:
+// comments, task relations, saved filters and labels which are not used on any task.
+// It needs to run after the structure was created so the tasks have their new ids.
+func createExtras(s *xorm.Session, u *user.User, data *exportData) (err error) {
+	for _, t := range data.tasks {
+		for _, c := range t.Comments {
+			c.ID = 0
+			c.TaskID = t.ID
+			c.AuthorID = u.ID
+			_, err = s.NoAutoTime().Insert(c)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for oldID, related := range data.related {
+		task := data.tasks[oldID]
+		for kind, relatedTasks := range related {
+			for _, rt := range relatedTasks {
+				other, exists := data.tasks[rt.ID]
+				if !exists {
+					continue
+				}
+				rel := &models.TaskRelation{
+					TaskID:       task.ID,
+					OtherTaskID:  other.ID,
+					RelationKind: kind,
+				}
+				err = rel.Create(s, u)
+				// Every relation is exported for both tasks but creating it once creates both directions
+				if err != nil && !models.IsErrRelationAlreadyExists(err) {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, f := range data.filters {
+		f.ID = 0
+		err = f.Create(s, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range data.labels {
+		exists, err := s.
+			Where("created_by_id = ? AND title = ? AND hex_color = ?", u.ID, l.Title, strings.TrimPrefix(l.HexColor, "#")).
+			Exist(&models.Label{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		l.ID = 0
+		err = l.Create(s, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package vikunjafile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/files"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestZip(t *testing.T, content map[string]interface{}) *bytes.Reader {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, c := range content {
+		f, err := w.Create(name)
+		assert.NoError(t, err)
+		if b, is := c.([]byte); is {
+			_, err = f.Write(b)
+		} else {
+			err = json.NewEncoder(f).Encode(c)
+		}
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestFileMigrator_Migrate(t *testing.T) {
+	config.InitDefaultConfig()
+	u := &user.User{ID: 1}
+
+	t.Run("not a zip file", func(t *testing.T) {
+		content := []byte("lorem ipsum")
+		err := (&FileMigrator{}).Migrate(u, bytes.NewReader(content), int64(len(content)), nil)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+	t.Run("missing info", func(t *testing.T) {
+		r := createTestZip(t, map[string]interface{}{
+			"data.json": []interface{}{},
+		})
+		err := (&FileMigrator{}).Migrate(u, r, r.Size(), nil)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+	t.Run("newer export version", func(t *testing.T) {
+		r := createTestZip(t, map[string]interface{}{
+			"info.json": &models.UserDataExportInfo{ExportVersion: models.UserDataExportVersion + 1},
+		})
+		err := (&FileMigrator{}).Migrate(u, r, r.Size(), nil)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrUnsupportedExportVersion(err))
+	})
+	t.Run("larger than the maximum file size", func(t *testing.T) {
+		maxSize := config.FilesMaxSize.GetString()
+		defer config.FilesMaxSize.Set(maxSize)
+		config.FilesMaxSize.Set("1KB")
+
+		r := createTestZip(t, map[string]interface{}{
+			"info.json": []byte(strings.Repeat(" ", 2048) + "{}"),
+		})
+		err := (&FileMigrator{}).Migrate(u, r, r.Size(), nil)
+		assert.Error(t, err)
+		assert.True(t, models.IsErrInvalidMigrationFile(err))
+	})
+}
+
+func TestConvertExport(t *testing.T) {
+	config.InitDefaultConfig()
+	task1 := &models.TaskWithComments{
+		Task: models.Task{
+			ID:        1,
+			Title:     "Task 1",
+			BucketID:  3,
+			Assignees: []*user.User{{ID: 2}},
+			Labels:    []*models.Label{{ID: 4, Title: "Label"}},
+			Attachments: []*models.TaskAttachment{
+				{ID: 5, File: &files.File{ID: 6, Name: "test.txt"}},
+			},
+			RelatedTasks: models.RelatedTaskMap{
+				models.RelationKindSubtask: []*models.Task{{ID: 2}},
+			},
+		},
+		Comments: []*models.TaskComment{{ID: 7, Comment: "Lorem Ipsum"}},
+	}
+	task2 := &models.TaskWithComments{
+		Task: models.Task{
+			ID:    2,
+			Title: "Task 2",
+		},
+	}
+
+	namespaces := []*models.NamespaceWithListsAndTasks{
+		{
+			Namespace: models.Namespace{ID: 8, Title: "Namespace"},
+			Lists: []*models.ListWithTasksAndBuckets{
+				{
+					List:             models.List{ID: 9, Title: "List", Identifier: "TEST"},
+					Tasks:            []*models.TaskWithComments{task1, task2},
+					Buckets:          []*models.Bucket{{ID: 3, Title: "Bucket"}},
+					BackgroundFileID: 10,
+				},
+			},
+		},
+	}
+
+	r := createTestZip(t, map[string]interface{}{
+		"files/6":  []byte("attachment"),
+		"files/10": []byte("background"),
+	})
+	zr, err := zip.NewReader(r, r.Size())
+	assert.NoError(t, err)
+	zipFiles := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		zipFiles[f.Name] = f
+	}
+
+	data := &exportData{
+		tasks:   make(map[int64]*models.TaskWithComments),
+		related: make(map[int64]models.RelatedTaskMap),
+	}
+	structure, err := convertExport(namespaces, zipFiles, data)
+	assert.NoError(t, err)
+
+	assert.Len(t, structure, 1)
+	assert.Equal(t, int64(0), structure[0].ID)
+	assert.Equal(t, "Namespace", structure[0].Title)
+	assert.Len(t, structure[0].Lists, 1)
+
+	list := structure[0].Lists[0]
+	assert.Equal(t, int64(0), list.ID)
+	assert.Equal(t, "TEST", list.Identifier)
+	assert.Equal(t, "background", list.BackgroundInformation.(*bytes.Buffer).String())
+	assert.Len(t, list.Buckets, 1)
+	assert.Equal(t, int64(3), list.Buckets[0].ID)
+	assert.Len(t, list.Tasks, 2)
+
+	task := list.Tasks[0]
+	assert.Equal(t, "Task 1", task.Title)
+	assert.Equal(t, int64(3), task.BucketID)
+	assert.Nil(t, task.Assignees)
+	assert.Nil(t, task.RelatedTasks)
+	assert.Equal(t, int64(0), task.Labels[0].ID)
+	assert.Equal(t, int64(0), task.Attachments[0].ID)
+	assert.Equal(t, []byte("attachment"), task.Attachments[0].File.FileContent)
+
+	assert.Len(t, data.tasks, 2)
+	assert.Same(t, task1, data.tasks[1])
+	assert.Len(t, data.related, 1)
+	assert.Equal(t, int64(2), data.related[1][models.RelationKindSubtask][0].ID)
+}
@@ -344,10 +344,11 @@ func makeAuthGetRequest(token *wunderlistAuthToken, urlPart string, v interface{
 // @Produce json
 // @Security JWTKeyAuth
 // @Param migrationCode body wunderlist.Migration true "The auth code previously obtained from the auth url. See the docs for /migration/wunderlist/auth."
-// @Success 200 {object} models.Message "A message telling you everything was migrated successfully."
+// @Param dry_run query bool false "If true, everything is only converted and returned as namespaces with their lists and tasks instead of being created."
+// @Success 200 {object} migration.Status "The status of the migration job which was started in the background."
 // @Failure 500 {object} models.Message "Internal server error"
 // @Router /migration/wunderlist/migrate [post]
-func (w *Migration) Migrate(user *user.User) (err error) {
+func (w *Migration) Migrate(user *user.User, status *migration.Status) (err error) {
 
 	log.Debugf("[Wunderlist migration] Starting wunderlist migration for user %d", user.ID)
 
@@ -468,7 +469,7 @@ func (w *Migration) Migrate(user *user.User) (err error) {
 	log.Debugf("[Wunderlist migration] Done migrating data to vikunja format for user %d", user.ID)
 	log.Debugf("[Wunderlist migration] Insert data into db for user %d", user.ID)
 
-	err = migration.InsertFromStructure(fullVikunjaHierachie, user)
+	err = migration.InsertFromStructure(fullVikunjaHierachie, user, status)
 	if err != nil {
 		return err
 	}
@@ -498,7 +499,7 @@ func (w *Migration) AuthURL() string {
 
 // Name is used to get the name of the wunderlist migration
 // @Summary Get migration status
-// @Description Returns if the current user already did the migation or not. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
+// @Description Returns the latest successful migration of the current user, jobs which are still running or failed are not taken into account. This is useful to show a confirmation message in the frontend if the user is trying to do the same migration again.
 // @tags migration
 // @Produce json
 // @Security JWTKeyAuth
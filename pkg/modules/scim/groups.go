@@ -0,0 +1,524 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+const maxGroupNameLength = 250
+
+// Group is the scim representation of a vikunja team
+type Group struct {
+	Schemas     []string  `json:"schemas"`
+	ID          string    `json:"id,omitempty"`
+	ExternalID  string    `json:"externalId,omitempty"`
+	DisplayName string    `json:"displayName"`
+	Members     []*Member `json:"members"`
+	Meta        *Meta     `json:"meta,omitempty"`
+}
+
+// Member is a user who is a member of a group
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+func groupToResource(t *models.Team, members []*user.User) *Group {
+	r := &Group{
+		Schemas:     []string{schemaGroup},
+		ID:          strconv.FormatInt(t.ID, 10),
+		ExternalID:  t.ExternalID,
+		DisplayName: t.Name,
+		Members:     make([]*Member, 0, len(members)),
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      t.Created,
+			LastModified: t.Updated,
+		},
+	}
+	for _, u := range members {
+		r.Members = append(r.Members, &Member{
+			Value:   strconv.FormatInt(u.ID, 10),
+			Display: u.GetName(),
+		})
+	}
+	return r
+}
+
+// getTeam returns a team managed through scim
+func getTeam(s *xorm.Session, id string) (t *models.Team, err error) {
+	teamID, err := parseID(id, "Group")
+	if err != nil {
+		return nil, err
+	}
+
+	t = &models.Team{}
+	exists, err := s.
+		Where("id = ? AND issuer = ?", teamID, getIssuer()).
+		Get(t)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, newNotFoundError("Group", id)
+	}
+	return
+}
+
+// getTeamMembers returns the members of all passed teams, keyed by team id
+func getTeamMembers(s *xorm.Session, teams []*models.Team) (members map[int64][]*user.User, err error) {
+	members = make(map[int64][]*user.User, len(teams))
+	if len(teams) == 0 {
+		return
+	}
+
+	teamIDs := make([]int64, 0, len(teams))
+	for _, t := range teams {
+		teamIDs = append(teamIDs, t.ID)
+	}
+
+	memberships := []*models.TeamMember{}
+	err = s.
+		In("team_id", teamIDs).
+		OrderBy("id asc").
+		Find(&memberships)
+	if err != nil {
+		return
+	}
+
+	userIDs := make([]int64, 0, len(memberships))
+	for _, m := range memberships {
+		userIDs = append(userIDs, m.UserID)
+	}
+
+	users, err := user.GetUsersByIDs(s, userIDs)
+	if err != nil {
+		return
+	}
+
+	for _, m := range memberships {
+		if u, has := users[m.UserID]; has {
+			members[m.TeamID] = append(members[m.TeamID], u)
+		}
+	}
+	return
+}
+
+func getGroup(s *xorm.Session, id string) (*Group, error) {
+	t, err := getTeam(s, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := getTeamMembers(s, []*models.Team{t})
+	if err != nil {
+		return nil, err
+	}
+	return groupToResource(t, members[t.ID]), nil
+}
+
+func listGroups(s *xorm.Session, f *filter, startIndex, count int) (groups []*Group, total int64, err error) {
+	cond := builder.NewCond().And(builder.Eq{"issuer": getIssuer()})
+	if f != nil {
+		switch f.attribute {
+		case "displayname":
+			cond = cond.And(builder.Eq{"name": f.value})
+		case "externalid":
+			cond = cond.And(builder.Eq{"external_id": f.value})
+		default:
+			return nil, 0, &scimError{status: http.StatusBadRequest, scimType: "invalidFilter", detail: "Groups can only be filtered by displayName or externalId."}
+		}
+	}
+
+	groups = []*Group{}
+	if count == 0 {
+		total, err = s.Where(cond).Count(&models.Team{})
+		return
+	}
+
+	teams := []*models.Team{}
+	total, err = s.
+		Where(cond).
+		OrderBy("id asc").
+		Limit(count, startIndex-1).
+		FindAndCount(&teams)
+	if err != nil {
+		return
+	}
+
+	members, err := getTeamMembers(s, teams)
+	if err != nil {
+		return
+	}
+
+	for _, t := range teams {
+		groups = append(groups, groupToResource(t, members[t.ID]))
+	}
+	return
+}
+
+func validateGroupName(name string) error {
+	if name == "" || len(name) > maxGroupNameLength {
+		return newInvalidValueError("The displayName is required and may not be longer than 250 characters.")
+	}
+	return nil
+}
+
+func checkExternalIDIsUnique(s *xorm.Session, externalID string, teamID int64) error {
+	exists, err := s.
+		Where("issuer = ? AND external_id = ? AND id != ?", getIssuer(), externalID, teamID).
+		Exist(&models.Team{})
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &scimError{status: http.StatusConflict, scimType: "uniqueness", detail: "A group with this externalId already exists."}
+	}
+	return nil
+}
+
+// getMemberIDs returns the user ids of all members and makes sure they are managed through scim
+func getMemberIDs(s *xorm.Session, members []*Member) (userIDs []int64, err error) {
+	userIDs = make([]int64, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m.Value, 10, 64)
+		if err != nil {
+			return nil, newInvalidValueError("User " + m.Value + " does not exist.")
+		}
+		userIDs = append(userIDs, id)
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	count, err := s.
+		In("id", userIDs).
+		And("issuer = ?", getIssuer()).
+		Count(&user.User{})
+	if err != nil {
+		return nil, err
+	}
+	if int(count) != len(uniqueIDs(userIDs)) {
+		return nil, newInvalidValueError("Not all members are users which exist.")
+	}
+
+	return
+}
+
+func uniqueIDs(ids []int64) map[int64]bool {
+	unique := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	return unique
+}
+
+func addTeamMembers(s *xorm.Session, t *models.Team, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	existing := []*models.TeamMember{}
+	err := s.
+		Where("team_id = ?", t.ID).
+		In("user_id", userIDs).
+		Find(&existing)
+	if err != nil {
+		return err
+	}
+
+	isMember := make(map[int64]bool, len(existing))
+	for _, m := range existing {
+		isMember[m.UserID] = true
+	}
+
+	for id := range uniqueIDs(userIDs) {
+		if isMember[id] {
+			continue
+		}
+		_, err = s.Insert(&models.TeamMember{
+			TeamID: t.ID,
+			UserID: id,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func removeTeamMembers(s *xorm.Session, t *models.Team, userIDs []int64) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	_, err := s.
+		Where("team_id = ?", t.ID).
+		In("user_id", userIDs).
+		Delete(&models.TeamMember{})
+	return err
+}
+
+// setTeamMembers makes sure exactly the passed users are members of the team
+func setTeamMembers(s *xorm.Session, t *models.Team, userIDs []int64) error {
+	memberships := []*models.TeamMember{}
+	err := s.
+		Where("team_id = ?", t.ID).
+		Find(&memberships)
+	if err != nil {
+		return err
+	}
+
+	wanted := uniqueIDs(userIDs)
+	toRemove := []int64{}
+	for _, m := range memberships {
+		if !wanted[m.UserID] {
+			toRemove = append(toRemove, m.UserID)
+		}
+	}
+
+	err = removeTeamMembers(s, t, toRemove)
+	if err != nil {
+		return err
+	}
+
+	return addTeamMembers(s, t, userIDs)
+}
+
+func createGroup(s *xorm.Session, r *Group) (*Group, error) {
+	if err := validateGroupName(r.DisplayName); err != nil {
+		return nil, err
+	}
+
+	externalID := r.ExternalID
+	if externalID == "" {
+		externalID = r.DisplayName
+	}
+	if err := checkExternalIDIsUnique(s, externalID, 0); err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := getMemberIDs(s, r.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	// Teams managed through scim are externally managed which means their members cannot be changed in Vikunja.
+	t := &models.Team{
+		Name:       r.DisplayName,
+		ExternalID: externalID,
+		Issuer:     getIssuer(),
+	}
+	_, err = s.Insert(t)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addTeamMembers(s, t, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = events.Dispatch(&models.TeamCreatedEvent{
+		Team: t,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return getGroup(s, strconv.FormatInt(t.ID, 10))
+}
+
+func updateTeam(s *xorm.Session, t *models.Team) error {
+	if err := validateGroupName(t.Name); err != nil {
+		return err
+	}
+	if err := checkExternalIDIsUnique(s, t.ExternalID, t.ID); err != nil {
+		return err
+	}
+
+	_, err := s.
+		ID(t.ID).
+		Cols("name", "external_id").
+		Update(t)
+	return err
+}
+
+func replaceGroup(s *xorm.Session, id string, r *Group) (*Group, error) {
+	t, err := getTeam(s, id)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Name = r.DisplayName
+	if r.ExternalID != "" {
+		t.ExternalID = r.ExternalID
+	}
+	err = updateTeam(s, t)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := getMemberIDs(s, r.Members)
+	if err != nil {
+		return nil, err
+	}
+	err = setTeamMembers(s, t, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return getGroup(s, id)
+}
+
+func unmarshalMembers(s *xorm.Session, value json.RawMessage) (userIDs []int64, err error) {
+	members := []*Member{}
+	if err := json.Unmarshal(value, &members); err != nil {
+		return nil, newInvalidValueError("Expected a list of members.")
+	}
+	return getMemberIDs(s, members)
+}
+
+// getMemberFromPath returns the user id in a path like `members[value eq "42"]`
+func getMemberFromPath(path string) (userID int64, err error) {
+	if !strings.HasPrefix(strings.ToLower(path), "members[") || !strings.HasSuffix(path, "]") {
+		return 0, &scimError{status: http.StatusBadRequest, scimType: "invalidPath", detail: "Unsupported path " + path + "."}
+	}
+
+	f, err := parseFilter(path[len("members[") : len(path)-1])
+	if err != nil || f == nil || f.attribute != "value" {
+		return 0, &scimError{status: http.StatusBadRequest, scimType: "invalidPath", detail: "Unsupported path " + path + "."}
+	}
+
+	userID, err = strconv.ParseInt(f.value, 10, 64)
+	if err != nil {
+		return 0, newInvalidValueError("User " + f.value + " does not exist.")
+	}
+	return
+}
+
+// applyGroupAttribute adds or replaces a single attribute of a group. Attributes vikunja does not store are ignored.
+func applyGroupAttribute(s *xorm.Session, t *models.Team, op string, path string, value json.RawMessage) (err error) {
+	switch strings.ToLower(path) {
+	case "displayname":
+		t.Name, err = unmarshalString(value)
+		if err != nil {
+			return
+		}
+		return updateTeam(s, t)
+	case "externalid":
+		t.ExternalID, err = unmarshalString(value)
+		if err != nil {
+			return
+		}
+		return updateTeam(s, t)
+	case "members":
+		userIDs, err := unmarshalMembers(s, value)
+		if err != nil {
+			return err
+		}
+		if op == "replace" {
+			return setTeamMembers(s, t, userIDs)
+		}
+		return addTeamMembers(s, t, userIDs)
+	}
+
+	return nil
+}
+
+func patchGroup(s *xorm.Session, id string, req *PatchRequest) (*Group, error) {
+	t, err := getTeam(s, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range req.Operations {
+		switch normalizeOp(op) {
+		case "add", "replace":
+			attributes := map[string]json.RawMessage{op.Path: op.Value}
+			if op.Path == "" {
+				attributes = map[string]json.RawMessage{}
+				if err := json.Unmarshal(op.Value, &attributes); err != nil {
+					return nil, newInvalidValueError("Expected an object of attributes.")
+				}
+			}
+
+			for path, value := range attributes {
+				err = applyGroupAttribute(s, t, normalizeOp(op), path, value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "remove":
+			var userIDs []int64
+			switch {
+			case strings.EqualFold(op.Path, "members") && (len(op.Value) == 0 || string(op.Value) == "null"):
+				// Removes all members
+				err = setTeamMembers(s, t, nil)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			case strings.EqualFold(op.Path, "members"):
+				members := []*Member{}
+				if err := json.Unmarshal(op.Value, &members); err != nil {
+					return nil, newInvalidValueError("Expected a list of members.")
+				}
+				for _, m := range members {
+					userID, err := strconv.ParseInt(m.Value, 10, 64)
+					if err != nil {
+						return nil, newInvalidValueError("User " + m.Value + " does not exist.")
+					}
+					userIDs = append(userIDs, userID)
+				}
+			default:
+				userID, err := getMemberFromPath(op.Path)
+				if err != nil {
+					return nil, err
+				}
+				userIDs = []int64{userID}
+			}
+
+			err = removeTeamMembers(s, t, userIDs)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, newInvalidValueError("Unsupported patch operation " + op.Op + ".")
+		}
+	}
+
+	return getGroup(s, id)
+}
+
+func deleteGroup(s *xorm.Session, id string) error {
+	t, err := getTeam(s, id)
+	if err != nil {
+		return err
+	}
+
+	return t.Delete(s, nil)
+}
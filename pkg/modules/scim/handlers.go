@@ -0,0 +1,214 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package scim
+
+import (
+	"net/http"
+
+	"code.vikunja.io/api/pkg/db"
+	"github.com/labstack/echo/v4"
+	"xorm.io/xorm"
+)
+
+// handle runs fn in a new database session and writes its result as a scim response
+func handle(c echo.Context, status int, fn func(s *xorm.Session) (interface{}, error)) error {
+	s := db.NewSession()
+	defer s.Close()
+
+	res, err := fn(s)
+	if err != nil {
+		_ = s.Rollback()
+		return handleError(c, err)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handleError(c, err)
+	}
+
+	if res == nil {
+		return c.NoContent(status)
+	}
+
+	return writeResponse(c, status, res)
+}
+
+// ListUsers returns all users managed through scim, optionally filtered
+func ListUsers(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		f, err := parseFilter(c.QueryParam("filter"))
+		if err != nil {
+			return nil, err
+		}
+		startIndex, count := getPagination(c)
+
+		users, total, err := listUsers(s, f, startIndex, count)
+		if err != nil {
+			return nil, err
+		}
+
+		resources := make([]*User, 0, len(users))
+		for _, u := range users {
+			resources = append(resources, userToResource(u))
+		}
+
+		return &ListResponse{
+			Schemas:      []string{schemaListResponse},
+			TotalResults: total,
+			StartIndex:   startIndex,
+			ItemsPerPage: len(resources),
+			Resources:    resources,
+		}, nil
+	})
+}
+
+// GetUser returns a single user managed through scim
+func GetUser(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		u, err := getUser(s, c.Param("id"))
+		if err != nil {
+			return nil, err
+		}
+		return userToResource(u), nil
+	})
+}
+
+// CreateUser provisions a new user
+func CreateUser(c echo.Context) error {
+	return handle(c, http.StatusCreated, func(s *xorm.Session) (interface{}, error) {
+		r := &User{}
+		if err := bindRequest(c, r); err != nil {
+			return nil, err
+		}
+
+		u, err := createUser(s, r)
+		if err != nil {
+			return nil, err
+		}
+		return userToResource(u), nil
+	})
+}
+
+// ReplaceUser updates all attributes of a user
+func ReplaceUser(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		r := &User{}
+		if err := bindRequest(c, r); err != nil {
+			return nil, err
+		}
+
+		u, err := replaceUser(s, c.Param("id"), r)
+		if err != nil {
+			return nil, err
+		}
+		return userToResource(u), nil
+	})
+}
+
+// PatchUser updates single attributes of a user
+func PatchUser(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		req := &PatchRequest{}
+		if err := bindRequest(c, req); err != nil {
+			return nil, err
+		}
+
+		u, err := patchUser(s, c.Param("id"), req)
+		if err != nil {
+			return nil, err
+		}
+		return userToResource(u), nil
+	})
+}
+
+// DeleteUser deprovisions a user by disabling their account
+func DeleteUser(c echo.Context) error {
+	return handle(c, http.StatusNoContent, func(s *xorm.Session) (interface{}, error) {
+		return nil, deleteUser(s, c.Param("id"))
+	})
+}
+
+// ListGroups returns all teams managed through scim, optionally filtered
+func ListGroups(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		f, err := parseFilter(c.QueryParam("filter"))
+		if err != nil {
+			return nil, err
+		}
+		startIndex, count := getPagination(c)
+
+		groups, total, err := listGroups(s, f, startIndex, count)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ListResponse{
+			Schemas:      []string{schemaListResponse},
+			TotalResults: total,
+			StartIndex:   startIndex,
+			ItemsPerPage: len(groups),
+			Resources:    groups,
+		}, nil
+	})
+}
+
+// GetGroup returns a single team managed through scim
+func GetGroup(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		return getGroup(s, c.Param("id"))
+	})
+}
+
+// CreateGroup creates a new team
+func CreateGroup(c echo.Context) error {
+	return handle(c, http.StatusCreated, func(s *xorm.Session) (interface{}, error) {
+		r := &Group{}
+		if err := bindRequest(c, r); err != nil {
+			return nil, err
+		}
+		return createGroup(s, r)
+	})
+}
+
+// ReplaceGroup updates the name and all members of a team
+func ReplaceGroup(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		r := &Group{}
+		if err := bindRequest(c, r); err != nil {
+			return nil, err
+		}
+		return replaceGroup(s, c.Param("id"), r)
+	})
+}
+
+// PatchGroup updates the name of a team or adds and removes members
+func PatchGroup(c echo.Context) error {
+	return handle(c, http.StatusOK, func(s *xorm.Session) (interface{}, error) {
+		req := &PatchRequest{}
+		if err := bindRequest(c, req); err != nil {
+			return nil, err
+		}
+		return patchGroup(s, c.Param("id"), req)
+	})
+}
+
+// DeleteGroup deletes a team
+func DeleteGroup(c echo.Context) error {
+	return handle(c, http.StatusNoContent, func(s *xorm.Session) (interface{}, error) {
+		return nil, deleteGroup(s, c.Param("id"))
+	})
+}
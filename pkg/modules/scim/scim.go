@@ -0,0 +1,292 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web"
+	"github.com/labstack/echo/v4"
+)
+
+// The schemas used in requests and responses, as defined in https://tools.ietf.org/html/rfc7643
+// and https://tools.ietf.org/html/rfc7644
+const (
+	schemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+const contentType = "application/scim+json"
+
+const defaultPageSize = 100
+
+// Meta holds the metadata of a scim resource
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// ListResponse is returned when querying multiple resources
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// PatchRequest holds a list of modifications to a resource
+type PatchRequest struct {
+	Schemas    []string          `json:"schemas"`
+	Operations []*PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is a single modification of a resource. The value depends on the path.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ErrorResponse is the body of all error responses
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimError is an error which is returned to the identity provider as is
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (err *scimError) Error() string {
+	return err.detail
+}
+
+func newInvalidValueError(detail string) *scimError {
+	return &scimError{status: http.StatusBadRequest, scimType: "invalidValue", detail: detail}
+}
+
+func newNotFoundError(resource string, id string) *scimError {
+	return &scimError{status: http.StatusNotFound, detail: fmt.Sprintf("%s %s does not exist.", resource, id)}
+}
+
+// getIssuer returns the issuer all users and teams managed through scim are saved with
+func getIssuer() string {
+	return config.SCIMIssuer.GetString()
+}
+
+// CheckToken checks the bearer token sent by the identity provider against the configured one.
+// It is meant to be used as the validator of echo's key auth middleware.
+func CheckToken(token string, c echo.Context) (bool, error) {
+	expected := config.SCIMToken.GetString()
+	if expected == "" {
+		log.Warning("SCIM is enabled but no token is configured, rejecting all requests.")
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1, nil
+}
+
+func writeResponse(c echo.Context, status int, body interface{}) error {
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().WriteHeader(status)
+	return json.NewEncoder(c.Response()).Encode(body)
+}
+
+// handleError converts any error to a scim error response
+func handleError(c echo.Context, err error) error {
+	res := &ErrorResponse{
+		Schemas: []string{schemaError},
+	}
+	status := http.StatusInternalServerError
+
+	if user.IsErrUsernameExists(err) || user.IsErrUserEmailExists(err) {
+		err = &scimError{status: http.StatusConflict, scimType: "uniqueness", detail: err.Error()}
+	}
+
+	switch e := err.(type) {
+	case *scimError:
+		status = e.status
+		res.ScimType = e.scimType
+		res.Detail = e.detail
+	case web.HTTPErrorProcessor:
+		httpErr := e.HTTPError()
+		status = httpErr.HTTPCode
+		res.Detail = httpErr.Message
+		if status == http.StatusBadRequest || status == http.StatusPreconditionFailed {
+			status = http.StatusBadRequest
+			res.ScimType = "invalidValue"
+		}
+	default:
+		log.Errorf("Error during scim request: %s", err)
+		res.Detail = "Internal server error."
+	}
+
+	res.Status = strconv.Itoa(status)
+	return writeResponse(c, status, res)
+}
+
+func bindRequest(c echo.Context, v interface{}) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(v); err != nil {
+		return &scimError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "The request body is not valid json."}
+	}
+	return nil
+}
+
+// getPagination returns the offset and limit from the 1-based startIndex and count query parameters
+func getPagination(c echo.Context) (startIndex, count int) {
+	startIndex, err := strconv.Atoi(c.QueryParam("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.QueryParam("count"))
+	if err != nil || count < 0 {
+		count = defaultPageSize
+	}
+	if count > defaultPageSize {
+		count = defaultPageSize
+	}
+	return
+}
+
+// filter is a parsed filter expression. Only simple equality filters like `userName eq "frederick"` are
+// supported since that is all identity providers use to look up existing resources.
+type filter struct {
+	attribute string
+	value     string
+}
+
+func parseFilter(raw string) (f *filter, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	invalid := &scimError{status: http.StatusBadRequest, scimType: "invalidFilter", detail: "Only filters of the form 'attribute eq \"value\"' are supported."}
+
+	parts := strings.SplitN(raw, " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+		return nil, invalid
+	}
+
+	value, err := strconv.Unquote(strings.TrimSpace(parts[2]))
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &filter{
+		attribute: strings.ToLower(parts[0]),
+		value:     value,
+	}, nil
+}
+
+// normalizeOp returns the lowercase operation of a patch operation as some identity providers capitalize it
+func normalizeOp(op *PatchOperation) string {
+	return strings.ToLower(op.Op)
+}
+
+// parseBool parses a boolean patch value. Some identity providers send booleans as strings like "False".
+func parseBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var str string
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return false, newInvalidValueError("Expected a boolean value.")
+	}
+	b, err := strconv.ParseBool(str)
+	if err != nil {
+		return false, newInvalidValueError("Expected a boolean value.")
+	}
+	return b, nil
+}
+
+func parseID(id string, resource string) (int64, error) {
+	i, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, newNotFoundError(resource, id)
+	}
+	return i, nil
+}
+
+// ServiceProviderConfig tells identity providers which features of the spec are supported
+type ServiceProviderConfig struct {
+	Schemas               []string          `json:"schemas"`
+	Patch                 supported         `json:"patch"`
+	Bulk                  bulkSupported     `json:"bulk"`
+	Filter                filterSupported   `json:"filter"`
+	ChangePassword        supported         `json:"changePassword"`
+	Sort                  supported         `json:"sort"`
+	Etag                  supported         `json:"etag"`
+	AuthenticationSchemes []*authentication `json:"authenticationSchemes"`
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type bulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type filterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type authentication struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// GetServiceProviderConfig returns the features of the scim implementation
+func GetServiceProviderConfig(c echo.Context) error {
+	return writeResponse(c, http.StatusOK, &ServiceProviderConfig{
+		Schemas: []string{schemaServiceProviderConfig},
+		Patch:   supported{Supported: true},
+		Filter:  filterSupported{Supported: true, MaxResults: defaultPageSize},
+		AuthenticationSchemes: []*authentication{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "Bearer Token",
+				Description: "Authentication with the token configured in Vikunja's config.",
+			},
+		},
+	})
+}
@@ -0,0 +1,261 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		f, err := parseFilter("")
+		assert.NoError(t, err)
+		assert.Nil(t, f)
+	})
+	t.Run("equality", func(t *testing.T) {
+		f, err := parseFilter(`userName eq "user 14"`)
+		assert.NoError(t, err)
+		assert.Equal(t, "username", f.attribute)
+		assert.Equal(t, "user 14", f.value)
+	})
+	t.Run("unsupported operator", func(t *testing.T) {
+		_, err := parseFilter(`userName sw "user"`)
+		assert.Error(t, err)
+		assert.Equal(t, "invalidFilter", err.(*scimError).scimType)
+	})
+}
+
+func TestUsers(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		users, total, err := listUsers(s, nil, 1, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, users, 1)
+		assert.Equal(t, int64(14), users[0].ID)
+	})
+	t.Run("filter by externalId", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		users, total, err := listUsers(s, &filter{attribute: "externalid", value: "54321"}, 1, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), total)
+		assert.Len(t, users, 0)
+	})
+	t.Run("get user of another issuer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := getUser(s, "1")
+		assert.Error(t, err)
+		assert.Equal(t, 404, err.(*scimError).status)
+	})
+	t.Run("create", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := createUser(s, &User{
+			ExternalID:  "67890",
+			UserName:    "provisioned",
+			DisplayName: "Provisioned User",
+			Emails:      []*Email{{Value: "provisioned@example.com", Primary: true}},
+		})
+		assert.NoError(t, err)
+		assert.True(t, u.IsActive)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "users", map[string]interface{}{
+			"id":        u.ID,
+			"username":  "provisioned",
+			"name":      "Provisioned User",
+			"email":     "provisioned@example.com",
+			"issuer":    "https://some.service.com",
+			"subject":   "67890",
+			"is_active": true,
+		}, false)
+	})
+	t.Run("create with existing externalId", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := createUser(s, &User{
+			ExternalID: "12345",
+			UserName:   "provisioned",
+			Emails:     []*Email{{Value: "provisioned@example.com"}},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "uniqueness", err.(*scimError).scimType)
+	})
+	t.Run("patch", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := patchUser(s, "14", &PatchRequest{
+			Operations: []*PatchOperation{
+				{Op: "Replace", Path: "displayName", Value: json.RawMessage(`"New Name"`)},
+				{Op: "Replace", Path: `emails[type eq "work"].value`, Value: json.RawMessage(`"new@some.service.com"`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "New Name", u.Name)
+		assert.Equal(t, "new@some.service.com", u.Email)
+		assert.True(t, u.IsActive)
+	})
+	t.Run("deactivate through patch", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := patchUser(s, "14", &PatchRequest{
+			Operations: []*PatchOperation{
+				{Op: "replace", Value: json.RawMessage(`{"active": "False"}`)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.False(t, u.IsActive)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := deleteUser(s, "14")
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "users", map[string]interface{}{
+			"id":        14,
+			"is_active": false,
+		}, false)
+
+		_, err = user.CheckUserCredentials(s, &user.Login{Username: "user14", Password: "1234"})
+		assert.Error(t, err)
+	})
+}
+
+func TestGroups(t *testing.T) {
+	t.Run("list", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		groups, total, err := listGroups(s, &filter{attribute: "displayname", value: "testteam14_external"}, 1, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Len(t, groups, 1)
+		assert.Equal(t, "14", groups[0].ID)
+		assert.Equal(t, "vikunja-admins", groups[0].ExternalID)
+		assert.Len(t, groups[0].Members, 1)
+		assert.Equal(t, "14", groups[0].Members[0].Value)
+	})
+	t.Run("get team of another issuer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := getGroup(s, "1")
+		assert.Error(t, err)
+		assert.Equal(t, 404, err.(*scimError).status)
+	})
+	t.Run("create", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		g, err := createGroup(s, &Group{
+			DisplayName: "Provisioned",
+			Members:     []*Member{{Value: "14"}},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, g.Members, 1)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertExists(t, "teams", map[string]interface{}{
+			"id":          g.ID,
+			"name":        "Provisioned",
+			"external_id": "Provisioned",
+			"issuer":      "https://some.service.com",
+		}, false)
+		db.AssertExists(t, "team_members", map[string]interface{}{
+			"team_id": g.ID,
+			"user_id": 14,
+		}, false)
+	})
+	t.Run("create with a member of another issuer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := createGroup(s, &Group{
+			DisplayName: "Provisioned",
+			Members:     []*Member{{Value: "1"}},
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "invalidValue", err.(*scimError).scimType)
+	})
+	t.Run("remove member through patch", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		g, err := patchGroup(s, "14", &PatchRequest{
+			Operations: []*PatchOperation{
+				{Op: "remove", Path: `members[value eq "14"]`},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Len(t, g.Members, 0)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "team_members", map[string]interface{}{
+			"team_id": 14,
+			"user_id": 14,
+		})
+	})
+	t.Run("delete", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := deleteGroup(s, "14")
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "teams", map[string]interface{}{
+			"id": 14,
+		})
+	})
+}
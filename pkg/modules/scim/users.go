@@ -0,0 +1,340 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/user"
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+// User is the scim representation of a vikunja user
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []*Email `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Name holds the name of a user. Vikunja only stores one name per user.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is an email address of a user. Vikunja only stores the primary one.
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary"`
+}
+
+func (n *Name) getName() string {
+	if n.Formatted != "" {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+func (r *User) getName() string {
+	if r.DisplayName != "" {
+		return r.DisplayName
+	}
+	if r.Name != nil {
+		return r.Name.getName()
+	}
+	return ""
+}
+
+func getPrimaryEmail(emails []*Email) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+func userToResource(u *user.User) *User {
+	active := u.IsActive
+	r := &User{
+		Schemas:     []string{schemaUser},
+		ID:          strconv.FormatInt(u.ID, 10),
+		ExternalID:  u.Subject,
+		UserName:    u.Username,
+		DisplayName: u.Name,
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      u.Created,
+			LastModified: u.Updated,
+		},
+	}
+	if u.Name != "" {
+		r.Name = &Name{Formatted: u.Name}
+	}
+	if u.Email != "" {
+		r.Emails = []*Email{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	return r
+}
+
+// getUser returns a user managed through scim, including their email address
+func getUser(s *xorm.Session, id string) (u *user.User, err error) {
+	userID, err := parseID(id, "User")
+	if err != nil {
+		return nil, err
+	}
+
+	u = &user.User{}
+	exists, err := s.
+		Where("id = ? AND issuer = ?", userID, getIssuer()).
+		Get(u)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, newNotFoundError("User", id)
+	}
+	return
+}
+
+func listUsers(s *xorm.Session, f *filter, startIndex, count int) (users []*user.User, total int64, err error) {
+	cond := builder.NewCond().And(builder.Eq{"issuer": getIssuer()})
+	if f != nil {
+		switch f.attribute {
+		case "username":
+			cond = cond.And(builder.Eq{"username": f.value})
+		case "externalid":
+			cond = cond.And(builder.Eq{"subject": f.value})
+		case "emails", "emails.value":
+			cond = cond.And(builder.Eq{"email": f.value})
+		default:
+			return nil, 0, &scimError{status: http.StatusBadRequest, scimType: "invalidFilter", detail: "Users can only be filtered by userName, externalId or emails."}
+		}
+	}
+
+	users = []*user.User{}
+	if count == 0 {
+		total, err = s.Where(cond).Count(&user.User{})
+		return
+	}
+
+	total, err = s.
+		Where(cond).
+		OrderBy("id asc").
+		Limit(count, startIndex-1).
+		FindAndCount(&users)
+	return
+}
+
+func checkSubjectIsUnique(s *xorm.Session, subject string, userID int64) error {
+	exists, err := s.
+		Where("issuer = ? AND subject = ? AND id != ?", getIssuer(), subject, userID).
+		Exist(&user.User{})
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &scimError{status: http.StatusConflict, scimType: "uniqueness", detail: "A user with this externalId already exists."}
+	}
+	return nil
+}
+
+func createUser(s *xorm.Session, r *User) (u *user.User, err error) {
+	if r.UserName == "" {
+		return nil, newInvalidValueError("The userName is required.")
+	}
+
+	// Users without an external id can be looked up through their username in the identity provider
+	subject := r.ExternalID
+	if subject == "" {
+		subject = r.UserName
+	}
+	if err := checkSubjectIsUnique(s, subject, 0); err != nil {
+		return nil, err
+	}
+
+	u, err = user.CreateUser(s, &user.User{
+		Username: r.UserName,
+		Name:     r.getName(),
+		Email:    getPrimaryEmail(r.Emails),
+		Issuer:   getIssuer(),
+		Subject:  subject,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Active != nil && !*r.Active {
+		err = user.SetUserStatus(s, u, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return user.GetUserWithEmail(s, &user.User{ID: u.ID})
+}
+
+// saveUser saves all changes to a user which were made through scim
+func saveUser(s *xorm.Session, u *user.User, subject string) (updated *user.User, err error) {
+	if subject != "" && subject != u.Subject {
+		if err := checkSubjectIsUnique(s, subject, u.ID); err != nil {
+			return nil, err
+		}
+		u.Subject = subject
+		_, err = s.ID(u.ID).Cols("subject").Update(u)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Deprovisioned users are only disabled, the same way as through the cli
+	err = user.SetUserStatus(s, u, u.IsActive)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.GetUserWithEmail(s, &user.User{ID: u.ID})
+}
+
+func replaceUser(s *xorm.Session, id string, r *User) (u *user.User, err error) {
+	u, err = getUser(s, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.UserName != "" {
+		u.Username = r.UserName
+	}
+	if name := r.getName(); name != "" {
+		u.Name = name
+	}
+	if email := getPrimaryEmail(r.Emails); email != "" {
+		u.Email = email
+	}
+	if r.Active != nil {
+		u.IsActive = *r.Active
+	}
+
+	return saveUser(s, u, r.ExternalID)
+}
+
+func unmarshalString(raw json.RawMessage) (str string, err error) {
+	if err := json.Unmarshal(raw, &str); err != nil {
+		return "", newInvalidValueError("Expected a string value.")
+	}
+	return
+}
+
+// applyUserAttribute sets a single attribute of a user from a patch operation and returns the new subject if the
+// externalId was changed. Attributes vikunja does not store are ignored.
+func applyUserAttribute(u *user.User, path string, value json.RawMessage) (subject string, err error) {
+	path = strings.ToLower(path)
+
+	switch {
+	case path == "active":
+		u.IsActive, err = parseBool(value)
+	case path == "username":
+		u.Username, err = unmarshalString(value)
+	case path == "displayname" || path == "name.formatted":
+		u.Name, err = unmarshalString(value)
+	case path == "name":
+		n := &Name{}
+		if err := json.Unmarshal(value, n); err != nil {
+			return "", newInvalidValueError("Expected a name object.")
+		}
+		if name := n.getName(); name != "" {
+			u.Name = name
+		}
+	case path == "externalid":
+		subject, err = unmarshalString(value)
+	case path == "emails":
+		emails := []*Email{}
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return "", newInvalidValueError("Expected a list of emails.")
+		}
+		if email := getPrimaryEmail(emails); email != "" {
+			u.Email = email
+		}
+	case strings.HasPrefix(path, "emails[") && strings.HasSuffix(path, "].value"):
+		u.Email, err = unmarshalString(value)
+	}
+
+	return
+}
+
+func patchUser(s *xorm.Session, id string, req *PatchRequest) (u *user.User, err error) {
+	u, err = getUser(s, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject string
+	for _, op := range req.Operations {
+		switch normalizeOp(op) {
+		case "add", "replace":
+			attributes := map[string]json.RawMessage{op.Path: op.Value}
+			if op.Path == "" {
+				attributes = map[string]json.RawMessage{}
+				if err := json.Unmarshal(op.Value, &attributes); err != nil {
+					return nil, newInvalidValueError("Expected an object of attributes.")
+				}
+			}
+
+			for path, value := range attributes {
+				newSubject, err := applyUserAttribute(u, path, value)
+				if err != nil {
+					return nil, err
+				}
+				if newSubject != "" {
+					subject = newSubject
+				}
+			}
+		case "remove":
+			return nil, &scimError{status: http.StatusBadRequest, scimType: "mutability", detail: "Attributes of users cannot be removed."}
+		default:
+			return nil, newInvalidValueError("Unsupported patch operation " + op.Op + ".")
+		}
+	}
+
+	return saveUser(s, u, subject)
+}
+
+// deleteUser deprovisions a user. Their account is disabled instead of deleted so nothing they created is lost.
+func deleteUser(s *xorm.Session, id string) error {
+	u, err := getUser(s, id)
+	if err != nil {
+		return err
+	}
+
+	return user.SetUserStatus(s, u, false)
+}
@@ -0,0 +1,323 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/auth"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+	"xorm.io/xorm"
+)
+
+// AdminUserStatus holds the new status of a user
+type AdminUserStatus struct {
+	// Whether the user should be able to log in.
+	IsActive bool `json:"is_active"`
+}
+
+// AdminPasswordReset holds the new password of a user
+type AdminPasswordReset struct {
+	// The new password of the user. If empty, the user gets a password reset email instead.
+	Password string `json:"password"`
+}
+
+// CheckAdmin only lets requests of instance administrators through. The admin status is always checked against the
+// database so that revoking it takes effect immediately.
+func CheckAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		a, err := auth.GetAuthFromClaims(c)
+		if err != nil {
+			return handler.HandleHTTPError(err, c)
+		}
+
+		s := db.NewSession()
+		defer s.Close()
+
+		isAdmin, err := models.IsInstanceAdmin(s, a)
+		if err != nil {
+			_ = s.Rollback()
+			return handler.HandleHTTPError(err, c)
+		}
+		if err := s.Commit(); err != nil {
+			return handler.HandleHTTPError(err, c)
+		}
+
+		if !isAdmin {
+			return handler.HandleHTTPError(models.ErrGenericForbidden{}, c)
+		}
+
+		return next(c)
+	}
+}
+
+func getUserFromParam(s *xorm.Session, c echo.Context) (*user.User, error) {
+	userID, err := strconv.ParseInt(c.Param("user"), 10, 64)
+	if err != nil {
+		return nil, user.ErrUserDoesNotExist{}
+	}
+	return user.GetUserByID(s, userID)
+}
+
+// AdminGetStats returns statistics about this instance
+// @Summary Get instance statistics
+// @Description Returns the number of users, namespaces, lists, tasks and teams on this instance. Only available to instance administrators.
+// @tags admin
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} models.InstanceStats "The statistics."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /admin/stats [get]
+func AdminGetStats(c echo.Context) error {
+	s := db.NewSession()
+	defer s.Close()
+
+	stats, err := models.GetInstanceStats(s)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// AdminSetUserStatus enables or disables a user
+// @Summary Enable or disable a user
+// @Description Enables or disables a user, the same way as the `user change-status` cli command. Disabled users cannot log in anymore. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param id path int true "User ID"
+// @Param status body v1.AdminUserStatus true "The new status of the user."
+// @Success 200 {object} models.Message "The status was changed successfully."
+// @Failure 400 {object} web.HTTPError "Something's invalid."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 404 {object} web.HTTPError "The user does not exist."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /admin/users/{id}/status [post]
+func AdminSetUserStatus(c echo.Context) error {
+	status := &AdminUserStatus{}
+	if err := c.Bind(status); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No or invalid status provided.")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := getUserFromParam(s, c)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	err = user.SetUserStatus(s, u, status.IsActive)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "The user status was changed successfully."})
+}
+
+// AdminResetUserPassword resets the password of a user
+// @Summary Reset the password of a user
+// @Description Sets a new password for a user or sends them a password reset email if no password is provided, the same way as the `user reset-password` cli command. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param id path int true "User ID"
+// @Param password body v1.AdminPasswordReset true "The new password."
+// @Success 200 {object} models.Message "The password was reset successfully."
+// @Failure 400 {object} web.HTTPError "Something's invalid."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 404 {object} web.HTTPError "The user does not exist."
+// @Failure 412 {object} web.HTTPError "The password does not follow the password policy."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /admin/users/{id}/password [post]
+func AdminResetUserPassword(c echo.Context) error {
+	reset := &AdminPasswordReset{}
+	if err := c.Bind(reset); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No or invalid password provided.")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := getUserFromParam(s, c)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	message := "The password was updated successfully."
+	if reset.Password != "" {
+		err = user.UpdateUserPassword(s, u, reset.Password)
+	} else {
+		err = user.RequestUserPasswordResetToken(s, u)
+		message = "The password reset email was sent successfully."
+	}
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: message})
+}
+
+// AdminResetUserTOTP disables totp for a user
+// @Summary Reset totp of a user
+// @Description Disables totp for a user and removes their recovery codes, the same way as the `user reset-totp` cli command. Use this if a user lost access to their totp device. Only available to instance administrators.
+// @tags admin
+// @Produce json
+// @Security JWTKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} models.Message "Totp was reset successfully."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 404 {object} web.HTTPError "The user does not exist."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /admin/users/{id}/totp [delete]
+func AdminResetUserTOTP(c echo.Context) error {
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := getUserFromParam(s, c)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	err = user.DisableTOTP(s, u)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "Totp was reset successfully."})
+}
+
+// AdminTransferNamespaceOwnership makes another user the owner of a namespace
+// @Summary Transfer the ownership of a namespace
+// @Description Makes another user the owner of a namespace. All lists in the namespace which belonged to the previous owner are transferred as well. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param id path int true "Namespace ID"
+// @Param owner body models.OwnershipTransfer true "The new owner."
+// @Success 200 {object} models.Namespace "The namespace with its new owner."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 404 {object} web.HTTPError "The namespace or the new owner does not exist."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /admin/namespaces/{id}/owner [post]
+func AdminTransferNamespaceOwnership(c echo.Context) error {
+	transfer := &models.OwnershipTransfer{}
+	if err := c.Bind(transfer); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No or invalid owner provided.")
+	}
+
+	namespaceID, err := strconv.ParseInt(c.Param("namespace"), 10, 64)
+	if err != nil {
+		return handler.HandleHTTPError(models.ErrNamespaceDoesNotExist{}, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	namespace, err := models.TransferNamespaceOwnership(s, namespaceID, transfer.OwnerID)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, namespace)
+}
+
+// AdminTransferListOwnership makes another user the owner of a list
+// @Summary Transfer the ownership of a list
+// @Description Makes another user the owner of a list and moves it into a namespace of the new owner. Only available to instance administrators.
+// @tags admin
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param id path int true "List ID"
+// @Param owner body models.OwnershipTransfer true "The new owner."
+// @Success 200 {object} models.List "The list with its new owner."
+// @Failure 403 {object} web.HTTPError "The user is not an administrator."
+// @Failure 404 {object} web.HTTPError "The list, the new owner or the namespace does not exist."
+// @Failure 412 {object} web.HTTPError "The namespace does not belong to the new owner."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /admin/lists/{id}/owner [post]
+func AdminTransferListOwnership(c echo.Context) error {
+	transfer := &models.OwnershipTransfer{}
+	if err := c.Bind(transfer); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No or invalid owner provided.")
+	}
+
+	listID, err := strconv.ParseInt(c.Param("list"), 10, 64)
+	if err != nil {
+		return handler.HandleHTTPError(models.ErrListDoesNotExist{}, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	list, err := models.TransferListOwnership(s, listID, transfer.OwnerID, transfer.NamespaceID)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, list)
+}
@@ -27,27 +27,37 @@ import (
 
 	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/modules/auth/openid"
+	"code.vikunja.io/api/pkg/modules/migration/asana"
+	"code.vikunja.io/api/pkg/modules/migration/ical"
+	"code.vikunja.io/api/pkg/modules/migration/jira"
+	"code.vikunja.io/api/pkg/modules/migration/taskwarrior"
+	todotxt "code.vikunja.io/api/pkg/modules/migration/todo-txt"
 	"code.vikunja.io/api/pkg/modules/migration/todoist"
+	vikunjafile "code.vikunja.io/api/pkg/modules/migration/vikunja-file"
 	"code.vikunja.io/api/pkg/modules/migration/wunderlist"
 	"code.vikunja.io/api/pkg/version"
 	"github.com/labstack/echo/v4"
 )
 
 type vikunjaInfos struct {
-	Version                    string    `json:"version"`
-	FrontendURL                string    `json:"frontend_url"`
-	Motd                       string    `json:"motd"`
-	LinkSharingEnabled         bool      `json:"link_sharing_enabled"`
-	MaxFileSize                string    `json:"max_file_size"`
-	RegistrationEnabled        bool      `json:"registration_enabled"`
-	AvailableMigrators         []string  `json:"available_migrators"`
-	TaskAttachmentsEnabled     bool      `json:"task_attachments_enabled"`
-	EnabledBackgroundProviders []string  `json:"enabled_background_providers"`
-	TotpEnabled                bool      `json:"totp_enabled"`
-	Legal                      legalInfo `json:"legal"`
-	CaldavEnabled              bool      `json:"caldav_enabled"`
-	AuthInfo                   authInfo  `json:"auth"`
-	EmailRemindersEnabled      bool      `json:"email_reminders_enabled"`
+	Version                     string    `json:"version"`
+	FrontendURL                 string    `json:"frontend_url"`
+	Motd                        string    `json:"motd"`
+	LinkSharingEnabled          bool      `json:"link_sharing_enabled"`
+	MaxFileSize                 string    `json:"max_file_size"`
+	RegistrationEnabled         bool      `json:"registration_enabled"`
+	AvailableMigrators          []string  `json:"available_migrators"`
+	TaskAttachmentsEnabled      bool      `json:"task_attachments_enabled"`
+	EnabledBackgroundProviders  []string  `json:"enabled_background_providers"`
+	TotpEnabled                 bool      `json:"totp_enabled"`
+	WebAuthnEnabled             bool      `json:"webauthn_enabled"`
+	WebAuthnPasswordlessEnabled bool      `json:"webauthn_passwordless_enabled"`
+	OAuth2ServerEnabled         bool      `json:"oauth2_server_enabled"`
+	UserDeletionEnabled         bool      `json:"user_deletion_enabled"`
+	Legal                       legalInfo `json:"legal"`
+	CaldavEnabled               bool      `json:"caldav_enabled"`
+	AuthInfo                    authInfo  `json:"auth"`
+	EmailRemindersEnabled       bool      `json:"email_reminders_enabled"`
 }
 
 type authInfo struct {
@@ -79,16 +89,20 @@ type legalInfo struct {
 // @Router /info [get]
 func Info(c echo.Context) error {
 	info := vikunjaInfos{
-		Version:                version.Version,
-		FrontendURL:            config.ServiceFrontendurl.GetString(),
-		Motd:                   config.ServiceMotd.GetString(),
-		LinkSharingEnabled:     config.ServiceEnableLinkSharing.GetBool(),
-		MaxFileSize:            config.FilesMaxSize.GetString(),
-		RegistrationEnabled:    config.ServiceEnableRegistration.GetBool(),
-		TaskAttachmentsEnabled: config.ServiceEnableTaskAttachments.GetBool(),
-		TotpEnabled:            config.ServiceEnableTotp.GetBool(),
-		CaldavEnabled:          config.ServiceEnableCaldav.GetBool(),
-		EmailRemindersEnabled:  config.ServiceEnableEmailReminders.GetBool(),
+		Version:                     version.Version,
+		FrontendURL:                 config.ServiceFrontendurl.GetString(),
+		Motd:                        config.ServiceMotd.GetString(),
+		LinkSharingEnabled:          config.ServiceEnableLinkSharing.GetBool(),
+		MaxFileSize:                 config.FilesMaxSize.GetString(),
+		RegistrationEnabled:         config.ServiceEnableRegistration.GetBool(),
+		TaskAttachmentsEnabled:      config.ServiceEnableTaskAttachments.GetBool(),
+		TotpEnabled:                 config.ServiceEnableTotp.GetBool(),
+		WebAuthnEnabled:             config.ServiceEnableWebAuthn.GetBool(),
+		WebAuthnPasswordlessEnabled: config.ServiceEnableWebAuthn.GetBool() && config.ServiceEnableWebAuthnPasswordless.GetBool(),
+		OAuth2ServerEnabled:         config.ServiceEnableOAuth2Server.GetBool(),
+		UserDeletionEnabled:         config.ServiceEnableUserDeletion.GetBool(),
+		CaldavEnabled:               config.ServiceEnableCaldav.GetBool(),
+		EmailRemindersEnabled:       config.ServiceEnableEmailReminders.GetBool(),
 		Legal: legalInfo{
 			ImprintURL:       config.LegalImprintURL.GetString(),
 			PrivacyPolicyURL: config.LegalPrivacyURL.GetString(),
@@ -129,6 +143,38 @@ func Info(c echo.Context) error {
 		m := &microsofttodo.Migration{}
 		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
 	}
+	if config.MigrationTrelloFileEnable.GetBool() {
+		m := &trello.FileMigrator{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationTodoistFileEnable.GetBool() {
+		m := &todoist.FileMigrator{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationVikunjaFileEnable.GetBool() {
+		m := &vikunjafile.FileMigrator{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationTodoTxtEnable.GetBool() {
+		m := &todotxt.Migration{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationTaskwarriorEnable.GetBool() {
+		m := &taskwarrior.Migration{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationAsanaEnable.GetBool() {
+		m := &asana.Migration{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationJiraEnable.GetBool() {
+		m := &jira.Migration{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
+	if config.MigrationICalEnable.GetBool() {
+		m := &ical.Migration{}
+		info.AvailableMigrators = append(info.AvailableMigrators, m.Name())
+	}
 
 	if config.BackgroundsEnabled.GetBool() {
 		if config.BackgroundsUploadEnabled.GetBool() {
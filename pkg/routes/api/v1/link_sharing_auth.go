@@ -34,6 +34,12 @@ type LinkShareToken struct {
 	ListID int64 `json:"list_id"`
 }
 
+// LinkShareAuth represents everything needed to authenticate with a link share
+type LinkShareAuth struct {
+	// The password of the link share. Only needed if the share is password protected.
+	Password string `json:"password"`
+}
+
 // AuthenticateLinkShare gives a jwt auth token for valid share hashes
 // @Summary Get an auth token for a share
 // @Description Get a jwt auth token for a shared list from a share hash.
@@ -41,13 +47,19 @@ type LinkShareToken struct {
 // @Accept json
 // @Produce json
 // @Param share path string true "The share hash"
+// @Param password body v1.LinkShareAuth false "The password for link shares which require one."
 // @Success 200 {object} auth.Token "The valid jwt auth token."
 // @Failure 400 {object} web.HTTPError "Invalid link share object provided."
+// @Failure 403 {object} web.HTTPError "The password is invalid."
 // @Failure 500 {object} models.Message "Internal error"
 // @Router /shares/{share}/auth [post]
 func AuthenticateLinkShare(c echo.Context) error {
 	hash := c.Param("share")
 
+	sa := &LinkShareAuth{}
+	// The body is optional, only password protected shares need it
+	_ = c.Bind(sa)
+
 	s := db.NewSession()
 	defer s.Close()
 
@@ -62,6 +74,12 @@ func AuthenticateLinkShare(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
+	err = models.VerifyLinkSharePassword(share, sa.Password)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	share.Password = ""
 	t, err := auth.NewLinkShareJWTAuthtoken(share)
 	if err != nil {
 		return handler.HandleHTTPError(err, c)
@@ -38,7 +38,7 @@ import (
 // @Param credentials body user.Login true "The login credentials"
 // @Success 200 {object} auth.Token
 // @Failure 400 {object} models.Message "Invalid user password model."
-// @Failure 412 {object} models.Message "Invalid totp passcode."
+// @Failure 412 {object} models.Message "Invalid totp passcode or WebAuthn assertion."
 // @Failure 403 {object} models.Message "Invalid username or password."
 // @Router /login [post]
 func Login(c echo.Context) error {
@@ -63,15 +63,33 @@ func Login(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
-	if totpEnabled {
-		_, err = user2.ValidateTOTPPasscode(s, &user2.TOTPPasscode{
+	webAuthnEnabled, err := user2.WebAuthnEnabledForUser(s, user)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	// Any configured second factor is enough to log in
+	switch {
+	case webAuthnEnabled && len(u.WebAuthnAssertion) > 0:
+		err = user2.ValidateWebAuthnLogin(s, user, u.WebAuthnAssertion, false)
+	case totpEnabled:
+		err = user2.ValidateTOTPPasscodeOrRecoveryCode(s, &user2.TOTPPasscode{
 			User:     user,
 			Passcode: u.TOTPPasscode,
 		})
-		if err != nil {
-			_ = s.Rollback()
-			return handler.HandleHTTPError(err, c)
+	case webAuthnEnabled:
+		err = user2.ErrWebAuthnAssertionRequired{}
+	}
+	if err != nil {
+		_ = s.Rollback()
+		// A missing passcode only means the client still needs to ask the user for it
+		if (user2.IsErrInvalidTOTPPasscode(err) && u.TOTPPasscode != "") || user2.IsErrInvalidWebAuthnResponse(err) {
+			if err := user2.RegisterFailedSecondFactor(user); err != nil {
+				return handler.HandleHTTPError(err, c)
+			}
 		}
+		return handler.HandleHTTPError(err, c)
 	}
 
 	if err := s.Commit(); err != nil {
@@ -79,6 +97,10 @@ func Login(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
+	if err := user2.ResetFailedLoginAttempts(user); err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
 	// Create token
 	return auth.NewUserAuthTokenResponse(user, c)
 }
@@ -20,6 +20,8 @@ import (
 	"net/http"
 
 	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/log"
 
 	"code.vikunja.io/api/pkg/models"
 	auth2 "code.vikunja.io/api/pkg/modules/auth"
@@ -105,6 +107,14 @@ func UploadTaskAttachment(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
+	// Previews are generated only once the attachments exist, if that fails they are generated when they are requested
+	for _, ta := range r.Success {
+		err = events.Dispatch(&models.TaskAttachmentCreatedEvent{Attachment: ta})
+		if err != nil {
+			log.Errorf("Could not dispatch the creation event of attachment %d: %s", ta.ID, err)
+		}
+	}
+
 	return c.JSON(http.StatusOK, r)
 }
 
@@ -115,10 +125,13 @@ func UploadTaskAttachment(c echo.Context) error {
 // @Produce octet-stream
 // @Param id path int true "Task ID"
 // @Param attachmentID path int true "Attachment ID"
+// @Param preview_size query string false "If set, a preview of an image attachment with this size is returned instead of the attachment itself. Can be `sm` (100px), `md` (200px), `lg` (400px) or `xl` (800px), the preview fits into a square of that size."
 // @Security JWTKeyAuth
 // @Success 200 {} string "The attachment file."
+// @Success 302 {} string "A redirect to a signed url of the file in the storage backend, if enabled."
 // @Failure 403 {object} models.Message "No access to this task."
-// @Failure 404 {object} models.Message "The task does not exist."
+// @Failure 400 {object} web.HTTPError "The preview size is invalid."
+// @Failure 404 {object} models.Message "The task does not exist or the attachment has no preview."
 // @Failure 500 {object} models.Message "Internal error"
 // @Router /tasks/{id}/attachments/{attachmentID} [get]
 func GetTaskAttachment(c echo.Context) error {
@@ -153,6 +166,34 @@ func GetTaskAttachment(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
+	if c.QueryParam("preview_size") != "" {
+		size, err := models.GetPreviewSizeFromString(c.QueryParam("preview_size"))
+		if err != nil {
+			_ = s.Rollback()
+			return handler.HandleHTTPError(err, c)
+		}
+		preview, err := taskAttachment.GetPreview(size)
+		if err != nil {
+			_ = s.Rollback()
+			return handler.HandleHTTPError(err, c)
+		}
+		taskAttachment.File = preview
+	}
+
+	// If the storage backend supports it, the client downloads the file directly from there
+	signedURL, err := taskAttachment.File.SignedURL()
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+	if signedURL != "" {
+		if err := s.Commit(); err != nil {
+			_ = s.Rollback()
+			return handler.HandleHTTPError(err, c)
+		}
+		return c.Redirect(http.StatusFound, signedURL)
+	}
+
 	// Open an send the file to the client
 	err = taskAttachment.File.LoadFileByID()
 	if err != nil {
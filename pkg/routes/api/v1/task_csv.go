@@ -0,0 +1,214 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	auth2 "code.vikunja.io/api/pkg/modules/auth"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// ExportTasksCSV exports all tasks of a list as csv
+// @Summary Export the tasks of a list as csv
+// @Description Returns all tasks of a list as csv file. Supports the same filter and sort parameters as the task collection. **Returns json on error.**
+// @tags task
+// @Produce text/csv
+// @Param listID path int true "The list ID."
+// @Param s query string false "Search tasks by task text."
+// @Param sort_by query string false "The sorting parameter, see the task collection."
+// @Param order_by query string false "The ordering parameter, see the task collection."
+// @Param filter_by query string false "The name of the field to filter by, see the task collection."
+// @Param filter_value query string false "The value to filter for, see the task collection."
+// @Param filter_comparator query string false "The comparator to use for a filter, see the task collection."
+// @Param filter_concat query string false "The concatinator to use for filters, see the task collection."
+// @Param filter_include_nulls query string false "If set to true the result will include filtered fields whose value is set to `null`."
+// @Param columns query string false "A comma-separated list of the columns to export, in the order they should appear in the file. Possible values are `id`, `title`, `description`, `done`, `done_at`, `due_date`, `start_date`, `end_date`, `priority`, `percent_done`, `repeat_after`, `hex_color`, `labels`, `assignees`, `bucket`, `created_by`, `created` and `updated`. Defaults to all columns."
+// @Param date_format query string false "The format of all dates as go time layout, for example `2006-01-02`. Defaults to RFC 3339."
+// @Security JWTKeyAuth
+// @Success 200 {} string "The csv file."
+// @Failure 400 {object} web.HTTPError "Invalid column or filter."
+// @Failure 403 {object} web.HTTPError "The user does not have access to the list."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /lists/{listID}/tasks/csv [get]
+func ExportTasksCSV(c echo.Context) error {
+	tf := &models.TaskCollection{}
+	if err := c.Bind(tf); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid model provided.")
+	}
+
+	auth, err := auth2.GetAuthFromClaims(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	var columns []string
+	if c.QueryParam("columns") != "" {
+		columns = strings.Split(c.QueryParam("columns"), ",")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	buf := &bytes.Buffer{}
+	err = models.ExportTasksToCSV(s, tf, auth, c.QueryParam("s"), columns, c.QueryParam("date_format"), buf)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="tasks.csv"`)
+	return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// canWriteList checks if the current user or link share can create tasks in the list from the request
+func canWriteList(c echo.Context) (listID int64, err error) {
+	listID, err = strconv.ParseInt(c.Param("list"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, "Invalid list id.")
+	}
+
+	auth, err := auth2.GetAuthFromClaims(c)
+	if err != nil {
+		return 0, handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	can, err := (&models.List{ID: listID}).CanWrite(s, auth)
+	if err != nil {
+		return 0, handler.HandleHTTPError(err, c)
+	}
+	if !can {
+		return 0, echo.ErrForbidden
+	}
+
+	return listID, nil
+}
+
+// PreviewTasksCSVImport shows the first rows of a csv file and how its columns would be imported
+// @Summary Preview a csv import
+// @Description Returns the columns and first rows of a csv file together with a suggested mapping of its columns to task fields. The mapping can be changed and then used to import the file. Nothing is created.
+// @tags task
+// @Accept mpfd
+// @Produce json
+// @Param listID path int true "The list ID."
+// @Param import formData string true "The csv file. The first row needs to contain the names of the columns."
+// @Security JWTKeyAuth
+// @Success 200 {object} models.TaskCSVImportPreview "The preview."
+// @Failure 400 {object} web.HTTPError "The file is not a valid csv file."
+// @Failure 403 {object} web.HTTPError "The user does not have write access to the list."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /lists/{listID}/tasks/csv/preview [put]
+func PreviewTasksCSVImport(c echo.Context) error {
+	if _, err := canWriteList(c); err != nil {
+		return err
+	}
+
+	file, err := c.FormFile("import")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No file provided. Please upload it as the form field 'import'.")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+	defer src.Close()
+
+	preview, err := models.PreviewTaskCSVImport(src)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, preview)
+}
+
+// ImportTasksCSV creates tasks from a csv file
+// @Summary Import tasks from a csv file
+// @Description Creates a task in the list for every row of a csv file. Labels and buckets are matched by their title and created if they don't exist, assignees are matched by their username. Use the preview to get a suggested mapping.
+// @tags task
+// @Accept mpfd
+// @Produce json
+// @Param listID path int true "The list ID."
+// @Param import formData string true "The csv file. The first row needs to contain the names of the columns."
+// @Param mapping formData string true "A json object which maps the names of the csv columns to task fields, for example `{\"Name\": \"title\", \"Tags\": \"labels\"}`. Columns which are not mapped are not imported."
+// @Param date_format formData string false "The format of all dates in the file as go time layout, for example `2006-01-02` or `02.01.2006 15:04`. Defaults to RFC 3339."
+// @Param separator formData string false "The separator of multiple labels or assignees in one column. Defaults to `,`."
+// @Security JWTKeyAuth
+// @Success 200 {array} models.Task "The created tasks."
+// @Failure 400 {object} web.HTTPError "Invalid mapping or a value in the file is invalid."
+// @Failure 403 {object} web.HTTPError "The user does not have write access to the list."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /lists/{listID}/tasks/csv [put]
+func ImportTasksCSV(c echo.Context) error {
+	listID, err := canWriteList(c)
+	if err != nil {
+		return err
+	}
+
+	auth, err := auth2.GetAuthFromClaims(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	opts := &models.TaskCSVImportOptions{
+		DateFormat: c.FormValue("date_format"),
+		Separator:  c.FormValue("separator"),
+	}
+	if err := json.Unmarshal([]byte(c.FormValue("mapping")), &opts.Mapping); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid mapping provided.")
+	}
+
+	file, err := c.FormFile("import")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No file provided. Please upload it as the form field 'import'.")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+	defer src.Close()
+
+	s := db.NewSession()
+	defer s.Close()
+
+	tasks, err := models.ImportTasksFromCSV(s, listID, auth, src, opts)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, tasks)
+}
@@ -0,0 +1,189 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+	"xorm.io/xorm"
+)
+
+// UserDeletionStatus holds the state of the deletion of the current user's account
+type UserDeletionStatus struct {
+	// When the account will be deleted. Null if the deletion is not scheduled.
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	// Whether the user requested the deletion but did not confirm it yet.
+	ConfirmationPending bool `json:"confirmation_pending"`
+}
+
+// UserDeletionStatusShow is the handler to show the deletion status of the current user's account
+// @Summary Get the account deletion status
+// @Description Returns whether and when the account of the current user will be deleted.
+// @tags user
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} v1.UserDeletionStatus
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/deletion [get]
+func UserDeletionStatusShow(c echo.Context) error {
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := getCurrentUserForDeletion(s, c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	status := &UserDeletionStatus{
+		ConfirmationPending: u.DeletionConfirmToken != "",
+	}
+	if !u.DeletionScheduledAt.IsZero() {
+		status.ScheduledAt = &u.DeletionScheduledAt
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// UserRequestDeletion is the handler to request the deletion of the current user's account
+// @Summary Request the deletion of the account
+// @Description Requests the deletion of the current user's account. If the mailer is enabled, the user gets an email with a link to confirm the deletion. The account is deleted once the configured grace period is over.
+// @tags user
+// @Accept json
+// @Produce json
+// @Param credentials body user.AccountDeletionRequest true "The user's password."
+// @Security JWTKeyAuth
+// @Success 200 {object} models.Message
+// @Failure 412 {object} web.HTTPError "Wrong password."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/deletion/request [post]
+func UserRequestDeletion(c echo.Context) error {
+	var request user.AccountDeletionRequest
+	if err := c.Bind(&request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No password provided.")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := getCurrentUserForDeletion(s, c)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	err = user.RequestDeletion(s, u, request.Password)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "The deletion of the account was requested successfully."})
+}
+
+// UserConfirmDeletion is the handler to confirm the deletion of the current user's account
+// @Summary Confirm the deletion of the account
+// @Description Confirms the deletion of the account with the token sent via email. The account is deleted once the configured grace period is over.
+// @tags user
+// @Accept json
+// @Produce json
+// @Param credentials body user.AccountDeletionConfirm true "The token."
+// @Security JWTKeyAuth
+// @Success 200 {object} models.Message
+// @Failure 412 {object} web.HTTPError "Bad token provided."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/deletion/confirm [post]
+func UserConfirmDeletion(c echo.Context) error {
+	var confirm user.AccountDeletionConfirm
+	if err := c.Bind(&confirm); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No token provided.")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err := getCurrentUserForDeletion(s, c)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	err = user.ConfirmDeletion(s, u, confirm.Token)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "The deletion of the account was scheduled successfully."})
+}
+
+// UserCancelDeletion is the handler to cancel the deletion of the current user's account
+// @Summary Cancel the deletion of the account
+// @Description Cancels a requested or scheduled deletion of the current user's account.
+// @tags user
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} models.Message
+// @Failure 412 {object} web.HTTPError "The deletion was not requested."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/deletion/cancel [post]
+func UserCancelDeletion(c echo.Context) error {
+	doer, err := user.GetCurrentUser(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Error getting current user.")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	err = user.CancelDeletion(s, doer)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "The deletion of the account was cancelled successfully."})
+}
+
+func getCurrentUserForDeletion(s *xorm.Session, c echo.Context) (*user.User, error) {
+	doer, err := user.GetCurrentUser(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.GetUserWithEmail(s, &user.User{ID: doer.ID})
+}
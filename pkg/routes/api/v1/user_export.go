@@ -0,0 +1,149 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// UserExportRequest holds the password of the user to request a data export
+type UserExportRequest struct {
+	// The password of the user, required for local users.
+	Password string `json:"password"`
+}
+
+// UserExportStatus holds information about the current data export of a user
+type UserExportStatus struct {
+	// The size of the export in bytes.
+	Size uint64 `json:"size"`
+	// When the export was created.
+	Created time.Time `json:"created"`
+	// When the export will be deleted. It cannot be downloaded afterwards.
+	Expires time.Time `json:"expires"`
+}
+
+// RequestUserDataExport is the handler to request a data export
+// @Summary Request a user data export
+// @Description Starts creating a zip file with all namespaces, lists, tasks, comments, labels, saved filters and files of the current user. Once it is ready, the user gets a notification with a link to download it.
+// @tags user
+// @Accept json
+// @Produce json
+// @Param password body v1.UserExportRequest true "The user's password."
+// @Security JWTKeyAuth
+// @Success 200 {object} models.Message
+// @Failure 412 {object} web.HTTPError "Wrong password."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/export [post]
+func RequestUserDataExport(c echo.Context) error {
+	var request UserExportRequest
+	if err := c.Bind(&request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "No password provided.")
+	}
+
+	doer, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	err = models.RequestUserDataExport(s, doer, request.Password)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "Successfully requested data export. We will send you a notification when it's ready."})
+}
+
+// GetUserDataExportStatus is the handler to show the current data export of a user
+// @Summary Get the current user data export
+// @Description Returns information about the current data export of the user if there is one which can still be downloaded.
+// @tags user
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} v1.UserExportStatus
+// @Failure 404 {object} web.HTTPError "There is no data export."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/export [get]
+func GetUserDataExportStatus(c echo.Context) error {
+	doer, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	exportFile, err := models.GetUserDataExport(s, doer)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, &UserExportStatus{
+		Size:    exportFile.Size,
+		Created: exportFile.Created,
+		Expires: exportFile.Created.Add(models.UserDataExportValidity),
+	})
+}
+
+// DownloadUserDataExport is the handler to download the current data export of a user
+// @Summary Download the current user data export
+// @Description Returns the zip file of the current data export. **Returns json on error.**
+// @tags user
+// @Produce octet-stream
+// @Security JWTKeyAuth
+// @Success 200 {} string "The export file."
+// @Failure 404 {object} web.HTTPError "There is no data export."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/export/download [get]
+func DownloadUserDataExport(c echo.Context) error {
+	doer, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	exportFile, err := models.GetUserDataExport(s, doer)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	err = exportFile.LoadFileByID()
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+	defer exportFile.File.Close()
+
+	http.ServeContent(c.Response(), c.Request(), exportFile.Name, exportFile.Created, exportFile.File)
+	return nil
+}
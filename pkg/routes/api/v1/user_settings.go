@@ -39,6 +39,10 @@ type UserSettings struct {
 	Name string `json:"name"`
 	// If enabled, sends email reminders of tasks to the user.
 	EmailRemindersEnabled bool `xorm:"bool default false" json:"email_reminders_enabled"`
+	// If enabled, users who share a list or team with the current user can see their name.
+	DiscoverableByName bool `json:"discoverable_by_name"`
+	// If enabled, users who share a list or team with the current user can see their email address.
+	DiscoverableByEmail bool `json:"discoverable_by_email"`
 }
 
 // GetUserAvatarProvider returns the currently set user avatar
@@ -161,6 +165,8 @@ func UpdateGeneralUserSettings(c echo.Context) error {
 
 	user.Name = us.Name
 	user.EmailRemindersEnabled = us.EmailRemindersEnabled
+	user.DiscoverableByName = us.DiscoverableByName
+	user.DiscoverableByEmail = us.DiscoverableByEmail
 
 	_, err = user2.UpdateUser(s, user)
 	if err != nil {
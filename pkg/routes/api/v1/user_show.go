@@ -26,6 +26,13 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// UserWithAdmin is the current user together with their instance administrator status
+type UserWithAdmin struct {
+	user2.User
+	// Whether the user is an administrator of this instance and can use the admin api.
+	IsAdmin bool `json:"is_admin"`
+}
+
 // UserShow gets all informations about the current user
 // @Summary Get user information
 // @Description Returns the current user object.
@@ -33,7 +40,7 @@ import (
 // @Accept json
 // @Produce json
 // @Security JWTKeyAuth
-// @Success 200 {object} user.User
+// @Success 200 {object} v1.UserWithAdmin
 // @Failure 404 {object} web.HTTPError "User does not exist."
 // @Failure 500 {object} models.Message "Internal server error."
 // @Router /user [get]
@@ -57,5 +64,8 @@ func UserShow(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
-	return c.JSON(http.StatusOK, user)
+	return c.JSON(http.StatusOK, &UserWithAdmin{
+		User:    *user,
+		IsAdmin: user.IsAdmin,
+	})
 }
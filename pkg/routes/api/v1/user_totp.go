@@ -68,13 +68,13 @@ func UserTOTPEnroll(c echo.Context) error {
 
 // UserTOTPEnable is the handler to enable totp for a user
 // @Summary Enable a previously enrolled totp setting.
-// @Description Enables a previously enrolled totp setting by providing a totp passcode.
+// @Description Enables a previously enrolled totp setting by providing a totp passcode. Returns a set of recovery codes which can each be used once instead of a totp passcode. They are only shown once.
 // @tags user
 // @Accept json
 // @Produce json
 // @Param totp body user.TOTPPasscode true "The totp passcode."
 // @Security JWTKeyAuth
-// @Success 200 {object} models.Message "Successfully enabled"
+// @Success 200 {object} user.TOTPRecoveryCodes "Successfully enabled"
 // @Failure 400 {object} web.HTTPError "Something's invalid."
 // @Failure 404 {object} web.HTTPError "User does not exist."
 // @Failure 412 {object} web.HTTPError "TOTP is not enrolled."
@@ -100,7 +100,7 @@ func UserTOTPEnable(c echo.Context) error {
 	s := db.NewSession()
 	defer s.Close()
 
-	err = user.EnableTOTP(s, passcode)
+	codes, err := user.EnableTOTP(s, passcode)
 	if err != nil {
 		_ = s.Rollback()
 		return handler.HandleHTTPError(err, c)
@@ -111,7 +111,7 @@ func UserTOTPEnable(c echo.Context) error {
 		return handler.HandleHTTPError(err, c)
 	}
 
-	return c.JSON(http.StatusOK, models.Message{Message: "TOTP was enabled successfully."})
+	return c.JSON(http.StatusOK, codes)
 }
 
 // UserTOTPDisable disables totp settings for the current user.
@@ -171,6 +171,63 @@ func UserTOTPDisable(c echo.Context) error {
 	return c.JSON(http.StatusOK, models.Message{Message: "TOTP was enabled successfully."})
 }
 
+// UserTOTPRegenerateRecoveryCodes creates new totp recovery codes for the current user.
+// @Summary Regenerate totp recovery codes
+// @Description Invalidates all existing totp recovery codes of the current user and returns new ones. They are only shown once.
+// @tags user
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param totp body user.Login true "The current user's password (only password is enough)."
+// @Success 200 {object} user.TOTPRecoveryCodes "The new recovery codes."
+// @Failure 400 {object} web.HTTPError "Something's invalid."
+// @Failure 412 {object} web.HTTPError "TOTP is not enabled."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/settings/totp/recovery-codes [post]
+func UserTOTPRegenerateRecoveryCodes(c echo.Context) error {
+	login := &user.Login{}
+	if err := c.Bind(login); err != nil {
+		log.Debugf("Invalid model error. Internal error was: %s", err.Error())
+		if he, is := err.(*echo.HTTPError); is {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid model provided. Error was: %s", he.Message))
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid model provided.")
+	}
+
+	u, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	u, err = user.GetUserByID(s, u.ID)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	err = user.CheckUserPassword(u, login.Password)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	codes, err := user.RegenerateTOTPRecoveryCodes(s, u)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, codes)
+}
+
 // UserTOTPQrCode is the handler to show a qr code to enroll the user into totp
 // @Summary Totp QR Code
 // @Description Returns a qr code for easier setup at end user's devices.
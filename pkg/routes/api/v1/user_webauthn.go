@@ -0,0 +1,255 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/modules/auth"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// UserWebAuthnCredentials returns all WebAuthn authenticators of the current user.
+// @Summary Get all WebAuthn authenticators
+// @Description Returns all WebAuthn authenticators the current user has registered.
+// @tags user
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {array} user.WebAuthnCredential "The authenticators."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/settings/webauthn [get]
+func UserWebAuthnCredentials(c echo.Context) error {
+	u, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	credentials, err := user.GetWebAuthnCredentialsForUser(s, u)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, credentials)
+}
+
+// UserWebAuthnRegisterBegin starts the registration of a new WebAuthn authenticator.
+// @Summary Start registering a WebAuthn authenticator
+// @Description Returns the options which need to be passed to `navigator.credentials.create()` to create a new credential on the authenticator. The registration needs to be finished within five minutes.
+// @tags user
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Success 200 {object} protocol.CredentialCreation "The credential creation options."
+// @Failure 412 {object} web.HTTPError "WebAuthn is not available."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/settings/webauthn/register [post]
+func UserWebAuthnRegisterBegin(c echo.Context) error {
+	u, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	options, err := user.BeginWebAuthnRegistration(s, u)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, options)
+}
+
+// UserWebAuthnRegisterFinish saves a new WebAuthn authenticator.
+// @Summary Finish registering a WebAuthn authenticator
+// @Description Verifies the response of the authenticator and saves it as a new authenticator of the current user.
+// @tags user
+// @Accept json
+// @Produce json
+// @Security JWTKeyAuth
+// @Param registration body user.WebAuthnRegistration true "The name and the response of the authenticator."
+// @Success 200 {object} user.WebAuthnCredential "The new authenticator."
+// @Failure 400 {object} web.HTTPError "Something's invalid."
+// @Failure 412 {object} web.HTTPError "The authenticator response is invalid or the registration expired."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/settings/webauthn [put]
+func UserWebAuthnRegisterFinish(c echo.Context) error {
+	u, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	registration := &user.WebAuthnRegistration{}
+	if err := c.Bind(registration); err != nil {
+		log.Debugf("Invalid model error. Internal error was: %s", err.Error())
+		if he, is := err.(*echo.HTTPError); is {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid model provided. Error was: %s", he.Message))
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid model provided.")
+	}
+
+	if err := c.Validate(registration); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	credential, err := user.FinishWebAuthnRegistration(s, u, registration)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, credential)
+}
+
+// UserWebAuthnDelete removes a WebAuthn authenticator of the current user.
+// @Summary Delete a WebAuthn authenticator
+// @Description Removes a WebAuthn authenticator. It can not be used to log in afterwards.
+// @tags user
+// @Produce json
+// @Security JWTKeyAuth
+// @Param credential path int true "The id of the authenticator"
+// @Success 200 {object} models.Message "Successfully deleted."
+// @Failure 404 {object} web.HTTPError "The authenticator does not exist."
+// @Failure 500 {object} models.Message "Internal server error."
+// @Router /user/settings/webauthn/{credential} [delete]
+func UserWebAuthnDelete(c echo.Context) error {
+	u, err := user.GetCurrentUser(c)
+	if err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	credentialID, err := strconv.ParseInt(c.Param("credential"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid credential id.")
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	err = user.DeleteWebAuthnCredential(s, u, credentialID)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, models.Message{Message: "The authenticator was deleted successfully."})
+}
+
+// WebAuthnLoginBegin creates a WebAuthn challenge to log a user in.
+// @Summary Start a WebAuthn login
+// @Description Returns the options which need to be passed to `navigator.credentials.get()`. If a password is provided, it is checked and the signed challenge can be passed as `webauthn_assertion` to `/login` as a second factor. Without a password, the authenticator needs to verify the user and the signed challenge can be passed to `/login/webauthn`. This requires passwordless login to be enabled.
+// @tags user
+// @Accept json
+// @Produce json
+// @Param credentials body user.Login true "The username and optionally the password of the user"
+// @Success 200 {object} protocol.CredentialAssertion "The credential request options."
+// @Failure 403 {object} models.Message "Invalid username or password."
+// @Failure 412 {object} web.HTTPError "WebAuthn is not available."
+// @Router /login/webauthn/begin [post]
+func WebAuthnLoginBegin(c echo.Context) error {
+	u := user.Login{}
+	if err := c.Bind(&u); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Message{Message: "Please provide a username."})
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	options, err := user.BeginWebAuthnLogin(s, &u)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, options)
+}
+
+// WebAuthnLogin logs a user in without a password
+// @Summary Passwordless login
+// @Description Logs a user in with their username and a WebAuthn assertion obtained after calling `/login/webauthn/begin` without a password. Returns a JWT-Token to authenticate further requests.
+// @tags user
+// @Accept json
+// @Produce json
+// @Param credentials body user.Login true "The username and the WebAuthn assertion"
+// @Success 200 {object} auth.Token
+// @Failure 400 {object} models.Message "Invalid user model."
+// @Failure 412 {object} models.Message "Invalid WebAuthn assertion."
+// @Failure 403 {object} models.Message "Invalid username."
+// @Router /login/webauthn [post]
+func WebAuthnLogin(c echo.Context) error {
+	u := user.Login{}
+	if err := c.Bind(&u); err != nil {
+		return c.JSON(http.StatusBadRequest, models.Message{Message: "Please provide a username and a WebAuthn assertion."})
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	usr, err := user.CheckUserWebAuthnCredentials(s, &u)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	return auth.NewUserAuthTokenResponse(usr, c)
+}
@@ -0,0 +1,573 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/caldav"
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/api/pkg/utils"
+	"github.com/labstack/echo/v4"
+)
+
+// AddressBookBasePath is the path of the collection which holds the address books of a user
+const AddressBookBasePath = DavBasePath + `addressbooks/`
+
+// AddressBookPath is the path of the address book with all users and teams the user shares something with
+const AddressBookPath = AddressBookBasePath + `contacts/`
+
+const (
+	carddavNamespace        = `urn:ietf:params:xml:ns:carddav`
+	calendarServerNamespace = `http://calendarserver.org/ns/`
+)
+
+// The address book is read-only, clients get a forbidden error for every request which would change it
+var addressBookWriteMethods = map[string]bool{
+	http.MethodPut:    true,
+	http.MethodPost:   true,
+	http.MethodDelete: true,
+	"PROPPATCH":       true,
+	"MKCOL":           true,
+	"COPY":            true,
+	"MOVE":            true,
+	"LOCK":            true,
+	"UNLOCK":          true,
+}
+
+type davPropertyList struct {
+	Properties []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (l *davPropertyList) names() []xml.Name {
+	names := make([]xml.Name, 0, len(l.Properties))
+	for _, p := range l.Properties {
+		names = append(names, p.XMLName)
+	}
+	return names
+}
+
+// https://tools.ietf.org/html/rfc4918#section-14.20
+type propfindRequest struct {
+	XMLName xml.Name         `xml:"DAV: propfind"`
+	Prop    *davPropertyList `xml:"DAV: prop"`
+}
+
+// Both the addressbook-multiget and the addressbook-query report, see https://tools.ietf.org/html/rfc6352#section-8
+type addressBookReportRequest struct {
+	XMLName xml.Name
+	Prop    *davPropertyList `xml:"DAV: prop"`
+	Hrefs   []string         `xml:"DAV: href"`
+}
+
+// davProperty is a single property of a resource with its complete xml element
+type davProperty struct {
+	name  xml.Name
+	value string
+}
+
+type addressBookContact struct {
+	uid   string
+	vcard string
+}
+
+func (c *addressBookContact) href() string {
+	return AddressBookPath + c.uid + `.vcf`
+}
+
+func (c *addressBookContact) etag() string {
+	return `"` + utils.Sha256(c.vcard) + `"`
+}
+
+func getUserContactUID(u *user.User) string {
+	return `vikunja-user-` + strconv.FormatInt(u.ID, 10)
+}
+
+func getTeamContactUID(t *models.Team) string {
+	return `vikunja-team-` + strconv.FormatInt(t.ID, 10)
+}
+
+// getAddressBookContacts returns a vcard for every user and team in the address book of a user
+func getAddressBookContacts(u *user.User) (contacts []*addressBookContact, err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	book, err := models.GetAddressBookForUser(s, u)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
+	for _, bu := range book.Users {
+		c := &caldav.Contact{
+			UID:      getUserContactUID(bu),
+			Name:     bu.Name,
+			Nickname: bu.Username,
+			Email:    bu.Email,
+			Updated:  bu.Updated,
+		}
+		// The name is only set if the user made it discoverable
+		if c.Name == "" {
+			c.Name = bu.Username
+		}
+		contacts = append(contacts, &addressBookContact{
+			uid:   c.UID,
+			vcard: caldav.ParseContact("Vikunja Todo App", c),
+		})
+	}
+
+	for _, t := range book.Teams {
+		c := &caldav.Contact{
+			UID:     getTeamContactUID(t),
+			Name:    t.Name,
+			Updated: t.Updated,
+			IsGroup: true,
+		}
+		for _, id := range book.TeamMemberIDs[t.ID] {
+			c.Members = append(c.Members, getUserContactUID(&user.User{ID: id}))
+		}
+		contacts = append(contacts, &addressBookContact{
+			uid:   c.UID,
+			vcard: caldav.ParseContact("Vikunja Todo App", c),
+		})
+	}
+
+	return contacts, s.Commit()
+}
+
+// getAddressBookCtag returns a tag which changes whenever any contact of the address book changes
+func getAddressBookCtag(contacts []*addressBookContact) string {
+	etags := make([]string, 0, len(contacts))
+	for _, c := range contacts {
+		etags = append(etags, c.etag())
+	}
+	return `"` + utils.Sha256(strings.Join(etags, ",")) + `"`
+}
+
+func davName(space, local string) xml.Name {
+	return xml.Name{Space: space, Local: local}
+}
+
+func getCommonAddressBookProperties(u *user.User) []*davProperty {
+	return []*davProperty{
+		{
+			name:  davName(davNamespace, "current-user-principal"),
+			value: `<D:current-user-principal><D:href>` + xmlEscape(DavBasePath+`principals/`+u.Username+`/`) + `</D:href></D:current-user-principal>`,
+		},
+		{
+			name:  davName(davNamespace, "current-user-privilege-set"),
+			value: `<D:current-user-privilege-set><D:privilege><D:read/></D:privilege></D:current-user-privilege-set>`,
+		},
+		{
+			name:  davName(carddavNamespace, "addressbook-home-set"),
+			value: `<CR:addressbook-home-set><D:href>` + AddressBookBasePath + `</D:href></CR:addressbook-home-set>`,
+		},
+	}
+}
+
+func getAddressBookHomeProperties(u *user.User) []*davProperty {
+	return append([]*davProperty{
+		{
+			name:  davName(davNamespace, "resourcetype"),
+			value: `<D:resourcetype><D:collection/></D:resourcetype>`,
+		},
+		{
+			name:  davName(davNamespace, "displayname"),
+			value: `<D:displayname>Address Books</D:displayname>`,
+		},
+	}, getCommonAddressBookProperties(u)...)
+}
+
+func getAddressBookProperties(u *user.User, contacts []*addressBookContact) []*davProperty {
+	ctag := xmlEscape(getAddressBookCtag(contacts))
+	return append([]*davProperty{
+		{
+			name:  davName(davNamespace, "resourcetype"),
+			value: `<D:resourcetype><D:collection/><CR:addressbook/></D:resourcetype>`,
+		},
+		{
+			name:  davName(davNamespace, "displayname"),
+			value: `<D:displayname>Vikunja</D:displayname>`,
+		},
+		{
+			name:  davName(carddavNamespace, "addressbook-description"),
+			value: `<CR:addressbook-description>Everyone you share a list or team with in Vikunja</CR:addressbook-description>`,
+		},
+		{
+			name:  davName(davNamespace, "getetag"),
+			value: `<D:getetag>` + ctag + `</D:getetag>`,
+		},
+		{
+			name:  davName(calendarServerNamespace, "getctag"),
+			value: `<CS:getctag>` + ctag + `</CS:getctag>`,
+		},
+		{
+			name: davName(davNamespace, "supported-report-set"),
+			value: `<D:supported-report-set>` +
+				`<D:supported-report><D:report><CR:addressbook-multiget/></D:report></D:supported-report>` +
+				`<D:supported-report><D:report><CR:addressbook-query/></D:report></D:supported-report>` +
+				`</D:supported-report-set>`,
+		},
+		{
+			name:  davName(carddavNamespace, "supported-address-data"),
+			value: `<CR:supported-address-data><CR:address-data-type content-type="text/vcard" version="3.0"/></CR:supported-address-data>`,
+		},
+	}, getCommonAddressBookProperties(u)...)
+}
+
+// getContactProperties returns all properties of a single contact. The vcard itself is only part of the
+// properties if withData is true, clients have to explicitly ask for it.
+func getContactProperties(c *addressBookContact, withData bool) []*davProperty {
+	props := []*davProperty{
+		{
+			name:  davName(davNamespace, "resourcetype"),
+			value: `<D:resourcetype/>`,
+		},
+		{
+			name:  davName(davNamespace, "getetag"),
+			value: `<D:getetag>` + xmlEscape(c.etag()) + `</D:getetag>`,
+		},
+		{
+			name:  davName(davNamespace, "getcontenttype"),
+			value: `<D:getcontenttype>text/vcard; charset=utf-8</D:getcontenttype>`,
+		},
+		{
+			name:  davName(davNamespace, "getcontentlength"),
+			value: `<D:getcontentlength>` + strconv.Itoa(len(c.vcard)) + `</D:getcontentlength>`,
+		},
+	}
+	if withData {
+		props = append(props, &davProperty{
+			name:  davName(carddavNamespace, "address-data"),
+			value: `<CR:address-data>` + xmlEscape(c.vcard) + `</CR:address-data>`,
+		})
+	}
+	return props
+}
+
+// writeDavResponse writes a single response of a multistatus. If requested is nil, all properties are returned.
+// Requested properties the resource does not have are reported as not found.
+func writeDavResponse(b *strings.Builder, href string, props []*davProperty, requested []xml.Name) {
+	var found, notFound strings.Builder
+	if requested == nil {
+		for _, p := range props {
+			found.WriteString(p.value)
+		}
+	}
+	for _, name := range requested {
+		var prop *davProperty
+		for _, p := range props {
+			if p.name == name {
+				prop = p
+				break
+			}
+		}
+		if prop == nil {
+			notFound.WriteString(`<` + name.Local + ` xmlns="` + xmlEscape(name.Space) + `"/>`)
+			continue
+		}
+		found.WriteString(prop.value)
+	}
+
+	b.WriteString(`<D:response><D:href>` + xmlEscape(href) + `</D:href>`)
+	if found.Len() > 0 || notFound.Len() == 0 {
+		b.WriteString(`<D:propstat><D:prop>` + found.String() + `</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>`)
+	}
+	if notFound.Len() > 0 {
+		b.WriteString(`<D:propstat><D:prop>` + notFound.String() + `</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`)
+	}
+	b.WriteString(`</D:response>`)
+}
+
+func newMultistatus() *strings.Builder {
+	b := &strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:CR="urn:ietf:params:xml:ns:carddav" xmlns:CS="http://calendarserver.org/ns/">`)
+	return b
+}
+
+func sendMultistatus(c echo.Context, b *strings.Builder) error {
+	b.WriteString(`</D:multistatus>`)
+	return c.Blob(http.StatusMultiStatus, "application/xml; charset=utf-8", []byte(b.String()))
+}
+
+// getRequestedProperties parses the body of a propfind request. A nil result means all properties were requested.
+func getRequestedProperties(c echo.Context) (requested []xml.Name, err error) {
+	body, _ := ioutil.ReadAll(c.Request().Body)
+	log.Debugf("[CARDDAV] Request Body: %v\n", string(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, nil
+	}
+
+	req := &propfindRequest{}
+	if err := xml.Unmarshal(body, req); err != nil {
+		log.Debugf("[CARDDAV] Invalid propfind request: %s", err)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid propfind request")
+	}
+	if req.Prop == nil {
+		return nil, nil
+	}
+	return req.Prop.names(), nil
+}
+
+// getAddressBookUser returns the user of a request to the address book. Link shares don't have an address book.
+func getAddressBookUser(c echo.Context) (u *user.User, err error) {
+	a, err := getBasicAuthFromContext(c)
+	if err != nil {
+		log.Error(err)
+		return nil, echo.ErrInternalServerError
+	}
+
+	u, is := a.(*user.User)
+	if !is {
+		return nil, echo.ErrForbidden
+	}
+	return u, nil
+}
+
+// handleAddressBookMethods answers all requests which are the same for every resource of the address book.
+// If handled is false, the request needs to be handled by the caller.
+func handleAddressBookMethods(c echo.Context) (handled bool, err error) {
+	method := c.Request().Method
+	if method == http.MethodOptions {
+		c.Response().Header().Set("DAV", "1, 3, addressbook")
+		c.Response().Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, REPORT")
+		return true, c.NoContent(http.StatusOK)
+	}
+	if addressBookWriteMethods[method] {
+		return true, c.NoContent(http.StatusForbidden)
+	}
+	return false, nil
+}
+
+// AddressBookHomeHandler handles all requests to the collection which holds the address books of a user
+func AddressBookHomeHandler(c echo.Context) error {
+	if handled, err := handleAddressBookMethods(c); handled {
+		return err
+	}
+
+	u, err := getAddressBookUser(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Request().Method != "PROPFIND" {
+		return c.NoContent(http.StatusMethodNotAllowed)
+	}
+
+	requested, err := getRequestedProperties(c)
+	if err != nil {
+		return err
+	}
+
+	b := newMultistatus()
+	writeDavResponse(b, AddressBookBasePath, getAddressBookHomeProperties(u), requested)
+
+	if c.Request().Header.Get("Depth") != "0" {
+		contacts, err := getAddressBookContacts(u)
+		if err != nil {
+			return handleCaldavError(c, err)
+		}
+		writeDavResponse(b, AddressBookPath, getAddressBookProperties(u, contacts), requested)
+	}
+
+	return sendMultistatus(c, b)
+}
+
+// AddressBookHandler handles all requests to the address book with all users and teams
+func AddressBookHandler(c echo.Context) error {
+	if handled, err := handleAddressBookMethods(c); handled {
+		return err
+	}
+
+	u, err := getAddressBookUser(c)
+	if err != nil {
+		return err
+	}
+
+	switch c.Request().Method {
+	case "PROPFIND":
+		requested, err := getRequestedProperties(c)
+		if err != nil {
+			return err
+		}
+
+		contacts, err := getAddressBookContacts(u)
+		if err != nil {
+			return handleCaldavError(c, err)
+		}
+
+		b := newMultistatus()
+		writeDavResponse(b, AddressBookPath, getAddressBookProperties(u, contacts), requested)
+		if c.Request().Header.Get("Depth") != "0" {
+			for _, contact := range contacts {
+				writeDavResponse(b, contact.href(), getContactProperties(contact, false), requested)
+			}
+		}
+		return sendMultistatus(c, b)
+	case "REPORT":
+		return handleAddressBookReport(c, u)
+	default:
+		return c.NoContent(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAddressBookReport answers addressbook-multiget and addressbook-query reports.
+// Filters of a query are not supported, it always returns all contacts.
+func handleAddressBookReport(c echo.Context, u *user.User) error {
+	body, _ := ioutil.ReadAll(c.Request().Body)
+	log.Debugf("[CARDDAV] Request Body: %v\n", string(body))
+
+	req := &addressBookReportRequest{}
+	if err := xml.Unmarshal(body, req); err != nil {
+		log.Debugf("[CARDDAV] Invalid report request: %s", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid report request")
+	}
+	if req.XMLName.Space != carddavNamespace ||
+		(req.XMLName.Local != "addressbook-multiget" && req.XMLName.Local != "addressbook-query") {
+		return c.Blob(http.StatusForbidden, "application/xml; charset=utf-8", []byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:error xmlns:D="DAV:"><D:supported-report/></D:error>`))
+	}
+
+	contacts, err := getAddressBookContacts(u)
+	if err != nil {
+		return handleCaldavError(c, err)
+	}
+
+	var requested []xml.Name
+	if req.Prop != nil {
+		requested = req.Prop.names()
+	}
+
+	b := newMultistatus()
+
+	if req.XMLName.Local == "addressbook-query" {
+		for _, contact := range contacts {
+			writeDavResponse(b, contact.href(), getContactProperties(contact, true), requested)
+		}
+		return sendMultistatus(c, b)
+	}
+
+	contactsByUID := make(map[string]*addressBookContact, len(contacts))
+	for _, contact := range contacts {
+		contactsByUID[contact.uid] = contact
+	}
+	for _, href := range req.Hrefs {
+		contact, exists := contactsByUID[getContactUIDFromHref(href)]
+		if !exists {
+			b.WriteString(`<D:response><D:href>` + xmlEscape(href) + `</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>`)
+			continue
+		}
+		writeDavResponse(b, href, getContactProperties(contact, true), requested)
+	}
+
+	return sendMultistatus(c, b)
+}
+
+func getContactUIDFromHref(href string) string {
+	href = strings.TrimSpace(href)
+	if u, err := url.Parse(href); err == nil {
+		href = u.Path
+	}
+	return strings.TrimSuffix(path.Base(href), ".vcf")
+}
+
+// ContactHandler handles all requests to a single user or team of the address book
+func ContactHandler(c echo.Context) error {
+	if handled, err := handleAddressBookMethods(c); handled {
+		return err
+	}
+
+	u, err := getAddressBookUser(c)
+	if err != nil {
+		return err
+	}
+
+	method := c.Request().Method
+	if method != http.MethodGet && method != http.MethodHead && method != "PROPFIND" {
+		return c.NoContent(http.StatusMethodNotAllowed)
+	}
+
+	contacts, err := getAddressBookContacts(u)
+	if err != nil {
+		return handleCaldavError(c, err)
+	}
+
+	uid := strings.TrimSuffix(c.Param("contact"), ".vcf")
+	var contact *addressBookContact
+	for _, ct := range contacts {
+		if ct.uid == uid {
+			contact = ct
+			break
+		}
+	}
+	if contact == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	if method == "PROPFIND" {
+		requested, err := getRequestedProperties(c)
+		if err != nil {
+			return err
+		}
+		b := newMultistatus()
+		writeDavResponse(b, contact.href(), getContactProperties(contact, false), requested)
+		return sendMultistatus(c, b)
+	}
+
+	c.Response().Header().Set("ETag", contact.etag())
+	if method == http.MethodHead {
+		c.Response().Header().Set("Content-Type", "text/vcard; charset=utf-8")
+		return c.NoContent(http.StatusOK)
+	}
+	return c.Blob(http.StatusOK, "text/vcard; charset=utf-8", []byte(contact.vcard))
+}
+
+// caldav-go only knows about calendars, clients looking for address books ask the principal for its
+// addressbook-home-set. We add it to the first response of a propfind request, which is the principal itself.
+var emptyAddressBookHomeSetPropertyRegex = regexp.MustCompile(`<(?:[A-Za-z0-9]+:)?addressbook-home-set(?:\s[^>]*)?/>`)
+
+func addAddressBookHomeSetToPropfindResponse(body string) string {
+	if !emptyAddressBookHomeSetPropertyRegex.MatchString(body) {
+		return body
+	}
+
+	body = emptyAddressBookHomeSetPropertyRegex.ReplaceAllString(body, "")
+	end := responseEndRegex.FindStringIndex(body)
+	if end == nil {
+		return body
+	}
+
+	propstat := `<propstat xmlns="DAV:"><prop>` +
+		`<addressbook-home-set xmlns="` + carddavNamespace + `"><href xmlns="DAV:">` + AddressBookBasePath + `</href></addressbook-home-set>` +
+		`</prop><status>HTTP/1.1 200 OK</status></propstat>`
+	return body[:end[0]] + propstat + body[end[0]:]
+}
@@ -0,0 +1,66 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContactUIDFromHref(t *testing.T) {
+	assert.Equal(t, "vikunja-user-1", getContactUIDFromHref("/dav/addressbooks/contacts/vikunja-user-1.vcf"))
+	assert.Equal(t, "vikunja-team-2", getContactUIDFromHref("https://vikunja.example.com/dav/addressbooks/contacts/vikunja-team-2.vcf"))
+}
+
+func TestWriteDavResponse(t *testing.T) {
+	props := []*davProperty{
+		{name: davName(davNamespace, "getetag"), value: `<D:getetag>"abc"</D:getetag>`},
+		{name: davName(davNamespace, "resourcetype"), value: `<D:resourcetype/>`},
+	}
+
+	t.Run("all properties", func(t *testing.T) {
+		b := &strings.Builder{}
+		writeDavResponse(b, "/dav/addressbooks/contacts/1.vcf", props, nil)
+		assert.Equal(t, `<D:response><D:href>/dav/addressbooks/contacts/1.vcf</D:href>`+
+			`<D:propstat><D:prop><D:getetag>"abc"</D:getetag><D:resourcetype/></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>`+
+			`</D:response>`, b.String())
+	})
+	t.Run("unknown property", func(t *testing.T) {
+		b := &strings.Builder{}
+		writeDavResponse(b, "/dav/addressbooks/contacts/1.vcf", props, []xml.Name{
+			davName(davNamespace, "getetag"),
+			davName(carddavNamespace, "unknown"),
+		})
+		assert.Equal(t, `<D:response><D:href>/dav/addressbooks/contacts/1.vcf</D:href>`+
+			`<D:propstat><D:prop><D:getetag>"abc"</D:getetag></D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>`+
+			`<D:propstat><D:prop><unknown xmlns="urn:ietf:params:xml:ns:carddav"/></D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`+
+			`</D:response>`, b.String())
+	})
+}
+
+func TestAddAddressBookHomeSetToPropfindResponse(t *testing.T) {
+	body := addAddressBookHomeSetToPropfindResponse(`<D:multistatus xmlns:D="DAV:"><D:response><D:href>/dav/principals/user1/</D:href>` +
+		`<D:propstat><D:prop><C:addressbook-home-set xmlns:C="urn:ietf:params:xml:ns:carddav"/></D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>` +
+		`</D:response></D:multistatus>`)
+	assert.Equal(t, `<D:multistatus xmlns:D="DAV:"><D:response><D:href>/dav/principals/user1/</D:href>`+
+		`<D:propstat><D:prop></D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`+
+		`<propstat xmlns="DAV:"><prop><addressbook-home-set xmlns="urn:ietf:params:xml:ns:carddav"><href xmlns="DAV:">/dav/addressbooks/</href></addressbook-home-set></prop><status>HTTP/1.1 200 OK</status></propstat>`+
+		`</D:response></D:multistatus>`, body)
+}
@@ -0,0 +1,385 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/caldav"
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/utils"
+	"code.vikunja.io/web"
+	"github.com/samedi/caldav-go/data"
+	"github.com/samedi/caldav-go/errs"
+	"xorm.io/xorm"
+)
+
+// EventBasePath is the base path for all calendars which contain the tasks of a list as events
+const EventBasePath = DavBasePath + `events`
+
+// VikunjaCaldavEventStorage serves all tasks of a list which have a date as VEVENTs.
+// This allows using them in calendar apps which don't support VTODOs.
+type VikunjaCaldavEventStorage struct {
+	// Used when handling a list
+	list *models.List
+	// Used when handling a single event
+	task *models.Task
+	// The current user or link share
+	auth web.Auth
+}
+
+// GetResources returns either all lists or only one list, depending on the request
+func (vces *VikunjaCaldavEventStorage) GetResources(rpath string, withChildren bool) ([]data.Resource, error) {
+	if vces.list != nil && vces.list.ID != 0 {
+		rr, err := vces.getListResource(true)
+		if err != nil {
+			return nil, err
+		}
+		r := data.NewResource(rpath, &rr)
+		r.Name = vces.list.Title
+		return []data.Resource{r}, nil
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	thelists, _, _, err := (&models.List{}).ReadAll(s, vces.auth, "", -1, 50)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+	if err := s.Commit(); err != nil {
+		return nil, err
+	}
+	lists := thelists.([]*models.List)
+
+	var resources []data.Resource
+	for _, l := range lists {
+		rr := VikunjaEventResourceAdapter{
+			list:         l,
+			isCollection: true,
+		}
+		r := data.NewResource(EventBasePath+"/"+strconv.FormatInt(l.ID, 10), &rr)
+		r.Name = l.Title
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+// GetResourcesByList fetches a list of resources from a slice of paths
+func (vces *VikunjaCaldavEventStorage) GetResourcesByList(rpaths []string) ([]data.Resource, error) {
+
+	// A path looks like this: /dav/events/10/a6eb526d5748a5c499da202fe74f36ed1aea2aef.ics
+	var uids []string
+	for _, path := range rpaths {
+		parts := strings.Split(path, "/")
+		uids = append(uids, strings.TrimSuffix(parts[len(parts)-1], ".ics"))
+	}
+
+	rr, err := vces.getListResource(false)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(uids))
+	for _, uid := range uids {
+		wanted[uid] = true
+	}
+
+	var resources []data.Resource
+	for _, t := range rr.tasks {
+		if !wanted[t.UID] {
+			continue
+		}
+		r := data.NewResource(getEventURL(t), &VikunjaEventResourceAdapter{list: vces.list, task: t})
+		r.Name = t.Title
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+// GetResourcesByFilters returns all events of a list. Filtering is not supported.
+func (vces *VikunjaCaldavEventStorage) GetResourcesByFilters(rpath string, filters *data.ResourceFilter) ([]data.Resource, error) {
+	rr, err := vces.getListResource(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []data.Resource
+	for _, t := range rr.tasks {
+		r := data.NewResource(getEventURL(t), &VikunjaEventResourceAdapter{list: vces.list, task: t})
+		r.Name = t.Title
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+func getEventURL(task *models.Task) string {
+	return EventBasePath + "/" + strconv.FormatInt(task.ListID, 10) + `/` + task.UID + `.ics`
+}
+
+// GetResource fetches a single resource
+func (vces *VikunjaCaldavEventStorage) GetResource(rpath string) (*data.Resource, bool, error) {
+	if vces.task != nil {
+		s := db.NewSession()
+		defer s.Close()
+
+		task, err := vces.getTask(s)
+		if err != nil {
+			_ = s.Rollback()
+			return nil, false, err
+		}
+		if err := s.Commit(); err != nil {
+			return nil, false, err
+		}
+
+		vces.task = task
+		r := data.NewResource(rpath, &VikunjaEventResourceAdapter{list: vces.list, task: task})
+		return &r, true, nil
+	}
+
+	rr, err := vces.getListResource(true)
+	if err != nil {
+		return nil, false, err
+	}
+	r := data.NewResource(rpath, &rr)
+	return &r, true, nil
+}
+
+// GetShallowResource gets a ressource without childs
+func (vces *VikunjaCaldavEventStorage) GetShallowResource(rpath string) (*data.Resource, bool, error) {
+	return vces.GetResource(rpath)
+}
+
+// CreateResource is not supported, new tasks can only be created as VTODO
+func (vces *VikunjaCaldavEventStorage) CreateResource(rpath, content string) (*data.Resource, error) {
+	return nil, errs.ForbiddenError
+}
+
+// UpdateResource writes the start and end time of an event back to the task
+func (vces *VikunjaCaldavEventStorage) UpdateResource(rpath, content string) (*data.Resource, error) {
+	start, end, allDay, err := caldav.ParseEventDatesFromVEVENT(content)
+	if err != nil {
+		return nil, err
+	}
+
+	s := db.NewSession()
+	defer s.Close()
+
+	task, err := vces.getTask(s)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
+	canUpdate, err := task.CanUpdate(s, vces.auth)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+	if !canUpdate {
+		_ = s.Rollback()
+		return nil, errs.ForbiddenError
+	}
+
+	applyEventDatesToTask(task, start, end, allDay)
+
+	err = task.Update(s, vces.auth)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
+	// Get the task again so the etag is the same as the one of a later request
+	task, err = vces.getTask(s)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
+	if err := s.Commit(); err != nil {
+		return nil, err
+	}
+
+	r := data.NewResource(rpath, &VikunjaEventResourceAdapter{list: vces.list, task: task})
+	return &r, nil
+}
+
+// DeleteResource is not supported, deleting an event in a calendar app should not delete the task
+func (vces *VikunjaCaldavEventStorage) DeleteResource(rpath string) error {
+	return errs.ForbiddenError
+}
+
+// getTask returns the full task of the storage if it belongs to the list, can be seen by the user and has dates
+func (vces *VikunjaCaldavEventStorage) getTask(s *xorm.Session) (task *models.Task, err error) {
+	can, _, err := vces.list.CanRead(s, vces.auth)
+	if err != nil {
+		return nil, err
+	}
+	if !can {
+		return nil, errs.ForbiddenError
+	}
+
+	tasks, err := models.GetTasksByUIDs(s, []string{vces.task.UID})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		if t.ListID != vces.list.ID {
+			continue
+		}
+		if _, has := getCaldavEventForTask(t); has {
+			return t, nil
+		}
+	}
+
+	return nil, errs.ResourceNotFoundError
+}
+
+func (vces *VikunjaCaldavEventStorage) getListResource(isCollection bool) (rr VikunjaEventResourceAdapter, err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	if vces.list == nil {
+		return
+	}
+
+	can, _, err := vces.list.CanRead(s, vces.auth)
+	if err != nil {
+		_ = s.Rollback()
+		return
+	}
+	if !can {
+		_ = s.Rollback()
+		log.Errorf("User or link share %v tried to access a caldav resource (List %v) which they are not allowed to access", vces.auth.GetID(), vces.list.ID)
+		return rr, models.ErrUserDoesNotHaveAccessToList{ListID: vces.list.ID}
+	}
+	err = vces.list.ReadOne(s, vces.auth)
+	if err != nil {
+		_ = s.Rollback()
+		return
+	}
+
+	tc := models.TaskCollection{
+		ListID: vces.list.ID,
+	}
+	iface, _, _, err := tc.ReadAll(s, vces.auth, "", 1, 1000)
+	if err != nil {
+		_ = s.Rollback()
+		return rr, err
+	}
+	tasks, ok := iface.([]*models.Task)
+	if !ok {
+		panic("Tasks returned from TaskCollection.ReadAll are not []*models.Task!")
+	}
+
+	syncToken, err := models.GetLatestTaskChangeID(s, vces.list.ID)
+	if err != nil {
+		_ = s.Rollback()
+		return rr, err
+	}
+
+	if err := s.Commit(); err != nil {
+		return rr, err
+	}
+
+	// Only tasks with dates can be shown in a calendar
+	var datedTasks []*models.Task
+	for _, t := range tasks {
+		if _, has := getCaldavEventForTask(t); has {
+			datedTasks = append(datedTasks, t)
+		}
+	}
+
+	rr = VikunjaEventResourceAdapter{
+		list:         vces.list,
+		tasks:        datedTasks,
+		syncToken:    syncToken,
+		isCollection: isCollection,
+	}
+	return
+}
+
+// VikunjaEventResourceAdapter holds either a calendar with all events of a list or a single event
+type VikunjaEventResourceAdapter struct {
+	list  *models.List
+	tasks []*models.Task
+	task  *models.Task
+	// The id of the latest change of a task in the list
+	syncToken int64
+
+	isCollection bool
+}
+
+// IsCollection checks if the resoure in the adapter is a collection
+func (vera *VikunjaEventResourceAdapter) IsCollection() bool {
+	return vera.isCollection
+}
+
+// CalculateEtag returns the etag of a resource
+func (vera *VikunjaEventResourceAdapter) CalculateEtag() string {
+	if vera.task != nil {
+		return `"` + utils.Sha256(vera.GetContent()) + `"`
+	}
+
+	if vera.list == nil {
+		return ""
+	}
+
+	if vera.syncToken > 0 {
+		return `"` + strconv.FormatInt(vera.list.ID, 10) + `-` + strconv.FormatInt(vera.syncToken, 10) + `"`
+	}
+
+	return `"` + strconv.FormatInt(vera.list.ID, 10) + `-` + strconv.FormatInt(vera.list.Updated.Unix(), 10) + `"`
+}
+
+// GetContent returns the content string of a resource
+func (vera *VikunjaEventResourceAdapter) GetContent() string {
+	if vera.task != nil {
+		return getCaldavEventsForTasks(&models.List{}, []*models.Task{vera.task})
+	}
+
+	if vera.list != nil {
+		return getCaldavEventsForTasks(vera.list, vera.tasks)
+	}
+
+	return ""
+}
+
+// GetContentSize is the size of a caldav content
+func (vera *VikunjaEventResourceAdapter) GetContentSize() int64 {
+	return int64(len(vera.GetContent()))
+}
+
+// GetModTime returns when the resource was last modified
+func (vera *VikunjaEventResourceAdapter) GetModTime() time.Time {
+	if vera.task != nil {
+		return vera.task.Updated
+	}
+
+	if vera.list != nil {
+		return vera.list.Updated
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,116 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"time"
+
+	"code.vikunja.io/api/pkg/caldav"
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+)
+
+// getCaldavEventForTask returns the event for a task. Tasks without any date don't have an event.
+//
+// Tasks with a start date and an end or due date are shown as events in between these dates.
+// Tasks with only one date are shown as all-day events on that day.
+func getCaldavEventForTask(t *models.Task) (event *caldav.Event, has bool) {
+	event = &caldav.Event{
+		Summary:     t.Title,
+		Description: t.Description,
+		UID:         t.UID,
+		Color:       t.HexColor,
+		Timestamp:   t.Updated,
+	}
+
+	switch {
+	case !t.StartDate.IsZero() && !t.EndDate.IsZero():
+		event.Start = t.StartDate
+		event.End = t.EndDate
+	case !t.StartDate.IsZero() && !t.DueDate.IsZero():
+		event.Start = t.StartDate
+		event.End = t.DueDate
+	case !t.DueDate.IsZero():
+		event.Start = t.DueDate
+		event.AllDay = true
+	case !t.StartDate.IsZero():
+		event.Start = t.StartDate
+		event.AllDay = true
+	case !t.EndDate.IsZero():
+		event.Start = t.EndDate
+		event.AllDay = true
+	default:
+		return nil, false
+	}
+
+	if event.AllDay {
+		event.End = event.Start.AddDate(0, 0, 1)
+	}
+
+	for _, r := range t.Reminders {
+		event.Alarms = append(event.Alarms, caldav.Alarm{Time: r})
+	}
+
+	return event, true
+}
+
+func getCaldavEventsForTasks(list *models.List, tasks []*models.Task) string {
+	var events []*caldav.Event
+	for _, t := range tasks {
+		if event, has := getCaldavEventForTask(t); has {
+			events = append(events, event)
+		}
+	}
+
+	caldavConfig := &caldav.Config{
+		Name:   list.Title,
+		ProdID: "Vikunja Todo App",
+		Color:  list.HexColor,
+	}
+
+	return caldav.ParseEvents(caldavConfig, events)
+}
+
+// applyEventDatesToTask sets the dates of a task from the times of its event, the opposite of getCaldavEventForTask.
+func applyEventDatesToTask(t *models.Task, start, end time.Time, allDay bool) {
+	switch {
+	case !t.StartDate.IsZero() && !t.EndDate.IsZero():
+		t.StartDate = start
+		t.EndDate = end
+	case !t.StartDate.IsZero() && !t.DueDate.IsZero():
+		t.StartDate = start
+		t.DueDate = end
+	case !t.DueDate.IsZero():
+		t.DueDate = moveTaskDate(t.DueDate, start, allDay)
+	case !t.StartDate.IsZero():
+		t.StartDate = moveTaskDate(t.StartDate, start, allDay)
+	case !t.EndDate.IsZero():
+		t.EndDate = moveTaskDate(t.EndDate, start, allDay)
+	}
+}
+
+// Tasks with only one date are shown as all-day events. When such an event is moved to another day, the
+// task should keep its time, only when it is changed to a timed event the time is changed as well.
+func moveTaskDate(old, date time.Time, allDay bool) time.Time {
+	if !allDay {
+		return date
+	}
+
+	tz := config.GetTimeZone()
+	old = old.In(tz)
+	return time.Date(date.Year(), date.Month(), date.Day(), old.Hour(), old.Minute(), old.Second(), 0, tz)
+}
@@ -0,0 +1,84 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCaldavEventForTask(t *testing.T) {
+	start := time.Date(2018, 12, 1, 10, 0, 0, 0, config.GetTimeZone())
+	end := time.Date(2018, 12, 1, 12, 0, 0, 0, config.GetTimeZone())
+
+	t.Run("start and end date", func(t *testing.T) {
+		event, has := getCaldavEventForTask(&models.Task{StartDate: start, EndDate: end, DueDate: end.Add(time.Hour)})
+		assert.True(t, has)
+		assert.False(t, event.AllDay)
+		assert.Equal(t, start, event.Start)
+		assert.Equal(t, end, event.End)
+	})
+	t.Run("start and due date", func(t *testing.T) {
+		event, has := getCaldavEventForTask(&models.Task{StartDate: start, DueDate: end})
+		assert.True(t, has)
+		assert.False(t, event.AllDay)
+		assert.Equal(t, end, event.End)
+	})
+	t.Run("only due date", func(t *testing.T) {
+		event, has := getCaldavEventForTask(&models.Task{DueDate: end, Reminders: []time.Time{start}})
+		assert.True(t, has)
+		assert.True(t, event.AllDay)
+		assert.Equal(t, end, event.Start)
+		assert.Equal(t, end.AddDate(0, 0, 1), event.End)
+		assert.Len(t, event.Alarms, 1)
+	})
+	t.Run("no dates", func(t *testing.T) {
+		_, has := getCaldavEventForTask(&models.Task{Title: "No dates"})
+		assert.False(t, has)
+	})
+}
+
+func TestApplyEventDatesToTask(t *testing.T) {
+	tz := config.GetTimeZone()
+	start := time.Date(2018, 12, 5, 8, 0, 0, 0, tz)
+	end := time.Date(2018, 12, 5, 9, 30, 0, 0, tz)
+
+	t.Run("start and due date", func(t *testing.T) {
+		task := &models.Task{
+			StartDate: time.Date(2018, 12, 1, 10, 0, 0, 0, tz),
+			DueDate:   time.Date(2018, 12, 1, 12, 0, 0, 0, tz),
+		}
+		applyEventDatesToTask(task, start, end, false)
+		assert.Equal(t, start, task.StartDate)
+		assert.Equal(t, end, task.DueDate)
+		assert.True(t, task.EndDate.IsZero())
+	})
+	t.Run("due date moved to another day", func(t *testing.T) {
+		task := &models.Task{DueDate: time.Date(2018, 12, 1, 17, 15, 0, 0, tz)}
+		applyEventDatesToTask(task, time.Date(2018, 12, 5, 0, 0, 0, 0, tz), time.Date(2018, 12, 6, 0, 0, 0, 0, tz), true)
+		assert.Equal(t, time.Date(2018, 12, 5, 17, 15, 0, 0, tz), task.DueDate)
+	})
+	t.Run("due date changed to a timed event", func(t *testing.T) {
+		task := &models.Task{DueDate: time.Date(2018, 12, 1, 17, 15, 0, 0, tz)}
+		applyEventDatesToTask(task, start, end, false)
+		assert.Equal(t, start, task.DueDate)
+	})
+}
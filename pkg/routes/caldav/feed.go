@@ -0,0 +1,88 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"net/http"
+	"strings"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"code.vikunja.io/api/pkg/utils"
+	"code.vikunja.io/web/handler"
+	"github.com/labstack/echo/v4"
+)
+
+// Calendar apps usually poll subscriptions every few hours anyway, this only avoids hammering the db if they don't.
+const iCalFeedMaxAge = "900"
+
+// ICalFeedHandler returns the tasks of an ical feed as events
+// @Summary Get an ical feed
+// @Description Returns all tasks with dates of an ical feed as ical events. The token in the url is the only authentication, anyone who knows it can see the tasks of the feed.
+// @tags ical
+// @Produce text/calendar
+// @Param token path string true "The token of the feed, followed by .ics"
+// @Success 200 {} string "The ical feed."
+// @Success 304 {} string "The feed did not change since the client last got it."
+// @Failure 404 {object} web.HTTPError "The feed does not exist."
+// @Failure 500 {object} models.Message "Internal error"
+// @Router /ical/{token}.ics [get]
+func ICalFeedHandler(c echo.Context) error {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	s := db.NewSession()
+	defer s.Close()
+
+	feed, err := models.GetICalFeedByToken(s, token)
+	if err != nil {
+		_ = s.Rollback()
+		return handler.HandleHTTPError(err, c)
+	}
+
+	title, tasks, err := feed.GetTasks(s)
+	if err != nil {
+		_ = s.Rollback()
+		// The owner of the feed lost access to its tasks, the feed is therefore not available anymore.
+		if models.IsErrUserDoesNotHaveAccessToList(err) ||
+			models.IsErrGenericForbidden(err) ||
+			models.IsErrListDoesNotExist(err) ||
+			models.IsErrSavedFilterDoesNotExist(err) {
+			log.Debugf("[ICAL] Feed %d is not available anymore: %s", feed.ID, err)
+			return handler.HandleHTTPError(models.ErrICalFeedDoesNotExist{ID: feed.ID}, c)
+		}
+		return handler.HandleHTTPError(err, c)
+	}
+
+	if err := s.Commit(); err != nil {
+		return handler.HandleHTTPError(err, c)
+	}
+
+	content := getCaldavEventsForTasks(&models.List{Title: title}, tasks)
+	etag := `"` + utils.Sha256(content) + `"`
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, `inline; filename="`+token+`.ics"`)
+	header.Set("Cache-Control", "private, max-age="+iCalFeedMaxAge)
+	header.Set("ETag", etag)
+
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(content))
+}
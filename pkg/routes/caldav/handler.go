@@ -20,25 +20,40 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
 
+	caldav2 "code.vikunja.io/api/pkg/caldav"
 	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/models"
 	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/web"
 	"code.vikunja.io/web/handler"
 	"github.com/labstack/echo/v4"
 	"github.com/samedi/caldav-go"
 	"github.com/samedi/caldav-go/lib"
 )
 
-func getBasicAuthUserFromContext(c echo.Context) (*user.User, error) {
-	u, is := c.Get("userBasicAuth").(*user.User)
-	if !is {
-		return &user.User{}, fmt.Errorf("user is not user element, is %s", reflect.TypeOf(c.Get("userBasicAuth")))
+// Link shares authenticate with their hash as username, everyone else with their username and password
+func getBasicAuthFromContext(c echo.Context) (web.Auth, error) {
+	switch a := c.Get("userBasicAuth").(type) {
+	case *user.User:
+		return a, nil
+	case *models.LinkSharing:
+		return a, nil
+	default:
+		return &user.User{}, fmt.Errorf("user is not user or link share element, is %s", reflect.TypeOf(c.Get("userBasicAuth")))
 	}
-	return u, nil
+}
+
+// getPrincipalName returns the name of the principal of a user or link share
+func getPrincipalName(a web.Auth) string {
+	if share, is := a.(*models.LinkSharing); is {
+		return share.Hash
+	}
+	return a.(*user.User).Username
 }
 
 // ListHandler returns all tasks from a list
@@ -48,7 +63,7 @@ func ListHandler(c echo.Context) error {
 		return err
 	}
 
-	u, err := getBasicAuthUserFromContext(c)
+	a, err := getBasicAuthFromContext(c)
 	if err != nil {
 		log.Error(err)
 		return echo.ErrInternalServerError
@@ -56,7 +71,7 @@ func ListHandler(c echo.Context) error {
 
 	storage := &VikunjaCaldavListStorage{
 		list: &models.List{ID: listID},
-		user: u,
+		auth: a,
 	}
 
 	// Try to parse a task from the request payload
@@ -66,20 +81,39 @@ func ListHandler(c echo.Context) error {
 	// Parse it
 	vtodo := string(body)
 	if vtodo != "" && strings.HasPrefix(vtodo, `BEGIN:VCALENDAR`) {
-		storage.task, err = parseTaskFromVTODO(vtodo)
+		ct, err := caldav2.ParseTaskFromVTODO(vtodo)
 		if err != nil {
 			log.Error(err)
 			return echo.ErrInternalServerError
 		}
+		storage.task = ct.Task
 	}
 
 	log.Debugf("[CALDAV] Request Body: %v\n", string(body))
 	log.Debugf("[CALDAV] Request Headers: %v\n", c.Request().Header)
 
+	// caldav-go does not support sync-collection reports, we handle these ourselves
+	if c.Request().Method == "REPORT" && listID > 0 && isSyncCollectionRequest(body) {
+		return handleSyncCollection(c, storage, body)
+	}
+
 	caldav.SetupStorage(storage)
 	caldav.SetupUser("dav/lists")
 	caldav.SetupSupportedComponents([]string{lib.VCALENDAR, lib.VTODO})
 	response := caldav.HandleRequest(c.Request())
+
+	if c.Request().Method == "PROPFIND" && listID > 0 && response.Status == http.StatusMultiStatus {
+		syncToken, err := storage.getSyncToken()
+		if err != nil {
+			log.Error(err)
+			return echo.ErrInternalServerError
+		}
+		response.Body = addSyncPropertiesToPropfindResponse(response.Body, syncToken)
+		if response.Header != nil && response.Header.Get("Content-Length") != "" {
+			response.Header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+		}
+	}
+
 	response.Write(c.Response())
 	return nil
 }
@@ -91,7 +125,7 @@ func TaskHandler(c echo.Context) error {
 		return err
 	}
 
-	u, err := getBasicAuthUserFromContext(c)
+	a, err := getBasicAuthFromContext(c)
 	if err != nil {
 		log.Error(err)
 		return echo.ErrInternalServerError
@@ -103,10 +137,77 @@ func TaskHandler(c echo.Context) error {
 	storage := &VikunjaCaldavListStorage{
 		list: &models.List{ID: listID},
 		task: &models.Task{UID: taskUID},
-		user: u,
+		auth: a,
+	}
+
+	if c.Request().Method == http.MethodPut || c.Request().Method == http.MethodDelete {
+		ok, err := checkTaskPreconditions(c, storage)
+		if err != nil {
+			log.Error(err)
+			return echo.ErrInternalServerError
+		}
+		if !ok {
+			return c.NoContent(http.StatusPreconditionFailed)
+		}
+	}
+
+	caldav.SetupStorage(storage)
+	response := caldav.HandleRequest(c.Request())
+	response.Write(c.Response())
+	return nil
+}
+
+// EventHandler returns all tasks with dates of a list as events
+func EventHandler(c echo.Context) error {
+	listID, err := getIntParam(c, "list")
+	if err != nil {
+		return err
+	}
+
+	a, err := getBasicAuthFromContext(c)
+	if err != nil {
+		log.Error(err)
+		return echo.ErrInternalServerError
+	}
+
+	storage := &VikunjaCaldavEventStorage{
+		list: &models.List{ID: listID},
+		auth: a,
+	}
+
+	caldav.SetupStorage(storage)
+	caldav.SetupUser("dav/events")
+	caldav.SetupSupportedComponents([]string{lib.VCALENDAR, lib.VEVENT})
+	response := caldav.HandleRequest(c.Request())
+	response.Write(c.Response())
+	return nil
+}
+
+// EventTaskHandler is the handler which manages getting and updating a single task as event
+func EventTaskHandler(c echo.Context) error {
+	listID, err := getIntParam(c, "list")
+	if err != nil {
+		return err
+	}
+
+	a, err := getBasicAuthFromContext(c)
+	if err != nil {
+		log.Error(err)
+		return echo.ErrInternalServerError
+	}
+
+	// Get the task uid
+	taskUID := strings.TrimSuffix(c.Param("task"), ".ics")
+
+	storage := &VikunjaCaldavEventStorage{
+		list: &models.List{ID: listID},
+		task: &models.Task{UID: taskUID},
+		auth: a,
 	}
 
 	caldav.SetupStorage(storage)
+	caldav.SetupUser("dav/events")
+	caldav.SetupSupportedComponents([]string{lib.VCALENDAR, lib.VEVENT})
 	response := caldav.HandleRequest(c.Request())
 	response.Write(c.Response())
 	return nil
@@ -114,14 +215,14 @@ func TaskHandler(c echo.Context) error {
 
 // PrincipalHandler handles all request to principal resources
 func PrincipalHandler(c echo.Context) error {
-	u, err := getBasicAuthUserFromContext(c)
+	a, err := getBasicAuthFromContext(c)
 	if err != nil {
 		log.Error(err)
 		return echo.ErrInternalServerError
 	}
 
 	storage := &VikunjaCaldavListStorage{
-		user:        u,
+		auth:        a,
 		isPrincipal: true,
 	}
 
@@ -134,24 +235,33 @@ func PrincipalHandler(c echo.Context) error {
 	log.Debugf("[CALDAV] Request Headers: %v\n", c.Request().Header)
 
 	caldav.SetupStorage(storage)
-	caldav.SetupUser("dav/principals/" + u.Username)
+	caldav.SetupUser("dav/principals/" + getPrincipalName(a))
 	caldav.SetupSupportedComponents([]string{lib.VCALENDAR, lib.VTODO})
 
 	response := caldav.HandleRequest(c.Request())
+
+	// Only users have an address book
+	if _, isUser := a.(*user.User); isUser && c.Request().Method == "PROPFIND" && response.Status == http.StatusMultiStatus {
+		response.Body = addAddressBookHomeSetToPropfindResponse(response.Body)
+		if response.Header != nil && response.Header.Get("Content-Length") != "" {
+			response.Header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+		}
+	}
+
 	response.Write(c.Response())
 	return nil
 }
 
 // EntryHandler handles all request to principal resources
 func EntryHandler(c echo.Context) error {
-	u, err := getBasicAuthUserFromContext(c)
+	a, err := getBasicAuthFromContext(c)
 	if err != nil {
 		log.Error(err)
 		return echo.ErrInternalServerError
 	}
 
 	storage := &VikunjaCaldavListStorage{
-		user:    u,
+		auth:    a,
 		isEntry: true,
 	}
 
@@ -164,10 +274,19 @@ func EntryHandler(c echo.Context) error {
 	log.Debugf("[CALDAV] Request Headers: %v\n", c.Request().Header)
 
 	caldav.SetupStorage(storage)
-	caldav.SetupUser("dav/principals/" + u.Username)
+	caldav.SetupUser("dav/principals/" + getPrincipalName(a))
 	caldav.SetupSupportedComponents([]string{lib.VCALENDAR, lib.VTODO})
 
 	response := caldav.HandleRequest(c.Request())
+
+	// Only users have an address book
+	if _, isUser := a.(*user.User); isUser && c.Request().Method == "PROPFIND" && response.Status == http.StatusMultiStatus {
+		response.Body = addAddressBookHomeSetToPropfindResponse(response.Body)
+		if response.Header != nil && response.Header.Get("Content-Length") != "" {
+			response.Header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+		}
+	}
+
 	response.Write(c.Response())
 	return nil
 }
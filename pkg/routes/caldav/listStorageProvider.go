@@ -21,13 +21,17 @@ import (
 	"strings"
 	"time"
 
+	"code.vikunja.io/api/pkg/caldav"
 	"code.vikunja.io/api/pkg/db"
 
 	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/models"
-	user2 "code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/api/pkg/user"
+	"code.vikunja.io/api/pkg/utils"
+	"code.vikunja.io/web"
 	"github.com/samedi/caldav-go/data"
 	"github.com/samedi/caldav-go/errs"
+	"xorm.io/xorm"
 )
 
 // DavBasePath is the base url path
@@ -42,8 +46,10 @@ type VikunjaCaldavListStorage struct {
 	list *models.List
 	// Used when handling a single task, like updating
 	task *models.Task
-	// The current user
-	user        *user2.User
+	// The current user or link share
+	auth web.Auth
+	// All caldav properties of the tasks in the list Vikunja does not know about, keyed by task id
+	properties  map[int64]string
 	isPrincipal bool
 	isEntry     bool // Entry level handling should only return a link to the principal url
 }
@@ -96,15 +102,26 @@ func (vcls *VikunjaCaldavListStorage) GetResources(rpath string, withChildren bo
 	defer s.Close()
 
 	// Otherwise get all lists
-	thelists, _, _, err := vcls.list.ReadAll(s, vcls.user, "", -1, 50)
+	thelists, _, _, err := vcls.list.ReadAll(s, vcls.auth, "", -1, 50)
 	if err != nil {
 		_ = s.Rollback()
 		return nil, err
 	}
+	lists := thelists.([]*models.List)
+
+	// Saved filters and the favorites are shown as their own collections so they can be used as smart lists
+	if u, is := vcls.auth.(*user.User); is {
+		pseudoLists, err := models.GetPseudoListsForUser(s, u)
+		if err != nil {
+			_ = s.Rollback()
+			return nil, err
+		}
+		lists = append(lists, pseudoLists...)
+	}
+
 	if err := s.Commit(); err != nil {
 		return nil, err
 	}
-	lists := thelists.([]*models.List)
 
 	var resources []data.Resource
 	for _, l := range lists {
@@ -144,6 +161,23 @@ func (vcls *VikunjaCaldavListStorage) GetResourcesByList(rpaths []string) ([]dat
 		_ = s.Rollback()
 		return nil, err
 	}
+
+	tasks, err = vcls.filterVisibleTasks(s, tasks)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
+	taskIDs := make([]int64, 0, len(tasks))
+	for _, t := range tasks {
+		taskIDs = append(taskIDs, t.ID)
+	}
+	properties, err := models.GetCaldavPropertiesForTasks(s, taskIDs)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
 	if err := s.Commit(); err != nil {
 		return nil, err
 	}
@@ -151,9 +185,10 @@ func (vcls *VikunjaCaldavListStorage) GetResourcesByList(rpaths []string) ([]dat
 	var resources []data.Resource
 	for _, t := range tasks {
 		rr := VikunjaListResourceAdapter{
-			task: t,
+			task:       t,
+			properties: properties,
 		}
-		r := data.NewResource(getTaskURL(t), &rr)
+		r := data.NewResource(vcls.getTaskURL(t), &rr)
 		r.Name = t.Title
 		resources = append(resources, r)
 	}
@@ -173,9 +208,10 @@ func (vcls *VikunjaCaldavListStorage) GetResourcesByFilters(rpath string, filter
 			rr := VikunjaListResourceAdapter{
 				list:         vcls.list,
 				task:         t,
+				properties:   vcls.properties,
 				isCollection: false,
 			}
-			r := data.NewResource(getTaskURL(t), &rr)
+			r := data.NewResource(vcls.getTaskURL(t), &rr)
 			r.Name = t.Title
 			resources = append(resources, r)
 		}
@@ -198,6 +234,34 @@ func getTaskURL(task *models.Task) string {
 	return ListBasePath + "/" + strconv.FormatInt(task.ListID, 10) + `/` + task.UID + `.ics`
 }
 
+// getTaskURL returns the url of a task in the requested collection. Tasks of saved filters and the favorites
+// live in other lists, but clients expect them below the collection they asked for.
+func (vcls *VikunjaCaldavListStorage) getTaskURL(task *models.Task) string {
+	if vcls.list != nil && vcls.list.ID < 0 {
+		return ListBasePath + "/" + strconv.FormatInt(vcls.list.ID, 10) + `/` + task.UID + `.ics`
+	}
+	return getTaskURL(task)
+}
+
+// filterVisibleTasks removes all tasks the current user or link share is not allowed to see.
+// Because clients can ask for any uid, tasks of other lists are removed as well when handling a normal list.
+func (vcls *VikunjaCaldavListStorage) filterVisibleTasks(s *xorm.Session, tasks []*models.Task) (visible []*models.Task, err error) {
+	visible = make([]*models.Task, 0, len(tasks))
+	for _, t := range tasks {
+		if vcls.list != nil && vcls.list.ID > 0 && t.ListID != vcls.list.ID {
+			continue
+		}
+		can, _, err := t.CanRead(s, vcls.auth)
+		if err != nil {
+			return nil, err
+		}
+		if can {
+			visible = append(visible, t)
+		}
+	}
+	return
+}
+
 // GetResource fetches a single resource
 func (vcls *VikunjaCaldavListStorage) GetResource(rpath string) (*data.Resource, bool, error) {
 
@@ -206,8 +270,6 @@ func (vcls *VikunjaCaldavListStorage) GetResource(rpath string) (*data.Resource,
 		s := db.NewSession()
 		defer s.Close()
 
-		// save and override the updated unix date to not break any later etag checks
-		updated := vcls.task.Updated
 		task, err := models.GetTaskSimple(s, &models.Task{ID: vcls.task.ID, UID: vcls.task.UID})
 		if err != nil {
 			_ = s.Rollback()
@@ -216,19 +278,27 @@ func (vcls *VikunjaCaldavListStorage) GetResource(rpath string) (*data.Resource,
 			}
 			return nil, false, err
 		}
-		if err := s.Commit(); err != nil {
+
+		can, _, err := task.CanRead(s, vcls.auth)
+		if err != nil {
+			_ = s.Rollback()
 			return nil, false, err
 		}
+		if !can {
+			_ = s.Rollback()
+			return nil, false, errs.ForbiddenError
+		}
 
-		vcls.task = &task
-		if updated.Unix() > 0 {
-			vcls.task.Updated = updated
+		rr, err := vcls.getTaskResource(s, &task)
+		if err != nil {
+			_ = s.Rollback()
+			return nil, false, err
 		}
 
-		rr := VikunjaListResourceAdapter{
-			list: vcls.list,
-			task: &task,
+		if err := s.Commit(); err != nil {
+			return nil, false, err
 		}
+
 		r := data.NewResource(rpath, &rr)
 		return &r, true, nil
 	}
@@ -253,18 +323,24 @@ func (vcls *VikunjaCaldavListStorage) GetShallowResource(rpath string) (*data.Re
 // CreateResource creates a new resource
 func (vcls *VikunjaCaldavListStorage) CreateResource(rpath, content string) (*data.Resource, error) {
 
+	// Saved filters and the favorites have no tasks of their own, so there is no list to create a new task in
+	if vcls.list.ID < 0 {
+		return nil, errs.ForbiddenError
+	}
+
 	s := db.NewSession()
 	defer s.Close()
 
-	vTask, err := parseTaskFromVTODO(content)
+	ct, err := caldav.ParseTaskFromVTODO(content)
 	if err != nil {
 		return nil, err
 	}
 
+	vTask := ct.Task
 	vTask.ListID = vcls.list.ID
 
 	// Check the rights
-	canCreate, err := vTask.CanCreate(s, vcls.user)
+	canCreate, err := vTask.CanCreate(s, vcls.auth)
 	if err != nil {
 		return nil, err
 	}
@@ -273,21 +349,29 @@ func (vcls *VikunjaCaldavListStorage) CreateResource(rpath, content string) (*da
 	}
 
 	// Create the task
-	err = vTask.Create(s, vcls.user)
+	err = vTask.Create(s, vcls.auth)
 	if err != nil {
 		_ = s.Rollback()
 		return nil, err
 	}
 
-	if err := s.Commit(); err != nil {
+	err = vcls.syncTaskDetails(s, ct)
+	if err != nil {
+		_ = s.Rollback()
 		return nil, err
 	}
 
 	// Build up the proper response
-	rr := VikunjaListResourceAdapter{
-		list: vcls.list,
-		task: vTask,
+	rr, err := vcls.getTaskResource(s, vTask)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
+	}
+
+	if err := s.Commit(); err != nil {
+		return nil, err
 	}
+
 	r := data.NewResource(rpath, &rr)
 	return &r, nil
 }
@@ -295,11 +379,13 @@ func (vcls *VikunjaCaldavListStorage) CreateResource(rpath, content string) (*da
 // UpdateResource updates a resource
 func (vcls *VikunjaCaldavListStorage) UpdateResource(rpath, content string) (*data.Resource, error) {
 
-	vTask, err := parseTaskFromVTODO(content)
+	ct, err := caldav.ParseTaskFromVTODO(content)
 	if err != nil {
 		return nil, err
 	}
 
+	vTask := ct.Task
+
 	// At this point, we already have the right task in vcls.task, so we can use that ID directly
 	vTask.ID = vcls.task.ID
 
@@ -307,7 +393,7 @@ func (vcls *VikunjaCaldavListStorage) UpdateResource(rpath, content string) (*da
 	defer s.Close()
 
 	// Check the rights
-	canUpdate, err := vTask.CanUpdate(s, vcls.user)
+	canUpdate, err := vTask.CanUpdate(s, vcls.auth)
 	if err != nil {
 		_ = s.Rollback()
 		return nil, err
@@ -318,25 +404,80 @@ func (vcls *VikunjaCaldavListStorage) UpdateResource(rpath, content string) (*da
 	}
 
 	// Update the task
-	err = vTask.Update(s, vcls.user)
+	err = vTask.Update(s, vcls.auth)
 	if err != nil {
 		_ = s.Rollback()
 		return nil, err
 	}
 
-	if err := s.Commit(); err != nil {
+	err = vcls.syncTaskDetails(s, ct)
+	if err != nil {
+		_ = s.Rollback()
 		return nil, err
 	}
 
 	// Build up the proper response
-	rr := VikunjaListResourceAdapter{
-		list: vcls.list,
-		task: vTask,
+	rr, err := vcls.getTaskResource(s, vTask)
+	if err != nil {
+		_ = s.Rollback()
+		return nil, err
 	}
+
+	if err := s.Commit(); err != nil {
+		return nil, err
+	}
+
 	r := data.NewResource(rpath, &rr)
 	return &r, nil
 }
 
+// getTaskResource gets a task with all of its labels, reminders, relations and caldav properties, exactly like
+// it is sent to clients. This makes sure the etag of a task is the same, no matter how the client got it.
+func (vcls *VikunjaCaldavListStorage) getTaskResource(s *xorm.Session, task *models.Task) (rr VikunjaListResourceAdapter, err error) {
+	tasks, err := models.GetTasksByUIDs(s, []string{task.UID})
+	if err != nil {
+		return
+	}
+	for _, t := range tasks {
+		if t.ID == task.ID {
+			task = t
+		}
+	}
+
+	vcls.properties, err = models.GetCaldavPropertiesForTasks(s, []int64{task.ID})
+	if err != nil {
+		return
+	}
+
+	vcls.task = task
+	rr = VikunjaListResourceAdapter{
+		list:       vcls.list,
+		task:       task,
+		properties: vcls.properties,
+	}
+	return
+}
+
+// syncTaskDetails saves everything from a VTODO which is not a plain field of the task itself
+func (vcls *VikunjaCaldavListStorage) syncTaskDetails(s *xorm.Session, ct *caldav.ParsedTask) (err error) {
+	// Link shares can't create or see labels
+	if _, is := vcls.auth.(*models.LinkSharing); !is {
+		err = ct.Task.UpdateLabelsByTitle(s, vcls.auth, ct.Labels)
+		if err != nil {
+			return err
+		}
+	}
+
+	unresolved, err := ct.Task.SyncCaldavRelations(s, vcls.auth, ct.Related)
+	if err != nil {
+		return err
+	}
+
+	// Relations to tasks we don't know (yet) are kept so they are not lost
+	ct.Properties = append(ct.Properties, caldav.UnresolvedRelationProperties(unresolved)...)
+	return ct.Task.SetCaldavProperties(s, strings.Join(ct.Properties, "\n"))
+}
+
 // DeleteResource deletes a resource
 func (vcls *VikunjaCaldavListStorage) DeleteResource(rpath string) error {
 	if vcls.task != nil {
@@ -344,7 +485,7 @@ func (vcls *VikunjaCaldavListStorage) DeleteResource(rpath string) error {
 		defer s.Close()
 
 		// Check the rights
-		canDelete, err := vcls.task.CanDelete(s, vcls.user)
+		canDelete, err := vcls.task.CanDelete(s, vcls.auth)
 		if err != nil {
 			_ = s.Rollback()
 			return err
@@ -354,7 +495,7 @@ func (vcls *VikunjaCaldavListStorage) DeleteResource(rpath string) error {
 		}
 
 		// Delete it
-		err = vcls.task.Delete(s, vcls.user)
+		err = vcls.task.Delete(s, vcls.auth)
 		if err != nil {
 			_ = s.Rollback()
 			return err
@@ -371,6 +512,10 @@ type VikunjaListResourceAdapter struct {
 	list      *models.List
 	listTasks []*models.Task
 	task      *models.Task
+	// The unknown caldav properties of all tasks in this resource, keyed by task id
+	properties map[int64]string
+	// The id of the latest change of a task in the list
+	syncToken int64
 
 	isPrincipal  bool
 	isCollection bool
@@ -385,38 +530,40 @@ func (vlra *VikunjaListResourceAdapter) IsCollection() bool {
 // CalculateEtag returns the etag of a resource
 func (vlra *VikunjaListResourceAdapter) CalculateEtag() string {
 
-	// If we're updating a task, the client sends the etag of the list instead of the one from the task.
-	// And therefore, updating the task fails since these etags don't match.
-	// To fix that, we use this extra field to determine if we're currently updating a task and return the
-	// etag of the list instead.
-	// if vlra.list != nil {
-	//	 return `"` + strconv.FormatInt(vlra.list.ID, 10) + `-` + strconv.FormatInt(vlra.list.Updated, 10) + `"`
-	// }
-
-	// Return the etag of a task if we have one
+	// The etag of a task is derived from its content. That way it changes with everything a client can see,
+	// including labels, reminders and relations which don't change the task's updated timestamp.
 	if vlra.task != nil {
-		return `"` + strconv.FormatInt(vlra.task.ID, 10) + `-` + strconv.FormatInt(vlra.task.Updated.Unix(), 10) + `"`
+		return `"` + utils.Sha256(vlra.GetContent()) + `"`
 	}
 
 	if vlra.list == nil {
 		return ""
 	}
 
-	// This also returns the etag of the list, and not of the task,
-	// which becomes problematic because the client uses this etag (= the one from the list) to make
-	// Requests to update a task. These do not match and thus updating a task fails.
+	// The etag of a collection is also used as its ctag. Because every change of a task in a list
+	// creates a new change log entry, the latest entry is a cheap way to tell if anything changed.
+	if vlra.syncToken > 0 {
+		return `"` + strconv.FormatInt(vlra.list.ID, 10) + `-` + strconv.FormatInt(vlra.syncToken, 10) + `"`
+	}
+
+	// Saved filters and the favorites don't have a change log since their tasks come from many lists.
+	// Their content changes whenever one of the tasks in them changes.
+	if vlra.list.ID < 0 && vlra.list.Tasks != nil {
+		return `"` + strconv.FormatInt(vlra.list.ID, 10) + `-` + utils.Sha256(vlra.GetContent()) + `"`
+	}
+
 	return `"` + strconv.FormatInt(vlra.list.ID, 10) + `-` + strconv.FormatInt(vlra.list.Updated.Unix(), 10) + `"`
 }
 
 // GetContent returns the content string of a resource (a task in our case)
 func (vlra *VikunjaListResourceAdapter) GetContent() string {
-	if vlra.list != nil && vlra.list.Tasks != nil {
-		return getCaldavTodosForTasks(vlra.list, vlra.listTasks)
-	}
-
 	if vlra.task != nil {
 		list := models.List{Tasks: []*models.Task{vlra.task}}
-		return getCaldavTodosForTasks(&list, list.Tasks)
+		return getCaldavTodosForTasks(&list, list.Tasks, vlra.properties)
+	}
+
+	if vlra.list != nil && vlra.list.Tasks != nil {
+		return getCaldavTodosForTasks(vlra.list, vlra.listTasks, vlra.properties)
 	}
 
 	return ""
@@ -448,17 +595,17 @@ func (vcls *VikunjaCaldavListStorage) getListRessource(isCollection bool) (rr Vi
 		return
 	}
 
-	can, _, err := vcls.list.CanRead(s, vcls.user)
+	can, _, err := vcls.list.CanRead(s, vcls.auth)
 	if err != nil {
 		_ = s.Rollback()
 		return
 	}
 	if !can {
 		_ = s.Rollback()
-		log.Errorf("User %v tried to access a caldav resource (List %v) which they are not allowed to access", vcls.user.Username, vcls.list.ID)
+		log.Errorf("User or link share %v tried to access a caldav resource (List %v) which they are not allowed to access", vcls.auth.GetID(), vcls.list.ID)
 		return rr, models.ErrUserDoesNotHaveAccessToList{ListID: vcls.list.ID}
 	}
-	err = vcls.list.ReadOne(s, vcls.user)
+	err = vcls.list.ReadOne(s, vcls.auth)
 	if err != nil {
 		_ = s.Rollback()
 		return
@@ -469,7 +616,7 @@ func (vcls *VikunjaCaldavListStorage) getListRessource(isCollection bool) (rr Vi
 		tk := models.TaskCollection{
 			ListID: vcls.list.ID,
 		}
-		iface, _, _, err := tk.ReadAll(s, vcls.user, "", 1, 1000)
+		iface, _, _, err := tk.ReadAll(s, vcls.auth, "", 1, 1000)
 		if err != nil {
 			_ = s.Rollback()
 			return rr, err
@@ -483,6 +630,22 @@ func (vcls *VikunjaCaldavListStorage) getListRessource(isCollection bool) (rr Vi
 		vcls.list.Tasks = tasks
 	}
 
+	taskIDs := make([]int64, 0, len(listTasks))
+	for _, t := range listTasks {
+		taskIDs = append(taskIDs, t.ID)
+	}
+	vcls.properties, err = models.GetCaldavPropertiesForTasks(s, taskIDs)
+	if err != nil {
+		_ = s.Rollback()
+		return rr, err
+	}
+
+	syncToken, err := models.GetLatestTaskChangeID(s, vcls.list.ID)
+	if err != nil {
+		_ = s.Rollback()
+		return rr, err
+	}
+
 	if err := s.Commit(); err != nil {
 		return rr, err
 	}
@@ -490,6 +653,8 @@ func (vcls *VikunjaCaldavListStorage) getListRessource(isCollection bool) (rr Vi
 	rr = VikunjaListResourceAdapter{
 		list:         vcls.list,
 		listTasks:    listTasks,
+		properties:   vcls.properties,
+		syncToken:    syncToken,
 		isCollection: isCollection,
 	}
 
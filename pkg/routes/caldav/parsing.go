@@ -17,17 +17,14 @@
 package caldav
 
 import (
-	"strconv"
+	"math"
 	"strings"
-	"time"
 
 	"code.vikunja.io/api/pkg/caldav"
-	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/models"
-	"github.com/laurent22/ical-go"
 )
 
-func getCaldavTodosForTasks(list *models.List, listTasks []*models.Task) string {
+func getCaldavTodosForTasks(list *models.List, listTasks []*models.Task, properties map[int64]string) string {
 
 	// Make caldav todos from Vikunja todos
 	var caldavtodos []*caldav.Todo
@@ -35,6 +32,26 @@ func getCaldavTodosForTasks(list *models.List, listTasks []*models.Task) string
 
 		duration := t.EndDate.Sub(t.StartDate)
 
+		var categories []string
+		for _, l := range t.Labels {
+			categories = append(categories, l.Title)
+		}
+
+		var alarms []caldav.Alarm
+		for _, r := range t.Reminders {
+			alarms = append(alarms, caldav.Alarm{Time: r})
+		}
+
+		var relations []caldav.Relation
+		for _, kind := range []models.RelationKind{models.RelationKindParenttask, models.RelationKindSubtask, models.RelationKindRelated} {
+			for _, rt := range t.RelatedTasks[kind] {
+				if rt == nil || rt.UID == "" {
+					continue
+				}
+				relations = append(relations, caldav.Relation{Type: caldav.RelationTypes[kind], UID: rt.UID})
+			}
+		}
+
 		caldavtodos = append(caldavtodos, &caldav.Todo{
 			Timestamp:   t.Updated,
 			UID:         t.UID,
@@ -42,13 +59,19 @@ func getCaldavTodosForTasks(list *models.List, listTasks []*models.Task) string
 			Description: t.Description,
 			Completed:   t.DoneAt,
 			// Organizer:     &t.CreatedBy, // Disabled until we figure out how this works
-			Priority: t.Priority,
-			Start:    t.StartDate,
-			End:      t.EndDate,
-			Created:  t.Created,
-			Updated:  t.Updated,
-			DueDate:  t.DueDate,
-			Duration: duration,
+			Priority:        t.Priority,
+			Start:           t.StartDate,
+			End:             t.EndDate,
+			Created:         t.Created,
+			Updated:         t.Updated,
+			DueDate:         t.DueDate,
+			Duration:        duration,
+			Color:           t.HexColor,
+			Categories:      categories,
+			Alarms:          alarms,
+			Relations:       relations,
+			PercentComplete: int64(math.Round(t.PercentDone * 100)),
+			Properties:      withoutResolvedRelations(properties[t.ID], relations),
 		})
 	}
 
@@ -60,74 +83,28 @@ func getCaldavTodosForTasks(list *models.List, listTasks []*models.Task) string
 	return caldav.ParseTodos(caldavConfig, caldavtodos)
 }
 
-func parseTaskFromVTODO(content string) (vTask *models.Task, err error) {
-	parsed, err := ical.ParseCalendar(content)
-	if err != nil {
-		return nil, err
+// Relations to tasks which did not exist when a task was synced are kept with the other unknown properties.
+// Once the other task exists, the relation is returned like any other relation and must not show up twice.
+func withoutResolvedRelations(properties string, relations []caldav.Relation) string {
+	if properties == "" || len(relations) == 0 {
+		return properties
 	}
 
-	// We put the task details in a map to be able to handle them more easily
-	task := make(map[string]string)
-	for _, c := range parsed.Children {
-		if c.Name == "VTODO" {
-			for _, entry := range c.Children {
-				task[entry.Name] = entry.Value
-			}
-			// Breaking, to only process the first task
-			break
-		}
+	resolved := make(map[string]bool, len(relations))
+	for _, r := range relations {
+		resolved[r.UID] = true
 	}
 
-	// Parse the UID
-	var priority int64
-	if _, ok := task["PRIORITY"]; ok {
-		priority, err = strconv.ParseInt(task["PRIORITY"], 10, 64)
-		if err != nil {
-			return nil, err
+	var lines []string
+	for _, line := range strings.Split(properties, "\n") {
+		if strings.HasPrefix(line, "RELATED-TO") {
+			uid := line[strings.LastIndex(line, ":")+1:]
+			if resolved[uid] {
+				continue
+			}
 		}
+		lines = append(lines, line)
 	}
 
-	// Parse the enddate
-	duration, _ := time.ParseDuration(task["DURATION"])
-
-	vTask = &models.Task{
-		UID:         task["UID"],
-		Title:       task["SUMMARY"],
-		Description: task["DESCRIPTION"],
-		Priority:    priority,
-		DueDate:     caldavTimeToTimestamp(task["DUE"]),
-		Updated:     caldavTimeToTimestamp(task["DTSTAMP"]),
-		StartDate:   caldavTimeToTimestamp(task["DTSTART"]),
-		DoneAt:      caldavTimeToTimestamp(task["COMPLETED"]),
-	}
-
-	if task["STATUS"] == "COMPLETED" {
-		vTask.Done = true
-	}
-
-	if duration > 0 && !vTask.StartDate.IsZero() {
-		vTask.EndDate = vTask.StartDate.Add(duration)
-	}
-
-	return
-}
-
-// https://tools.ietf.org/html/rfc5545#section-3.3.5
-func caldavTimeToTimestamp(tstring string) time.Time {
-	if tstring == "" {
-		return time.Time{}
-	}
-
-	format := caldav.DateFormat
-
-	if strings.HasSuffix(tstring, "Z") {
-		format = `20060102T150405Z`
-	}
-
-	t, err := time.Parse(format, tstring)
-	if err != nil {
-		log.Warningf("Error while parsing caldav time %s to TimeStamp: %s", tstring, err)
-		return time.Time{}
-	}
-	return t
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,340 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/models"
+	"github.com/labstack/echo/v4"
+	"github.com/samedi/caldav-go/errs"
+)
+
+// All sync tokens are uris with this prefix, followed by the id of the latest change in the list.
+const syncTokenPrefix = `https://vikunja.io/ns/sync/`
+
+const (
+	davNamespace    = `DAV:`
+	caldavNamespace = `urn:ietf:params:xml:ns:caldav`
+)
+
+// https://tools.ietf.org/html/rfc6578#section-6.1
+type syncCollectionRequest struct {
+	XMLName   xml.Name `xml:"DAV: sync-collection"`
+	SyncToken string   `xml:"DAV: sync-token"`
+	// Lists don't have nested collections, so the sync level does not make any difference
+	SyncLevel string `xml:"DAV: sync-level"`
+	Prop      struct {
+		Properties []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"DAV: prop"`
+}
+
+func makeSyncToken(changeID int64) string {
+	return syncTokenPrefix + strconv.FormatInt(changeID, 10)
+}
+
+func parseSyncToken(token string) (changeID int64, valid bool) {
+	token = strings.TrimSpace(token)
+	if !strings.HasPrefix(token, syncTokenPrefix) {
+		return 0, false
+	}
+
+	changeID, err := strconv.ParseInt(strings.TrimPrefix(token, syncTokenPrefix), 10, 64)
+	if err != nil || changeID < 0 {
+		return 0, false
+	}
+	return changeID, true
+}
+
+func isSyncCollectionRequest(body []byte) bool {
+	return bytes.Contains(body, []byte("sync-collection"))
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// getSyncToken returns the id of the latest change of a task in the list of the storage
+func (vcls *VikunjaCaldavListStorage) getSyncToken() (changeID int64, err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	changeID, err = models.GetLatestTaskChangeID(s, vcls.list.ID)
+	if err != nil {
+		_ = s.Rollback()
+		return
+	}
+
+	return changeID, s.Commit()
+}
+
+// getChangedTaskResources returns all tasks which changed since the provided change along with the uids of all tasks
+// which were deleted or moved to another list since then.
+func (vcls *VikunjaCaldavListStorage) getChangedTaskResources(since int64) (resources []*VikunjaListResourceAdapter, deleted []string, latest int64, err error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	can, _, err := vcls.list.CanRead(s, vcls.auth)
+	if err != nil {
+		_ = s.Rollback()
+		return
+	}
+	if !can {
+		_ = s.Rollback()
+		return nil, nil, 0, errs.ForbiddenError
+	}
+
+	latest, err = models.GetLatestTaskChangeID(s, vcls.list.ID)
+	if err != nil {
+		_ = s.Rollback()
+		return
+	}
+
+	changes, err := models.GetTaskChangesSince(s, vcls.list.ID, since)
+	if err != nil {
+		_ = s.Rollback()
+		return
+	}
+
+	var uids []string
+	for _, change := range changes {
+		if change.Deleted {
+			deleted = append(deleted, change.TaskUID)
+			continue
+		}
+		uids = append(uids, change.TaskUID)
+	}
+
+	if len(uids) > 0 {
+		tasks, err := models.GetTasksByUIDs(s, uids)
+		if err != nil {
+			_ = s.Rollback()
+			return nil, nil, 0, err
+		}
+
+		taskIDs := make([]int64, 0, len(tasks))
+		for _, t := range tasks {
+			taskIDs = append(taskIDs, t.ID)
+		}
+		properties, err := models.GetCaldavPropertiesForTasks(s, taskIDs)
+		if err != nil {
+			_ = s.Rollback()
+			return nil, nil, 0, err
+		}
+
+		for _, t := range tasks {
+			// Uids are only unique per list
+			if t.ListID != vcls.list.ID {
+				continue
+			}
+			resources = append(resources, &VikunjaListResourceAdapter{
+				list:       vcls.list,
+				task:       t,
+				properties: properties,
+			})
+		}
+	}
+
+	return resources, deleted, latest, s.Commit()
+}
+
+// handleSyncCollection answers a sync-collection report as defined in https://tools.ietf.org/html/rfc6578.
+// Clients send the sync token they got on their last sync and only get the tasks which changed since then.
+// Deleted tasks are reported with a 404 status.
+func handleSyncCollection(c echo.Context, storage *VikunjaCaldavListStorage, body []byte) error {
+	req := &syncCollectionRequest{}
+	if err := xml.Unmarshal(body, req); err != nil {
+		log.Debugf("[CALDAV] Invalid sync-collection request: %s", err)
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid sync-collection request")
+	}
+
+	var resources []*VikunjaListResourceAdapter
+	var deleted []string
+	var latest int64
+
+	if req.SyncToken == "" {
+		// Initial sync, the client gets everything
+		rr, err := storage.getListRessource(false)
+		if err != nil {
+			return handleCaldavError(c, err)
+		}
+		for _, t := range rr.listTasks {
+			resources = append(resources, &VikunjaListResourceAdapter{
+				list:       storage.list,
+				task:       t,
+				properties: rr.properties,
+			})
+		}
+		latest = rr.syncToken
+	} else {
+		since, valid := parseSyncToken(req.SyncToken)
+		if !valid {
+			return invalidSyncToken(c)
+		}
+
+		var err error
+		resources, deleted, latest, err = storage.getChangedTaskResources(since)
+		if err != nil {
+			return handleCaldavError(c, err)
+		}
+
+		// A token from the future was not issued by us
+		if since > latest {
+			return invalidSyncToken(c)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>
+<D:multistatus xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">`)
+
+	for _, r := range resources {
+		var found, notFound strings.Builder
+		for _, p := range req.Prop.Properties {
+			switch {
+			case p.XMLName.Space == davNamespace && p.XMLName.Local == "getetag":
+				found.WriteString(`<D:getetag>` + xmlEscape(r.CalculateEtag()) + `</D:getetag>`)
+			case p.XMLName.Space == davNamespace && p.XMLName.Local == "getcontenttype":
+				found.WriteString(`<D:getcontenttype>text/calendar; charset=utf-8; component=vtodo</D:getcontenttype>`)
+			case p.XMLName.Space == caldavNamespace && p.XMLName.Local == "calendar-data":
+				found.WriteString(`<C:calendar-data>` + xmlEscape(r.GetContent()) + `</C:calendar-data>`)
+			default:
+				notFound.WriteString(`<` + p.XMLName.Local + ` xmlns="` + xmlEscape(p.XMLName.Space) + `"/>`)
+			}
+		}
+
+		b.WriteString(`<D:response><D:href>` + xmlEscape(getTaskURL(r.task)) + `</D:href>`)
+		b.WriteString(`<D:propstat><D:prop>` + found.String() + `</D:prop><D:status>HTTP/1.1 200 OK</D:status></D:propstat>`)
+		if notFound.Len() > 0 {
+			b.WriteString(`<D:propstat><D:prop>` + notFound.String() + `</D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`)
+		}
+		b.WriteString(`</D:response>`)
+	}
+
+	for _, uid := range deleted {
+		href := getTaskURL(&models.Task{ListID: storage.list.ID, UID: uid})
+		b.WriteString(`<D:response><D:href>` + xmlEscape(href) + `</D:href><D:status>HTTP/1.1 404 Not Found</D:status></D:response>`)
+	}
+
+	b.WriteString(`<D:sync-token>` + makeSyncToken(latest) + `</D:sync-token></D:multistatus>`)
+
+	return c.Blob(http.StatusMultiStatus, "application/xml; charset=utf-8", []byte(b.String()))
+}
+
+func invalidSyncToken(c echo.Context) error {
+	return c.Blob(http.StatusForbidden, "application/xml; charset=utf-8", []byte(`<?xml version="1.0" encoding="utf-8"?>
+<D:error xmlns:D="DAV:"><D:valid-sync-token/></D:error>`))
+}
+
+func handleCaldavError(c echo.Context, err error) error {
+	if err == errs.ForbiddenError || models.IsErrUserDoesNotHaveAccessToList(err) {
+		return c.NoContent(http.StatusForbidden)
+	}
+	if models.IsErrListDoesNotExist(err) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	log.Error(err)
+	return echo.ErrInternalServerError
+}
+
+// caldav-go does not know about sync tokens, we therefore add them (and announce that we support the
+// sync-collection report) to the first response of a propfind request, which is the list itself.
+var (
+	emptySyncTokenPropertyRegex          = regexp.MustCompile(`<(?:[A-Za-z0-9]+:)?sync-token(?:\s[^>]*)?/>`)
+	emptySupportedReportSetPropertyRegex = regexp.MustCompile(`<(?:[A-Za-z0-9]+:)?supported-report-set(?:\s[^>]*)?/>`)
+	responseEndRegex                     = regexp.MustCompile(`</(?:[A-Za-z0-9]+:)?response>`)
+)
+
+func addSyncPropertiesToPropfindResponse(body string, syncToken int64) string {
+	var props string
+	if emptySyncTokenPropertyRegex.MatchString(body) {
+		body = emptySyncTokenPropertyRegex.ReplaceAllString(body, "")
+		props += `<sync-token>` + makeSyncToken(syncToken) + `</sync-token>`
+	}
+	if emptySupportedReportSetPropertyRegex.MatchString(body) {
+		body = emptySupportedReportSetPropertyRegex.ReplaceAllString(body, "")
+		props += `<supported-report-set>` +
+			`<supported-report><report><C:calendar-query xmlns:C="urn:ietf:params:xml:ns:caldav"/></report></supported-report>` +
+			`<supported-report><report><C:calendar-multiget xmlns:C="urn:ietf:params:xml:ns:caldav"/></report></supported-report>` +
+			`<supported-report><report><sync-collection/></report></supported-report>` +
+			`</supported-report-set>`
+	}
+	if props == "" {
+		return body
+	}
+
+	end := responseEndRegex.FindStringIndex(body)
+	if end == nil {
+		return body
+	}
+
+	propstat := `<propstat xmlns="DAV:"><prop>` + props + `</prop><status>HTTP/1.1 200 OK</status></propstat>`
+	return body[:end[0]] + propstat + body[end[0]:]
+}
+
+// checkTaskPreconditions checks the If-Match and If-None-Match headers of a request to a task.
+// This prevents clients from overwriting changes they did not know about.
+func checkTaskPreconditions(c echo.Context, storage *VikunjaCaldavListStorage) (ok bool, err error) {
+	ifMatch := c.Request().Header.Get("If-Match")
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return true, nil
+	}
+
+	var etag string
+	_, found, err := storage.GetResource(c.Request().URL.Path)
+	if err != nil && err != errs.ResourceNotFoundError {
+		return false, err
+	}
+	if found {
+		etag = (&VikunjaListResourceAdapter{
+			list:       storage.list,
+			task:       storage.task,
+			properties: storage.properties,
+		}).CalculateEtag()
+	}
+
+	if ifNoneMatch != "" && found && (ifNoneMatch == "*" || etagMatches(ifNoneMatch, etag)) {
+		return false, nil
+	}
+
+	if ifMatch != "" && (!found || (ifMatch != "*" && !etagMatches(ifMatch, etag))) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func etagMatches(header, etag string) bool {
+	for _, e := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(e), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
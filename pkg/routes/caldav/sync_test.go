@@ -0,0 +1,57 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package caldav
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSyncToken(t *testing.T) {
+	id, valid := parseSyncToken(makeSyncToken(42))
+	assert.True(t, valid)
+	assert.Equal(t, int64(42), id)
+
+	_, valid = parseSyncToken("http://sabre.io/ns/sync/42")
+	assert.False(t, valid)
+
+	_, valid = parseSyncToken(syncTokenPrefix + "foo")
+	assert.False(t, valid)
+}
+
+func TestAddSyncPropertiesToPropfindResponse(t *testing.T) {
+	t.Run("missing properties", func(t *testing.T) {
+		body := addSyncPropertiesToPropfindResponse(`<D:multistatus xmlns:D="DAV:"><D:response><D:href>/dav/lists/1</D:href>`+
+			`<D:propstat><D:prop><D:sync-token/></D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`+
+			`</D:response></D:multistatus>`, 12)
+		assert.Equal(t, `<D:multistatus xmlns:D="DAV:"><D:response><D:href>/dav/lists/1</D:href>`+
+			`<D:propstat><D:prop></D:prop><D:status>HTTP/1.1 404 Not Found</D:status></D:propstat>`+
+			`<propstat xmlns="DAV:"><prop><sync-token>`+syncTokenPrefix+`12</sync-token></prop><status>HTTP/1.1 200 OK</status></propstat>`+
+			`</D:response></D:multistatus>`, body)
+	})
+	t.Run("not requested", func(t *testing.T) {
+		body := `<D:multistatus xmlns:D="DAV:"><D:response><D:href>/dav/lists/1</D:href></D:response></D:multistatus>`
+		assert.Equal(t, body, addSyncPropertiesToPropfindResponse(body, 12))
+	})
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"abc"`, `"abc"`))
+	assert.True(t, etagMatches(`"def", W/"abc"`, `"abc"`))
+	assert.False(t, etagMatches(`"def"`, `"abc"`))
+}
@@ -55,17 +55,25 @@ import (
 	"code.vikunja.io/api/pkg/log"
 	"code.vikunja.io/api/pkg/models"
 	"code.vikunja.io/api/pkg/modules/auth"
+	"code.vikunja.io/api/pkg/modules/auth/oauth2server"
 	"code.vikunja.io/api/pkg/modules/auth/openid"
 	"code.vikunja.io/api/pkg/modules/background"
 	backgroundHandler "code.vikunja.io/api/pkg/modules/background/handler"
 	"code.vikunja.io/api/pkg/modules/background/unsplash"
 	"code.vikunja.io/api/pkg/modules/background/upload"
 	"code.vikunja.io/api/pkg/modules/migration"
+	"code.vikunja.io/api/pkg/modules/migration/asana"
 	migrationHandler "code.vikunja.io/api/pkg/modules/migration/handler"
+	"code.vikunja.io/api/pkg/modules/migration/ical"
+	"code.vikunja.io/api/pkg/modules/migration/jira"
 	microsofttodo "code.vikunja.io/api/pkg/modules/migration/microsoft-todo"
+	"code.vikunja.io/api/pkg/modules/migration/taskwarrior"
+	todotxt "code.vikunja.io/api/pkg/modules/migration/todo-txt"
 	"code.vikunja.io/api/pkg/modules/migration/todoist"
 	"code.vikunja.io/api/pkg/modules/migration/trello"
+	vikunjafile "code.vikunja.io/api/pkg/modules/migration/vikunja-file"
 	"code.vikunja.io/api/pkg/modules/migration/wunderlist"
+	"code.vikunja.io/api/pkg/modules/scim"
 	apiv1 "code.vikunja.io/api/pkg/routes/api/v1"
 	"code.vikunja.io/api/pkg/routes/caldav"
 	_ "code.vikunja.io/api/pkg/swagger" // To generate swagger docs
@@ -79,6 +87,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	elog "github.com/labstack/gommon/log"
+	"xorm.io/xorm"
 )
 
 // CustomValidator is a dummy struct to use govalidator with echo
@@ -188,10 +197,17 @@ func RegisterRoutes(e *echo.Echo) {
 		wkg.Use(middleware.BasicAuth(caldavBasicAuth))
 		wkg.Any("/caldav", caldav.PrincipalHandler)
 		wkg.Any("/caldav/", caldav.PrincipalHandler)
+		wkg.Any("/carddav", caldav.PrincipalHandler)
+		wkg.Any("/carddav/", caldav.PrincipalHandler)
 		c := e.Group("/dav")
 		registerCalDavRoutes(c)
 	}
 
+	if config.SCIMEnabled.GetBool() {
+		sc := e.Group("/scim/v2")
+		registerSCIMRoutes(sc)
+	}
+
 	// CORS_SHIT
 	if config.CorsEnable.GetBool() {
 		e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
@@ -233,12 +249,24 @@ func registerAPIRoutes(a *echo.Group) {
 		n.POST("/user/password/token", apiv1.UserRequestResetPasswordToken)
 		n.POST("/user/password/reset", apiv1.UserResetPassword)
 		n.POST("/user/confirm", apiv1.UserConfirmEmail)
+
+		if config.ServiceEnableWebAuthn.GetBool() {
+			n.POST("/login/webauthn/begin", apiv1.WebAuthnLoginBegin)
+			if config.ServiceEnableWebAuthnPasswordless.GetBool() {
+				n.POST("/login/webauthn", apiv1.WebAuthnLogin)
+			}
+		}
 	}
 
 	if config.AuthOpenIDEnabled.GetBool() {
 		n.POST("/auth/openid/:provider/callback", openid.HandleCallback)
 	}
 
+	if config.ServiceEnableOAuth2Server.GetBool() {
+		n.POST("/oauth2/token", oauth2server.Token)
+		n.POST("/oauth2/revoke", oauth2server.Revoke)
+	}
+
 	// Testing
 	if config.ServiceTestingtoken.GetString() != "" {
 		n.PATCH("/test/:table", apiv1.HandleTesting)
@@ -255,9 +283,16 @@ func registerAPIRoutes(a *echo.Group) {
 		n.POST("/shares/:share/auth", apiv1.AuthenticateLinkShare)
 	}
 
+	// iCal feeds, authenticated by the token in their url
+	if config.ServiceEnableCaldav.GetBool() {
+		n.GET("/ical/:token", caldav.ICalFeedHandler)
+	}
+
 	// ===== Routes with Authetication =====
 	// Authetification
 	a.Use(middleware.JWT([]byte(config.ServiceJWTSecret.GetString())))
+	// Access tokens of oauth2 clients are limited to what the user granted them
+	a.Use(oauth2server.CheckAccessToken)
 
 	// Rate limit
 	setupRateLimit(a, config.RateLimitKind.GetString())
@@ -286,7 +321,103 @@ func registerAPIRoutes(a *echo.Group) {
 		u.POST("/settings/totp/enable", apiv1.UserTOTPEnable)
 		u.POST("/settings/totp/disable", apiv1.UserTOTPDisable)
 		u.GET("/settings/totp/qrcode", apiv1.UserTOTPQrCode)
+		u.POST("/settings/totp/recovery-codes", apiv1.UserTOTPRegenerateRecoveryCodes)
+	}
+
+	u.GET("/export", apiv1.GetUserDataExportStatus)
+	u.POST("/export", apiv1.RequestUserDataExport)
+	u.GET("/export/download", apiv1.DownloadUserDataExport)
+
+	if config.ServiceEnableUserDeletion.GetBool() {
+		u.GET("/deletion", apiv1.UserDeletionStatusShow)
+		u.POST("/deletion/request", apiv1.UserRequestDeletion)
+		u.POST("/deletion/confirm", apiv1.UserConfirmDeletion)
+		u.POST("/deletion/cancel", apiv1.UserCancelDeletion)
+
+		userDeletionTransferHandler := &handler.WebHandler{
+			EmptyStruct: func() handler.CObject {
+				return &models.UserDeletionTransfer{}
+			},
+		}
+		u.GET("/deletion/transfers", userDeletionTransferHandler.ReadAllWeb)
+		u.PUT("/deletion/transfers", userDeletionTransferHandler.CreateWeb)
+		u.DELETE("/deletion/transfers/:transfer", userDeletionTransferHandler.DeleteWeb)
+	}
+
+	if config.ServiceEnableWebAuthn.GetBool() {
+		u.GET("/settings/webauthn", apiv1.UserWebAuthnCredentials)
+		u.PUT("/settings/webauthn", apiv1.UserWebAuthnRegisterFinish)
+		u.POST("/settings/webauthn/register", apiv1.UserWebAuthnRegisterBegin)
+		u.DELETE("/settings/webauthn/:credential", apiv1.UserWebAuthnDelete)
+	}
+
+	if config.ServiceEnableOAuth2Server.GetBool() {
+		a.GET("/oauth2/authorize", oauth2server.GetAuthorizationConsent)
+		a.POST("/oauth2/authorize", oauth2server.Authorize)
+
+		oauth2ClientHandler := &handler.WebHandler{
+			EmptyStruct: func() handler.CObject {
+				return &models.OAuth2Client{}
+			},
+		}
+		a.GET("/oauth2/clients", oauth2ClientHandler.ReadAllWeb)
+		a.GET("/oauth2/clients/:client", oauth2ClientHandler.ReadOneWeb)
+		a.PUT("/oauth2/clients", oauth2ClientHandler.CreateWeb)
+		a.POST("/oauth2/clients/:client", oauth2ClientHandler.UpdateWeb)
+		a.DELETE("/oauth2/clients/:client", oauth2ClientHandler.DeleteWeb)
+
+		oauth2GrantHandler := &handler.WebHandler{
+			EmptyStruct: func() handler.CObject {
+				return &models.OAuth2Grant{}
+			},
+		}
+		a.GET("/oauth2/grants", oauth2GrantHandler.ReadAllWeb)
+		a.DELETE("/oauth2/grants/:grant", oauth2GrantHandler.DeleteWeb)
+	}
+
+	if config.ServiceEnableCaldav.GetBool() {
+		iCalFeedHandler := &handler.WebHandler{
+			EmptyStruct: func() handler.CObject {
+				return &models.ICalFeed{}
+			},
+		}
+		a.GET("/ical-feeds", iCalFeedHandler.ReadAllWeb)
+		a.PUT("/ical-feeds", iCalFeedHandler.CreateWeb)
+		a.DELETE("/ical-feeds/:feed", iCalFeedHandler.DeleteWeb)
+	}
+
+	// Instance administration
+	ad := a.Group("/admin")
+	ad.Use(apiv1.CheckAdmin)
+
+	ad.GET("/stats", apiv1.AdminGetStats)
+
+	adminUserHandler := &handler.WebHandler{
+		EmptyStruct: func() handler.CObject {
+			return &models.AdminUser{}
+		},
+	}
+	ad.GET("/users", adminUserHandler.ReadAllWeb)
+	ad.PUT("/users", adminUserHandler.CreateWeb)
+	ad.POST("/users/:user/status", apiv1.AdminSetUserStatus)
+	ad.POST("/users/:user/password", apiv1.AdminResetUserPassword)
+	ad.DELETE("/users/:user/totp", apiv1.AdminResetUserTOTP)
+
+	adminNamespaceHandler := &handler.WebHandler{
+		EmptyStruct: func() handler.CObject {
+			return &models.AdminNamespace{}
+		},
 	}
+	ad.GET("/namespaces", adminNamespaceHandler.ReadAllWeb)
+	ad.POST("/namespaces/:namespace/owner", apiv1.AdminTransferNamespaceOwnership)
+
+	adminListHandler := &handler.WebHandler{
+		EmptyStruct: func() handler.CObject {
+			return &models.AdminList{}
+		},
+	}
+	ad.GET("/lists", adminListHandler.ReadAllWeb)
+	ad.POST("/lists/:list/owner", apiv1.AdminTransferListOwnership)
 
 	listHandler := &handler.WebHandler{
 		EmptyStruct: func() handler.CObject {
@@ -318,6 +449,9 @@ func registerAPIRoutes(a *echo.Group) {
 		},
 	}
 	a.GET("/lists/:list/tasks", taskCollectionHandler.ReadAllWeb)
+	a.GET("/lists/:list/tasks/csv", apiv1.ExportTasksCSV)
+	a.PUT("/lists/:list/tasks/csv", apiv1.ImportTasksCSV)
+	a.PUT("/lists/:list/tasks/csv/preview", apiv1.PreviewTasksCSVImport)
 
 	kanbanBucketHandler := &handler.WebHandler{
 		EmptyStruct: func() handler.CObject {
@@ -532,6 +666,7 @@ func registerAPIRoutes(a *echo.Group) {
 
 	// Migrations
 	m := a.Group("/migration")
+	m.GET("/jobs/:job", migrationHandler.JobStatus)
 
 	// Wunderlist
 	if config.MigrationWunderlistEnable.GetBool() {
@@ -573,6 +708,79 @@ func registerAPIRoutes(a *echo.Group) {
 		microsoftTodoMigrationHandler.RegisterRoutes(m)
 	}
 
+	// File migrators
+	if config.MigrationTrelloFileEnable.GetBool() {
+		trelloFileMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &trello.FileMigrator{}
+			},
+		}
+		trelloFileMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationTodoistFileEnable.GetBool() {
+		todoistFileMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &todoist.FileMigrator{}
+			},
+		}
+		todoistFileMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationTodoTxtEnable.GetBool() {
+		todoTxtMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &todotxt.Migration{}
+			},
+		}
+		todoTxtMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationTaskwarriorEnable.GetBool() {
+		taskwarriorMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &taskwarrior.Migration{}
+			},
+		}
+		taskwarriorMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationICalEnable.GetBool() {
+		icalMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &ical.Migration{}
+			},
+		}
+		icalMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationAsanaEnable.GetBool() {
+		asanaMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &asana.Migration{}
+			},
+		}
+		asanaMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationJiraEnable.GetBool() {
+		jiraMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &jira.Migration{}
+			},
+		}
+		jiraMigrationHandler.RegisterRoutes(m)
+	}
+
+	if config.MigrationVikunjaFileEnable.GetBool() {
+		vikunjaFileMigrationHandler := &migrationHandler.FileMigratorWeb{
+			MigrationStruct: func() migration.FileMigrator {
+				return &vikunjafile.FileMigrator{}
+			},
+		}
+		vikunjaFileMigrationHandler.RegisterRoutes(m)
+	}
+
 	// List Backgrounds
 	if config.BackgroundsEnabled.GetBool() {
 		a.GET("/lists/:list/background", backgroundHandler.GetListBackground)
@@ -612,15 +820,63 @@ func registerCalDavRoutes(c *echo.Group) {
 	c.Any("/lists/:list", caldav.ListHandler)
 	c.Any("/lists/:list/", caldav.ListHandler)
 	c.Any("/lists/:list/:task", caldav.TaskHandler) // Mostly used for editing
+	c.Any("/events", caldav.EventHandler)
+	c.Any("/events/", caldav.EventHandler)
+	c.Any("/events/:list", caldav.EventHandler)
+	c.Any("/events/:list/", caldav.EventHandler)
+	c.Any("/events/:list/:task", caldav.EventTaskHandler)
+	c.Any("/addressbooks", caldav.AddressBookHomeHandler)
+	c.Any("/addressbooks/", caldav.AddressBookHomeHandler)
+	c.Any("/addressbooks/contacts", caldav.AddressBookHandler)
+	c.Any("/addressbooks/contacts/", caldav.AddressBookHandler)
+	c.Any("/addressbooks/contacts/:contact", caldav.ContactHandler)
+}
+
+func registerSCIMRoutes(sc *echo.Group) {
+
+	// Identity providers authenticate with the token from the config
+	sc.Use(middleware.KeyAuth(scim.CheckToken))
+
+	sc.GET("/ServiceProviderConfig", scim.GetServiceProviderConfig)
+	sc.GET("/Users", scim.ListUsers)
+	sc.POST("/Users", scim.CreateUser)
+	sc.GET("/Users/:id", scim.GetUser)
+	sc.PUT("/Users/:id", scim.ReplaceUser)
+	sc.PATCH("/Users/:id", scim.PatchUser)
+	sc.DELETE("/Users/:id", scim.DeleteUser)
+	sc.GET("/Groups", scim.ListGroups)
+	sc.POST("/Groups", scim.CreateGroup)
+	sc.GET("/Groups/:id", scim.GetGroup)
+	sc.PUT("/Groups/:id", scim.ReplaceGroup)
+	sc.PATCH("/Groups/:id", scim.PatchGroup)
+	sc.DELETE("/Groups/:id", scim.DeleteGroup)
 }
 
 func caldavBasicAuth(username, password string, c echo.Context) (bool, error) {
+	s := db.NewSession()
+	defer s.Close()
+
+	// Link shares authenticate with their hash as username. They are checked first so that requests of
+	// link shares don't go through the much more expensive user login and don't count as failed logins.
+	share, err := caldavLinkShareAuth(s, username, password)
+	if err == nil {
+		if err := s.Commit(); err != nil {
+			return false, err
+		}
+
+		c.Set("userBasicAuth", share)
+		return true, nil
+	}
+	if !models.IsErrListShareDoesNotExist(err) {
+		_ = s.Rollback()
+		log.Debugf("Error during link share basic auth for caldav: %v", err)
+		return false, nil
+	}
+
 	creds := &user.Login{
 		Username: username,
 		Password: password,
 	}
-	s := db.NewSession()
-	defer s.Close()
 	u, err := user.CheckUserCredentials(s, creds)
 	if err != nil {
 		_ = s.Rollback()
@@ -632,7 +888,55 @@ func caldavBasicAuth(username, password string, c echo.Context) (bool, error) {
 		return false, err
 	}
 
+	if err := user.ResetFailedLoginAttempts(u); err != nil {
+		return false, err
+	}
+
 	// Save the user in echo context for later use
 	c.Set("userBasicAuth", u)
 	return true, nil
 }
+
+func caldavLinkShareAuth(s *xorm.Session, hash, password string) (share *models.LinkSharing, err error) {
+	if !config.ServiceEnableLinkSharing.GetBool() {
+		return nil, models.ErrListShareDoesNotExist{Hash: hash}
+	}
+
+	share, err = models.GetLinkShareByHash(s, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = verifyCaldavLinkSharePassword(share, password)
+	if err != nil {
+		return nil, err
+	}
+
+	share.Password = ""
+	return share, nil
+}
+
+// verifyCaldavLinkSharePassword checks the password of a link share. Wrong passwords are slowed down and locked like
+// failed logins of users so they can't be guessed.
+func verifyCaldavLinkSharePassword(share *models.LinkSharing, password string) error {
+	if share.SharingType != models.SharingTypeWithPassword {
+		return nil
+	}
+
+	err := user.CheckLinkShareLoginAllowed(share.Hash)
+	if err != nil {
+		return err
+	}
+
+	err = models.VerifyLinkSharePassword(share, password)
+	if models.IsErrLinkSharePasswordInvalid(err) {
+		if err := user.RegisterFailedLinkShareLogin(share.Hash); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return user.ResetFailedLinkShareLoginAttempts(share.Hash)
+}
@@ -0,0 +1,257 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // breached password lists use sha1
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+)
+
+type breachedPasswordList interface {
+	contains(hash [sha1.Size]byte) (bool, error)
+}
+
+// breachedPasswords holds the configured list of breached passwords, loaded once when starting Vikunja.
+var breachedPasswords breachedPasswordList
+
+// sortedBreachedPasswordsFile is a file with one sha1 hash per line which is sorted by the hashes, like the files
+// from haveibeenpwned.com. These are too large to read them completely, they are binary searched on disk instead.
+type sortedBreachedPasswordsFile struct {
+	file *os.File
+	size int64
+}
+
+// breachedPasswordHashes holds the hashes of all passwords of a list which is not sorted
+type breachedPasswordHashes map[[sha1.Size]byte]bool
+
+// InitBreachedPasswords loads the configured list of breached passwords. If the list is a file of sorted
+// sha1 hashes, it is only opened and kept open. All other lists are read completely into memory.
+// A file of hashes which is not sorted can't be searched and is too large to load it, it returns an error instead.
+// Only a sample of the lines of such a file is checked here to keep the startup fast.
+func InitBreachedPasswords() error {
+	if f, is := breachedPasswords.(*sortedBreachedPasswordsFile); is {
+		_ = f.file.Close()
+	}
+	breachedPasswords = nil
+
+	path := config.AuthLocalPasswordPolicyBreachedPasswordsFile.GetString()
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	first, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		_ = f.Close()
+		return err
+	}
+	if _, isHash := parseBreachedPasswordHash(strings.TrimSpace(first)); isHash {
+		sorted := &sortedBreachedPasswordsFile{
+			file: f,
+			size: stat.Size(),
+		}
+		err = sorted.checkSorted()
+		if err != nil {
+			_ = f.Close()
+			return fmt.Errorf("breached passwords file %s: %w", path, err)
+		}
+		breachedPasswords = sorted
+		log.Debugf("Using %s as sorted list of breached password hashes", path)
+		return nil
+	}
+
+	defer f.Close()
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	hashes := make(breachedPasswordHashes)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if hash, isHash := parseBreachedPasswordHash(line); isHash {
+			hashes[hash] = true
+			continue
+		}
+		hashes[sha1.Sum([]byte(line))] = true //nolint:gosec
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	breachedPasswords = hashes
+	log.Debugf("Loaded %d breached passwords from %s", len(hashes), path)
+	return nil
+}
+
+// breachedPasswordsSortedSamples is how many lines of a sorted file are checked when it is loaded
+const breachedPasswordsSortedSamples = 128
+
+// errBreachedPasswordsNotSorted is returned when a file of hashes turns out not to be sorted
+var errBreachedPasswordsNotSorted = errors.New("the hashes are not sorted, they need to be sorted like the \"ordered by hash\" files from haveibeenpwned.com")
+
+// checkSorted checks lines spread evenly over the file to make sure they are hashes and sorted. Reading the whole file
+// would take minutes for the files from haveibeenpwned.com, this catches files like the ones ordered by prevalence.
+// Violations in between are detected while searching the file.
+func (f *sortedBreachedPasswordsFile) checkSorted() error {
+	previous := ""
+	for i := int64(0); i < breachedPasswordsSortedSamples; i++ {
+		line, next, err := f.lineStartingAt(f.size * i / breachedPasswordsSortedSamples)
+		if err != nil {
+			return err
+		}
+		if next < 0 {
+			break
+		}
+
+		hash, isHash := parseBreachedPasswordHash(line)
+		if !isHash {
+			return fmt.Errorf("%q is not a sha1 hash, a file starting with a hash must only contain hashes", line)
+		}
+		current := strings.ToUpper(hex.EncodeToString(hash[:]))
+		if current < previous {
+			return errBreachedPasswordsNotSorted
+		}
+		previous = current
+	}
+	return nil
+}
+
+// parseBreachedPasswordHash parses a line with a sha1 hash, optionally followed by `:<count>`.
+func parseBreachedPasswordHash(line string) (hash [sha1.Size]byte, isHash bool) {
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+	if len(line) != hex.EncodedLen(sha1.Size) {
+		return hash, false
+	}
+	_, err := hex.Decode(hash[:], []byte(line))
+	return hash, err == nil
+}
+
+func (h breachedPasswordHashes) contains(hash [sha1.Size]byte) (bool, error) {
+	return h[hash], nil
+}
+
+func (f *sortedBreachedPasswordsFile) contains(hash [sha1.Size]byte) (bool, error) {
+	target := strings.ToUpper(hex.EncodeToString(hash[:]))
+
+	// Searches the range of offsets the line with the hash could start in. In every step,
+	// the first line which starts at or after the middle of the range is compared.
+	// All lines in the range need to be between the lines which limit it, otherwise the file is not sorted.
+	lo, hi := int64(0), f.size
+	lower, upper := "", ""
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, next, err := f.lineStartingAt(mid)
+		if err != nil {
+			return false, err
+		}
+		if next < 0 {
+			hi = mid
+			continue
+		}
+
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.ToUpper(line)
+		if line < lower || (upper != "" && line > upper) {
+			log.Errorf("The breached passwords file %s is not sorted, passwords can't be checked", f.file.Name())
+			return false, errBreachedPasswordsNotSorted
+		}
+
+		switch c := strings.Compare(line, target); {
+		case c == 0:
+			return true, nil
+		case c < 0:
+			lo = next
+			lower = line
+		default:
+			hi = mid
+			upper = line
+		}
+	}
+
+	return false, nil
+}
+
+// lineStartingAt returns the first line which starts at or after an offset and the offset of the line after it.
+// If there is no such line, next is -1.
+func (f *sortedBreachedPasswordsFile) lineStartingAt(offset int64) (line string, next int64, err error) {
+	start := offset
+	if offset > 0 {
+		// Starts one byte earlier to know if the offset is the beginning of a line
+		start = offset - 1
+	}
+	r := bufio.NewReaderSize(io.NewSectionReader(f.file, start, f.size-start), 256)
+
+	if offset > 0 {
+		skipped, err := r.ReadString('\n')
+		if err == io.EOF {
+			return "", -1, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err = r.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return "", -1, nil
+		}
+		// The last line does not need to end with a line break
+		err = nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	return strings.TrimSpace(line), start + int64(len(line)), nil
+}
+
+// isBreachedPassword checks if the password is contained in the configured list of breached passwords.
+func isBreachedPassword(password string) (bool, error) {
+	if breachedPasswords == nil {
+		return false, nil
+	}
+	return breachedPasswords.contains(sha1.Sum([]byte(password))) //nolint:gosec
+}
@@ -36,5 +36,7 @@ func GetTables() []interface{} {
 	return []interface{}{
 		&User{},
 		&TOTP{},
+		&TOTPRecoveryCode{},
+		&WebAuthnCredential{},
 	}
 }
@@ -19,6 +19,7 @@ package user
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"code.vikunja.io/web"
 )
@@ -399,3 +400,279 @@ func (err ErrInvalidAvatarProvider) HTTPError() web.HTTPError {
 		Message:  "Invalid avatar provider setting. See docs for valid types.",
 	}
 }
+
+// ErrWebAuthnAssertionRequired represents a "WebAuthnAssertionRequired" kind of error.
+type ErrWebAuthnAssertionRequired struct{}
+
+// IsErrWebAuthnAssertionRequired checks if an error is a ErrWebAuthnAssertionRequired.
+func IsErrWebAuthnAssertionRequired(err error) bool {
+	_, ok := err.(ErrWebAuthnAssertionRequired)
+	return ok
+}
+
+func (err ErrWebAuthnAssertionRequired) Error() string {
+	return "A WebAuthn assertion is required to log in"
+}
+
+// ErrCodeWebAuthnAssertionRequired holds the unique world-error code of this error
+const ErrCodeWebAuthnAssertionRequired = 1019
+
+// HTTPError holds the http error description
+func (err ErrWebAuthnAssertionRequired) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeWebAuthnAssertionRequired,
+		Message:  "Please provide a WebAuthn assertion or totp passcode to log in.",
+	}
+}
+
+// ErrInvalidWebAuthnResponse represents a "InvalidWebAuthnResponse" kind of error.
+type ErrInvalidWebAuthnResponse struct {
+	Details string
+}
+
+// IsErrInvalidWebAuthnResponse checks if an error is a ErrInvalidWebAuthnResponse.
+func IsErrInvalidWebAuthnResponse(err error) bool {
+	_, ok := err.(ErrInvalidWebAuthnResponse)
+	return ok
+}
+
+func (err ErrInvalidWebAuthnResponse) Error() string {
+	return fmt.Sprintf("Invalid WebAuthn response [Details: %s]", err.Details)
+}
+
+// ErrCodeInvalidWebAuthnResponse holds the unique world-error code of this error
+const ErrCodeInvalidWebAuthnResponse = 1020
+
+// HTTPError holds the http error description
+func (err ErrInvalidWebAuthnResponse) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeInvalidWebAuthnResponse,
+		Message:  "The WebAuthn response is invalid.",
+	}
+}
+
+// ErrWebAuthnCredentialDoesNotExist represents a "WebAuthnCredentialDoesNotExist" kind of error.
+type ErrWebAuthnCredentialDoesNotExist struct {
+	CredentialID int64
+}
+
+// IsErrWebAuthnCredentialDoesNotExist checks if an error is a ErrWebAuthnCredentialDoesNotExist.
+func IsErrWebAuthnCredentialDoesNotExist(err error) bool {
+	_, ok := err.(ErrWebAuthnCredentialDoesNotExist)
+	return ok
+}
+
+func (err ErrWebAuthnCredentialDoesNotExist) Error() string {
+	return fmt.Sprintf("WebAuthn credential does not exist [Credential ID: %d]", err.CredentialID)
+}
+
+// ErrCodeWebAuthnCredentialDoesNotExist holds the unique world-error code of this error
+const ErrCodeWebAuthnCredentialDoesNotExist = 1021
+
+// HTTPError holds the http error description
+func (err ErrWebAuthnCredentialDoesNotExist) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusNotFound,
+		Code:     ErrCodeWebAuthnCredentialDoesNotExist,
+		Message:  "This WebAuthn credential does not exist.",
+	}
+}
+
+// ErrWebAuthnSessionExpired represents a "WebAuthnSessionExpired" kind of error.
+type ErrWebAuthnSessionExpired struct {
+	UserID int64
+}
+
+// IsErrWebAuthnSessionExpired checks if an error is a ErrWebAuthnSessionExpired.
+func IsErrWebAuthnSessionExpired(err error) bool {
+	_, ok := err.(ErrWebAuthnSessionExpired)
+	return ok
+}
+
+func (err ErrWebAuthnSessionExpired) Error() string {
+	return fmt.Sprintf("No WebAuthn ceremony in progress or it expired [User ID: %d]", err.UserID)
+}
+
+// ErrCodeWebAuthnSessionExpired holds the unique world-error code of this error
+const ErrCodeWebAuthnSessionExpired = 1022
+
+// HTTPError holds the http error description
+func (err ErrWebAuthnSessionExpired) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeWebAuthnSessionExpired,
+		Message:  "The WebAuthn challenge expired or was never requested. Please start again.",
+	}
+}
+
+// ErrWebAuthnNotAvailable represents a "WebAuthnNotAvailable" kind of error.
+type ErrWebAuthnNotAvailable struct {
+	Reason string
+}
+
+// IsErrWebAuthnNotAvailable checks if an error is a ErrWebAuthnNotAvailable.
+func IsErrWebAuthnNotAvailable(err error) bool {
+	_, ok := err.(ErrWebAuthnNotAvailable)
+	return ok
+}
+
+func (err ErrWebAuthnNotAvailable) Error() string {
+	return fmt.Sprintf("WebAuthn is not available [Reason: %s]", err.Reason)
+}
+
+// ErrCodeWebAuthnNotAvailable holds the unique world-error code of this error
+const ErrCodeWebAuthnNotAvailable = 1023
+
+// HTTPError holds the http error description
+func (err ErrWebAuthnNotAvailable) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeWebAuthnNotAvailable,
+		Message:  "WebAuthn is not available on this instance.",
+	}
+}
+
+// ErrLoginDelayed represents a "LoginDelayed" kind of error.
+type ErrLoginDelayed struct {
+	UserID int64
+}
+
+// IsErrLoginDelayed checks if an error is a ErrLoginDelayed.
+func IsErrLoginDelayed(err error) bool {
+	_, ok := err.(ErrLoginDelayed)
+	return ok
+}
+
+func (err ErrLoginDelayed) Error() string {
+	return fmt.Sprintf("Login attempt is too early after a failed attempt [UserID: %d]", err.UserID)
+}
+
+// ErrCodeLoginDelayed holds the unique world-error code of this error
+const ErrCodeLoginDelayed = 1024
+
+// HTTPError holds the http error description
+func (err ErrLoginDelayed) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusTooManyRequests,
+		Code:     ErrCodeLoginDelayed,
+		Message:  "Too many failed login attempts. Please wait a few seconds before trying again.",
+	}
+}
+
+// ErrAccountLocked represents a "AccountLocked" kind of error.
+type ErrAccountLocked struct {
+	UserID int64
+}
+
+// IsErrAccountLocked checks if an error is a ErrAccountLocked.
+func IsErrAccountLocked(err error) bool {
+	_, ok := err.(ErrAccountLocked)
+	return ok
+}
+
+func (err ErrAccountLocked) Error() string {
+	return fmt.Sprintf("Account is locked after too many failed login attempts [UserID: %d]", err.UserID)
+}
+
+// ErrCodeAccountLocked holds the unique world-error code of this error
+const ErrCodeAccountLocked = 1025
+
+// HTTPError holds the http error description
+func (err ErrAccountLocked) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusTooManyRequests,
+		Code:     ErrCodeAccountLocked,
+		Message:  "This account is temporarily locked because of too many failed login attempts. Please try again later.",
+	}
+}
+
+// ErrPasswordPolicyViolated represents a "PasswordPolicyViolated" kind of error.
+type ErrPasswordPolicyViolated struct {
+	Violations []*PasswordPolicyViolation
+}
+
+// IsErrPasswordPolicyViolated checks if an error is a ErrPasswordPolicyViolated.
+func IsErrPasswordPolicyViolated(err error) bool {
+	_, ok := err.(ErrPasswordPolicyViolated)
+	return ok
+}
+
+func (err ErrPasswordPolicyViolated) Error() string {
+	rules := make([]string, 0, len(err.Violations))
+	for _, v := range err.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return fmt.Sprintf("Password does not match the password policy [Rules: %s]", strings.Join(rules, ", "))
+}
+
+// ErrCodePasswordPolicyViolated holds the unique world-error code of this error
+const ErrCodePasswordPolicyViolated = 1026
+
+// HTTPError holds the http error description
+func (err ErrPasswordPolicyViolated) HTTPError() web.HTTPError {
+	messages := make([]string, 0, len(err.Violations))
+	for _, v := range err.Violations {
+		messages = append(messages, v.Message)
+	}
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodePasswordPolicyViolated,
+		Message:  "The password does not match the password policy: " + strings.Join(messages, " "),
+	}
+}
+
+// ErrInvalidAccountDeletionToken is an error where the account deletion token is invalid
+type ErrInvalidAccountDeletionToken struct {
+	Token string
+}
+
+// IsErrInvalidAccountDeletionToken checks if an error is a ErrInvalidAccountDeletionToken.
+func IsErrInvalidAccountDeletionToken(err error) bool {
+	_, ok := err.(ErrInvalidAccountDeletionToken)
+	return ok
+}
+
+func (err ErrInvalidAccountDeletionToken) Error() string {
+	return fmt.Sprintf("Invalid account deletion token [Token: %s]", err.Token)
+}
+
+// ErrCodeInvalidAccountDeletionToken holds the unique world-error code of this error
+const ErrCodeInvalidAccountDeletionToken = 1027
+
+// HTTPError holds the http error description
+func (err ErrInvalidAccountDeletionToken) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeInvalidAccountDeletionToken,
+		Message:  "Invalid token to confirm the account deletion.",
+	}
+}
+
+// ErrAccountDeletionNotScheduled is an error where a user tries to change the deletion of their account without having it scheduled
+type ErrAccountDeletionNotScheduled struct {
+	UserID int64
+}
+
+// IsErrAccountDeletionNotScheduled checks if an error is a ErrAccountDeletionNotScheduled.
+func IsErrAccountDeletionNotScheduled(err error) bool {
+	_, ok := err.(ErrAccountDeletionNotScheduled)
+	return ok
+}
+
+func (err ErrAccountDeletionNotScheduled) Error() string {
+	return fmt.Sprintf("Account deletion is not scheduled [UserID: %d]", err.UserID)
+}
+
+// ErrCodeAccountDeletionNotScheduled holds the unique world-error code of this error
+const ErrCodeAccountDeletionNotScheduled = 1028
+
+// HTTPError holds the http error description
+func (err ErrAccountDeletionNotScheduled) HTTPError() web.HTTPError {
+	return web.HTTPError{
+		HTTPCode: http.StatusPreconditionFailed,
+		Code:     ErrCodeAccountDeletionNotScheduled,
+		Message:  "The deletion of your account is not scheduled.",
+	}
+}
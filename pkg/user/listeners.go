@@ -25,6 +25,7 @@ import (
 
 func RegisterListeners() {
 	events.RegisterListener((&CreatedEvent{}).Name(), &IncreaseUserCounter{})
+	events.RegisterListener((&DeletedEvent{}).Name(), &DecreaseUserCounter{})
 }
 
 ///////
@@ -43,3 +44,17 @@ func (s *IncreaseUserCounter) Name() string {
 func (s *IncreaseUserCounter) Handle(msg *message.Message) (err error) {
 	return keyvalue.IncrBy(metrics.UserCountKey, 1)
 }
+
+// DecreaseUserCounter  represents a listener
+type DecreaseUserCounter struct {
+}
+
+// Name defines the name for the DecreaseUserCounter listener
+func (s *DecreaseUserCounter) Name() string {
+	return "decrease.user.counter"
+}
+
+// Hanlde is executed when the event DecreaseUserCounter listens on is fired
+func (s *DecreaseUserCounter) Handle(msg *message.Message) (err error) {
+	return keyvalue.DecrBy(metrics.UserCountKey, 1)
+}
@@ -0,0 +1,277 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"code.vikunja.io/api/pkg/notifications"
+)
+
+// The number of failed attempts which are possible without any delay so users who mistyped are not slowed down.
+const failedLoginAttemptsWithoutDelay = 3
+const maxFailedLoginDelay = 30 * time.Second
+
+// failedLoginDelay returns how long a user needs to wait after the last failed attempt. It doubles with every failed attempt.
+func failedLoginDelay(count int64) time.Duration {
+	if count < failedLoginAttemptsWithoutDelay {
+		return 0
+	}
+
+	exp := count - failedLoginAttemptsWithoutDelay
+	if exp >= 5 {
+		return maxFailedLoginDelay
+	}
+
+	d := time.Second << exp
+	if d > maxFailedLoginDelay {
+		return maxFailedLoginDelay
+	}
+	return d
+}
+
+// Failed attempts are counted per account, so that the username and email address of a user share the same
+// attempts and lockout. Names which don't belong to any user get their own attempts, that way the responses
+// for names which don't exist can't be told apart from the ones of existing users.
+func failedLoginAttemptsKeys(name string, u *User) (count, lastAttempt, lockedUntil string) {
+	subject := "name_" + strings.ToLower(strings.TrimSpace(name))
+	if u != nil && u.ID != 0 {
+		subject = "user_" + strconv.FormatInt(u.ID, 10)
+	}
+	return failedAttemptsKeys(subject)
+}
+
+// Link shares with a password have their own attempts, counted by their hash.
+func failedLinkShareLoginAttemptsKeys(hash string) (count, lastAttempt, lockedUntil string) {
+	return failedAttemptsKeys("link_share_" + hash)
+}
+
+func failedAttemptsKeys(subject string) (count, lastAttempt, lockedUntil string) {
+	return "failed_login_attempts_" + subject,
+		"failed_login_last_attempt_" + subject,
+		"login_locked_until_" + subject
+}
+
+func loginLockoutEnabled() bool {
+	return config.ServiceMaxFailedLoginAttempts.GetInt64() > 0
+}
+
+func getLoginAttemptTime(key string) (t time.Time, err error) {
+	_, err = keyvalue.GetWithValue(key, &t)
+	return
+}
+
+func getFailedLoginCount(key string) (count int64, err error) {
+	_, err = keyvalue.GetWithValue(key, &count)
+	return
+}
+
+// checkLoginAllowed checks if the user is locked or has to wait a bit longer after a failed login attempt.
+// If no user with the name exists, u is nil and the name is checked instead.
+func checkLoginAllowed(name string, u *User) error {
+	if !loginLockoutEnabled() {
+		return nil
+	}
+
+	return checkAttemptsAllowed(failedLoginAttemptsKeys(name, u))
+}
+
+func checkAttemptsAllowed(countKey, lastAttemptKey, lockedUntilKey string) error {
+	now := time.Now()
+	lockedUntil, err := getLoginAttemptTime(lockedUntilKey)
+	if err != nil {
+		return err
+	}
+	if lockedUntil.After(now) {
+		return ErrAccountLocked{}
+	}
+
+	count, err := getFailedLoginCount(countKey)
+	if err != nil {
+		return err
+	}
+	lastAttempt, err := getLoginAttemptTime(lastAttemptKey)
+	if err != nil {
+		return err
+	}
+	if lastAttempt.Add(failedLoginDelay(count)).After(now) {
+		return ErrLoginDelayed{}
+	}
+
+	return nil
+}
+
+// registerFailedLogin counts a failed login attempt and locks the user if there were too many of them.
+// If the name belongs to a user, u is the user and will be notified about the lockout. Otherwise the name is locked.
+func registerFailedLogin(name string, u *User) error {
+	if !loginLockoutEnabled() {
+		return nil
+	}
+
+	lockedUntil, locked, err := registerFailedAttempt(failedLoginAttemptsKeys(name, u))
+	if err != nil || !locked {
+		return err
+	}
+
+	if u == nil {
+		log.Infof("Locked logins with a name which does not exist until %s after too many failed login attempts", lockedUntil.Format(time.RFC3339))
+		return nil
+	}
+
+	log.Infof("Locked user %d until %s after too many failed login attempts", u.ID, lockedUntil.Format(time.RFC3339))
+
+	n := &AccountLockedNotification{
+		User:        u,
+		LockedUntil: lockedUntil,
+	}
+	err = notifications.Notify(u, n)
+	if err != nil {
+		// The lockout itself worked, not being able to notify the user should not prevent that
+		log.Errorf("Could not notify user %d about their account being locked: %s", u.ID, err)
+	}
+
+	return nil
+}
+
+// registerFailedAttempt counts a failed attempt and returns whether it led to a lockout.
+func registerFailedAttempt(countKey, lastAttemptKey, lockedUntilKey string) (lockedUntil time.Time, locked bool, err error) {
+	now := time.Now()
+	lockoutDuration := config.ServiceLoginLockoutDuration.GetDuration() * time.Second
+
+	// Failed attempts are forgotten after a while so they don't add up over months
+	lastAttempt, err := getLoginAttemptTime(lastAttemptKey)
+	if err != nil {
+		return
+	}
+	if !lastAttempt.IsZero() && lastAttempt.Add(lockoutDuration).Before(now) {
+		if err = keyvalue.Del(countKey); err != nil {
+			return
+		}
+	}
+
+	// The counter is increased atomically so parallel attempts are all counted
+	err = keyvalue.IncrBy(countKey, 1)
+	if err != nil {
+		return
+	}
+	err = keyvalue.Put(lastAttemptKey, now)
+	if err != nil {
+		return
+	}
+
+	count, err := getFailedLoginCount(countKey)
+	if err != nil {
+		return
+	}
+	if count < config.ServiceMaxFailedLoginAttempts.GetInt64() {
+		return
+	}
+
+	lockedUntil = now.Add(lockoutDuration)
+	err = keyvalue.Put(lockedUntilKey, lockedUntil)
+	if err != nil {
+		return
+	}
+	err = keyvalue.Del(countKey)
+	return lockedUntil, err == nil, err
+}
+
+// ResetFailedLoginAttempts needs to be called once a user passed all checks of a login, including any second factor.
+// It removes all failed login attempts of the user.
+func ResetFailedLoginAttempts(u *User) error {
+	if !loginLockoutEnabled() {
+		return nil
+	}
+
+	countKey, lastAttemptKey, _ := failedLoginAttemptsKeys("", u)
+	if err := keyvalue.Del(countKey); err != nil {
+		return err
+	}
+	return keyvalue.Del(lastAttemptKey)
+}
+
+// RegisterFailedSecondFactor counts a wrong second factor like a totp passcode or WebAuthn assertion as a failed
+// login attempt so it can't be guessed without being slowed down.
+func RegisterFailedSecondFactor(u *User) error {
+	return registerFailedLogin("", u)
+}
+
+// CheckLinkShareLoginAllowed checks if the password of a link share can be tried again or if there were too many
+// wrong passwords recently. Wrong passwords are counted per share like failed logins are counted per user.
+func CheckLinkShareLoginAllowed(hash string) error {
+	if !loginLockoutEnabled() {
+		return nil
+	}
+	return checkAttemptsAllowed(failedLinkShareLoginAttemptsKeys(hash))
+}
+
+// RegisterFailedLinkShareLogin counts a wrong password of a link share and locks it if there were too many of them.
+func RegisterFailedLinkShareLogin(hash string) error {
+	if !loginLockoutEnabled() {
+		return nil
+	}
+
+	lockedUntil, locked, err := registerFailedAttempt(failedLinkShareLoginAttemptsKeys(hash))
+	if err != nil || !locked {
+		return err
+	}
+
+	log.Infof("Locked logins with a link share until %s after too many wrong passwords", lockedUntil.Format(time.RFC3339))
+	return nil
+}
+
+// ResetFailedLinkShareLoginAttempts removes all wrong password attempts of a link share after the right one was used.
+func ResetFailedLinkShareLoginAttempts(hash string) error {
+	if !loginLockoutEnabled() {
+		return nil
+	}
+
+	countKey, lastAttemptKey, _ := failedLinkShareLoginAttemptsKeys(hash)
+	if err := keyvalue.Del(countKey); err != nil {
+		return err
+	}
+	return keyvalue.Del(lastAttemptKey)
+}
+
+// UnlockUser removes a temporary lockout and all failed login attempts of a user.
+// The attempts with the username and email as name are removed as well in case they were made before the user existed.
+func UnlockUser(u *User) error {
+	keys := []string{}
+	if u.ID != 0 {
+		count, lastAttempt, lockedUntil := failedLoginAttemptsKeys("", u)
+		keys = append(keys, count, lastAttempt, lockedUntil)
+	}
+	for _, name := range []string{u.Username, u.Email} {
+		if name == "" {
+			continue
+		}
+		count, lastAttempt, lockedUntil := failedLoginAttemptsKeys(name, nil)
+		keys = append(keys, count, lastAttempt, lockedUntil)
+	}
+
+	for _, key := range keys {
+		if err := keyvalue.Del(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -17,6 +17,8 @@
 package user
 
 import (
+	"time"
+
 	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/notifications"
 )
@@ -107,3 +109,104 @@ func (n *ResetPasswordNotification) ToDB() interface{} {
 func (n *ResetPasswordNotification) Name() string {
 	return ""
 }
+
+// AccountLockedNotification represents a AccountLockedNotification notification
+type AccountLockedNotification struct {
+	User        *User
+	LockedUntil time.Time
+}
+
+// ToMail returns the mail notification for AccountLockedNotification
+func (n *AccountLockedNotification) ToMail() *notifications.Mail {
+	return notifications.NewMail().
+		Subject("Your account on Vikunja was locked").
+		Greeting("Hi " + n.User.GetName() + ",").
+		Line("There were too many failed attempts to log in to your account. To protect it, your account was locked until " + n.LockedUntil.Format(time.RFC1123) + ".").
+		Line("If this wasn't you, it could mean someone tries to guess your password. In this case consider changing it to a stronger one once you are able to log in again.").
+		Line("Have a nice day!")
+}
+
+// ToDB returns the AccountLockedNotification notification in a format which can be saved in the db
+func (n *AccountLockedNotification) ToDB() interface{} {
+	return nil
+}
+
+// Name returns the name of the notification
+func (n *AccountLockedNotification) Name() string {
+	return ""
+}
+
+// AccountDeletionConfirmNotification represents a AccountDeletionConfirmNotification notification
+type AccountDeletionConfirmNotification struct {
+	User *User
+}
+
+// ToMail returns the mail notification for AccountDeletionConfirmNotification
+func (n *AccountDeletionConfirmNotification) ToMail() *notifications.Mail {
+	return notifications.NewMail().
+		Subject("Please confirm the deletion of your Vikunja account").
+		Greeting("Hi "+n.User.GetName()+",").
+		Line("You requested to delete your account. To confirm, click the link below:").
+		Action("Confirm the deletion", config.ServiceFrontendurl.GetString()+"?accountDeletionConfirm="+n.User.DeletionConfirmToken).
+		Line("If you did not request this, you can ignore this email.").
+		Line("Have a nice day!")
+}
+
+// ToDB returns the AccountDeletionConfirmNotification notification in a format which can be saved in the db
+func (n *AccountDeletionConfirmNotification) ToDB() interface{} {
+	return nil
+}
+
+// Name returns the name of the notification
+func (n *AccountDeletionConfirmNotification) Name() string {
+	return ""
+}
+
+// AccountDeletionScheduledNotification represents a AccountDeletionScheduledNotification notification
+type AccountDeletionScheduledNotification struct {
+	User *User
+}
+
+// ToMail returns the mail notification for AccountDeletionScheduledNotification
+func (n *AccountDeletionScheduledNotification) ToMail() *notifications.Mail {
+	return notifications.NewMail().
+		Subject("Your Vikunja account will be deleted").
+		Greeting("Hi " + n.User.GetName() + ",").
+		Line("Your account will be deleted on " + n.User.DeletionScheduledAt.Format(time.RFC1123) + ".").
+		Line("Until then, you can still log in to choose which of your namespaces and lists should be given to other users instead of being deleted or to cancel the deletion.").
+		Line("Have a nice day!")
+}
+
+// ToDB returns the AccountDeletionScheduledNotification notification in a format which can be saved in the db
+func (n *AccountDeletionScheduledNotification) ToDB() interface{} {
+	return nil
+}
+
+// Name returns the name of the notification
+func (n *AccountDeletionScheduledNotification) Name() string {
+	return ""
+}
+
+// AccountDeletedNotification represents a AccountDeletedNotification notification
+type AccountDeletedNotification struct {
+	User *User
+}
+
+// ToMail returns the mail notification for AccountDeletedNotification
+func (n *AccountDeletedNotification) ToMail() *notifications.Mail {
+	return notifications.NewMail().
+		Subject("Your Vikunja account was deleted").
+		Greeting("Hi " + n.User.GetName() + ",").
+		Line("Your account and all data in it was deleted as you requested.").
+		Line("Thank you for using Vikunja!")
+}
+
+// ToDB returns the AccountDeletedNotification notification in a format which can be saved in the db
+func (n *AccountDeletedNotification) ToDB() interface{} {
+	return nil
+}
+
+// Name returns the name of the notification
+func (n *AccountDeletedNotification) Name() string {
+	return ""
+}
@@ -0,0 +1,138 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"code.vikunja.io/api/pkg/config"
+)
+
+// PasswordPolicyViolation describes a rule of the password policy which a password does not follow.
+type PasswordPolicyViolation struct {
+	// The identifier of the rule.
+	Rule string `json:"rule"`
+	// A human readable explanation what the password is missing.
+	Message string `json:"message"`
+}
+
+// All rules of the password policy
+const (
+	PasswordPolicyRuleMinLength = "min_length"
+	PasswordPolicyRuleUppercase = "uppercase"
+	PasswordPolicyRuleLowercase = "lowercase"
+	PasswordPolicyRuleDigit     = "digit"
+	PasswordPolicyRuleSpecial   = "special"
+	PasswordPolicyRuleUserInfo  = "user_info"
+	PasswordPolicyRuleBreached  = "breached"
+)
+
+// checkPasswordPolicy checks a new password of a user against all configured rules and returns an
+// ErrPasswordPolicyViolated with all rules the password violates.
+func checkPasswordPolicy(user *User, password string) error {
+	violations := []*PasswordPolicyViolation{}
+
+	minLength := config.AuthLocalPasswordPolicyMinLength.GetInt()
+	if utf8.RuneCountInString(password) < minLength {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleMinLength,
+			Message: "It needs to be at least " + strconv.Itoa(minLength) + " characters long.",
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSpecial = true
+		}
+	}
+
+	if config.AuthLocalPasswordPolicyRequireUppercase.GetBool() && !hasUpper {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleUppercase,
+			Message: "It needs to contain at least one uppercase letter.",
+		})
+	}
+	if config.AuthLocalPasswordPolicyRequireLowercase.GetBool() && !hasLower {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleLowercase,
+			Message: "It needs to contain at least one lowercase letter.",
+		})
+	}
+	if config.AuthLocalPasswordPolicyRequireDigit.GetBool() && !hasDigit {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleDigit,
+			Message: "It needs to contain at least one digit.",
+		})
+	}
+	if config.AuthLocalPasswordPolicyRequireSpecial.GetBool() && !hasSpecial {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleSpecial,
+			Message: "It needs to contain at least one special character.",
+		})
+	}
+
+	if config.AuthLocalPasswordPolicyDisallowUserInfo.GetBool() && passwordContainsUserInfo(user, password) {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleUserInfo,
+			Message: "It must not be the username or email address.",
+		})
+	}
+
+	breached, err := isBreachedPassword(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		violations = append(violations, &PasswordPolicyViolation{
+			Rule:    PasswordPolicyRuleBreached,
+			Message: "It is known from a data breach and therefore not safe to use.",
+		})
+	}
+
+	if len(violations) > 0 {
+		return ErrPasswordPolicyViolated{Violations: violations}
+	}
+
+	return nil
+}
+
+func passwordContainsUserInfo(user *User, password string) bool {
+	password = strings.ToLower(password)
+
+	infos := []string{user.Username, user.Email}
+	if i := strings.Index(user.Email, "@"); i > 0 {
+		infos = append(infos, user.Email[:i])
+	}
+
+	for _, info := range infos {
+		if info != "" && password == strings.ToLower(info) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"code.vikunja.io/api/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func getViolatedRules(err error) (rules []string) {
+	if !IsErrPasswordPolicyViolated(err) {
+		return nil
+	}
+	for _, v := range err.(ErrPasswordPolicyViolated).Violations {
+		rules = append(rules, v.Rule)
+	}
+	return
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	u := &User{
+		Username: "user1",
+		Email:    "someone@example.com",
+	}
+
+	t.Run("default policy", func(t *testing.T) {
+		err := checkPasswordPolicy(u, "1234")
+		assert.NoError(t, err)
+	})
+	t.Run("all character rules", func(t *testing.T) {
+		config.AuthLocalPasswordPolicyMinLength.Set(10)
+		config.AuthLocalPasswordPolicyRequireUppercase.Set(true)
+		config.AuthLocalPasswordPolicyRequireLowercase.Set(true)
+		config.AuthLocalPasswordPolicyRequireDigit.Set(true)
+		config.AuthLocalPasswordPolicyRequireSpecial.Set(true)
+		defer func() {
+			config.AuthLocalPasswordPolicyMinLength.Set(0)
+			config.AuthLocalPasswordPolicyRequireUppercase.Set(false)
+			config.AuthLocalPasswordPolicyRequireLowercase.Set(false)
+			config.AuthLocalPasswordPolicyRequireDigit.Set(false)
+			config.AuthLocalPasswordPolicyRequireSpecial.Set(false)
+		}()
+
+		err := checkPasswordPolicy(u, "1234")
+		assert.Error(t, err)
+		assert.Equal(t, []string{
+			PasswordPolicyRuleMinLength,
+			PasswordPolicyRuleUppercase,
+			PasswordPolicyRuleLowercase,
+			PasswordPolicyRuleSpecial,
+		}, getViolatedRules(err))
+
+		err = checkPasswordPolicy(u, "Very-long-passw0rd")
+		assert.NoError(t, err)
+	})
+	t.Run("username", func(t *testing.T) {
+		err := checkPasswordPolicy(u, "User1")
+		assert.Error(t, err)
+		assert.Equal(t, []string{PasswordPolicyRuleUserInfo}, getViolatedRules(err))
+	})
+	t.Run("email", func(t *testing.T) {
+		err := checkPasswordPolicy(u, "someone@example.com")
+		assert.Error(t, err)
+		assert.Equal(t, []string{PasswordPolicyRuleUserInfo}, getViolatedRules(err))
+
+		err = checkPasswordPolicy(u, "someone")
+		assert.Error(t, err)
+		assert.Equal(t, []string{PasswordPolicyRuleUserInfo}, getViolatedRules(err))
+	})
+	t.Run("breached", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "breached")
+		assert.NoError(t, err)
+		defer os.Remove(f.Name())
+		_, err = f.WriteString("password\nF3BBBD66A63D4BF1747940578EC3D0103530E21D:17\n")
+		assert.NoError(t, err)
+		_ = f.Close()
+		path := f.Name()
+
+		config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set(path)
+		defer func() {
+			config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set("")
+			_ = InitBreachedPasswords()
+		}()
+		err = InitBreachedPasswords()
+		assert.NoError(t, err)
+
+		err = checkPasswordPolicy(u, "password")
+		assert.Error(t, err)
+		assert.Equal(t, []string{PasswordPolicyRuleBreached}, getViolatedRules(err))
+
+		// Only contained as sha1 hash
+		err = checkPasswordPolicy(u, "hunter2")
+		assert.Error(t, err)
+		assert.Equal(t, []string{PasswordPolicyRuleBreached}, getViolatedRules(err))
+
+		err = checkPasswordPolicy(u, "not in the list")
+		assert.NoError(t, err)
+	})
+	t.Run("sorted breached hashes", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "breached")
+		assert.NoError(t, err)
+		defer os.Remove(f.Name())
+		// The sha1 hashes of "password", "123456", "qwerty" and "hunter2", sorted like in the files from haveibeenpwned.com
+		_, err = f.WriteString("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n" +
+			"7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577\n" +
+			"B1B3773A05C0ED0176787A4F1574FF0075F7521E:3946737\n" +
+			"F3BBBD66A63D4BF1747940578EC3D0103530E21D:17")
+		assert.NoError(t, err)
+		_ = f.Close()
+
+		config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set(f.Name())
+		defer func() {
+			config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set("")
+			_ = InitBreachedPasswords()
+		}()
+		err = InitBreachedPasswords()
+		assert.NoError(t, err)
+		assert.IsType(t, &sortedBreachedPasswordsFile{}, breachedPasswords)
+
+		for _, password := range []string{"123456", "hunter2", "password", "qwerty"} {
+			breached, err := isBreachedPassword(password)
+			assert.NoError(t, err)
+			assert.True(t, breached, password)
+		}
+		for _, password := range []string{"", "not in the list", "1234567"} {
+			breached, err := isBreachedPassword(password)
+			assert.NoError(t, err)
+			assert.False(t, breached, password)
+		}
+	})
+	t.Run("unsorted breached hashes", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "breached")
+		assert.NoError(t, err)
+		defer os.Remove(f.Name())
+		// The same hashes, ordered by prevalence instead of by hash
+		_, err = f.WriteString("7C4A8D09CA3762AF61E59520943DC26494F8941B:24230577\n" +
+			"B1B3773A05C0ED0176787A4F1574FF0075F7521E:3946737\n" +
+			"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n" +
+			"F3BBBD66A63D4BF1747940578EC3D0103530E21D:17")
+		assert.NoError(t, err)
+		_ = f.Close()
+
+		config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set(f.Name())
+		defer func() {
+			config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set("")
+			_ = InitBreachedPasswords()
+		}()
+		err = InitBreachedPasswords()
+		assert.Error(t, err)
+		assert.Nil(t, breachedPasswords)
+	})
+	t.Run("missing breached passwords file", func(t *testing.T) {
+		config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set("/does/not/exist")
+		defer func() {
+			config.AuthLocalPasswordPolicyBreachedPasswordsFile.Set("")
+			_ = InitBreachedPasswords()
+		}()
+
+		err := InitBreachedPasswords()
+		assert.Error(t, err)
+	})
+}
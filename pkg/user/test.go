@@ -20,6 +20,8 @@ import (
 	"code.vikunja.io/api/pkg/db"
 	"code.vikunja.io/api/pkg/events"
 	"code.vikunja.io/api/pkg/log"
+	"code.vikunja.io/api/pkg/mail"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
 )
 
 // InitTests handles the actual bootstrapping of the test env
@@ -34,10 +36,13 @@ func InitTests() {
 		log.Fatal(err)
 	}
 
-	err = db.InitTestFixtures("users")
+	err = db.InitTestFixtures("users", "webauthn_credentials", "totp", "totp_recovery_codes")
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	keyvalue.InitStorage()
+
 	events.Fake()
+	mail.Fake()
 }
@@ -17,7 +17,13 @@
 package user
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
 	"image"
+	"strings"
+	"time"
 
 	"xorm.io/xorm"
 
@@ -42,6 +48,31 @@ func (t *TOTP) TableName() string {
 	return "totp"
 }
 
+// TOTPRecoveryCode holds a hashed one-time code which can be used instead of a totp passcode.
+type TOTPRecoveryCode struct {
+	ID      int64     `xorm:"bigint autoincr not null unique pk" json:"-"`
+	UserID  int64     `xorm:"bigint not null INDEX" json:"-"`
+	Code    string    `xorm:"varchar(64) not null" json:"-"`
+	Created time.Time `xorm:"created not null" json:"-"`
+}
+
+// TableName holds the table name for totp recovery codes
+func (t *TOTPRecoveryCode) TableName() string {
+	return "totp_recovery_codes"
+}
+
+// TOTPRecoveryCodes holds newly generated recovery codes. They are only shown once when they are created.
+type TOTPRecoveryCodes struct {
+	// Each code can be used once instead of a totp passcode to log in.
+	Codes []string `json:"codes"`
+}
+
+const (
+	totpRecoveryCodeCount = 10
+	// The number of random bytes per code, 6 bytes result in 10 characters.
+	totpRecoveryCodeBytes = 6
+)
+
 // TOTPPasscode is used to validate a users totp passcode
 type TOTPPasscode struct {
 	User     *User  `json:"-"`
@@ -101,7 +132,8 @@ func EnrollTOTP(s *xorm.Session, user *User) (t *TOTP, err error) {
 }
 
 // EnableTOTP enables totp for a user. The provided passcode is used to verify the user has a working totp setup.
-func EnableTOTP(s *xorm.Session, passcode *TOTPPasscode) (err error) {
+// It returns a new set of recovery codes which can be used in case the user loses their totp device.
+func EnableTOTP(s *xorm.Session, passcode *TOTPPasscode) (codes *TOTPRecoveryCodes, err error) {
 	t, err := ValidateTOTPPasscode(s, passcode)
 	if err != nil {
 		return
@@ -111,17 +143,105 @@ func EnableTOTP(s *xorm.Session, passcode *TOTPPasscode) (err error) {
 		Where("id = ?", t.ID).
 		Cols("enabled").
 		Update(&TOTP{Enabled: true})
-	return
+	if err != nil {
+		return
+	}
+
+	return generateTOTPRecoveryCodes(s, passcode.User)
 }
 
-// DisableTOTP removes all totp settings for a user.
+// DisableTOTP removes all totp settings and recovery codes for a user.
 func DisableTOTP(s *xorm.Session, user *User) (err error) {
 	_, err = s.
 		Where("user_id = ?", user.ID).
 		Delete(&TOTP{})
+	if err != nil {
+		return
+	}
+
+	_, err = s.
+		Where("user_id = ?", user.ID).
+		Delete(&TOTPRecoveryCode{})
+	return
+}
+
+// normalizeTOTPRecoveryCode makes sure recovery codes are accepted regardless of case or the separator.
+func normalizeTOTPRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// Recovery codes are random and long enough to not need a slow hash like passwords do.
+// Using sha256 allows us to look them up directly.
+func hashTOTPRecoveryCode(code string) string {
+	h := sha256.Sum256([]byte(normalizeTOTPRecoveryCode(code)))
+	return hex.EncodeToString(h[:])
+}
+
+func generateTOTPRecoveryCode() (string, error) {
+	b := make([]byte, totpRecoveryCodeBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	return code[:5] + "-" + code[5:], nil
+}
+
+// generateTOTPRecoveryCodes replaces all recovery codes of a user with new ones.
+func generateTOTPRecoveryCodes(s *xorm.Session, user *User) (codes *TOTPRecoveryCodes, err error) {
+	_, err = s.
+		Where("user_id = ?", user.ID).
+		Delete(&TOTPRecoveryCode{})
+	if err != nil {
+		return
+	}
+
+	codes = &TOTPRecoveryCodes{Codes: make([]string, 0, totpRecoveryCodeCount)}
+	hashed := make([]*TOTPRecoveryCode, 0, totpRecoveryCodeCount)
+	for i := 0; i < totpRecoveryCodeCount; i++ {
+		code, err := generateTOTPRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes.Codes = append(codes.Codes, code)
+		hashed = append(hashed, &TOTPRecoveryCode{
+			UserID: user.ID,
+			Code:   hashTOTPRecoveryCode(code),
+		})
+	}
+
+	_, err = s.Insert(&hashed)
 	return
 }
 
+// RegenerateTOTPRecoveryCodes invalidates all recovery codes of a user and creates new ones.
+// Only works if the user has totp enabled.
+func RegenerateTOTPRecoveryCodes(s *xorm.Session, user *User) (codes *TOTPRecoveryCodes, err error) {
+	t, err := GetTOTPForUser(s, user)
+	if err != nil {
+		return
+	}
+	if !t.Enabled {
+		return nil, ErrTOTPNotEnabled{}
+	}
+
+	return generateTOTPRecoveryCodes(s, user)
+}
+
+// useTOTPRecoveryCode checks if the code is a valid recovery code of the user and removes it so it can't be used again.
+func useTOTPRecoveryCode(s *xorm.Session, user *User, code string) (valid bool, err error) {
+	if normalizeTOTPRecoveryCode(code) == "" {
+		return false, nil
+	}
+
+	deleted, err := s.
+		Where("user_id = ? AND code = ?", user.ID, hashTOTPRecoveryCode(code)).
+		Delete(&TOTPRecoveryCode{})
+	return deleted > 0, err
+}
+
 // ValidateTOTPPasscode validated totp codes of users.
 func ValidateTOTPPasscode(s *xorm.Session, passcode *TOTPPasscode) (t *TOTP, err error) {
 	t, err = GetTOTPForUser(s, passcode.User)
@@ -136,6 +256,25 @@ func ValidateTOTPPasscode(s *xorm.Session, passcode *TOTPPasscode) (t *TOTP, err
 	return
 }
 
+// ValidateTOTPPasscodeOrRecoveryCode checks the provided passcode as a totp passcode and, if that fails, as a
+// recovery code of the user. A recovery code is invalidated once it was used.
+func ValidateTOTPPasscodeOrRecoveryCode(s *xorm.Session, passcode *TOTPPasscode) (err error) {
+	_, err = ValidateTOTPPasscode(s, passcode)
+	if err == nil || !IsErrInvalidTOTPPasscode(err) {
+		return
+	}
+
+	valid, err := useTOTPRecoveryCode(s, passcode.User, passcode.Passcode)
+	if err != nil {
+		return
+	}
+	if !valid {
+		return ErrInvalidTOTPPasscode{Passcode: passcode.Passcode}
+	}
+
+	return nil
+}
+
 // GetTOTPQrCodeForUser returns a qrcode for a user's totp setting
 func GetTOTPQrCodeForUser(s *xorm.Session, user *User) (qrcode image.Image, err error) {
 	t, err := GetTOTPForUser(s, user)
@@ -0,0 +1,135 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTOTPPasscodeOrRecoveryCode(t *testing.T) {
+	t.Run("recovery code", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := ValidateTOTPPasscodeOrRecoveryCode(s, &TOTPPasscode{
+			User:     &User{ID: 10},
+			Passcode: "ABCDE-12345",
+		})
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "totp_recovery_codes", map[string]interface{}{
+			"id": 1,
+		})
+	})
+	t.Run("recovery code used twice", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		passcode := &TOTPPasscode{
+			User:     &User{ID: 10},
+			Passcode: "abcde12345",
+		}
+		err := ValidateTOTPPasscodeOrRecoveryCode(s, passcode)
+		assert.NoError(t, err)
+		err = ValidateTOTPPasscodeOrRecoveryCode(s, passcode)
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidTOTPPasscode(err))
+	})
+	t.Run("recovery code of another user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := EnrollTOTP(s, &User{ID: 1, Username: "user1"})
+		assert.NoError(t, err)
+
+		err = ValidateTOTPPasscodeOrRecoveryCode(s, &TOTPPasscode{
+			User:     &User{ID: 1},
+			Passcode: "abcde-12345",
+		})
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidTOTPPasscode(err))
+	})
+	t.Run("invalid code", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := ValidateTOTPPasscodeOrRecoveryCode(s, &TOTPPasscode{
+			User:     &User{ID: 10},
+			Passcode: "invalid",
+		})
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidTOTPPasscode(err))
+	})
+}
+
+func TestRegenerateTOTPRecoveryCodes(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		codes, err := RegenerateTOTPRecoveryCodes(s, &User{ID: 10})
+		assert.NoError(t, err)
+		assert.Len(t, codes.Codes, totpRecoveryCodeCount)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "totp_recovery_codes", map[string]interface{}{
+			"id": 1,
+		})
+		db.AssertExists(t, "totp_recovery_codes", map[string]interface{}{
+			"user_id": 10,
+			"code":    hashTOTPRecoveryCode(codes.Codes[0]),
+		}, false)
+	})
+	t.Run("totp not enabled", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := RegenerateTOTPRecoveryCodes(s, &User{ID: 1})
+		assert.Error(t, err)
+		assert.True(t, IsErrTOTPNotEnabled(err))
+	})
+}
+
+func TestDisableTOTP(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	err := DisableTOTP(s, &User{ID: 10})
+	assert.NoError(t, err)
+	err = s.Commit()
+	assert.NoError(t, err)
+
+	db.AssertMissing(t, "totp", map[string]interface{}{
+		"user_id": 10,
+	})
+	db.AssertMissing(t, "totp_recovery_codes", map[string]interface{}{
+		"user_id": 10,
+	})
+}
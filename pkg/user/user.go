@@ -17,6 +17,7 @@
 package user
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
@@ -40,8 +41,11 @@ type Login struct {
 	Username string `json:"username"`
 	// The password for the user.
 	Password string `json:"password"`
-	// The totp passcode of a user. Only needs to be provided when enabled.
+	// The totp passcode of a user or one of their recovery codes. Only needs to be provided when enabled.
 	TOTPPasscode string `json:"totp_passcode"`
+	// The response of a WebAuthn authenticator as returned by navigator.credentials.get(). Can be provided instead
+	// of a totp passcode if the user has WebAuthn authenticators registered.
+	WebAuthnAssertion json.RawMessage `json:"webauthn_assertion,omitempty" swaggertype:"object"`
 }
 
 // User holds information about an user
@@ -60,6 +64,12 @@ type User struct {
 	PasswordResetToken string `xorm:"varchar(450) null" json:"-"`
 	EmailConfirmToken  string `xorm:"varchar(450) null" json:"-"`
 
+	DeletionConfirmToken string `xorm:"varchar(450) null" json:"-"`
+	// When the account will be deleted. Zero if the user did not request the deletion.
+	DeletionScheduledAt time.Time `xorm:"datetime null" json:"-"`
+	// The id of the file holding the latest data export of this user.
+	ExportFileID int64 `xorm:"bigint null" json:"-"`
+
 	AvatarProvider string `xorm:"varchar(255) null" json:"-"`
 	AvatarFileID   int64  `xorn:"null" json:"-"`
 
@@ -70,6 +80,14 @@ type User struct {
 	// If enabled, sends email reminders of tasks to the user.
 	EmailRemindersEnabled bool `xorm:"bool default true" json:"-"`
 
+	// If enabled, users who share a list or team with this user can see their name, for example in the caldav address book.
+	DiscoverableByName bool `xorm:"bool default false" json:"-"`
+	// If enabled, users who share a list or team with this user can see their email address.
+	DiscoverableByEmail bool `xorm:"bool default false" json:"-"`
+
+	// Administrators of an instance can manage all users, namespaces and lists through the admin api.
+	IsAdmin bool `xorm:"bool default false" json:"-"`
+
 	// A timestamp when this task was created. You cannot change this value.
 	Created time.Time `xorm:"created not null" json:"created"`
 	// A timestamp when this task was last updated. You cannot change this value.
@@ -99,6 +117,11 @@ func (u *User) RouteForDB() int64 {
 	return u.ID
 }
 
+// IsLocalUser returns true if the user authenticates with a password stored in Vikunja.
+func (u *User) IsLocalUser() bool {
+	return u.Issuer == issuerLocal
+}
+
 // GetID implements the Auth interface
 func (u *User) GetID() int64 {
 	return u.ID
@@ -235,7 +258,8 @@ func getUserByUsernameOrEmail(s *xorm.Session, usernameOrEmail string) (u *User,
 	return
 }
 
-// CheckUserCredentials checks user credentials
+// CheckUserCredentials checks user credentials. Once all other checks of the login passed,
+// ResetFailedLoginAttempts needs to be called.
 func CheckUserCredentials(s *xorm.Session, u *Login) (*User, error) {
 	// Check if we have any credentials
 	if u.Password == "" || u.Username == "" {
@@ -245,11 +269,25 @@ func CheckUserCredentials(s *xorm.Session, u *Login) (*User, error) {
 	// Check if the user exists
 	user, err := getUserByUsernameOrEmail(s, u.Username)
 	if err != nil {
+		// Failed attempts are limited for names which don't exist as well so that
+		// the responses for users which don't exist are the same as for ones who do.
+		if err := checkLoginAllowed(u.Username, nil); err != nil {
+			return nil, err
+		}
 		// hashing the password takes a long time, so we hash something to not make it clear if the username was wrong
 		_, _ = bcrypt.GenerateFromPassword([]byte(u.Username), 14)
+		if err := registerFailedLogin(u.Username, nil); err != nil {
+			return nil, err
+		}
 		return nil, ErrWrongUsernameOrPassword{}
 	}
 
+	// Failed attempts are limited per user, regardless of whether the username or email was used
+	err = checkLoginAllowed(u.Username, user)
+	if err != nil {
+		return nil, err
+	}
+
 	// The user is invalid if they need to verify their email address
 	if !user.IsActive {
 		return &User{}, ErrEmailNotConfirmed{UserID: user.ID}
@@ -258,9 +296,15 @@ func CheckUserCredentials(s *xorm.Session, u *Login) (*User, error) {
 	// Check the users password
 	err = CheckUserPassword(user, u.Password)
 	if err != nil {
+		if IsErrWrongUsernameOrPassword(err) {
+			if err := registerFailedLogin(u.Username, user); err != nil {
+				return nil, err
+			}
+		}
 		return nil, err
 	}
 
+	// Failed attempts are only reset by the caller once the login is complete, which can include a second factor.
 	return user, nil
 }
 
@@ -366,6 +410,8 @@ func UpdateUser(s *xorm.Session, user *User) (updatedUser *User, err error) {
 			"is_active",
 			"name",
 			"email_reminders_enabled",
+			"discoverable_by_name",
+			"discoverable_by_email",
 		).
 		Update(user)
 	if err != nil {
@@ -381,6 +427,23 @@ func UpdateUser(s *xorm.Session, user *User) (updatedUser *User, err error) {
 	return updatedUser, err
 }
 
+// SetUserStatus enables or disables a user. Disabled users cannot log in anymore.
+func SetUserStatus(s *xorm.Session, user *User, active bool) (err error) {
+	user.IsActive = active
+	_, err = UpdateUser(s, user)
+	return
+}
+
+// SetUserAdmin grants or revokes the instance administrator role of a user.
+func SetUserAdmin(s *xorm.Session, user *User, admin bool) (err error) {
+	user.IsAdmin = admin
+	_, err = s.
+		ID(user.ID).
+		Cols("is_admin").
+		Update(user)
+	return
+}
+
 // UpdateUserPassword updates the password of a user
 func UpdateUserPassword(s *xorm.Session, user *User, newPassword string) (err error) {
 
@@ -394,8 +457,13 @@ func UpdateUserPassword(s *xorm.Session, user *User, newPassword string) (err er
 		return err
 	}
 
+	err = checkPasswordPolicy(theUser, newPassword)
+	if err != nil {
+		return err
+	}
+
 	// Hash the new password and set it
-	hashed, err := hashPassword(newPassword)
+	hashed, err := HashPassword(newPassword)
 	if err != nil {
 		return err
 	}
@@ -47,8 +47,13 @@ func CreateUser(s *xorm.Session, user *User) (newUser *User, err error) {
 	}
 
 	if user.Issuer == issuerLocal {
+		err = checkPasswordPolicy(user, user.Password)
+		if err != nil {
+			return nil, err
+		}
+
 		// Hash the password
-		user.Password, err = hashPassword(user.Password)
+		user.Password, err = HashPassword(user.Password)
 		if err != nil {
 			return nil, err
 		}
@@ -98,7 +103,7 @@ func CreateUser(s *xorm.Session, user *User) (newUser *User, err error) {
 }
 
 // HashPassword hashes a password
-func hashPassword(password string) (string, error) {
+func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 11)
 	return string(bytes), err
 }
@@ -0,0 +1,163 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/events"
+	"code.vikunja.io/api/pkg/notifications"
+	"code.vikunja.io/api/pkg/utils"
+	"xorm.io/xorm"
+)
+
+// AccountDeletionRequest holds the data to request the deletion of an account
+type AccountDeletionRequest struct {
+	// The password of the user, required for local users.
+	Password string `json:"password"`
+}
+
+// AccountDeletionConfirm holds the token to confirm the deletion of an account
+type AccountDeletionConfirm struct {
+	// The token sent to the user via email.
+	Token string `json:"token"`
+}
+
+// RequestDeletion starts the deletion of a user account. If the mailer is enabled, the user gets an email with a link
+// to confirm the deletion, otherwise the deletion is scheduled right away.
+func RequestDeletion(s *xorm.Session, user *User, password string) (err error) {
+	if user.Issuer == issuerLocal {
+		err = CheckUserPassword(user, password)
+		if err != nil {
+			return
+		}
+	}
+
+	if !config.MailerEnabled.GetBool() {
+		return scheduleDeletion(s, user)
+	}
+
+	user.DeletionConfirmToken, err = utils.MakeSecureRandomString(60)
+	if err != nil {
+		return
+	}
+	_, err = s.
+		Where("id = ?", user.ID).
+		Cols("deletion_confirm_token").
+		Update(user)
+	if err != nil {
+		return
+	}
+
+	n := &AccountDeletionConfirmNotification{
+		User: user,
+	}
+
+	err = notifications.Notify(user, n)
+	return
+}
+
+// ConfirmDeletion schedules the deletion of the account of the user if the token belongs to them.
+func ConfirmDeletion(s *xorm.Session, user *User, token string) (err error) {
+	if token == "" {
+		return ErrInvalidAccountDeletionToken{Token: token}
+	}
+
+	full := &User{}
+	exists, err := s.
+		Where("id = ? AND deletion_confirm_token = ?", user.ID, token).
+		Get(full)
+	if err != nil {
+		return
+	}
+	if !exists {
+		return ErrInvalidAccountDeletionToken{Token: token}
+	}
+
+	return scheduleDeletion(s, full)
+}
+
+func scheduleDeletion(s *xorm.Session, user *User) (err error) {
+	gracePeriod := time.Duration(config.ServiceUserDeletionGracePeriod.GetInt64()) * 24 * time.Hour
+	user.DeletionScheduledAt = time.Now().Add(gracePeriod)
+	user.DeletionConfirmToken = ""
+	_, err = s.
+		Where("id = ?", user.ID).
+		Cols("deletion_scheduled_at", "deletion_confirm_token").
+		Update(user)
+	if err != nil {
+		return
+	}
+
+	if !config.MailerEnabled.GetBool() {
+		return
+	}
+
+	n := &AccountDeletionScheduledNotification{
+		User: user,
+	}
+
+	err = notifications.Notify(user, n)
+	return
+}
+
+// CancelDeletion cancels a requested or scheduled deletion of a user account.
+func CancelDeletion(s *xorm.Session, user *User) (err error) {
+	full, err := GetUserByID(s, user.ID)
+	if err != nil {
+		return
+	}
+
+	if full.DeletionScheduledAt.IsZero() && full.DeletionConfirmToken == "" {
+		return ErrAccountDeletionNotScheduled{UserID: user.ID}
+	}
+
+	full.DeletionScheduledAt = time.Time{}
+	full.DeletionConfirmToken = ""
+	_, err = s.
+		Where("id = ?", user.ID).
+		Cols("deletion_scheduled_at", "deletion_confirm_token").
+		Nullable("deletion_scheduled_at").
+		Update(full)
+	return
+}
+
+// DeleteUser removes a user and everything only the user package knows about from the database.
+// Everything else the user owns needs to be cleaned up before calling this.
+func DeleteUser(s *xorm.Session, user *User) (err error) {
+	_, err = s.Where("user_id = ?", user.ID).Delete(&TOTP{})
+	if err != nil {
+		return
+	}
+	_, err = s.Where("user_id = ?", user.ID).Delete(&TOTPRecoveryCode{})
+	if err != nil {
+		return
+	}
+	_, err = s.Where("user_id = ?", user.ID).Delete(&WebAuthnCredential{})
+	if err != nil {
+		return
+	}
+	_, err = s.Where("id = ?", user.ID).Delete(&User{})
+	if err != nil {
+		return
+	}
+
+	return events.Dispatch(&DeletedEvent{
+		User: user,
+	})
+}
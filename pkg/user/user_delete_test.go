@@ -0,0 +1,154 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"testing"
+
+	"code.vikunja.io/api/pkg/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestDeletion(t *testing.T) {
+	t.Run("wrong password", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := GetUserByID(s, 1)
+		assert.NoError(t, err)
+		err = RequestDeletion(s, u, "wrong")
+		assert.Error(t, err)
+		assert.True(t, IsErrWrongUsernameOrPassword(err))
+	})
+	t.Run("schedules without mailer", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := GetUserByID(s, 1)
+		assert.NoError(t, err)
+		err = RequestDeletion(s, u, "1234")
+		assert.NoError(t, err)
+
+		u, err = GetUserByID(s, 1)
+		assert.NoError(t, err)
+		assert.False(t, u.DeletionScheduledAt.IsZero())
+		assert.Empty(t, u.DeletionConfirmToken)
+	})
+}
+
+func TestConfirmDeletion(t *testing.T) {
+	t.Run("empty token", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := ConfirmDeletion(s, &User{ID: 1}, "")
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidAccountDeletionToken(err))
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := ConfirmDeletion(s, &User{ID: 1}, "invalid")
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidAccountDeletionToken(err))
+	})
+	t.Run("valid token", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.
+			Where("id = ?", 1).
+			Cols("deletion_confirm_token").
+			Update(&User{DeletionConfirmToken: "deletiontoken"})
+		assert.NoError(t, err)
+
+		err = ConfirmDeletion(s, &User{ID: 1}, "deletiontoken")
+		assert.NoError(t, err)
+
+		u, err := GetUserByID(s, 1)
+		assert.NoError(t, err)
+		assert.False(t, u.DeletionScheduledAt.IsZero())
+		assert.Empty(t, u.DeletionConfirmToken)
+	})
+	t.Run("token of another user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := s.
+			Where("id = ?", 1).
+			Cols("deletion_confirm_token").
+			Update(&User{DeletionConfirmToken: "deletiontoken"})
+		assert.NoError(t, err)
+
+		err = ConfirmDeletion(s, &User{ID: 2}, "deletiontoken")
+		assert.Error(t, err)
+		assert.True(t, IsErrInvalidAccountDeletionToken(err))
+
+		u, err := GetUserByID(s, 1)
+		assert.NoError(t, err)
+		assert.True(t, u.DeletionScheduledAt.IsZero())
+	})
+}
+
+func TestCancelDeletion(t *testing.T) {
+	t.Run("not scheduled", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := CancelDeletion(s, &User{ID: 1})
+		assert.Error(t, err)
+		assert.True(t, IsErrAccountDeletionNotScheduled(err))
+	})
+	t.Run("scheduled", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u, err := GetUserByID(s, 1)
+		assert.NoError(t, err)
+		err = scheduleDeletion(s, u)
+		assert.NoError(t, err)
+
+		err = CancelDeletion(s, &User{ID: 1})
+		assert.NoError(t, err)
+
+		u, err = GetUserByID(s, 1)
+		assert.NoError(t, err)
+		assert.True(t, u.DeletionScheduledAt.IsZero())
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	err := DeleteUser(s, &User{ID: 10})
+	assert.NoError(t, err)
+
+	db.AssertMissing(t, "users", map[string]interface{}{"id": 10})
+	db.AssertMissing(t, "totp", map[string]interface{}{"user_id": 10})
+	db.AssertMissing(t, "totp_recovery_codes", map[string]interface{}{"user_id": 10})
+}
@@ -56,8 +56,13 @@ func ResetPassword(s *xorm.Session, reset *PasswordReset) (err error) {
 		return ErrInvalidPasswordResetToken{Token: reset.Token}
 	}
 
+	err = checkPasswordPolicy(user, reset.NewPassword)
+	if err != nil {
+		return
+	}
+
 	// Hash the password
-	user.Password, err = hashPassword(reset.NewPassword)
+	user.Password, err = HashPassword(reset.NewPassword)
 	if err != nil {
 		return
 	}
@@ -19,6 +19,7 @@ package user
 import (
 	"testing"
 
+	"code.vikunja.io/api/pkg/config"
 	"code.vikunja.io/api/pkg/db"
 	"github.com/stretchr/testify/assert"
 )
@@ -278,6 +279,138 @@ func TestCheckUserCredentials(t *testing.T) {
 		_, err := CheckUserCredentials(s, &Login{Username: "user1@example.com", Password: "1234"})
 		assert.NoError(t, err)
 	})
+	t.Run("delayed after failed attempts", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u := &User{ID: 11, Username: "user11"}
+		defer func() { _ = UnlockUser(u) }()
+
+		for i := 0; i < failedLoginAttemptsWithoutDelay; i++ {
+			_, err := CheckUserCredentials(s, &Login{Username: "user11", Password: "wrong"})
+			assert.Error(t, err)
+			assert.True(t, IsErrWrongUsernameOrPassword(err))
+		}
+
+		_, err := CheckUserCredentials(s, &Login{Username: "user11", Password: "1234"})
+		assert.Error(t, err)
+		assert.True(t, IsErrLoginDelayed(err))
+
+		err = UnlockUser(u)
+		assert.NoError(t, err)
+		_, err = CheckUserCredentials(s, &Login{Username: "user11", Password: "1234"})
+		assert.NoError(t, err)
+	})
+	t.Run("username and email share the failed attempts", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u := &User{ID: 11, Username: "user11"}
+		defer func() { _ = UnlockUser(u) }()
+
+		for i := 0; i < failedLoginAttemptsWithoutDelay; i++ {
+			_, err := CheckUserCredentials(s, &Login{Username: "user11", Password: "wrong"})
+			assert.Error(t, err)
+			assert.True(t, IsErrWrongUsernameOrPassword(err))
+		}
+
+		_, err := CheckUserCredentials(s, &Login{Username: "user11@example.com", Password: "1234"})
+		assert.Error(t, err)
+		assert.True(t, IsErrLoginDelayed(err))
+	})
+	t.Run("delayed for users which don't exist", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u := &User{Username: "userdoesnotexist"}
+		defer func() { _ = UnlockUser(u) }()
+
+		for i := 0; i < failedLoginAttemptsWithoutDelay; i++ {
+			_, err := CheckUserCredentials(s, &Login{Username: "userdoesnotexist", Password: "wrong"})
+			assert.Error(t, err)
+			assert.True(t, IsErrWrongUsernameOrPassword(err))
+		}
+
+		_, err := CheckUserCredentials(s, &Login{Username: "userdoesnotexist", Password: "wrong"})
+		assert.Error(t, err)
+		assert.True(t, IsErrLoginDelayed(err))
+	})
+	t.Run("not reset before the login is complete", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		u := &User{ID: 11, Username: "user11"}
+		defer func() { _ = UnlockUser(u) }()
+
+		for i := 0; i < failedLoginAttemptsWithoutDelay-1; i++ {
+			_, err := CheckUserCredentials(s, &Login{Username: "user11", Password: "wrong"})
+			assert.Error(t, err)
+		}
+		_, err := CheckUserCredentials(s, &Login{Username: "user11", Password: "1234"})
+		assert.NoError(t, err)
+
+		// A wrong second factor after the correct password
+		err = RegisterFailedSecondFactor(u)
+		assert.NoError(t, err)
+		_, err = CheckUserCredentials(s, &Login{Username: "user11", Password: "1234"})
+		assert.Error(t, err)
+		assert.True(t, IsErrLoginDelayed(err))
+	})
+	t.Run("locked after too many failed attempts", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		config.ServiceMaxFailedLoginAttempts.Set(2)
+		defer config.ServiceMaxFailedLoginAttempts.Set(10)
+
+		u := &User{ID: 12, Username: "user12"}
+		defer func() { _ = UnlockUser(u) }()
+
+		for i := 0; i < 2; i++ {
+			_, err := CheckUserCredentials(s, &Login{Username: "user12", Password: "wrong"})
+			assert.Error(t, err)
+			assert.True(t, IsErrWrongUsernameOrPassword(err))
+		}
+
+		_, err := CheckUserCredentials(s, &Login{Username: "user12", Password: "1234"})
+		assert.Error(t, err)
+		assert.True(t, IsErrAccountLocked(err))
+
+		err = UnlockUser(u)
+		assert.NoError(t, err)
+		_, err = CheckUserCredentials(s, &Login{Username: "user12", Password: "1234"})
+		assert.NoError(t, err)
+	})
+}
+
+func TestLinkShareLoginAttempts(t *testing.T) {
+	hash := "testlinksharehash"
+	defer func() { _ = ResetFailedLinkShareLoginAttempts(hash) }()
+
+	for i := 0; i < failedLoginAttemptsWithoutDelay; i++ {
+		err := CheckLinkShareLoginAllowed(hash)
+		assert.NoError(t, err)
+		err = RegisterFailedLinkShareLogin(hash)
+		assert.NoError(t, err)
+	}
+
+	err := CheckLinkShareLoginAllowed(hash)
+	assert.Error(t, err)
+	assert.True(t, IsErrLoginDelayed(err))
+
+	// Other link shares are not affected
+	err = CheckLinkShareLoginAllowed("otherlinksharehash")
+	assert.NoError(t, err)
+
+	err = ResetFailedLinkShareLoginAttempts(hash)
+	assert.NoError(t, err)
+	err = CheckLinkShareLoginAllowed(hash)
+	assert.NoError(t, err)
 }
 
 func TestUpdateUser(t *testing.T) {
@@ -375,7 +508,7 @@ func TestListUsers(t *testing.T) {
 
 		all, err := ListUsers(s, "")
 		assert.NoError(t, err)
-		assert.Len(t, all, 14)
+		assert.Len(t, all, 15)
 	})
 }
 
@@ -0,0 +1,468 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+	"xorm.io/xorm"
+)
+
+// How long a started registration or login is valid
+const webAuthnSessionTimeout = 5 * time.Minute
+
+// WebAuthnCredential holds a WebAuthn authenticator (security key, passkey, ...) of a user.
+type WebAuthnCredential struct {
+	// The unique, numeric id of this credential.
+	ID     int64 `xorm:"bigint autoincr not null unique pk" json:"id" param:"credential"`
+	UserID int64 `xorm:"bigint not null INDEX" json:"-"`
+	// The name of this authenticator as chosen by the user.
+	Name string `xorm:"varchar(250) not null" json:"name" valid:"runelength(0|250)" maxLength:"250"`
+
+	CredentialID    string `xorm:"text not null" json:"-"`
+	PublicKey       string `xorm:"text not null" json:"-"`
+	AttestationType string `xorm:"varchar(50) null" json:"-"`
+	AAGUID          string `xorm:"varchar(250) null" json:"-"`
+	SignCount       int64  `xorm:"bigint null" json:"-"`
+
+	// When this authenticator was last used to log in.
+	LastUsed time.Time `xorm:"datetime null" json:"last_used"`
+	// A timestamp when this credential was registered. You cannot change this value.
+	Created time.Time `xorm:"created not null" json:"created"`
+}
+
+// TableName holds the table name for WebAuthn credentials
+func (*WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// WebAuthnRegistration is used to finish the registration of a new authenticator
+type WebAuthnRegistration struct {
+	// The name of the new authenticator.
+	Name string `json:"name" valid:"runelength(0|250)" maxLength:"250"`
+	// The response of the authenticator as returned by navigator.credentials.create().
+	Credential json.RawMessage `json:"credential" swaggertype:"object"`
+}
+
+// webAuthnSession holds the state of a registration or login between beginning and finishing it
+type webAuthnSession struct {
+	Session      webauthn.SessionData `json:"session"`
+	Passwordless bool                 `json:"passwordless"`
+	Expires      time.Time            `json:"expires"`
+}
+
+// webAuthnUser wraps a user and its credentials to implement webauthn.User
+type webAuthnUser struct {
+	user        *User
+	credentials []*WebAuthnCredential
+}
+
+func (w *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatInt(w.user.ID, 10))
+}
+
+func (w *webAuthnUser) WebAuthnName() string {
+	return w.user.Username
+}
+
+func (w *webAuthnUser) WebAuthnDisplayName() string {
+	return w.user.GetName()
+}
+
+func (w *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (w *webAuthnUser) WebAuthnCredentials() (credentials []webauthn.Credential) {
+	for _, c := range w.credentials {
+		cred, err := c.toWebAuthn()
+		if err != nil {
+			continue
+		}
+		credentials = append(credentials, *cred)
+	}
+	return
+}
+
+func (c *WebAuthnCredential) toWebAuthn() (cred *webauthn.Credential, err error) {
+	id, err := base64.RawURLEncoding.DecodeString(c.CredentialID)
+	if err != nil {
+		return
+	}
+	publicKey, err := base64.RawURLEncoding.DecodeString(c.PublicKey)
+	if err != nil {
+		return
+	}
+	aaguid, err := base64.RawURLEncoding.DecodeString(c.AAGUID)
+	if err != nil {
+		return
+	}
+
+	return &webauthn.Credential{
+		ID:              id,
+		PublicKey:       publicKey,
+		AttestationType: c.AttestationType,
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    aaguid,
+			SignCount: uint32(c.SignCount),
+		},
+	}, nil
+}
+
+func getWebAuthn() (*webauthn.WebAuthn, error) {
+	if !config.ServiceEnableWebAuthn.GetBool() {
+		return nil, ErrWebAuthnNotAvailable{Reason: "disabled"}
+	}
+
+	frontend, err := url.Parse(config.ServiceFrontendurl.GetString())
+	if err != nil || frontend.Hostname() == "" {
+		return nil, ErrWebAuthnNotAvailable{Reason: "the frontend url is not configured"}
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPDisplayName: "Vikunja",
+		RPID:          frontend.Hostname(),
+		RPOrigin:      frontend.Scheme + "://" + frontend.Host,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			UserVerification: protocol.VerificationPreferred,
+		},
+	})
+}
+
+func getWebAuthnUser(s *xorm.Session, u *User) (w *webAuthnUser, err error) {
+	credentials, err := GetWebAuthnCredentialsForUser(s, u)
+	if err != nil {
+		return
+	}
+
+	return &webAuthnUser{
+		user:        u,
+		credentials: credentials,
+	}, nil
+}
+
+// Sessions are saved by their random challenge so that starting a login for a user does not
+// replace a login of the same user which is already in progress.
+func webAuthnSessionKey(kind string, challenge string) string {
+	return "webauthn_" + kind + "_" + challenge
+}
+
+// Sessions expire on their own so that logins which are started but never finished don't pile up in the store.
+func saveWebAuthnSession(kind string, session *webauthn.SessionData, passwordless bool) error {
+	return keyvalue.PutWithExpiration(webAuthnSessionKey(kind, session.Challenge), webAuthnSession{
+		Session:      *session,
+		Passwordless: passwordless,
+		Expires:      time.Now().Add(webAuthnSessionTimeout),
+	}, webAuthnSessionTimeout)
+}
+
+// getWebAuthnSession retrieves the saved session of a challenge and removes it from the store so that every challenge
+// can only be used once.
+func getWebAuthnSession(kind string, u *User, challenge string) (session *webAuthnSession, err error) {
+	key := webAuthnSessionKey(kind, challenge)
+	session = &webAuthnSession{}
+	exists, err := keyvalue.GetWithValue(key, session)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || challenge == "" || session.Expires.Before(time.Now()) {
+		return nil, ErrWebAuthnSessionExpired{UserID: u.ID}
+	}
+	if !bytes.Equal(session.Session.UserID, (&webAuthnUser{user: u}).WebAuthnID()) {
+		return nil, ErrWebAuthnSessionExpired{UserID: u.ID}
+	}
+
+	// Only the one who actually removed the session may use it, even if the same challenge is used in parallel
+	removed, err := keyvalue.DelIfExists(key)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return nil, ErrWebAuthnSessionExpired{UserID: u.ID}
+	}
+	return session, nil
+}
+
+// WebAuthnEnabledForUser checks if the user has at least one WebAuthn authenticator registered.
+func WebAuthnEnabledForUser(s *xorm.Session, u *User) (bool, error) {
+	if !config.ServiceEnableWebAuthn.GetBool() {
+		return false, nil
+	}
+
+	return s.Where("user_id = ?", u.ID).Exist(&WebAuthnCredential{})
+}
+
+// GetWebAuthnCredentialsForUser returns all WebAuthn authenticators of a user.
+func GetWebAuthnCredentialsForUser(s *xorm.Session, u *User) (credentials []*WebAuthnCredential, err error) {
+	credentials = []*WebAuthnCredential{}
+	err = s.
+		Where("user_id = ?", u.ID).
+		OrderBy("id asc").
+		Find(&credentials)
+	return
+}
+
+// BeginWebAuthnRegistration starts the registration of a new authenticator for the user.
+// The returned options need to be passed to navigator.credentials.create() by the client.
+func BeginWebAuthnRegistration(s *xorm.Session, u *User) (options *protocol.CredentialCreation, err error) {
+	w, err := getWebAuthn()
+	if err != nil {
+		return
+	}
+
+	wu, err := getWebAuthnUser(s, u)
+	if err != nil {
+		return
+	}
+
+	// Prevent registering the same authenticator twice
+	exclusions := []protocol.CredentialDescriptor{}
+	for _, c := range wu.WebAuthnCredentials() {
+		exclusions = append(exclusions, protocol.CredentialDescriptor{
+			Type:         protocol.PublicKeyCredentialType,
+			CredentialID: c.ID,
+		})
+	}
+
+	options, session, err := w.BeginRegistration(wu, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveWebAuthnSession("registration", session, false)
+	return
+}
+
+// FinishWebAuthnRegistration verifies the response of the authenticator and saves it as a new credential of the user.
+func FinishWebAuthnRegistration(s *xorm.Session, u *User, registration *WebAuthnRegistration) (credential *WebAuthnCredential, err error) {
+	w, err := getWebAuthn()
+	if err != nil {
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(registration.Credential))
+	if err != nil {
+		return nil, ErrInvalidWebAuthnResponse{Details: err.Error()}
+	}
+
+	session, err := getWebAuthnSession("registration", u, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return
+	}
+
+	wu, err := getWebAuthnUser(s, u)
+	if err != nil {
+		return
+	}
+
+	cred, err := w.CreateCredential(wu, session.Session, parsed)
+	if err != nil {
+		return nil, ErrInvalidWebAuthnResponse{Details: err.Error()}
+	}
+
+	credential = &WebAuthnCredential{
+		UserID:          u.ID,
+		Name:            registration.Name,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(cred.ID),
+		PublicKey:       base64.RawURLEncoding.EncodeToString(cred.PublicKey),
+		AttestationType: cred.AttestationType,
+		AAGUID:          base64.RawURLEncoding.EncodeToString(cred.Authenticator.AAGUID),
+		SignCount:       int64(cred.Authenticator.SignCount),
+	}
+	if credential.Name == "" {
+		credential.Name = "Security key"
+	}
+
+	_, err = s.Insert(credential)
+	return
+}
+
+// DeleteWebAuthnCredential removes an authenticator of a user.
+func DeleteWebAuthnCredential(s *xorm.Session, u *User, credentialID int64) (err error) {
+	deleted, err := s.
+		Where("id = ? AND user_id = ?", credentialID, u.ID).
+		Delete(&WebAuthnCredential{})
+	if err != nil {
+		return
+	}
+	if deleted == 0 {
+		return ErrWebAuthnCredentialDoesNotExist{CredentialID: credentialID}
+	}
+	return
+}
+
+// BeginWebAuthnLogin creates a challenge for the user which needs to be signed by one of their authenticators.
+// If a password is provided, the challenge is used as a second factor after checking the password.
+// Without a password, the authenticator is required to verify the user (pin, biometrics, ...) to allow a passwordless login.
+func BeginWebAuthnLogin(s *xorm.Session, login *Login) (options *protocol.CredentialAssertion, err error) {
+	w, err := getWebAuthn()
+	if err != nil {
+		return
+	}
+
+	passwordless := login.Password == ""
+
+	var u *User
+	if passwordless {
+		if !config.ServiceEnableWebAuthnPasswordless.GetBool() {
+			return nil, ErrNoUsernamePassword{}
+		}
+		u, err = getUserByUsernameOrEmail(s, login.Username)
+		if err != nil {
+			if err := checkLoginAllowed(login.Username, nil); err != nil {
+				return nil, err
+			}
+			return nil, ErrWrongUsernameOrPassword{}
+		}
+		err = checkLoginAllowed(login.Username, u)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		u, err = CheckUserCredentials(s, login)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	wu, err := getWebAuthnUser(s, u)
+	if err != nil {
+		return
+	}
+	if len(wu.credentials) == 0 {
+		// Without a password, users without authenticators can't be told apart from users which don't exist
+		if passwordless {
+			return nil, ErrWrongUsernameOrPassword{}
+		}
+		return nil, ErrWebAuthnCredentialDoesNotExist{}
+	}
+
+	verification := protocol.VerificationDiscouraged
+	if passwordless {
+		verification = protocol.VerificationRequired
+	}
+
+	options, session, err := w.BeginLogin(wu, webauthn.WithUserVerification(verification))
+	if err != nil {
+		return nil, err
+	}
+
+	err = saveWebAuthnSession("login", session, passwordless)
+	return
+}
+
+// ValidateWebAuthnLogin checks the signed challenge of a login previously started with BeginWebAuthnLogin.
+// If passwordless is true, only challenges which required user verification are accepted.
+func ValidateWebAuthnLogin(s *xorm.Session, u *User, assertion json.RawMessage, passwordless bool) (err error) {
+	w, err := getWebAuthn()
+	if err != nil {
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(assertion))
+	if err != nil {
+		return ErrInvalidWebAuthnResponse{Details: err.Error()}
+	}
+
+	session, err := getWebAuthnSession("login", u, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return
+	}
+	if passwordless && !session.Passwordless {
+		return ErrInvalidWebAuthnResponse{Details: "user verification was not requested"}
+	}
+
+	wu, err := getWebAuthnUser(s, u)
+	if err != nil {
+		return
+	}
+
+	cred, err := w.ValidateLogin(wu, session.Session, parsed)
+	if err != nil {
+		return ErrInvalidWebAuthnResponse{Details: err.Error()}
+	}
+	if cred.Authenticator.CloneWarning {
+		return ErrInvalidWebAuthnResponse{Details: "the signature counter of the authenticator did not increase"}
+	}
+
+	_, err = s.
+		Where("user_id = ? AND credential_id = ?", u.ID, base64.RawURLEncoding.EncodeToString(cred.ID)).
+		Cols("sign_count", "last_used").
+		Update(&WebAuthnCredential{
+			SignCount: int64(cred.Authenticator.SignCount),
+			LastUsed:  time.Now(),
+		})
+	return
+}
+
+// CheckUserWebAuthnCredentials logs a user in with only their username and a WebAuthn assertion.
+func CheckUserWebAuthnCredentials(s *xorm.Session, login *Login) (*User, error) {
+	if !config.ServiceEnableWebAuthnPasswordless.GetBool() {
+		return nil, ErrWebAuthnNotAvailable{Reason: "passwordless login is disabled"}
+	}
+
+	if login.Username == "" || len(login.WebAuthnAssertion) == 0 {
+		return nil, ErrWebAuthnAssertionRequired{}
+	}
+
+	user, err := getUserByUsernameOrEmail(s, login.Username)
+	if err != nil {
+		if err := checkLoginAllowed(login.Username, nil); err != nil {
+			return nil, err
+		}
+		if err := registerFailedLogin(login.Username, nil); err != nil {
+			return nil, err
+		}
+		return nil, ErrWrongUsernameOrPassword{}
+	}
+
+	err = checkLoginAllowed(login.Username, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, ErrEmailNotConfirmed{UserID: user.ID}
+	}
+
+	err = ValidateWebAuthnLogin(s, user, login.WebAuthnAssertion, true)
+	if err != nil {
+		if IsErrInvalidWebAuthnResponse(err) {
+			if err := registerFailedLogin(login.Username, user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	// The assertion is the only factor of a passwordless login, the login is complete now
+	err = ResetFailedLoginAttempts(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
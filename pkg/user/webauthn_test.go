@@ -0,0 +1,205 @@
+// Vikunja is a to-do list application to facilitate your life.
+// Copyright 2018-2021 Vikunja and contributors. All rights reserved.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public Licensee as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public Licensee for more details.
+//
+// You should have received a copy of the GNU Affero General Public Licensee
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package user
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.vikunja.io/api/pkg/config"
+	"code.vikunja.io/api/pkg/db"
+	"code.vikunja.io/api/pkg/modules/keyvalue"
+	"github.com/duo-labs/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWebAuthnCredentialsForUser(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	credentials, err := GetWebAuthnCredentialsForUser(s, &User{ID: 1})
+	assert.NoError(t, err)
+	assert.Len(t, credentials, 1)
+	assert.Equal(t, "YubiKey", credentials[0].Name)
+
+	wu, err := getWebAuthnUser(s, &User{ID: 1})
+	assert.NoError(t, err)
+	creds := wu.WebAuthnCredentials()
+	assert.Len(t, creds, 1)
+	assert.Equal(t, []byte("testcredential1"), creds[0].ID)
+	assert.Equal(t, uint32(5), creds[0].Authenticator.SignCount)
+}
+
+func TestWebAuthnEnabledForUser(t *testing.T) {
+	db.LoadAndAssertFixtures(t)
+	s := db.NewSession()
+	defer s.Close()
+
+	config.ServiceEnableWebAuthn.Set(true)
+	defer config.ServiceEnableWebAuthn.Set(false)
+
+	enabled, err := WebAuthnEnabledForUser(s, &User{ID: 1})
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	enabled, err = WebAuthnEnabledForUser(s, &User{ID: 3})
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestDeleteWebAuthnCredential(t *testing.T) {
+	t.Run("normal", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := DeleteWebAuthnCredential(s, &User{ID: 1}, 1)
+		assert.NoError(t, err)
+		err = s.Commit()
+		assert.NoError(t, err)
+
+		db.AssertMissing(t, "webauthn_credentials", map[string]interface{}{
+			"id": 1,
+		})
+	})
+	t.Run("credential of another user", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		err := DeleteWebAuthnCredential(s, &User{ID: 1}, 2)
+		assert.Error(t, err)
+		assert.True(t, IsErrWebAuthnCredentialDoesNotExist(err))
+	})
+}
+
+func TestBeginWebAuthnLogin(t *testing.T) {
+	config.ServiceEnableWebAuthn.Set(true)
+	defer config.ServiceEnableWebAuthn.Set(false)
+	config.ServiceEnableWebAuthnPasswordless.Set(true)
+	defer config.ServiceEnableWebAuthnPasswordless.Set(false)
+	frontendURL := config.ServiceFrontendurl.GetString()
+	config.ServiceFrontendurl.Set("https://vikunja.example/")
+	defer config.ServiceFrontendurl.Set(frontendURL)
+
+	t.Run("passwordless", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		options, err := BeginWebAuthnLogin(s, &Login{Username: "user1"})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, options.Response.Challenge)
+	})
+	t.Run("passwordless without authenticators", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := BeginWebAuthnLogin(s, &Login{Username: "user3"})
+		assert.Error(t, err)
+		assert.True(t, IsErrWrongUsernameOrPassword(err))
+	})
+	t.Run("passwordless for a user which does not exist", func(t *testing.T) {
+		db.LoadAndAssertFixtures(t)
+		s := db.NewSession()
+		defer s.Close()
+
+		_, err := BeginWebAuthnLogin(s, &Login{Username: "userdoesnotexist"})
+		assert.Error(t, err)
+		assert.True(t, IsErrWrongUsernameOrPassword(err))
+	})
+}
+
+func TestWebAuthnSession(t *testing.T) {
+	u := &User{ID: 1}
+	userID := (&webAuthnUser{user: u}).WebAuthnID()
+
+	t.Run("can only be used once", func(t *testing.T) {
+		err := saveWebAuthnSession("login", &webauthn.SessionData{Challenge: "challenge", UserID: userID}, true)
+		assert.NoError(t, err)
+
+		session, err := getWebAuthnSession("login", u, "challenge")
+		assert.NoError(t, err)
+		assert.Equal(t, "challenge", session.Session.Challenge)
+		assert.True(t, session.Passwordless)
+
+		_, err = getWebAuthnSession("login", u, "challenge")
+		assert.Error(t, err)
+		assert.True(t, IsErrWebAuthnSessionExpired(err))
+	})
+	t.Run("not started", func(t *testing.T) {
+		_, err := getWebAuthnSession("registration", &User{ID: 2}, "challenge")
+		assert.Error(t, err)
+		assert.True(t, IsErrWebAuthnSessionExpired(err))
+	})
+	t.Run("a new login does not replace a pending one", func(t *testing.T) {
+		err := saveWebAuthnSession("login", &webauthn.SessionData{Challenge: "first", UserID: userID}, false)
+		assert.NoError(t, err)
+		err = saveWebAuthnSession("login", &webauthn.SessionData{Challenge: "second", UserID: userID}, false)
+		assert.NoError(t, err)
+
+		session, err := getWebAuthnSession("login", u, "first")
+		assert.NoError(t, err)
+		assert.Equal(t, "first", session.Session.Challenge)
+	})
+	t.Run("of another user", func(t *testing.T) {
+		err := saveWebAuthnSession("login", &webauthn.SessionData{Challenge: "other", UserID: userID}, false)
+		assert.NoError(t, err)
+
+		_, err = getWebAuthnSession("login", &User{ID: 2}, "other")
+		assert.Error(t, err)
+		assert.True(t, IsErrWebAuthnSessionExpired(err))
+	})
+	t.Run("can only be used once in parallel", func(t *testing.T) {
+		err := saveWebAuthnSession("login", &webauthn.SessionData{Challenge: "parallel", UserID: userID}, false)
+		assert.NoError(t, err)
+
+		var used int64
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := getWebAuthnSession("login", u, "parallel"); err == nil {
+					atomic.AddInt64(&used, 1)
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(1), used)
+	})
+	t.Run("removed from the store once expired", func(t *testing.T) {
+		key := webAuthnSessionKey("login", "expired")
+		err := keyvalue.PutWithExpiration(key, webAuthnSession{
+			Session: webauthn.SessionData{Challenge: "expired", UserID: userID},
+			Expires: time.Now().Add(webAuthnSessionTimeout),
+		}, time.Millisecond)
+		assert.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		exists, err := keyvalue.GetWithValue(key, &webAuthnSession{})
+		assert.NoError(t, err)
+		assert.False(t, exists)
+		_, err = getWebAuthnSession("login", u, "expired")
+		assert.Error(t, err)
+		assert.True(t, IsErrWebAuthnSessionExpired(err))
+	})
+}
@@ -17,6 +17,7 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
 	"math/rand"
 	"time"
 )
@@ -50,3 +51,23 @@ func MakeRandomString(n int) string {
 
 	return string(b)
 }
+
+// MakeSecureRandomString returns a random string generated with a cryptographically secure random source.
+// Use this for secrets like tokens which must not be guessable.
+func MakeSecureRandomString(n int) (string, error) {
+	b := make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(b) < n {
+		if _, err := cryptorand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, r := range buf {
+			// Only use the lower bits and discard values outside the alphabet to not favor any letters
+			if idx := int(r & letterIdxMask); idx < len(letterBytes) && len(b) < n {
+				b = append(b, letterBytes[idx])
+			}
+		}
+	}
+
+	return string(b), nil
+}
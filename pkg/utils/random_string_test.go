@@ -27,3 +27,10 @@ func TestMakeRandomString(t *testing.T) {
 	assert.NotEqual(t, rand, "loremipsuim")
 	assert.Equal(t, len(rand), 32)
 }
+
+func TestMakeSecureRandomString(t *testing.T) {
+	rand, err := MakeSecureRandomString(32)
+	assert.NoError(t, err)
+	assert.NotEqual(t, rand, "loremipsuim")
+	assert.Equal(t, len(rand), 32)
+}